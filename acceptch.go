@@ -0,0 +1,245 @@
+package mimic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// maxCriticalCHDrain bounds how much of a response retried for Critical-CH is
+// read so its connection can carry the retry.
+const maxCriticalCHDrain = 64 << 10
+
+// ClientHintStore remembers which client hints each origin asked for with the
+// Accept-CH header. Implement it to keep grants somewhere other than memory;
+// ClientHintCache keeps them in memory and can be saved across restarts.
+type ClientHintStore interface {
+	// Hints returns the hints origin asked for, lowercased. Origins are
+	// scheme, host, and port, such as "https://example.com:443".
+	Hints(origin string) []string
+
+	// SetHints replaces the hints origin asked for. An empty list forgets the
+	// origin.
+	SetHints(origin string, hints []string)
+}
+
+// ClientHintCache is a ClientHintStore that keeps grants in memory. Save and
+// Load carry it across restarts, as a browser profile does.
+// The zero value is an empty cache ready to use.
+type ClientHintCache struct {
+	// TTL is how long an origin's grant lasts after its last Accept-CH header.
+	// Zero keeps grants until they are cleared, as Chrome keeps them until the
+	// user clears the site's data.
+	TTL time.Duration
+
+	mu     sync.Mutex
+	grants map[string]clientHintGrant
+}
+
+type clientHintGrant struct {
+	Hints   []string  `json:"hints"`
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+// WithClientHintStore sends the high-entropy client hints an origin asked for
+// with Accept-CH on later requests to it, and records the Accept-CH headers of
+// navigation responses in store. A navigation response whose Critical-CH header
+// names a granted hint the request did not carry is discarded and the request
+// sent again, once, with the hint. As in Chrome, the first request to an origin
+// carries only the low-entropy hints, grants are only taken from HTTPS
+// responses, and subresource requests carry the hints their page's origin was
+// granted, only to that origin.
+//
+// Hints the spec has no value for, such as every hint for Firefox and Safari
+// specs, are not sent.
+func WithClientHintStore(store ClientHintStore) TransportOption {
+	return func(c *transportConfig) {
+		c.clientHints = store
+	}
+}
+
+// Hints returns the hints origin was granted, if its grant has not expired.
+func (c *ClientHintCache) Hints(origin string) []string {
+	origin = normalizeOrigin(origin)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	grant, ok := c.grants[origin]
+	if !ok {
+		return nil
+	}
+	if !grant.Expires.IsZero() && !time.Now().Before(grant.Expires) {
+		delete(c.grants, origin)
+		return nil
+	}
+	return slices.Clone(grant.Hints)
+}
+
+// SetHints replaces the hints origin is granted, starting its TTL.
+func (c *ClientHintCache) SetHints(origin string, hints []string) {
+	origin = normalizeOrigin(origin)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(hints) == 0 {
+		delete(c.grants, origin)
+		return
+	}
+
+	grant := clientHintGrant{Hints: slices.Clone(hints)}
+	if c.TTL > 0 {
+		grant.Expires = time.Now().Add(c.TTL)
+	}
+	if c.grants == nil {
+		c.grants = make(map[string]clientHintGrant)
+	}
+	c.grants[origin] = grant
+}
+
+// Clear forgets the hints origin was granted.
+func (c *ClientHintCache) Clear(origin string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.grants, normalizeOrigin(origin))
+}
+
+// Save writes the unexpired grants to w as JSON.
+func (c *ClientHintCache) Save(w io.Writer) error {
+	c.mu.Lock()
+	grants := make(map[string]clientHintGrant, len(c.grants))
+	now := time.Now()
+	for origin, grant := range c.grants {
+		if grant.Expires.IsZero() || now.Before(grant.Expires) {
+			grants[origin] = grant
+		}
+	}
+	c.mu.Unlock()
+
+	if err := json.NewEncoder(w).Encode(grants); err != nil {
+		return fmt.Errorf("encoding client hint grants: %w", err)
+	}
+	return nil
+}
+
+// Load adds the grants written by Save to the cache, replacing grants it holds
+// for the same origins. Grants that expired since they were saved are skipped;
+// the rest keep their saved expiry.
+func (c *ClientHintCache) Load(r io.Reader) error {
+	var grants map[string]clientHintGrant
+	if err := json.NewDecoder(r).Decode(&grants); err != nil {
+		return fmt.Errorf("decoding client hint grants: %w", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.grants == nil {
+		c.grants = make(map[string]clientHintGrant, len(grants))
+	}
+	now := time.Now()
+	for origin, grant := range grants {
+		if len(grant.Hints) == 0 || !grant.Expires.IsZero() && !now.Before(grant.Expires) {
+			continue
+		}
+		c.grants[normalizeOrigin(origin)] = grant
+	}
+	return nil
+}
+
+// ParseAcceptCH parses an Accept-CH header value into the hints it names,
+// lowercased and without duplicates.
+func ParseAcceptCH(value string) []string {
+	var hints []string
+	for _, token := range strings.Split(value, ",") {
+		hint := strings.ToLower(strings.TrimSpace(token))
+		if hint != "" && !slices.Contains(hints, hint) {
+			hints = append(hints, hint)
+		}
+	}
+	return hints
+}
+
+// recordAcceptCH updates store from the Accept-CH header of a navigation
+// response. An empty header withdraws the origin's grant; a missing one keeps
+// it.
+func recordAcceptCH(store ClientHintStore, req *http.Request, res *http.Response) {
+	values, ok := res.Header["Accept-Ch"]
+	if !ok || res.TLS == nil || res.Request == nil || !isNavigation(req) {
+		return
+	}
+	store.SetHints(altSvcOrigin(res.Request.URL), ParseAcceptCH(strings.Join(values, ",")))
+}
+
+// addClientHints adds the hints granted to the origin of the page sending a
+// request for target to header, leaving hints the request sets alone.
+func (t *Transport) addClientHints(header http.Header, target *url.URL, intent *fetchIntent) {
+	if target.Scheme != "https" || len(t.highEntropyHints) == 0 {
+		return
+	}
+
+	page := altSvcOrigin(target)
+	if intent != nil && intent.mode != ModeNavigate && intent.initiator != nil {
+		// hints are delegated to other origins only by Permissions-Policy,
+		// which mimic does not see
+		if altSvcOrigin(intent.initiator) != page {
+			return
+		}
+	}
+
+	for _, hint := range t.clientHints.Hints(page) {
+		key := http.CanonicalHeaderKey(hint)
+		value, ok := t.highEntropyHints[key]
+		if !ok {
+			continue
+		}
+		if existing := header[key]; len(existing) > 0 && existing[0] != "" {
+			continue
+		}
+		header[key] = value[:len(value):len(value)]
+	}
+}
+
+// missedCriticalHint reports whether res, the response to a request sent with
+// header, names a critical hint its Accept-CH grants that t has a value for
+// but header lacks. Only the first navigation response is checked.
+func (t *Transport) missedCriticalHint(req *http.Request, header http.Header, res *http.Response) bool {
+	critical := res.Header.Get("Critical-CH")
+	if critical == "" || res.TLS == nil || !isNavigation(req) || req.Context().Value(criticalCHRetryKey) != nil {
+		return false
+	}
+
+	granted := ParseAcceptCH(strings.Join(res.Header["Accept-Ch"], ","))
+	for _, hint := range ParseAcceptCH(critical) {
+		key := http.CanonicalHeaderKey(hint)
+		if _, ok := t.highEntropyHints[key]; !ok || !slices.Contains(granted, hint) {
+			continue
+		}
+		if len(header[key]) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// retryCriticalCH discards res and sends retry, a replay of its request, now
+// that its hints are granted. The response is drained first so the retry can
+// reuse its connection.
+func (t *Transport) retryCriticalCH(retry *http.Request, res *http.Response) (*http.Response, error) {
+	if res.Body != nil {
+		io.Copy(io.Discard, io.LimitReader(res.Body, maxCriticalCHDrain))
+		res.Body.Close()
+	}
+
+	return t.RoundTrip(retry.WithContext(context.WithValue(retry.Context(), criticalCHRetryKey, true)))
+}
@@ -0,0 +1,181 @@
+package mimic
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// acceptCHRoundTripper answers HTTPS requests with an Accept-CH header.
+type acceptCHRoundTripper struct {
+	acceptCH string
+}
+
+func (rt acceptCHRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req}
+	if req.URL.Scheme == "https" {
+		res.TLS = &utls.ConnectionState{}
+	}
+	res.Header.Set("Accept-CH", rt.acceptCH)
+	return res, nil
+}
+
+func TestParseAcceptCH(t *testing.T) {
+	got := ParseAcceptCH(" Sec-CH-UA-Arch,sec-ch-ua-bitness, , sec-ch-ua-arch")
+	want := []string{"sec-ch-ua-arch", "sec-ch-ua-bitness"}
+	if !slices.Equal(got, want) {
+		t.Errorf("want %q; got %q", want, got)
+	}
+}
+
+func TestClientHintCache(t *testing.T) {
+	var cache ClientHintCache
+	cache.SetHints("https://example.com", []string{"sec-ch-ua-arch"})
+
+	if got := cache.Hints("https://EXAMPLE.com:443"); !slices.Equal(got, []string{"sec-ch-ua-arch"}) {
+		t.Errorf("want the grant under the normalized origin; got %q", got)
+	}
+
+	var saved bytes.Buffer
+	if err := cache.Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+
+	var restored ClientHintCache
+	if err := restored.Load(&saved); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Hints("https://example.com"); !slices.Equal(got, []string{"sec-ch-ua-arch"}) {
+		t.Errorf("want the grant restored; got %q", got)
+	}
+
+	restored.SetHints("https://example.com", nil)
+	if got := restored.Hints("https://example.com"); got != nil {
+		t.Errorf("want an empty list to forget the origin; got %q", got)
+	}
+
+	expiring := ClientHintCache{TTL: time.Nanosecond}
+	expiring.SetHints("https://example.com", []string{"sec-ch-ua-arch"})
+	time.Sleep(time.Millisecond)
+	if got := expiring.Hints("https://example.com"); got != nil {
+		t.Errorf("want the grant expired; got %q", got)
+	}
+}
+
+func TestRoundTripClientHints(t *testing.T) {
+	var cache ClientHintCache
+
+	tr := newTestTransport(t)
+	tr.clientHints = &cache
+	tr.transport = acceptCHRoundTripper{acceptCH: "Sec-CH-UA-Arch, Sec-CH-UA-Bitness, Sec-CH-Unknown"}
+
+	send := func(rawURL, mode string) http.Header {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode != "" {
+			req.Header.Set("Sec-Fetch-Mode", mode)
+		}
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Request.Header
+	}
+
+	if h := send("https://example.com/", ""); h.Get("Sec-CH-UA-Arch") != "" {
+		t.Error("want no high-entropy hints before the origin asks for them")
+	}
+
+	h := send("https://example.com/page", "")
+	if h.Get("Sec-CH-UA-Arch") != `"x86"` || h.Get("Sec-CH-UA-Bitness") != `"64"` {
+		t.Errorf("want the granted hints; got arch %q, bitness %q", h.Get("Sec-CH-UA-Arch"), h.Get("Sec-CH-UA-Bitness"))
+	}
+	if h.Get("Sec-CH-UA-Wow64") != "" || h.Get("Sec-CH-Unknown") != "" {
+		t.Error("want only granted hints the spec has values for")
+	}
+
+	if h := send("https://other.example/", ""); h.Get("Sec-CH-UA-Arch") != "" {
+		t.Error("want no hints for an origin that was not granted them")
+	}
+
+	send("http://plain.example/", "")
+	if cache.Hints("http://plain.example") != nil {
+		t.Error("want Accept-CH ignored on plaintext responses")
+	}
+
+	send("https://cors.example/", "cors")
+	if cache.Hints("https://cors.example") != nil {
+		t.Error("want Accept-CH ignored on subresource responses")
+	}
+}
+
+// criticalCHRoundTripper demands sec-ch-ua-arch as a critical hint, recording
+// the requests it answers.
+type criticalCHRoundTripper struct {
+	sent []*http.Request
+}
+
+func (rt *criticalCHRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.sent = append(rt.sent, req)
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: http.NoBody, Request: req, TLS: &utls.ConnectionState{}}
+	res.Header.Set("Accept-CH", "Sec-CH-UA-Arch, Sec-CH-Unknown")
+	res.Header.Set("Critical-CH", "Sec-CH-UA-Arch, Sec-CH-Unknown")
+	return res, nil
+}
+
+func TestRoundTripCriticalCH(t *testing.T) {
+	rt := &criticalCHRoundTripper{}
+
+	tr := newTestTransport(t)
+	tr.clientHints = &ClientHintCache{}
+	tr.transport = rt
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rt.sent) != 2 {
+		t.Fatalf("want the request retried once; got %d requests", len(rt.sent))
+	}
+	if rt.sent[0].Header.Get("Sec-CH-UA-Arch") != "" || rt.sent[1].Header.Get("Sec-CH-UA-Arch") == "" {
+		t.Error("want the hint only on the retry")
+	}
+	if res.Request != rt.sent[1] {
+		t.Error("want the retry's response returned")
+	}
+
+	// the hint is granted now, and the spec has no value for the other
+	if _, err := tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.sent) != 3 {
+		t.Errorf("want no retry once the critical hints are sent; got %d requests", len(rt.sent))
+	}
+
+	cors, err := http.NewRequest(http.MethodGet, "https://other.example/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cors.Header.Set("Sec-Fetch-Mode", "cors")
+	if _, err := tr.RoundTrip(cors); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.sent) != 4 {
+		t.Errorf("want subresources not retried; got %d requests", len(rt.sent))
+	}
+}
@@ -0,0 +1,76 @@
+package mimic
+
+import (
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+// ALPSMode selects whether the ClientHello carries the ALPS (application
+// settings) extension and under which codepoint. Chromium uses ALPS to receive
+// accept-ch and other settings with the handshake.
+type ALPSMode int
+
+const (
+	// ALPSAuto sends ALPS as the claimed browser version does: codepoint 17513
+	// for Chromium up to 132, 17613 from 133, and not at all for Firefox and
+	// Safari.
+	ALPSAuto ALPSMode = iota
+
+	// ALPSDisabled omits ALPS, as Chromium does when its ALPS feature is turned
+	// off by a field trial or an embedder.
+	ALPSDisabled
+
+	// ALPSLegacy sends ALPS under the original codepoint 17513.
+	ALPSLegacy
+
+	// ALPSNew sends ALPS under codepoint 17613, which Chromium moved to in 133
+	// after the draft changed the extension's encoding.
+	ALPSNew
+)
+
+// WithALPS overrides whether and how a browser that sends ALPS sends it. It
+// is ignored for browsers whose hello has no ALPS extension. Sending the
+// codepoint that does not match the claimed Chromium version is detectable,
+// so only use ALPSLegacy and ALPSNew to reproduce a client that does.
+func WithALPS(mode ALPSMode) SpecOption {
+	return func(c *specConfig) {
+		if mode == ALPSAuto {
+			return
+		}
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			setALPS(spec, mode)
+			return nil
+		})
+	}
+}
+
+// setALPS rewrites the ALPS extension in spec, keeping its position and
+// protocols.
+func setALPS(spec *utls.ClientHelloSpec, mode ALPSMode) {
+	i := slices.IndexFunc(spec.Extensions, func(ext utls.TLSExtension) bool {
+		id := extensionID(ext)
+		return id == dicttls.ExtType_application_settings || id == dicttls.ExtType_application_settings_new
+	})
+	if i < 0 {
+		return
+	}
+
+	var protocols []string
+	switch ext := spec.Extensions[i].(type) {
+	case *utls.ApplicationSettingsExtension:
+		protocols = ext.SupportedProtocols
+	case *utls.ApplicationSettingsExtensionNew:
+		protocols = ext.SupportedProtocols
+	}
+
+	switch mode {
+	case ALPSDisabled:
+		spec.Extensions = slices.Delete(spec.Extensions, i, i+1)
+	case ALPSLegacy:
+		spec.Extensions[i] = &utls.ApplicationSettingsExtension{SupportedProtocols: protocols}
+	case ALPSNew:
+		spec.Extensions[i] = &utls.ApplicationSettingsExtensionNew{SupportedProtocols: protocols}
+	}
+}
@@ -0,0 +1,49 @@
+package mimic
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+func TestWithALPS(t *testing.T) {
+	tests := []struct {
+		version string
+		mode    ALPSMode
+		want    []uint16
+	}{
+		{"120.0.0.0", ALPSAuto, []uint16{dicttls.ExtType_application_settings}},
+		{"137.0.0.0", ALPSAuto, []uint16{dicttls.ExtType_application_settings_new}},
+		{"120.0.0.0", ALPSNew, []uint16{dicttls.ExtType_application_settings_new}},
+		{"137.0.0.0", ALPSLegacy, []uint16{dicttls.ExtType_application_settings}},
+		{"137.0.0.0", ALPSDisabled, nil},
+	}
+
+	for _, tt := range tests {
+		spec, err := Chromium(BrandChrome, tt.version, WithALPS(tt.mode))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		helloSpec, err := spec.ClientHelloSpec(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []uint16
+		for _, ext := range helloSpec.Extensions {
+			if id := extensionID(ext); id == dicttls.ExtType_application_settings || id == dicttls.ExtType_application_settings_new {
+				got = append(got, id)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("chrome %s mode %d: want ALPS %v; got %v", tt.version, tt.mode, tt.want, got)
+		}
+	}
+
+	// browsers without ALPS are left alone
+	if _, err := Firefox("133.0", WithALPS(ALPSNew)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,276 @@
+package mimic
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// defaultAltSvcMaxAge is how long an alternative is fresh when the header has
+// no ma parameter (RFC 7838, section 3.1).
+const defaultAltSvcMaxAge = 24 * time.Hour
+
+// AltSvc is an alternative service a server advertised with the Alt-Svc header.
+type AltSvc struct {
+	// Protocol is the ALPN protocol ID of the alternative, such as "h3".
+	Protocol string
+
+	// Host is the alternative host. It is empty when the alternative is on the
+	// same host as the origin.
+	Host string
+
+	// Port is the alternative port.
+	Port int
+
+	// Expires is when the alternative stops being fresh, from the ma parameter.
+	Expires time.Time
+
+	// Persist reports whether the alternative should survive network changes.
+	Persist bool
+}
+
+// ParseAltSvc parses an Alt-Svc header value received at now. It reports
+// clearAll when the value is "clear", which withdraws all alternatives for the
+// origin.
+func ParseAltSvc(value string, now time.Time) (services []AltSvc, clearAll bool, err error) {
+	value = strings.TrimSpace(value)
+	if value == "clear" {
+		return nil, true, nil
+	}
+
+	for _, entry := range splitQuoted(value, ',') {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		svc, err := parseAltSvcEntry(entry, now)
+		if err != nil {
+			return nil, false, fmt.Errorf("parsing alt-svc %q: %w", entry, err)
+		}
+		services = append(services, svc)
+	}
+
+	return services, false, nil
+}
+
+func parseAltSvcEntry(entry string, now time.Time) (AltSvc, error) {
+	params := splitQuoted(entry, ';')
+
+	protocol, authority, ok := strings.Cut(params[0], "=")
+	if !ok {
+		return AltSvc{}, errors.New("missing alt-authority")
+	}
+
+	protocol, err := url.PathUnescape(strings.TrimSpace(protocol))
+	if err != nil {
+		return AltSvc{}, fmt.Errorf("decoding protocol id: %w", err)
+	}
+
+	authority, err = strconv.Unquote(strings.TrimSpace(authority))
+	if err != nil {
+		return AltSvc{}, fmt.Errorf("unquoting alt-authority: %w", err)
+	}
+
+	host, portStr, err := net.SplitHostPort(authority)
+	if err != nil {
+		return AltSvc{}, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return AltSvc{}, fmt.Errorf("invalid port %q", portStr)
+	}
+
+	svc := AltSvc{
+		Protocol: protocol,
+		Host:     host,
+		Port:     port,
+		Expires:  now.Add(defaultAltSvcMaxAge),
+	}
+
+	for _, param := range params[1:] {
+		name, val, _ := strings.Cut(param, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+
+		switch name {
+		case "ma":
+			seconds, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || seconds < 0 {
+				return AltSvc{}, fmt.Errorf("invalid ma %q", val)
+			}
+			svc.Expires = now.Add(time.Duration(seconds) * time.Second)
+		case "persist":
+			svc.Persist = val == "1"
+		}
+	}
+
+	return svc, nil
+}
+
+// splitQuoted splits s at sep, ignoring separators inside double quotes and
+// inside the angle brackets that enclose URLs in Link headers.
+func splitQuoted(s string, sep byte) []string {
+	var (
+		parts     []string
+		start     int
+		quoted    bool
+		bracketed bool
+	)
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quoted {
+				i++
+			}
+		case '"':
+			if !bracketed {
+				quoted = !quoted
+			}
+		case '<':
+			if !quoted {
+				bracketed = true
+			}
+		case '>':
+			if !quoted {
+				bracketed = false
+			}
+		case sep:
+			if !quoted && !bracketed {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// AltSvcCache remembers the alternative services each origin advertised, the
+// way browsers do: a new Alt-Svc header replaces the origin's alternatives,
+// "clear" removes them, and each alternative expires after its ma.
+// The zero value is an empty cache ready to use.
+type AltSvcCache struct {
+	mu      sync.Mutex
+	origins map[string][]AltSvc
+}
+
+// WithAltSvcCache records the Alt-Svc headers of responses received over TLS in
+// cache. Browsers ignore Alt-Svc on plaintext connections, so mimic does too.
+func WithAltSvcCache(cache *AltSvcCache) TransportOption {
+	return func(c *transportConfig) {
+		c.altSvc = cache
+	}
+}
+
+// Get returns the fresh alternatives for origin, such as "https://example.com".
+func (c *AltSvcCache) Get(origin string) []AltSvc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.fresh(normalizeOrigin(origin), time.Now())
+}
+
+// All returns the fresh alternatives for every origin, keyed by scheme, host,
+// and port, such as "https://example.com:443".
+func (c *AltSvcCache) All() map[string][]AltSvc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	all := make(map[string][]AltSvc, len(c.origins))
+	for origin := range c.origins {
+		if services := c.fresh(origin, now); len(services) > 0 {
+			all[origin] = services
+		}
+	}
+	return all
+}
+
+// Clear forgets the alternatives for origin.
+func (c *AltSvcCache) Clear(origin string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.origins, normalizeOrigin(origin))
+}
+
+// fresh returns the unexpired alternatives for origin, dropping expired ones.
+// The caller must hold c.mu.
+func (c *AltSvcCache) fresh(origin string, now time.Time) []AltSvc {
+	var services []AltSvc
+	for _, svc := range c.origins[origin] {
+		if now.Before(svc.Expires) {
+			services = append(services, svc)
+		}
+	}
+
+	if len(services) == 0 {
+		delete(c.origins, origin)
+	} else {
+		c.origins[origin] = services
+	}
+
+	return services
+}
+
+// record updates the cache from a response's Alt-Svc headers. Invalid headers
+// are ignored, as browsers ignore them.
+func (c *AltSvcCache) record(res *http.Response, now time.Time) {
+	values := res.Header.Values("Alt-Svc")
+	if len(values) == 0 || res.TLS == nil || res.Request == nil {
+		return
+	}
+
+	services, clearAll, err := ParseAltSvc(strings.Join(values, ","), now)
+	if err != nil {
+		return
+	}
+
+	origin := altSvcOrigin(res.Request.URL)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if clearAll {
+		delete(c.origins, origin)
+		return
+	}
+
+	if c.origins == nil {
+		c.origins = make(map[string][]AltSvc)
+	}
+	c.origins[origin] = services
+}
+
+// altSvcOrigin returns the scheme, host, and port of u, with the default port
+// filled in.
+func altSvcOrigin(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return u.Scheme + "://" + net.JoinHostPort(strings.ToLower(u.Hostname()), port)
+}
+
+// normalizeOrigin returns origin in the form the cache is keyed by, or origin
+// unchanged if it does not parse as a URL.
+func normalizeOrigin(origin string) string {
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return origin
+	}
+	return altSvcOrigin(u)
+}
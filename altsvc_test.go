@@ -0,0 +1,80 @@
+package mimic
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestParseAltSvc(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	services, clearAll, err := ParseAltSvc(`h3=":443"; ma=86400, h3-29="alt.example.com:8443"; ma=60; persist=1, h2="a,b.example.com:443"`, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clearAll {
+		t.Fatal("want clearAll false")
+	}
+
+	want := []AltSvc{
+		{Protocol: "h3", Port: 443, Expires: now.Add(24 * time.Hour)},
+		{Protocol: "h3-29", Host: "alt.example.com", Port: 8443, Expires: now.Add(time.Minute), Persist: true},
+		{Protocol: "h2", Host: "a,b.example.com", Port: 443, Expires: now.Add(24 * time.Hour)},
+	}
+	if len(services) != len(want) {
+		t.Fatalf("want %d services; got %d: %+v", len(want), len(services), services)
+	}
+	for i := range want {
+		if services[i] != want[i] {
+			t.Errorf("service %d: want %+v; got %+v", i, want[i], services[i])
+		}
+	}
+
+	if _, clearAll, err := ParseAltSvc("clear", now); err != nil || !clearAll {
+		t.Errorf("clear: want clearAll; got %t, %v", clearAll, err)
+	}
+
+	for _, value := range []string{`h3`, `h3=443`, `h3=":0"`, `h3=":443"; ma=soon`} {
+		if _, _, err := ParseAltSvc(value, now); err == nil {
+			t.Errorf("%s: want error", value)
+		}
+	}
+}
+
+func TestAltSvcCache(t *testing.T) {
+	var cache AltSvcCache
+
+	respond := func(value string) {
+		u, _ := url.Parse("https://Example.com/path")
+		cache.record(&http.Response{
+			Header:  http.Header{"Alt-Svc": {value}},
+			TLS:     &utls.ConnectionState{},
+			Request: &http.Request{URL: u},
+		}, time.Now())
+	}
+
+	respond(`h3=":443"; ma=3600, h3-29=":443"; ma=0`)
+
+	got := cache.Get("https://example.com")
+	if len(got) != 1 || got[0].Protocol != "h3" {
+		t.Fatalf("want only the unexpired h3 alternative; got %+v", got)
+	}
+
+	if all := cache.All(); len(all["https://example.com:443"]) != 1 {
+		t.Errorf("want All keyed by origin with port; got %+v", all)
+	}
+
+	respond(`h2="alt.example.com:443"`)
+	if got := cache.Get("https://example.com:443"); len(got) != 1 || got[0].Protocol != "h2" {
+		t.Errorf("want a new header to replace alternatives; got %+v", got)
+	}
+
+	respond("clear")
+	if got := cache.Get("https://example.com"); len(got) != 0 {
+		t.Errorf("want clear to remove alternatives; got %+v", got)
+	}
+}
@@ -0,0 +1,215 @@
+package mimic
+
+import (
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// defaultPorts are the ports browsers leave out of :authority and Host.
+var defaultPorts = map[string]int{"http": 80, "https": 443, "ws": 80, "wss": 443}
+
+// browserIDNA is the UTS #46 profile of the URL Standard's domain to ASCII:
+// nontransitional, so "ß" stays distinct from "ss", with the bidi and joiner
+// checks but without STD3 rules, hyphen checks, or DNS length limits.
+var browserIDNA = idna.New(
+	idna.MapForLookup(),
+	idna.Transitional(false),
+	idna.BidiRule(),
+	idna.CheckJoiners(true),
+	idna.CheckHyphens(false),
+	idna.StrictDomainName(false),
+	idna.VerifyDNSLength(false),
+)
+
+// browserURL returns u with its host serialized the way browsers serialize it,
+// which is what they send in :authority and Host. u is returned unchanged when
+// its host is already in that form.
+func browserURL(u *url.URL) *url.URL {
+	host := browserHost(u.Host, u.Scheme)
+	if host == u.Host {
+		return u
+	}
+
+	normalized := *u
+	normalized.Host = host
+	return &normalized
+}
+
+// browserHost serializes a host[:port] per the URL Standard:
+//   - domains are lowercased and internationalized ones converted to
+//     punycode with browserIDNA, so DNS, SNI, and :authority all see the ASCII
+//     form; a trailing dot is kept
+//   - IPv4 addresses in shorthand, octal, or hex forms become dotted decimal
+//   - IPv6 literals are lowercased with the longest run of zeros compressed,
+//     never in the dotted form Go uses for IPv4-mapped addresses
+//   - the port is dropped when it is the scheme's default, and loses leading zeros
+//
+// Hosts browsers would reject are returned unchanged.
+func browserHost(hostport, scheme string) string {
+	host, port := splitHostPort(hostport)
+
+	if port != "" {
+		n, err := strconv.ParseUint(port, 10, 16)
+		if err != nil {
+			return hostport
+		}
+		if int(n) == defaultPorts[scheme] {
+			port = ""
+		} else {
+			port = strconv.FormatUint(n, 10)
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(host, "["):
+		addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(host, "["), "]"))
+		if err != nil || !addr.Is6() || addr.Zone() != "" {
+			return hostport
+		}
+		host = "[" + serializeIPv6(addr) + "]"
+	default:
+		ascii, err := domainToASCII(host)
+		if err != nil {
+			return hostport
+		}
+		host = ascii
+		if ip, ok, valid := parseIPv4(host); !valid {
+			return hostport
+		} else if ok {
+			host = ip.String()
+		}
+	}
+
+	if port == "" {
+		return host
+	}
+	return host + ":" + port
+}
+
+// domainToASCII lowercases an ASCII domain and converts any other to punycode.
+func domainToASCII(host string) (string, error) {
+	for i := range len(host) {
+		if host[i] >= 0x80 {
+			return browserIDNA.ToASCII(host)
+		}
+	}
+	return strings.ToLower(host), nil
+}
+
+// splitHostPort splits hostport at its port, if it has one. Unlike
+// net.SplitHostPort it accepts hosts without a port and keeps IPv6 brackets.
+func splitHostPort(hostport string) (host, port string) {
+	i := strings.LastIndexByte(hostport, ':')
+	if i < 0 || strings.LastIndexByte(hostport, ']') > i {
+		return hostport, ""
+	}
+	return hostport[:i], hostport[i+1:]
+}
+
+// serializeIPv6 writes addr as the URL Standard does: lowercase hex pieces
+// with the first longest run of two or more zero pieces replaced by "::".
+func serializeIPv6(addr netip.Addr) string {
+	b := addr.As16()
+	var pieces [8]uint16
+	for i := range pieces {
+		pieces[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+
+	start, length := -1, 1
+	for i := 0; i < len(pieces); {
+		if pieces[i] != 0 {
+			i++
+			continue
+		}
+		j := i
+		for j < len(pieces) && pieces[j] == 0 {
+			j++
+		}
+		if j-i > length {
+			start, length = i, j-i
+		}
+		i = j
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(pieces); i++ {
+		if i == start {
+			sb.WriteString("::")
+			i += length - 1
+			continue
+		}
+		if i > 0 && i != start+length {
+			sb.WriteByte(':')
+		}
+		sb.WriteString(strconv.FormatUint(uint64(pieces[i]), 16))
+	}
+	return sb.String()
+}
+
+// parseIPv4 parses host with the URL Standard's IPv4 parser. ok reports whether
+// host is an IPv4 address, which it is when its last label is a number; valid
+// is false when it looks like one but does not parse, which browsers reject.
+func parseIPv4(host string) (addr netip.Addr, ok, valid bool) {
+	labels := strings.Split(host, ".")
+	if labels[len(labels)-1] == "" && len(labels) > 1 {
+		labels = labels[:len(labels)-1]
+	}
+
+	if _, err := parseIPv4Number(labels[len(labels)-1]); err != nil {
+		return netip.Addr{}, false, true
+	}
+	if len(labels) > 4 {
+		return netip.Addr{}, false, false
+	}
+
+	numbers := make([]uint64, len(labels))
+	for i, label := range labels {
+		n, err := parseIPv4Number(label)
+		if err != nil {
+			return netip.Addr{}, false, false
+		}
+		numbers[i] = n
+	}
+
+	last := len(numbers) - 1
+	for _, n := range numbers[:last] {
+		if n > 255 {
+			return netip.Addr{}, false, false
+		}
+	}
+	if numbers[last] >= 1<<(8*(5-len(numbers))) {
+		return netip.Addr{}, false, false
+	}
+
+	ipv4 := numbers[last]
+	for i, n := range numbers[:last] {
+		ipv4 += n << (8 * (3 - i))
+	}
+
+	return netip.AddrFrom4([4]byte{byte(ipv4 >> 24), byte(ipv4 >> 16), byte(ipv4 >> 8), byte(ipv4)}), true, true
+}
+
+// parseIPv4Number parses one label of an IPv4 address: decimal, octal with a
+// leading 0, or hex with a leading 0x.
+func parseIPv4Number(label string) (uint64, error) {
+	if label == "" {
+		return 0, strconv.ErrSyntax
+	}
+
+	base := 10
+	switch {
+	case len(label) >= 2 && (label[:2] == "0x" || label[:2] == "0X"):
+		label, base = label[2:], 16
+		if label == "" {
+			return 0, nil
+		}
+	case len(label) >= 2 && label[0] == '0':
+		label, base = label[1:], 8
+	}
+
+	return strconv.ParseUint(label, base, 64)
+}
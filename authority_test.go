@@ -0,0 +1,68 @@
+package mimic
+
+import (
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestBrowserHost(t *testing.T) {
+	tests := []struct {
+		host   string
+		scheme string
+		want   string
+	}{
+		{"Example.COM", "https", "example.com"},
+		{"example.com:443", "https", "example.com"},
+		{"example.com:0443", "https", "example.com"},
+		{"example.com:80", "https", "example.com:80"},
+		{"example.com:080", "http", "example.com"},
+		{"example.com.:8443", "https", "example.com.:8443"},
+		{"XN--BCHER-KVA.example", "https", "xn--bcher-kva.example"},
+		{"Bücher.example:8443", "https", "xn--bcher-kva.example:8443"},
+		{"faß.de", "https", "xn--fa-hia.de"},
+		{"ＥＸＡＭＰＬＥ.com", "https", "example.com"},
+		{"例え.テスト.", "https", "xn--r8jz45g.xn--zckzah."},
+		{"a\u200cb.example", "https", "a\u200cb.example"},
+		{"[2001:DB8:0:0:0:0:0:1]:443", "https", "[2001:db8::1]"},
+		{"[0:0:1:0:0:0:0:0]", "https", "[0:0:1::]"},
+		{"[::ffff:192.0.2.1]:8080", "https", "[::ffff:c000:201]:8080"},
+		{"[1:0:0:2:0:0:0:3]", "https", "[1:0:0:2::3]"},
+		{"0x7f.1", "http", "127.0.0.1"},
+		{"0177.0.0.1:8080", "http", "127.0.0.1:8080"},
+		{"3232235777", "https", "192.168.1.1"},
+		{"example.256", "https", "example.256"},
+		{"example.com:99999", "https", "example.com:99999"},
+	}
+
+	for _, tt := range tests {
+		if got := browserHost(tt.host, tt.scheme); got != tt.want {
+			t.Errorf("browserHost(%q, %q) = %q; want %q", tt.host, tt.scheme, got, tt.want)
+		}
+	}
+}
+
+func TestRoundTripAuthority(t *testing.T) {
+	tr := newTestTransport(t)
+
+	req, err := http.NewRequest(http.MethodGet, "https://WWW.Example.com:443/path", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Host = "Other.example:0443"
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := res.Request.URL.Host; got != "www.example.com" {
+		t.Errorf("want URL host www.example.com; got %q", got)
+	}
+	if got := res.Request.Host; got != "other.example" {
+		t.Errorf("want Host other.example; got %q", got)
+	}
+	if req.URL.Host != "WWW.Example.com:443" {
+		t.Errorf("want the request unmodified; got %q", req.URL.Host)
+	}
+}
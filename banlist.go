@@ -0,0 +1,292 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// anyProxy is the proxy key of a ban that applies however the host is reached.
+const anyProxy = "*"
+
+// BanList remembers hosts that have stopped letting the client through, such
+// as after a challenge page or a 429, and keeps requests away from them for a
+// cooldown. A ban can cover a host however it is reached, or only through one
+// proxy, so a host that banned one exit IP can still be reached through
+// another. Banning a host closes the connections made to it by Transports
+// using the list, failing their in-flight requests.
+//
+// Set Cooldown and Wait before the list is used. The zero value is an empty
+// list ready to use.
+type BanList struct {
+	// Cooldown, when set, bans a host through the proxy a request used when
+	// it answers with 429 Too Many Requests, or with a challenge
+	// DetectChallenge recognizes from the response's status and headers. The
+	// ban lasts Cooldown or the response's Retry-After, whichever is longer,
+	// and starts once the response body is closed, so the response can still
+	// be read.
+	Cooldown time.Duration
+
+	// Wait makes requests to a banned host wait for the ban to end, or for
+	// their context to be done, instead of failing with a BannedError.
+	Wait bool
+
+	mu    sync.Mutex
+	bans  map[string]map[string]time.Time
+	conns map[banTarget]map[*banConn]struct{}
+}
+
+// banTarget is a host as reached through a proxy, "" for none.
+type banTarget struct {
+	proxy string
+	host  string
+}
+
+// WithBanList refuses requests to hosts list bans, failing them with a
+// BannedError or, when list.Wait is set, holding them until the ban ends. It
+// also lets list close the connections it bans and, when list.Cooldown is
+// set, ban hosts that challenge or throttle the client. Connections made by
+// an Engine are not tracked, so banning a host does not close them.
+func WithBanList(list *BanList) TransportOption {
+	return func(c *transportConfig) {
+		c.bans = list
+	}
+}
+
+// Ban bans host for d however it is reached, directly or through any proxy.
+// A ban that already lasts longer is kept.
+func (l *BanList) Ban(host string, d time.Duration) {
+	l.ban(banTarget{proxy: anyProxy, host: normalizeBanHost(host)}, time.Now().Add(d))
+}
+
+// BanVia bans host for d only when it is reached through proxy, or directly
+// when proxy is nil. A ban that already lasts longer is kept.
+func (l *BanList) BanVia(proxy *url.URL, host string, d time.Duration) {
+	l.ban(banTarget{proxy: proxyKey(proxy), host: normalizeBanHost(host)}, time.Now().Add(d))
+}
+
+// Unban lifts every ban on host.
+func (l *BanList) Unban(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.bans, normalizeBanHost(host))
+}
+
+// Banned reports whether host is banned when reached through proxy, or
+// directly when proxy is nil, and until when.
+func (l *BanList) Banned(proxy *url.URL, host string) (until time.Time, ok bool) {
+	return l.bannedUntil(banTarget{proxy: proxyKey(proxy), host: normalizeBanHost(host)}, time.Now())
+}
+
+// ban bans target until the given time and closes its connections.
+func (l *BanList) ban(target banTarget, until time.Time) {
+	l.mu.Lock()
+	if l.bans == nil {
+		l.bans = make(map[string]map[string]time.Time)
+	}
+	byProxy := l.bans[target.host]
+	if byProxy == nil {
+		byProxy = make(map[string]time.Time)
+		l.bans[target.host] = byProxy
+	}
+	if until.After(byProxy[target.proxy]) {
+		byProxy[target.proxy] = until
+	}
+
+	var closing []*banConn
+	for t, conns := range l.conns {
+		if t.host != target.host || target.proxy != anyProxy && t.proxy != target.proxy {
+			continue
+		}
+		for c := range conns {
+			closing = append(closing, c)
+		}
+	}
+	l.mu.Unlock()
+
+	// closing removes each connection from l.conns
+	for _, c := range closing {
+		c.Close()
+	}
+}
+
+// bannedUntil returns when the ban on target ends, if it is banned at now.
+func (l *BanList) bannedUntil(target banTarget, now time.Time) (time.Time, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	byProxy := l.bans[target.host]
+	until := byProxy[anyProxy]
+	if via := byProxy[target.proxy]; via.After(until) {
+		until = via
+	}
+	if !until.After(now) {
+		if until.IsZero() {
+			return time.Time{}, false
+		}
+		// lazily forget bans that have ended
+		for proxy, end := range byProxy {
+			if !end.After(now) {
+				delete(byProxy, proxy)
+			}
+		}
+		if len(byProxy) == 0 {
+			delete(l.bans, target.host)
+		}
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// admit returns nil once a request to target may be sent: immediately if
+// target is not banned, after the ban ends when l.Wait is set, and otherwise
+// never, returning a BannedError.
+func (l *BanList) admit(ctx context.Context, target banTarget) error {
+	for {
+		until, banned := l.bannedUntil(target, time.Now())
+		if !banned {
+			return nil
+		}
+		if !l.Wait {
+			return &BannedError{Host: target.host, Until: until}
+		}
+
+		timer := time.NewTimer(time.Until(until))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// cooldown returns how long to ban the host that sent res, if res challenges
+// or throttles the client and l.Cooldown is set.
+func (l *BanList) cooldown(res *http.Response, now time.Time) (time.Duration, bool) {
+	if l.Cooldown <= 0 {
+		return 0, false
+	}
+	if res.StatusCode != http.StatusTooManyRequests {
+		if _, ok := DetectChallenge(res, nil); !ok {
+			return 0, false
+		}
+	}
+
+	d := l.Cooldown
+	if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), now); ok {
+		d = max(d, retryAfter)
+	}
+	return d, true
+}
+
+// dialContext refuses to dial banned targets and tracks the connections it
+// dials so bans can close them.
+func (l *BanList) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		target, ok := ctx.Value(banTargetKey).(banTarget)
+		if !ok {
+			return dial(ctx, network, addr)
+		}
+
+		if until, banned := l.bannedUntil(target, time.Now()); banned {
+			return nil, &BannedError{Host: target.host, Until: until}
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		c := &banConn{Conn: conn, list: l, target: target}
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if l.conns == nil {
+			l.conns = make(map[banTarget]map[*banConn]struct{})
+		}
+		if l.conns[target] == nil {
+			l.conns[target] = make(map[*banConn]struct{})
+		}
+		l.conns[target][c] = struct{}{}
+
+		return c, nil
+	}
+}
+
+// banConn is a connection a BanList can close, which stops tracking itself
+// when closed.
+type banConn struct {
+	net.Conn
+	list   *BanList
+	target banTarget
+	close  sync.Once
+}
+
+func (c *banConn) Close() error {
+	c.close.Do(func() {
+		c.list.mu.Lock()
+		defer c.list.mu.Unlock()
+
+		delete(c.list.conns[c.target], c)
+		if len(c.list.conns[c.target]) == 0 {
+			delete(c.list.conns, c.target)
+		}
+	})
+	return c.Conn.Close()
+}
+
+// banOnClose bans a target once the body of the response that earned the ban
+// is closed.
+type banOnClose struct {
+	io.ReadCloser
+	list   *BanList
+	target banTarget
+	until  time.Time
+	once   sync.Once
+}
+
+func (b *banOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		b.list.ban(b.target, b.until)
+	})
+	return err
+}
+
+// banTargetFor returns the target of req, which t sends to host.
+func (t *Transport) banTargetFor(req *http.Request, host string) banTarget {
+	var proxy *url.URL
+	if t.base.Proxy != nil {
+		proxy, _ = t.base.Proxy(req)
+	}
+	return banTarget{proxy: proxyKey(proxy), host: normalizeBanHost(host)}
+}
+
+// proxyKey identifies proxy by its scheme, user, and address, since proxy
+// services often pick the exit IP by username.
+func proxyKey(proxy *url.URL) string {
+	if proxy == nil {
+		return ""
+	}
+	key := &url.URL{Scheme: proxy.Scheme, Host: strings.ToLower(proxy.Host)}
+	if proxy.User != nil {
+		key.User = url.User(proxy.User.Username())
+	}
+	return key.String()
+}
+
+func normalizeBanHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
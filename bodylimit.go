@@ -0,0 +1,77 @@
+package mimic
+
+import (
+	"context"
+	"io"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// WithMaxBodySize fails response body reads once the decoded body passes n
+// bytes, so a compressed response that expands to gigabytes cannot exhaust
+// memory. The limit counts decoded bytes, whatever the Content-Encoding.
+// Browsers have no such limit, but the server cannot tell it is there.
+func WithMaxBodySize(n int64) ClientOption {
+	return func(c *clientConfig) {
+		c.maxBodySize = n
+	}
+}
+
+// WithRequestMaxBodySize returns a copy of ctx that gives the request sent
+// with it a body size limit of n bytes in place of the client's. Zero lifts
+// the limit for the request.
+func WithRequestMaxBodySize(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxBodySizeKey, n)
+}
+
+// maxBodySize returns the body size limit for a request with ctx, where n is
+// the client's.
+func maxBodySize(ctx context.Context, n int64) int64 {
+	if limit, ok := ctx.Value(maxBodySizeKey).(int64); ok {
+		return limit
+	}
+	return n
+}
+
+// limitedBody fails reads past limit bytes with a BodyTooLargeError.
+type limitedBody struct {
+	body  io.ReadCloser
+	limit int64
+	read  int64
+}
+
+func newLimitedBody(body io.ReadCloser, limit int64) *limitedBody {
+	return &limitedBody{body: body, limit: limit}
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.read > b.limit {
+		return 0, &BodyTooLargeError{Limit: b.limit}
+	}
+
+	// read one byte past the limit to tell a body of exactly limit bytes
+	// from a longer one
+	if remaining := b.limit - b.read + 1; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := b.body.Read(p)
+	b.read += int64(n)
+	if b.read > b.limit {
+		return n - int(b.read-b.limit), &BodyTooLargeError{Limit: b.limit}
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.body.Close()
+}
+
+// limitBody applies the body size limit for req to res.
+func limitBody(req *http.Request, res *http.Response, limit int64) {
+	limit = maxBodySize(req.Context(), limit)
+	if limit <= 0 || res.Body == nil || res.Body == http.NoBody {
+		return
+	}
+	res.Body = newLimitedBody(res.Body, limit)
+}
@@ -0,0 +1,87 @@
+package mimic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestLimitedBody(t *testing.T) {
+	for _, size := range []int{99, 100} {
+		b := newLimitedBody(io.NopCloser(bytes.NewReader(make([]byte, size))), 100)
+		if got, err := io.ReadAll(b); err != nil || len(got) != size {
+			t.Errorf("%d bytes: got %d, %v, want the whole body", size, len(got), err)
+		}
+	}
+
+	b := newLimitedBody(io.NopCloser(bytes.NewReader(make([]byte, 101))), 100)
+	got, err := io.ReadAll(b)
+	var tooLarge *BodyTooLargeError
+	if !errors.As(err, &tooLarge) || !errors.Is(err, ErrBodyTooLarge) || tooLarge.Limit != 100 {
+		t.Fatalf("want a BodyTooLargeError at 100 bytes; got %v", err)
+	}
+	if len(got) != 100 {
+		t.Errorf("read %d bytes before the error, want 100", len(got))
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	var bomb bytes.Buffer
+	zw := gzip.NewWriter(&bomb)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(bomb.Bytes())
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithMaxBodySize(64<<10),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(res.Body)
+	res.Body.Close()
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("want the decoded body limited; got %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(WithRequestMaxBodySize(context.Background(), 0), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil || len(body) != 1<<20 {
+		t.Errorf("want WithRequestMaxBodySize(0) to lift the limit; got %d bytes, %v", len(body), err)
+	}
+}
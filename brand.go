@@ -0,0 +1,96 @@
+package mimic
+
+import "strings"
+
+// chromiumBrand is how a Chromium derivative names itself.
+type chromiumBrand struct {
+	// hint is the brand in sec-ch-ua
+	hint string
+	// token is the product token the derivative adds to the user agent, with
+	// its own version
+	token string
+	// beforeSafari puts the token before "Safari/537.36" rather than at the
+	// end of the user agent
+	beforeSafari bool
+}
+
+// BrandCustomChromium returns a Brand for a Chromium derivative mimic has no
+// brand for, such as a regional browser. Its user agent ends with uaSuffix, a
+// product token like "OPR/115.0.0.0", and its sec-ch-ua names hintBrand at the
+// major version in uaSuffix, or at Chromium's if uaSuffix has no version. An
+// empty uaSuffix leaves the user agent as Chrome's.
+//
+// The Brand spells out all of it, as "name (uaSuffix; hintBrand)", leaving
+// out what matches name, so it needs no registering and equal Brands mimic
+// the same browser.
+func BrandCustomChromium(name, uaSuffix, hintBrand string) Brand {
+	if uaSuffix == "" && hintBrand == name {
+		return Brand(name)
+	}
+
+	s := name + " (" + uaSuffix
+	if hintBrand != name {
+		s += "; " + hintBrand
+	}
+	return Brand(s + ")")
+}
+
+// BrandWhale returns the Brand for Naver Whale at the given Whale version
+// (e.g., "4.29.282.14"), which is independent of the Chromium version passed to
+// Chromium. Whale puts its token before Safari's in the user agent.
+func BrandWhale(version string) Brand {
+	return BrandCustomChromium("Naver Whale", "Whale/"+version, "Whale")
+}
+
+// chromiumBrandFor returns how brand names itself at the given Chromium
+// version. Brands mimic does not know name themselves in sec-ch-ua only.
+func chromiumBrandFor(brand Brand, version string) chromiumBrand {
+	switch brand {
+	case BrandEdge:
+		return chromiumBrand{hint: string(brand), token: "Edg/" + version}
+	case BrandChrome, BrandBrave:
+		// Brave uses the same user agent as Chrome
+		return chromiumBrand{hint: string(brand)}
+	}
+
+	name, rest, ok := strings.Cut(string(brand), " (")
+	details, closed := strings.CutSuffix(rest, ")")
+	if !ok || !closed {
+		return chromiumBrand{hint: string(brand)}
+	}
+
+	token, hint, ok := strings.Cut(details, "; ")
+	if !ok {
+		hint = name
+	}
+	return chromiumBrand{hint: hint, token: token, beforeSafari: strings.HasPrefix(token, "Whale/")}
+}
+
+// hintVersion returns the version b gives itself in sec-ch-ua: the major
+// version of its token, or chromiumMajor if the token has none.
+func (b chromiumBrand) hintVersion(chromiumMajor string) string {
+	product, _, _ := strings.Cut(b.token, " ")
+	_, version, ok := strings.Cut(product, "/")
+	if !ok {
+		return chromiumMajor
+	}
+
+	major, _, err := parseMajorVersion(version)
+	if err != nil {
+		return chromiumMajor
+	}
+	return major
+}
+
+// userAgent adds b's token to ua, Chrome's user agent.
+func (b chromiumBrand) userAgent(ua string) string {
+	switch {
+	case b.token == "":
+		return ua
+	case b.beforeSafari:
+		if prefix, ok := strings.CutSuffix(ua, " Safari/537.36"); ok {
+			return prefix + " " + b.token + " Safari/537.36"
+		}
+	}
+	return ua + " " + b.token
+}
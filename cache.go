@@ -0,0 +1,553 @@
+package mimic
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// defaultCacheSize is the size of a Cache whose MaxSize is zero.
+const defaultCacheSize = 64 << 20
+
+// heuristicallyCacheable are the statuses a response may be cached with
+// without explicit freshness (RFC 9110, section 15.1).
+var heuristicallyCacheable = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusMethodNotAllowed:     true,
+	http.StatusGone:                 true,
+	http.StatusRequestURITooLong:    true,
+	http.StatusNotImplemented:       true,
+}
+
+// conditionalHeaders are the request headers that make a request conditional
+// or partial, which the cache leaves to the server.
+var conditionalHeaders = []string{"If-None-Match", "If-Modified-Since", "If-Match", "If-Unmodified-Since", "If-Range", "Range"}
+
+// Cache is a private HTTP cache (RFC 9111) that stores responses to GET
+// requests the way a browser's cache does. Fresh responses are served without
+// a request; stale ones are revalidated with If-None-Match and
+// If-Modified-Since, carrying the stored ETag and Last-Modified values
+// verbatim, and a 304 response is answered from the cache.
+//
+// Like Chrome's, the cache is partitioned by the site of the top-level page,
+// so a subresource cached for one site is fetched again for another, and it
+// does not store Set-Cookie headers.
+// The zero value is an empty cache ready to use.
+type Cache struct {
+	// MaxSize caps the bytes of response bodies the cache holds, 64 MiB by
+	// default. As in Chrome, a response larger than an eighth of it is not
+	// stored, and the least recently used responses are evicted to make room.
+	MaxSize int64
+
+	mu      sync.Mutex
+	entries map[string][]*cacheEntry
+	recent  *list.List // of *cacheEntry, most recently used first
+	size    int64
+}
+
+// cacheEntry is a stored response. Entries are replaced rather than changed,
+// so responses can be built from them without holding the cache's lock.
+type cacheEntry struct {
+	key  string
+	vary map[string]string
+
+	status       int
+	proto        string
+	protoMajor   int
+	protoMinor   int
+	header       http.Header
+	body         []byte
+	uncompressed bool
+
+	requestTime  time.Time
+	responseTime time.Time
+
+	// used is the entry's element in the cache's recent list, guarded by the
+	// cache's lock
+	used *list.Element
+}
+
+// WithCache serves GET requests from cache when it holds a fresh response, and
+// revalidates stale responses with conditional requests. Requests that are
+// already conditional or ask for a range bypass it. A request with
+// "cache-control: no-store" is neither served nor stored; one with
+// "cache-control: no-cache" or "pragma: no-cache", as a hard reload sends, is
+// sent unconditionally and its response stored; one with
+// "cache-control: max-age=0", as a reload sends, is revalidated.
+//
+// Responses are stored as received, before decompression, and their Vary
+// headers compare the request headers set before the Transport adds the
+// browser's defaults, which are the same for every request.
+func WithCache(cache *Cache) ClientOption {
+	return func(c *clientConfig) {
+		c.cache = cache
+	}
+}
+
+// cacheTransport answers requests from a Cache.
+type cacheTransport struct {
+	wrapped
+	cache *Cache
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		res, err := t.transport.RoundTrip(req)
+		if err == nil && req.Method != http.MethodHead && res.StatusCode < 400 {
+			// unsafe methods invalidate the responses stored for their target
+			// (RFC 9111, section 4.4)
+			t.cache.invalidate(req.URL)
+		}
+		return res, err
+	}
+
+	directives := parseCacheControl(req.Header.Values("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return t.transport.RoundTrip(req)
+	}
+	for _, key := range conditionalHeaders {
+		if _, ok := req.Header[key]; ok {
+			return t.transport.RoundTrip(req)
+		}
+	}
+
+	key := cacheKey(req)
+	_, noCache := directives["no-cache"]
+	if strings.EqualFold(req.Header.Get("Pragma"), "no-cache") {
+		noCache = true
+	}
+
+	var entry *cacheEntry
+	if !noCache {
+		entry = t.cache.lookup(key, req.Header)
+	}
+
+	sent := req
+	if entry != nil {
+		if !entry.mustRevalidate(directives, time.Now()) {
+			return entry.response(req, time.Now()), nil
+		}
+
+		etag, lastModified := entry.header.Get("Etag"), entry.header.Get("Last-Modified")
+		if etag == "" && lastModified == "" {
+			entry = nil
+		} else {
+			sent = req.Clone(req.Context())
+			if etag != "" {
+				sent.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				sent.Header.Set("If-Modified-Since", lastModified)
+			}
+		}
+	}
+
+	requestTime := time.Now()
+	res, err := t.transport.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+
+	if entry != nil && res.StatusCode == http.StatusNotModified {
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		freshened := t.cache.freshen(entry, res, requestTime, time.Now())
+		return freshened.response(req, time.Now()), nil
+	}
+
+	t.cache.capture(key, req, res, requestTime)
+	return res, nil
+}
+
+// Clear removes every stored response.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = nil
+	c.recent = nil
+	c.size = 0
+}
+
+// lookup returns the stored response for key whose Vary headers match header.
+func (c *Cache) lookup(key string, header http.Header) *cacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.entries[key] {
+		if e.matches(header) {
+			c.recent.MoveToFront(e.used)
+			return e
+		}
+	}
+	return nil
+}
+
+// capture stores res once its body has been read, if it may be stored.
+func (c *Cache) capture(key string, req *http.Request, res *http.Response, requestTime time.Time) {
+	if !storable(res) {
+		return
+	}
+
+	e := &cacheEntry{
+		key:          key,
+		vary:         varyValues(res.Header, req.Header),
+		status:       res.StatusCode,
+		proto:        res.Proto,
+		protoMajor:   res.ProtoMajor,
+		protoMinor:   res.ProtoMinor,
+		header:       res.Header.Clone(),
+		uncompressed: res.Uncompressed,
+		requestTime:  requestTime,
+		responseTime: time.Now(),
+	}
+	e.header.Del("Set-Cookie")
+
+	if res.Body == nil || res.Body == http.NoBody {
+		c.store(e)
+		return
+	}
+
+	res.Body = &cacheBody{ReadCloser: res.Body, cache: c, entry: e, limit: c.maxSize() / 8}
+}
+
+// store adds e, replacing the response stored for the same request, and evicts
+// the least recently used responses until the cache fits its size.
+func (c *Cache) store(e *cacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.entries == nil {
+		c.entries = make(map[string][]*cacheEntry)
+		c.recent = list.New()
+	}
+
+	variants := c.entries[e.key]
+	for i, old := range variants {
+		if old.matchesVary(e.vary) {
+			c.size -= int64(len(old.body))
+			c.recent.Remove(old.used)
+			variants = append(variants[:i], variants[i+1:]...)
+			break
+		}
+	}
+	c.entries[e.key] = append(variants, e)
+	e.used = c.recent.PushFront(e)
+	c.size += int64(len(e.body))
+
+	for c.size > c.maxSize() {
+		c.evictLocked()
+	}
+}
+
+// freshen replaces e with a copy updated by a 304 response to its
+// revalidation (RFC 9111, section 4.3.4).
+func (c *Cache) freshen(e *cacheEntry, res *http.Response, requestTime, responseTime time.Time) *cacheEntry {
+	updated := *e
+	updated.header = e.header.Clone()
+	for key, values := range res.Header {
+		switch key {
+		case "Content-Length", "Set-Cookie":
+			continue
+		}
+		updated.header[key] = values
+	}
+	updated.requestTime = requestTime
+	updated.responseTime = responseTime
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	variants := c.entries[e.key]
+	for i, old := range variants {
+		if old == e {
+			variants[i] = &updated
+			updated.used.Value = &updated
+			c.recent.MoveToFront(updated.used)
+			break
+		}
+	}
+	return &updated
+}
+
+// invalidate removes the responses stored for u in every partition.
+func (c *Cache) invalidate(u *url.URL) {
+	suffix := " " + cacheURL(u)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, variants := range c.entries {
+		if strings.HasSuffix(key, suffix) {
+			for _, e := range variants {
+				c.size -= int64(len(e.body))
+				c.recent.Remove(e.used)
+			}
+			delete(c.entries, key)
+		}
+	}
+}
+
+// evictLocked removes the least recently used response. The caller must hold
+// c.mu.
+func (c *Cache) evictLocked() {
+	last := c.recent.Back()
+	if last == nil {
+		c.size = 0
+		return
+	}
+	oldest := c.recent.Remove(last).(*cacheEntry)
+
+	variants := c.entries[oldest.key]
+	for i, e := range variants {
+		if e == oldest {
+			variants = append(variants[:i], variants[i+1:]...)
+			break
+		}
+	}
+	if len(variants) == 0 {
+		delete(c.entries, oldest.key)
+	} else {
+		c.entries[oldest.key] = variants
+	}
+	c.size -= int64(len(oldest.body))
+}
+
+func (c *Cache) maxSize() int64 {
+	if c.MaxSize > 0 {
+		return c.MaxSize
+	}
+	return defaultCacheSize
+}
+
+// cacheBody collects a response body as it is read and stores its response
+// once the body is read to the end.
+type cacheBody struct {
+	io.ReadCloser
+	cache *Cache
+	entry *cacheEntry
+	buf   bytes.Buffer
+	limit int64
+	done  bool
+}
+
+func (b *cacheBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if !b.done {
+		if int64(b.buf.Len()+n) > b.limit {
+			b.done = true
+			b.buf = bytes.Buffer{}
+		} else {
+			b.buf.Write(p[:n])
+		}
+		if err == io.EOF && !b.done {
+			b.done = true
+			b.entry.body = b.buf.Bytes()
+			b.cache.store(b.entry)
+		}
+	}
+	return n, err
+}
+
+// matches reports whether header selects e under e's Vary headers.
+func (e *cacheEntry) matches(header http.Header) bool {
+	for key, value := range e.vary {
+		if strings.Join(header.Values(key), ", ") != value {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesVary reports whether e was stored for the same Vary header values.
+func (e *cacheEntry) matchesVary(vary map[string]string) bool {
+	if len(e.vary) != len(vary) {
+		return false
+	}
+	for key, value := range vary {
+		if e.vary[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// mustRevalidate reports whether e cannot answer a request with directives at
+// now without asking the server.
+func (e *cacheEntry) mustRevalidate(directives map[string]string, now time.Time) bool {
+	response := parseCacheControl(e.header.Values("Cache-Control"))
+	if _, ok := response["no-cache"]; ok {
+		return true
+	}
+
+	age, lifetime := e.age(now), e.lifetime()
+	if age >= lifetime {
+		return true
+	}
+
+	if value, ok := directives["max-age"]; ok {
+		// immutable responses are not revalidated on reload while fresh
+		if _, immutable := response["immutable"]; immutable {
+			return false
+		}
+		maxAge, err := strconv.ParseInt(value, 10, 64)
+		return err != nil || age > time.Duration(maxAge)*time.Second
+	}
+	return false
+}
+
+// date returns the response's Date, or when it was received if it has none.
+func (e *cacheEntry) date() time.Time {
+	if date, err := http.ParseTime(e.header.Get("Date")); err == nil {
+		return date
+	}
+	return e.responseTime
+}
+
+// age returns the response's current age (RFC 9111, section 4.2.3).
+func (e *cacheEntry) age(now time.Time) time.Duration {
+	apparent := max(e.responseTime.Sub(e.date()), 0)
+
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(e.header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+
+	corrected := ageValue + e.responseTime.Sub(e.requestTime)
+	return max(apparent, corrected) + now.Sub(e.responseTime)
+}
+
+// lifetime returns how long the response is fresh (RFC 9111, section 4.2.1).
+// Without max-age or Expires, a heuristically cacheable response is fresh for
+// a tenth of the time since it was last modified, as browsers estimate it.
+func (e *cacheEntry) lifetime() time.Duration {
+	directives := parseCacheControl(e.header.Values("Cache-Control"))
+	if value, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if value := e.header.Get("Expires"); value != "" {
+		expires, err := http.ParseTime(value)
+		if err != nil {
+			return 0
+		}
+		return expires.Sub(e.date())
+	}
+
+	if lastModified, err := http.ParseTime(e.header.Get("Last-Modified")); err == nil && heuristicallyCacheable[e.status] {
+		return max(e.date().Sub(lastModified)/10, 0)
+	}
+	return 0
+}
+
+// response builds the response e answers req with.
+func (e *cacheEntry) response(req *http.Request, now time.Time) *http.Response {
+	header := e.header.Clone()
+	header.Set("Age", strconv.FormatInt(int64(e.age(now)/time.Second), 10))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         e.proto,
+		ProtoMajor:    e.protoMajor,
+		ProtoMinor:    e.protoMinor,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Uncompressed:  e.uncompressed,
+		Request:       req,
+	}
+}
+
+// storable reports whether res may be stored by a private cache.
+func storable(res *http.Response) bool {
+	directives := parseCacheControl(res.Header.Values("Cache-Control"))
+	if _, ok := directives["no-store"]; ok {
+		return false
+	}
+	for _, vary := range res.Header.Values("Vary") {
+		if strings.Contains(vary, "*") {
+			return false
+		}
+	}
+
+	_, maxAge := directives["max-age"]
+	explicit := maxAge || res.Header.Get("Expires") != ""
+	if !explicit && !heuristicallyCacheable[res.StatusCode] {
+		return false
+	}
+	return explicit || res.Header.Get("Etag") != "" || res.Header.Get("Last-Modified") != ""
+}
+
+// varyValues returns the values the request header has for each header the
+// response varies on.
+func varyValues(res, req http.Header) map[string]string {
+	var vary map[string]string
+	for _, value := range res.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			if vary == nil {
+				vary = make(map[string]string)
+			}
+			vary[name] = strings.Join(req.Values(name), ", ")
+		}
+	}
+	return vary
+}
+
+// parseCacheControl parses Cache-Control header values into their directives,
+// lowercased, with unquoted arguments.
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, directive := range splitQuoted(value, ',') {
+			name, arg, _ := strings.Cut(directive, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+		}
+	}
+	return directives
+}
+
+// cacheKey returns the key req's response is stored under: the site of the
+// top-level page, as Chrome partitions its cache, and the URL.
+func cacheKey(req *http.Request) string {
+	top := req.URL
+	if intent := fetchIntentFrom(req.Context()); intent != nil && intent.mode != ModeNavigate && intent.initiator != nil {
+		top = intent.initiator
+	}
+	return top.Scheme + "://" + registrableDomain(top.Hostname()) + " " + cacheURL(req.URL)
+}
+
+// cacheURL returns u without its fragment.
+func cacheURL(u *url.URL) string {
+	stripped := *u
+	stripped.Fragment = ""
+	stripped.RawFragment = ""
+	return stripped.String()
+}
@@ -0,0 +1,171 @@
+package mimic
+
+import (
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestCacheLifetime(t *testing.T) {
+	date := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header http.Header
+		status int
+		want   time.Duration
+	}{
+		{http.Header{"Cache-Control": {"public, max-age=60"}}, 200, time.Minute},
+		{http.Header{"Cache-Control": {`max-age="30"`}, "Expires": {date.Add(time.Hour).Format(http.TimeFormat)}}, 200, 30 * time.Second},
+		{http.Header{"Expires": {date.Add(time.Hour).Format(http.TimeFormat)}}, 200, time.Hour},
+		{http.Header{"Expires": {"0"}}, 200, 0},
+		{http.Header{"Last-Modified": {date.Add(-10 * time.Hour).Format(http.TimeFormat)}}, 200, time.Hour},
+		{http.Header{"Last-Modified": {date.Add(-10 * time.Hour).Format(http.TimeFormat)}}, 302, 0},
+	}
+
+	for _, test := range tests {
+		test.header.Set("Date", date.Format(http.TimeFormat))
+		e := &cacheEntry{status: test.status, header: test.header, responseTime: date}
+		if got := e.lifetime(); got != test.want {
+			t.Errorf("%d %v: want %s; got %s", test.status, test.header, test.want, got)
+		}
+	}
+}
+
+func TestCache(t *testing.T) {
+	requests := make(map[string][]*stdhttp.Request)
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		requests[r.URL.Path] = append(requests[r.URL.Path], r)
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "max-age=60")
+			stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "seen", Value: "1"})
+		case "/stale":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(stdhttp.StatusNotModified)
+				return
+			}
+		case "/vary":
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Header().Set("Vary", "X-Variant")
+		}
+		io.WriteString(w, "body of "+r.URL.Path+" "+r.Header.Get("X-Variant"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithCache(&Cache{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		res, err := client.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res, string(body)
+	}
+
+	get("/fresh", nil)
+	res, body := get("/fresh", nil)
+	if len(requests["/fresh"]) != 1 || body != "body of /fresh " {
+		t.Errorf("want a fresh response served from the cache; got %d requests, %q", len(requests["/fresh"]), body)
+	}
+	if res.Header.Get("Age") == "" || res.Header.Get("Set-Cookie") != "" {
+		t.Errorf("want Age set and Set-Cookie not stored; got %v", res.Header)
+	}
+
+	get("/fresh", http.Header{"Cache-Control": {"max-age=0"}})
+	if len(requests["/fresh"]) != 2 {
+		t.Errorf("want a reload to go to the server; got %d requests", len(requests["/fresh"]))
+	}
+
+	get("/stale", nil)
+	res, body = get("/stale", nil)
+	if len(requests["/stale"]) != 2 || res.StatusCode != http.StatusOK || body != "body of /stale " {
+		t.Fatalf("want a revalidated response; got %d requests, %d %q", len(requests["/stale"]), res.StatusCode, body)
+	}
+	revalidation := requests["/stale"][1]
+	if revalidation.Header.Get("If-None-Match") != `"v1"` || revalidation.Header.Get("If-Modified-Since") != "Wed, 01 Jan 2025 00:00:00 GMT" {
+		t.Errorf("want the stored validators sent verbatim; got %v", revalidation.Header)
+	}
+
+	get("/stale", http.Header{"Cache-Control": {"no-cache"}, "Pragma": {"no-cache"}})
+	if hard := requests["/stale"][2]; hard.Header.Get("If-None-Match") != "" {
+		t.Error("want a hard reload sent unconditionally")
+	}
+
+	get("/vary", http.Header{"X-Variant": {"a"}})
+	_, body = get("/vary", http.Header{"X-Variant": {"b"}})
+	if len(requests["/vary"]) != 2 || body != "body of /vary b" {
+		t.Errorf("want a different Vary value to miss; got %d requests, %q", len(requests["/vary"]), body)
+	}
+	_, body = get("/vary", http.Header{"X-Variant": {"a"}})
+	if len(requests["/vary"]) != 2 || body != "body of /vary a" {
+		t.Errorf("want each variant stored; got %d requests, %q", len(requests["/vary"]), body)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/fresh", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	get("/fresh", nil)
+	if len(requests["/fresh"]) != 4 {
+		t.Errorf("want a POST to invalidate the stored response; got %d requests", len(requests["/fresh"]))
+	}
+}
+
+func TestCacheEviction(t *testing.T) {
+	cache := &Cache{MaxSize: 80}
+	for _, key := range []string{"a", "b", "c"} {
+		cache.store(&cacheEntry{key: key, body: make([]byte, 10)})
+	}
+	cache.lookup("a", nil)
+	cache.store(&cacheEntry{key: "d", body: make([]byte, 60)})
+
+	if cache.lookup("b", nil) != nil || cache.lookup("a", nil) == nil {
+		t.Error("want the least recently used response evicted")
+	}
+	if cache.size > 80 {
+		t.Errorf("want the cache within its size; got %d", cache.size)
+	}
+}
@@ -0,0 +1,150 @@
+package mimic
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// maxCertLifetime is the longest validity period browsers accept for a
+// publicly trusted leaf certificate.
+const maxCertLifetime = 398 * 24 * time.Hour
+
+// oidSCTList is the X.509 extension carrying embedded signed certificate
+// timestamps (RFC 6962, section 3.3).
+var oidSCTList = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// WithBrowserCertificatePolicy rejects certificates that verify under Go's rules
+// but that the mimicked browser refuses, since accepting them is detectable
+// from hosts serving such certificates on purpose. On top of normal
+// verification, it rejects:
+//   - leaf certificates valid for more than 398 days
+//   - certificates signed with SHA-1, other than the root
+//   - for Chromium and Safari specs, leaf certificates without enough signed
+//     certificate timestamps to satisfy Certificate Transparency policy
+//
+// As in browsers, the checks are skipped for certificates that chain to roots
+// set in TLSClientConfig.RootCAs, and when InsecureSkipVerify is set.
+// Verification itself uses the platform verifier on macOS and Windows, as the
+// browsers do; see WithPlatformVerifier to keep it when RootCAs is set.
+func WithBrowserCertificatePolicy() TransportOption {
+	return func(c *transportConfig) {
+		c.certPolicy = true
+	}
+}
+
+// certificatePolicy checks verified connections against browser rules.
+type certificatePolicy struct {
+	requireSCTs bool
+}
+
+// install adds the policy to cfg, keeping any VerifyConnection already set.
+func (p certificatePolicy) install(cfg *utls.Config) {
+	next := cfg.VerifyConnection
+	privateRoots := cfg.RootCAs
+
+	cfg.VerifyConnection = func(cs utls.ConnectionState) error {
+		if len(cs.VerifiedChains) > 0 && !chainsTo(cs.VerifiedChains[0], privateRoots) {
+			if err := p.check(cs); err != nil {
+				return err
+			}
+		}
+		if next != nil {
+			return next(cs)
+		}
+		return nil
+	}
+}
+
+// chainsTo reports whether chain ends at a root in roots.
+func chainsTo(chain []*x509.Certificate, roots *x509.CertPool) bool {
+	if roots == nil {
+		return false
+	}
+
+	root := chain[len(chain)-1]
+	_, err := root.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: root.NotBefore,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err == nil
+}
+
+func (p certificatePolicy) check(cs utls.ConnectionState) error {
+	leaf := cs.PeerCertificates[0]
+
+	lifetime := leaf.NotAfter.Sub(leaf.NotBefore)
+	if lifetime > maxCertLifetime {
+		return &CertificatePolicyError{Subject: leaf.Subject.String(), Reason: "validity period exceeds 398 days"}
+	}
+
+	chain := cs.VerifiedChains[0]
+	for _, cert := range chain[:len(chain)-1] {
+		switch cert.SignatureAlgorithm {
+		case x509.SHA1WithRSA, x509.ECDSAWithSHA1, x509.DSAWithSHA1:
+			return &CertificatePolicyError{Subject: cert.Subject.String(), Reason: "signed with SHA-1"}
+		}
+	}
+
+	if p.requireSCTs && !hasEnoughSCTs(leaf, lifetime, len(cs.SignedCertificateTimestamps)) {
+		return &CertificatePolicyError{Subject: leaf.Subject.String(), Reason: "not enough signed certificate timestamps"}
+	}
+
+	return nil
+}
+
+// hasEnoughSCTs applies the SCT counts Chrome and Apple require: two delivered
+// in the TLS handshake, or embedded in the certificate two for lifetimes up to
+// 180 days and three beyond. The SCTs' signatures are not checked, which needs
+// the browsers' CT log lists.
+func hasEnoughSCTs(leaf *x509.Certificate, lifetime time.Duration, handshakeSCTs int) bool {
+	if handshakeSCTs >= 2 {
+		return true
+	}
+
+	required := 3
+	if lifetime <= 180*24*time.Hour {
+		required = 2
+	}
+
+	return countEmbeddedSCTs(leaf) >= required
+}
+
+// countEmbeddedSCTs returns the number of SCTs in the certificate's SCT list
+// extension.
+func countEmbeddedSCTs(cert *x509.Certificate) int {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidSCTList) {
+			continue
+		}
+
+		// the extension value is an OCTET STRING wrapping the TLS-encoded list
+		var octets []byte
+		if _, err := asn1.Unmarshal(ext.Value, &octets); err != nil {
+			return 0
+		}
+
+		if len(octets) < 2 || int(binary.BigEndian.Uint16(octets)) != len(octets)-2 {
+			return 0
+		}
+
+		n := 0
+		for list := octets[2:]; len(list) > 0; n++ {
+			if len(list) < 2 {
+				return 0
+			}
+			size := 2 + int(binary.BigEndian.Uint16(list))
+			if len(list) < size {
+				return 0
+			}
+			list = list[size:]
+		}
+		return n
+	}
+
+	return 0
+}
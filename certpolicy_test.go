@@ -0,0 +1,105 @@
+package mimic
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// sctListExtension returns an SCT list extension holding n empty SCTs.
+func sctListExtension(t *testing.T, n int) pkix.Extension {
+	t.Helper()
+
+	list := []byte{0, byte(n * 2)}
+	for range n {
+		list = append(list, 0, 0)
+	}
+
+	value, err := asn1.Marshal(list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pkix.Extension{Id: oidSCTList, Value: value}
+}
+
+func TestCertificatePolicy(t *testing.T) {
+	now := time.Now()
+	root := &x509.Certificate{SignatureAlgorithm: x509.SHA1WithRSA}
+
+	newState := func(lifetime time.Duration, alg x509.SignatureAlgorithm, embedded, handshake int) utls.ConnectionState {
+		leaf := &x509.Certificate{
+			NotBefore:          now,
+			NotAfter:           now.Add(lifetime),
+			SignatureAlgorithm: alg,
+		}
+		if embedded > 0 {
+			leaf.Extensions = []pkix.Extension{sctListExtension(t, embedded)}
+		}
+		return utls.ConnectionState{
+			PeerCertificates:            []*x509.Certificate{leaf, root},
+			VerifiedChains:              [][]*x509.Certificate{{leaf, root}},
+			SignedCertificateTimestamps: make([][]byte, handshake),
+		}
+	}
+
+	const day = 24 * time.Hour
+
+	tests := []struct {
+		name  string
+		state utls.ConnectionState
+		ok    bool
+	}{
+		{"valid", newState(90*day, x509.SHA256WithRSA, 2, 0), true},
+		{"sha1 root", newState(90*day, x509.ECDSAWithSHA256, 2, 0), true},
+		{"handshake scts", newState(90*day, x509.SHA256WithRSA, 0, 2), true},
+		{"long lifetime", newState(399*day, x509.SHA256WithRSA, 3, 0), false},
+		{"sha1 leaf", newState(90*day, x509.SHA1WithRSA, 2, 0), false},
+		{"no scts", newState(90*day, x509.SHA256WithRSA, 0, 1), false},
+		{"few scts for lifetime", newState(200*day, x509.SHA256WithRSA, 2, 0), false},
+	}
+
+	policy := certificatePolicy{requireSCTs: true}
+	for _, test := range tests {
+		err := policy.check(test.state)
+		if (err == nil) != test.ok {
+			t.Errorf("%s: want ok %t; got %v", test.name, test.ok, err)
+		}
+
+		var policyErr *CertificatePolicyError
+		if err != nil && !errors.As(classifyError(err), &policyErr) {
+			t.Errorf("%s: want CertificatePolicyError; got %T", test.name, err)
+		}
+	}
+
+	if err := (certificatePolicy{}).check(newState(90*day, x509.SHA256WithRSA, 0, 0)); err != nil {
+		t.Errorf("want scts optional without requireSCTs; got %v", err)
+	}
+}
+
+func TestCertificatePolicyInstall(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBrowserCertificatePolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verify := tr.base.TLSClientConfig.VerifyConnection
+	if verify == nil {
+		t.Fatal("want VerifyConnection set")
+	}
+
+	// InsecureSkipVerify leaves VerifiedChains empty, so nothing is enforced
+	leaf := &x509.Certificate{NotAfter: time.Now().Add(10 * 365 * 24 * time.Hour)}
+	if err := verify(utls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}); err != nil {
+		t.Errorf("want unverified connections allowed; got %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+package mimic
+
+import (
+	"bytes"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Challenge is a bot management product's response to a request it did not
+// let through.
+type Challenge struct {
+	// Vendor names the product, such as "cloudflare" or "datadome".
+	Vendor string
+
+	// Blocked is set when the response refuses the request outright rather
+	// than asking the client to solve a challenge.
+	Blocked bool
+}
+
+// challengeDetector recognizes one vendor's challenges.
+type challengeDetector struct {
+	vendor string
+	detect func(res *http.Response, body []byte) (found, blocked bool)
+}
+
+var challengeDetectors = []challengeDetector{
+	{"cloudflare", func(res *http.Response, body []byte) (bool, bool) {
+		if res.Header.Get("Cf-Mitigated") == "challenge" {
+			return true, false
+		}
+		if !strings.EqualFold(res.Header.Get("Server"), "cloudflare") || res.StatusCode < 400 {
+			return false, false
+		}
+		switch {
+		case bytes.Contains(body, []byte("challenge-platform")), bytes.Contains(body, []byte("Just a moment...")):
+			return true, false
+		case bytes.Contains(body, []byte("cf-error-details")):
+			return true, true
+		}
+		return false, false
+	}},
+	{"aws-waf", func(res *http.Response, body []byte) (bool, bool) {
+		switch res.Header.Get("X-Amzn-Waf-Action") {
+		case "challenge", "captcha":
+			return true, false
+		case "block":
+			return true, true
+		}
+		return false, false
+	}},
+	{"datadome", func(res *http.Response, body []byte) (bool, bool) {
+		if res.StatusCode != http.StatusForbidden || res.Header.Get("X-Datadome") == "" && res.Header.Get("X-Dd-B") == "" {
+			return false, false
+		}
+		return true, !bytes.Contains(body, []byte("captcha-delivery.com"))
+	}},
+	{"perimeterx", func(res *http.Response, body []byte) (bool, bool) {
+		if res.StatusCode != http.StatusForbidden {
+			return false, false
+		}
+		found := bytes.Contains(body, []byte("px-captcha")) || bytes.Contains(body, []byte("_pxAppId"))
+		return found, false
+	}},
+	{"kasada", func(res *http.Response, body []byte) (bool, bool) {
+		if res.StatusCode != http.StatusTooManyRequests {
+			return false, false
+		}
+		return res.Header.Get("X-Kpsdk-Ct") != "" || bytes.Contains(body, []byte("KPSDK")), false
+	}},
+	{"imperva", func(res *http.Response, body []byte) (bool, bool) {
+		switch {
+		case bytes.Contains(body, []byte("_Incapsula_Resource")):
+			return true, false
+		case bytes.Contains(body, []byte("Incapsula incident ID")):
+			return true, true
+		}
+		return false, false
+	}},
+	{"akamai", func(res *http.Response, body []byte) (bool, bool) {
+		if bytes.Contains(body, []byte("sec-if-cpt-container")) {
+			return true, false
+		}
+		blocked := res.StatusCode == http.StatusForbidden && res.Header.Get("Server") == "AkamaiGHost"
+		return blocked, blocked
+	}},
+}
+
+// DetectChallenge reports whether res is a bot management challenge or block
+// page, looking at its status, headers, and body, which holds the start of
+// the response body. Products are recognized by the markers their pages
+// carry; a challenge from one that is not recognized is not reported.
+func DetectChallenge(res *http.Response, body []byte) (Challenge, bool) {
+	for _, d := range challengeDetectors {
+		if found, blocked := d.detect(res, body); found {
+			return Challenge{Vendor: d.vendor, Blocked: blocked}, true
+		}
+	}
+	return Challenge{}, false
+}
@@ -0,0 +1,40 @@
+package mimic
+
+import (
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestDetectChallenge(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   Challenge
+		found  bool
+	}{
+		{"cloudflare header", 403, http.Header{"Cf-Mitigated": {"challenge"}}, "", Challenge{"cloudflare", false}, true},
+		{"cloudflare interstitial", 503, http.Header{"Server": {"cloudflare"}}, "<title>Just a moment...</title>", Challenge{"cloudflare", false}, true},
+		{"cloudflare block", 403, http.Header{"Server": {"cloudflare"}}, `<div id="cf-error-details">`, Challenge{"cloudflare", true}, true},
+		{"cloudflare ok", 200, http.Header{"Server": {"cloudflare"}}, "Just a moment...", Challenge{}, false},
+		{"aws waf", 202, http.Header{"X-Amzn-Waf-Action": {"challenge"}}, "", Challenge{"aws-waf", false}, true},
+		{"datadome captcha", 403, http.Header{"X-Datadome": {"protected"}}, "geo.captcha-delivery.com", Challenge{"datadome", false}, true},
+		{"datadome block", 403, http.Header{"X-Datadome": {"protected"}}, "", Challenge{"datadome", true}, true},
+		{"datadome ok", 200, http.Header{"X-Datadome": {"protected"}}, "", Challenge{}, false},
+		{"perimeterx", 403, http.Header{}, `<div id="px-captcha">`, Challenge{"perimeterx", false}, true},
+		{"kasada", 429, http.Header{"X-Kpsdk-Ct": {"token"}}, "", Challenge{"kasada", false}, true},
+		{"imperva", 200, http.Header{}, `<script src="/_Incapsula_Resource?SWJIYLWA=1">`, Challenge{"imperva", false}, true},
+		{"akamai block", 403, http.Header{"Server": {"AkamaiGHost"}}, "Access Denied", Challenge{"akamai", true}, true},
+		{"plain forbidden", 403, http.Header{"Server": {"nginx"}}, "Forbidden", Challenge{}, false},
+	}
+
+	for _, test := range tests {
+		res := &http.Response{StatusCode: test.status, Header: test.header}
+		got, found := DetectChallenge(res, []byte(test.body))
+		if found != test.found || got != test.want {
+			t.Errorf("%s: want %+v, %t; got %+v, %t", test.name, test.want, test.found, got, found)
+		}
+	}
+}
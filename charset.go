@@ -0,0 +1,209 @@
+package mimic
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// charsetPeekLen is how much of a body is read to find its charset, the
+// 1024 bytes the HTML standard prescans for a <meta> charset.
+const charsetPeekLen = 1024
+
+// xmlEncoding matches the encoding of an XML declaration.
+var xmlEncoding = regexp.MustCompile(`^<\?xml[^>]*?\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// byteOrderMarks are the byte order marks a browser reads a charset from.
+var byteOrderMarks = [][]byte{
+	{0xef, 0xbb, 0xbf}, // UTF-8
+	{0xfe, 0xff},       // UTF-16BE
+	{0xff, 0xfe},       // UTF-16LE
+}
+
+// DecodeCharset replaces the body of res with its text in UTF-8 and returns
+// the name of the charset it was decoded from, reading up to the first 1024
+// bytes of the body to find it. HTML is decoded as a browser decodes it: a
+// byte order mark wins, then the Content-Type charset, then a <meta> charset
+// in the first 1024 bytes, then UTF-8 if those bytes are valid UTF-8, and
+// windows-1252 otherwise. Other text, XML, JSON, and JavaScript take a byte
+// order mark, the Content-Type charset, or an XML declaration's encoding, and
+// are otherwise UTF-8.
+//
+// The Content-Type charset is set to utf-8 and a byte order mark is dropped.
+// When that changes the body's length, Content-Length is removed. Responses
+// that are not text, or have no body, are left as they are and "" is
+// returned.
+func DecodeCharset(res *http.Response) (string, error) {
+	contentType := res.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !isText(mediaType) || res.Body == nil || res.Body == http.NoBody {
+		return "", nil
+	}
+
+	head, err := readCharsetHead(res.Body)
+	if err != nil {
+		return "", err
+	}
+
+	body, name, changed := decodeText(contentType, head, res.Body)
+	res.Body = &prefixedBody{Reader: body, body: res.Body}
+
+	if changed {
+		res.ContentLength = -1
+		res.Header.Del("Content-Length")
+	}
+	params["charset"] = "utf-8"
+	res.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+
+	return name, nil
+}
+
+// readCharsetHead reads the first charsetPeekLen bytes of body, or all of it
+// if shorter.
+func readCharsetHead(body io.Reader) ([]byte, error) {
+	head := make([]byte, charsetPeekLen)
+	n, err := io.ReadFull(body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return head[:n], nil
+}
+
+// decodeText returns the UTF-8 text of a body of contentType that starts with
+// head, read so far, and goes on with rest. It also returns the name of the
+// charset it was decoded from and whether decoding changes the body's bytes.
+func decodeText(contentType string, head []byte, rest io.Reader) (io.Reader, string, bool) {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+
+	var e encoding.Encoding
+	var name string
+	if mediaType == "text/html" {
+		e, name, _ = charset.DetermineEncoding(head, contentType)
+	} else {
+		e, name = textEncoding(params["charset"], head)
+	}
+
+	bom := 0
+	for _, mark := range byteOrderMarks {
+		if bytes.HasPrefix(head, mark) {
+			bom = len(mark)
+			break
+		}
+	}
+
+	var body io.Reader = io.MultiReader(bytes.NewReader(head[bom:]), rest)
+	if name != "utf-8" {
+		body = transform.NewReader(body, e.NewDecoder())
+	}
+	return body, name, bom > 0 || name != "utf-8"
+}
+
+// textEncoding returns the encoding of a text body that is not HTML, given
+// its Content-Type charset label and first bytes.
+func textEncoding(label string, head []byte) (encoding.Encoding, string) {
+	switch {
+	case bytes.HasPrefix(head, byteOrderMarks[0]):
+		return charset.Lookup("utf-8")
+	case bytes.HasPrefix(head, byteOrderMarks[1]):
+		return charset.Lookup("utf-16be")
+	case bytes.HasPrefix(head, byteOrderMarks[2]):
+		return charset.Lookup("utf-16le")
+	}
+
+	if e, name := charset.Lookup(label); e != nil {
+		return e, name
+	}
+	if m := xmlEncoding.FindSubmatch(head); m != nil {
+		if e, name := charset.Lookup(string(m[1])); e != nil {
+			return e, name
+		}
+	}
+	return charset.Lookup("utf-8")
+}
+
+// isText reports whether mediaType is a type whose body is text in a charset.
+func isText(mediaType string) bool {
+	switch mediaType {
+	case "application/json", "application/javascript", "application/ecmascript", "application/xml":
+		return true
+	}
+	return strings.HasPrefix(mediaType, "text/") || strings.HasSuffix(mediaType, "+xml") || strings.HasSuffix(mediaType, "+json")
+}
+
+// WithCharsetDecoding decodes the body of each text response to UTF-8 as
+// DecodeCharset does, so scrapers of sites that serve Shift_JIS, GBK, or
+// windows-1251 read the text a browser would show. Layers that act on HTML,
+// such as WithMetaRefresh and WithCookieGates, then see the decoded body.
+//
+// Unlike DecodeCharset, the charset is found on the first Read of the body,
+// so streamed text such as text/event-stream is not held up. As the decoded
+// length is not known until then, Content-Length is removed from every text
+// response.
+func WithCharsetDecoding() ClientOption {
+	return func(c *clientConfig) {
+		c.charset = true
+	}
+}
+
+// charsetTransport decodes the bodies of text responses to UTF-8.
+type charsetTransport struct {
+	wrapped
+}
+
+func (t *charsetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := res.Header.Get("Content-Type")
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !isText(mediaType) || req.Method == http.MethodHead || res.Body == nil || res.Body == http.NoBody {
+		return res, nil
+	}
+
+	res.Body = &charsetReader{body: res.Body, contentType: contentType}
+	res.ContentLength = -1
+	res.Header.Del("Content-Length")
+	params["charset"] = "utf-8"
+	res.Header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+
+	return res, nil
+}
+
+// charsetReader finds the charset of a body of contentType on the first call
+// to Read, so that RoundTrip does not block waiting for body bytes.
+type charsetReader struct {
+	body        io.ReadCloser
+	contentType string
+	decoded     io.Reader
+	err         error
+}
+
+func (c *charsetReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	if c.decoded == nil {
+		var head []byte
+		head, c.err = readCharsetHead(c.body)
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.decoded, _, _ = decodeText(c.contentType, head, c.body)
+	}
+
+	return c.decoded.Read(p)
+}
+
+func (c *charsetReader) Close() error {
+	return c.body.Close()
+}
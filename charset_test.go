@@ -0,0 +1,183 @@
+package mimic
+
+import (
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+)
+
+func TestDecodeCharset(t *testing.T) {
+	encode := func(e encoding.Encoding, s string) string {
+		b, err := e.NewEncoder().String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantName    string
+		wantType    string
+		wantBody    string
+	}{
+		{
+			name:        "content type",
+			contentType: "text/plain; charset=windows-1251",
+			body:        encode(charmap.Windows1251, "Привет"),
+			wantName:    "windows-1251",
+			wantType:    "text/plain; charset=utf-8",
+			wantBody:    "Привет",
+		},
+		{
+			name:        "meta",
+			contentType: "text/html",
+			body:        `<html><head><meta charset="shift_jis"></head><body>` + encode(japanese.ShiftJIS, "こんにちは") + `</body></html>`,
+			wantName:    "shift_jis",
+			wantType:    "text/html; charset=utf-8",
+			wantBody:    `<html><head><meta charset="shift_jis"></head><body>こんにちは</body></html>`,
+		},
+		{
+			name:        "html fallback",
+			contentType: "text/html",
+			body:        "caf\xe9",
+			wantName:    "windows-1252",
+			wantType:    "text/html; charset=utf-8",
+			wantBody:    "café",
+		},
+		{
+			name:        "xml declaration",
+			contentType: "application/xml",
+			body:        `<?xml version="1.0" encoding="ISO-8859-1"?><a>` + "caf\xe9" + `</a>`,
+			wantName:    "windows-1252",
+			wantType:    "application/xml; charset=utf-8",
+			wantBody:    `<?xml version="1.0" encoding="ISO-8859-1"?><a>café</a>`,
+		},
+		{
+			name:        "bom",
+			contentType: "application/json; charset=windows-1252",
+			body:        "\xef\xbb\xbf{}",
+			wantName:    "utf-8",
+			wantType:    "application/json; charset=utf-8",
+			wantBody:    "{}",
+		},
+		{
+			name:        "not text",
+			contentType: "image/png",
+			body:        "\x89PNG",
+			wantType:    "image/png",
+			wantBody:    "\x89PNG",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &http.Response{
+				Header:        http.Header{"Content-Type": {tt.contentType}},
+				Body:          io.NopCloser(strings.NewReader(tt.body)),
+				ContentLength: int64(len(tt.body)),
+			}
+
+			name, err := DecodeCharset(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if name != tt.wantName {
+				t.Errorf("charset = %q, want %q", name, tt.wantName)
+			}
+			if got := res.Header.Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCharsetDecodingStream(t *testing.T) {
+	body, err := charmap.Windows1251.NewEncoder().String("Привет")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release := make(chan struct{})
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=windows-1251")
+		io.WriteString(w, body[:2])
+		w.(stdhttp.Flusher).Flush()
+		<-release
+		io.WriteString(w, body[2:])
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithCharsetDecoding(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	got := make(chan *http.Response, 1)
+	go func() {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Error(err)
+		}
+		got <- res
+	}()
+
+	var res *http.Response
+	select {
+	case res = <-got:
+	case <-time.After(5 * time.Second):
+		close(release)
+		t.Fatal("want the response before its body has arrived")
+	}
+	close(release)
+	if res == nil {
+		return
+	}
+	defer res.Body.Close()
+
+	if got := res.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/plain; charset=utf-8", got)
+	}
+	decoded, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != "Привет" {
+		t.Errorf("body = %q, want Привет", decoded)
+	}
+}
+
+func TestCharsetDecodingCloseIdleConnections(t *testing.T) {
+	testClientClosesIdle(t, WithCharsetDecoding())
+}
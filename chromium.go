@@ -2,6 +2,7 @@ package mimic
 
 import (
 	"fmt"
+	"time"
 
 	utls "github.com/refraction-networking/utls"
 	http "github.com/saucesteals/fhttp"
@@ -9,53 +10,126 @@ import (
 )
 
 // Chromium creates a ClientSpec that mimics a Chromium-based browser's TLS and HTTP/2
-// fingerprint. Supported brands are BrandChrome, BrandBrave, and BrandEdge.
+// fingerprint. Supported brands are BrandChrome, BrandBrave, BrandEdge,
+// BrandWhale, and derivatives described with BrandCustomChromium.
 // Version should be the full Chromium version string (e.g., "137.0.0.0").
-// Minimum supported version is 100.
-func Chromium(brand Brand, version string) (*ClientSpec, error) {
+// Minimum supported version is 83.
+//
+// A version without a build number, like "137.0.0.0", is given one from the
+// major's stable releases, picked once per spec, for the full version client
+// hints and, before the reduced user agent of 110, the user agent. Pass a full
+// version (e.g., "99.0.4844.51") to choose the build. From 110 the user agent
+// carries the major alone whichever is passed. Client hints follow their rollout: sec-ch-ua and sec-ch-ua-mobile
+// from 89, sec-ch-ua-platform from 93.
+func Chromium(brand Brand, version string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
 	majorStr, majorNum, err := parseMajorVersion(version)
 	if err != nil {
 		return nil, err
 	}
 
-	if majorNum < 100 {
-		return nil, fmt.Errorf("chromium %s: %w", version, ErrUnsupportedVersion)
+	if majorNum < chromiumMinMajor {
+		return nil, &VersionTooOldError{Browser: "chromium", Min: chromiumMinMajor, Got: majorNum}
+	}
+
+	windows, err := resolveWindows(cfg.windowsGeneration, cfg.windowsVersion, version, majorNum)
+	if err != nil {
+		return nil, err
+	}
+
+	arch := cfg.arch
+	switch arch {
+	case "":
+		arch = ArchX64
+	case ArchX64, ArchARM64, ArchX86:
+	default:
+		return nil, fmt.Errorf("chromium arch %q: %w", arch, ErrUnsupportedPlatform)
 	}
 
 	helloID := chromiumTLSHelloID(majorNum)
-	if err := validateTLSHelloID(helloID); err != nil {
+	ts, err := cfg.newTLSSpec(helloID)
+	if err != nil {
+		return nil, fmt.Errorf("chromium %s: %w", version, err)
+	}
+	platformTS, err := cfg.platformTLSSpecs(helloID)
+	if err != nil {
 		return nil, fmt.Errorf("chromium %s: %w", version, err)
 	}
 
+	// the user agent carries the full version until the reduced user agent,
+	// and the full version hints carry it after
+	fullVersion := chromiumFullVersion(version, majorNum)
+	uaVersion := fullVersion
+	if majorNum >= 110 {
+		uaVersion = majorStr + ".0.0.0"
+	}
+
+	named := chromiumBrandFor(brand, uaVersion)
+
+	brands := cfg.brands
+	if brands == nil {
+		seed := greaseSeed(majorNum)
+		if cfg.greaseSeed != nil {
+			seed = *cfg.greaseSeed
+		}
+		hint := BrandVersion{Brand: named.hint, Version: named.hintVersion(majorStr)}
+		brands = clientHintBrands(hint, majorStr, majorNum, seed, cfg.greaseStrategy)
+	}
+
 	return &ClientSpec{
 		version:      version,
-		http2Options: chromiumHTTP2Options(majorNum),
-		tlsSpecFn: func(_ Platform) (func() *utls.ClientHelloSpec, error) {
-			return newTLSSpecFunc(helloID), nil
-		},
-		buildHeaders: chromiumBuildHeaders(brand, version, majorStr, majorNum),
+		fullVersion:  fullVersion,
+		http2Options: cfg.http2Options(chromiumHTTP2Options(majorNum)),
+		timeouts:     chromiumTimeouts(),
+		brands:       brands,
+		windows:      windows.generation,
+		requireSCTs:  true,
+		saveData:     true,
+		tlsSpecFor: cfg.tlsSpecFor(func(p Platform) (*tlsSpec, error) {
+			if pts, ok := platformTS[p]; ok {
+				return pts, nil
+			}
+			return ts, nil
+		}),
+		buildHeaders: cfg.buildHeaders(chromiumBuildHeaders(named, uaVersion, majorNum, brands, windows.nt, arch), expandedAcceptLanguage),
+		clientHints:  chromiumClientHints(majorNum, fullVersion, brands, arch, windows, cfg.preferences, cfg.device),
+		fetch:        chromiumFetchHeaders(majorNum),
+
+		quicParameters: chromiumQUICParameters,
 	}, nil
 }
 
+// chromiumTLSHelloID returns the ClientHello Chromium majorNum sends on a new
+// connection. The hellos utls offers with a PSK extension are those sent when
+// resuming a session, so they are not used.
 func chromiumTLSHelloID(majorNum int) utls.ClientHelloID {
 	switch {
+	case majorNum < 87:
+		return utls.HelloChrome_83
+	case majorNum < 96:
+		return utls.HelloChrome_87
+	case majorNum < 100:
+		return utls.HelloChrome_96
 	case majorNum < 102:
 		return utls.HelloChrome_100
 	case majorNum < 106:
 		return utls.HelloChrome_102
-	case majorNum < 112:
+	case majorNum < 117:
 		return utls.HelloChrome_106_Shuffle
-	case majorNum < 114:
-		return utls.HelloChrome_112_PSK_Shuf
-	case majorNum < 115:
-		return utls.HelloChrome_114_Padding_PSK_Shuf
-	case majorNum < 120:
-		return utls.HelloChrome_115_PQ
-	case majorNum < 131:
+	case majorNum < 124:
+		// GREASE ECH from 117
 		return utls.HelloChrome_120
+	case majorNum < 131:
+		// the X25519Kyber768Draft00 key share, on by default from 124
+		return utls.HelloChrome_120_PQ
 	case majorNum < 133:
+		// X25519MLKEM768 in place of Kyber from 131
 		return utls.HelloChrome_131
 	default: // >=133
+		// later releases send the same extensions, X25519MLKEM768 key share,
+		// and ALPS codepoint 17613; only the extension order, which is
+		// shuffled per connection, differs between handshakes
 		return utls.HelloChrome_133
 	}
 }
@@ -66,6 +140,9 @@ func chromiumHTTP2Options(majorNum int) *HTTP2Options {
 		MaxHeaderListSize: 262144,
 		InitialWindowSize: 6291456,
 		HeaderTableSize:   65536,
+		UploadChunkSize:   16384,
+		// Chromium never runs more than 256 streams on a session
+		MaxConcurrentStreams: 256,
 	}
 
 	switch {
@@ -97,41 +174,187 @@ func chromiumHTTP2Options(majorNum int) *HTTP2Options {
 	return opts
 }
 
+var (
+	chromiumNavigationOrder = []string{
+		"content-length", "pragma", "cache-control", "sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "origin",
+		"content-type", "upgrade-insecure-requests", "user-agent", "accept", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-user", "sec-fetch-dest", "referer",
+		"accept-encoding", "accept-language", "cookie", "if-none-match", "if-modified-since", "priority",
+	}
+	chromiumFetchOrder = []string{
+		"content-length", "pragma", "cache-control", "sec-ch-ua-platform", "user-agent", "sec-ch-ua", "content-type",
+		"sec-ch-ua-mobile", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer",
+		"accept-encoding", "accept-language", "cookie", "if-none-match", "if-modified-since", "priority",
+	}
+)
+
+// chromiumFetchHeaders follows Chromium adding AVIF in 85, lowering the
+// signed exchange weight in 100, and adding zstd in 123.
+func chromiumFetchHeaders(majorNum int) *fetchHeaders {
+	fh := &fetchHeaders{
+		document:        "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.7",
+		image:           "image/avif,image/webp,image/apng,image/svg+xml,image/*,*/*;q=0.8",
+		acceptEncoding:  "gzip, deflate, br",
+		metadata:        true,
+		navigationOrder: chromiumNavigationOrder,
+		fetchOrder:      chromiumFetchOrder,
+		revalidatePost:  true,
+	}
+	switch {
+	case majorNum < 85:
+		fh.document = "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
+		fh.image = "image/webp,image/apng,image/*,*/*;q=0.8"
+	case majorNum < 100:
+		fh.document = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,image/apng,*/*;q=0.8,application/signed-exchange;v=b3;q=0.9"
+	case majorNum >= 123:
+		fh.acceptEncoding = "gzip, deflate, br, zstd"
+	}
+	return fh
+}
+
+// chromiumTimeouts approximates Chromium's network stack limits. Chromium has no
+// dedicated response header timeout, so waiting for headers is bounded by the same
+// five minute limit its socket pools use for unresponsive requests. Its pools
+// keep used idle sockets for five minutes. Chromium does not ping idle HTTP/2
+// sessions on a timer; it only checks one that has been idle for ten seconds
+// when it next sends a request, which Ping cannot express, so Ping is unset.
+func chromiumTimeouts() Timeouts {
+	return Timeouts{
+		Connect:        30 * time.Second,
+		TLSHandshake:   30 * time.Second,
+		ResponseHeader: 5 * time.Minute,
+		BodyStall:      30 * time.Second,
+		Idle:           5 * time.Minute,
+	}
+}
+
 // chromiumBuildHeaders returns a function that generates Chromium-appropriate default headers
 // for a given platform. This includes User-Agent, sec-ch-ua, sec-ch-ua-mobile,
-// and sec-ch-ua-platform.
-func chromiumBuildHeaders(brand Brand, version string, majorStr string, majorNum int) func(Platform) (http.Header, error) {
+// and sec-ch-ua-platform, for the versions that send them. WindowsNT is the
+// Windows NT version in the user agent.
+func chromiumBuildHeaders(brand chromiumBrand, version string, majorNum int, brands []BrandVersion, windowsNT string, arch Arch) func(Platform) (http.Header, error) {
+	// the reduced user agent names x64 whatever the build
+	uaArch := arch
+	if majorNum >= 110 {
+		uaArch = ArchX64
+	}
+
 	return func(p Platform) (http.Header, error) {
 		var uaPlatform, hintPlatform string
 
 		switch p {
 		case PlatformWindows:
-			uaPlatform = "Windows NT 10.0; Win64; x64"
+			switch uaArch {
+			case ArchX86:
+				uaPlatform = "Windows NT " + windowsNT + "; WOW64"
+			case ArchARM64:
+				uaPlatform = "Windows NT " + windowsNT
+			default:
+				uaPlatform = "Windows NT " + windowsNT + "; Win64; x64"
+			}
 			hintPlatform = "Windows"
 		case PlatformMac:
 			uaPlatform = "Macintosh; Intel Mac OS X 10_15_7"
 			hintPlatform = "macOS"
 		case PlatformLinux:
 			uaPlatform = "X11; Linux x86_64"
+			if uaArch == ArchARM64 {
+				uaPlatform = "X11; Linux aarch64"
+			}
 			hintPlatform = "Linux"
 		default:
-			return nil, fmt.Errorf("chromium on %s: %w", p, ErrUnsupportedPlatform)
+			return nil, &PlatformError{Browser: "chromium", Platform: p}
 		}
 
-		ua := fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", uaPlatform, version)
-
-		// Real Edge appends "Edg/{version}" to the UA string.
-		// Brave uses the same UA as Chrome (no additional suffix).
-		if brand == BrandEdge {
-			ua += fmt.Sprintf(" Edg/%s", version)
+		if arch == ArchX86 && p != PlatformWindows {
+			return nil, &PlatformError{Browser: "32-bit chromium", Platform: p}
 		}
 
+		ua := brand.userAgent(fmt.Sprintf("Mozilla/5.0 (%s) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36", uaPlatform, version))
+
 		h := http.Header{}
 		h.Set("user-agent", ua)
-		h.Set("sec-ch-ua", clientHintUA(brand, majorStr, majorNum))
-		h.Set("sec-ch-ua-mobile", "?0")
-		h.Set("sec-ch-ua-platform", fmt.Sprintf(`"%s"`, hintPlatform))
+		if majorNum >= 89 {
+			h.Set("sec-ch-ua", formatBrandList(brands))
+			h.Set("sec-ch-ua-mobile", "?0")
+		}
+		if majorNum >= 93 {
+			h.Set("sec-ch-ua-platform", fmt.Sprintf(`"%s"`, hintPlatform))
+		}
+
+		return h, nil
+	}
+}
+
+// chromiumClientHints returns a function that generates the high-entropy
+// client hints for arch and, on PlatformWindows, windows, following their
+// rollout: sec-ch-ua-arch, sec-ch-ua-platform-version, and
+// sec-ch-ua-full-version from 89, sec-ch-ua-bitness from 93,
+// sec-ch-ua-full-version-list for brands from 98, and sec-ch-ua-wow64 from
+// 100. The preference hints for prefs and the device hints for device are
+// included too.
+func chromiumClientHints(majorNum int, fullVersion string, brands []BrandVersion, arch Arch, windows windowsIdentity, prefs Preferences, device *Device) func(Platform) (http.Header, error) {
+	hintArch, bitness := "x86", "64"
+	switch arch {
+	case ArchARM64:
+		hintArch = "arm"
+	case ArchX86:
+		bitness = "32"
+	}
 
+	return func(p Platform) (http.Header, error) {
+		switch {
+		case p != PlatformWindows && p != PlatformMac && p != PlatformLinux:
+			return nil, &PlatformError{Browser: "chromium", Platform: p}
+		case arch == ArchX86 && p != PlatformWindows:
+			return nil, &PlatformError{Browser: "32-bit chromium", Platform: p}
+		}
+
+		h := http.Header{}
+		if majorNum >= 89 {
+			h.Set("sec-ch-ua-arch", fmt.Sprintf(`"%s"`, hintArch))
+			if p == PlatformWindows {
+				h.Set("sec-ch-ua-platform-version", fmt.Sprintf(`"%s"`, windows.platformVersion))
+			}
+			h.Set("sec-ch-ua-full-version", fmt.Sprintf(`"%s"`, fullVersion))
+		}
+		if majorNum >= 93 {
+			h.Set("sec-ch-ua-bitness", fmt.Sprintf(`"%s"`, bitness))
+		}
+		if majorNum >= 98 {
+			h.Set("sec-ch-ua-full-version-list", formatBrandList(fullVersionList(brands, fullVersion)))
+		}
+		if majorNum >= 100 {
+			wow64 := "?0"
+			if arch == ArchX86 {
+				wow64 = "?1"
+			}
+			h.Set("sec-ch-ua-wow64", wow64)
+		}
+		preferenceHints(h, majorNum, prefs)
+		deviceHints(h, majorNum, p, device)
 		return h, nil
 	}
 }
+
+// chromiumQUICParameters returns the transport parameters Chromium's QUIC stack
+// sends, in a fresh random order as Chromium picks one per connection.
+func chromiumQUICParameters() utls.TransportParameters {
+	return shuffleTransportParameters(utls.TransportParameters{
+		utls.MaxIdleTimeout(30000),
+		utls.MaxUDPPayloadSize(1472),
+		utls.InitialMaxData(15728640),
+		utls.InitialMaxStreamDataBidiLocal(6291456),
+		utls.InitialMaxStreamDataBidiRemote(6291456),
+		utls.InitialMaxStreamDataUni(6291456),
+		utls.InitialMaxStreamsBidi(100),
+		utls.InitialMaxStreamsUni(103),
+		utls.MaxDatagramFrameSize(65536),
+		utls.InitialSourceConnectionID{},
+		&utls.VersionInformation{
+			ChoosenVersion:    utls.VERSION_1,
+			AvailableVersions: []uint32{utls.VERSION_GREASE, utls.VERSION_1},
+		},
+		&utls.GREASEQUICBit{},
+		&utls.GREASETransportParameter{},
+	})
+}
@@ -0,0 +1,146 @@
+package mimic
+
+import (
+	"fmt"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/cookiejar"
+	"golang.org/x/net/publicsuffix"
+)
+
+// maxRedirects is the number of redirects Chromium and Firefox follow before
+// failing a navigation with ERR_TOO_MANY_REDIRECTS.
+const maxRedirects = 20
+
+// ClientOption configures a Client created by NewClient.
+type ClientOption func(*clientConfig)
+
+type clientConfig struct {
+	transportOpts   []TransportOption
+	jar             http.CookieJar
+	checkRedirect   func(req *http.Request, via []*http.Request) error
+	rateLimiter     *RateLimiter
+	pacer           *Pacer
+	refreshMaxDelay *time.Duration
+	middleware      []Middleware
+	cache           *Cache
+	cookieGates     *CookieGates
+	maxBodySize     int64
+	sniff           bool
+	charset         bool
+
+	decompressionLimits DecompressionLimits
+	decompressionStats  *DecompressionStats
+}
+
+// WithTransportOptions passes options through to the underlying NewTransport call.
+func WithTransportOptions(opts ...TransportOption) ClientOption {
+	return func(c *clientConfig) {
+		c.transportOpts = append(c.transportOpts, opts...)
+	}
+}
+
+// WithCookieJar sets the cookie jar used by the client.
+// If not set, a jar backed by the public suffix list is created.
+func WithCookieJar(jar http.CookieJar) ClientOption {
+	return func(c *clientConfig) {
+		c.jar = jar
+	}
+}
+
+// WithCheckRedirect sets the redirect policy used by the client.
+// If not set, redirects are followed the way browsers follow them.
+func WithCheckRedirect(fn func(req *http.Request, via []*http.Request) error) ClientOption {
+	return func(c *clientConfig) {
+		c.checkRedirect = fn
+	}
+}
+
+// NewClient creates an http.Client that mimics the given browser spec on the given platform.
+// The client is wired with a Transport from NewTransport, a cookie jar that enforces
+// public suffix boundaries, browser redirect limits, and transparent decompression
+// of gzip, deflate, br, and zstd response bodies.
+func NewClient(spec *ClientSpec, platform Platform, opts ...ClientOption) (*http.Client, error) {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	transport, err := NewTransport(spec, platform, cfg.transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newClient(transport, cfg)
+}
+
+// newClient wraps transport in an http.Client configured by cfg.
+func newClient(transport *Transport, cfg *clientConfig) (*http.Client, error) {
+	if cfg.jar == nil {
+		jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+		if err != nil {
+			return nil, fmt.Errorf("creating cookie jar: %w", err)
+		}
+		cfg.jar = jar
+	}
+
+	if cfg.checkRedirect == nil {
+		cfg.checkRedirect = browserCheckRedirect
+	}
+
+	var rt http.RoundTripper = transport
+	if cfg.rateLimiter != nil {
+		rt = &rateLimitTransport{wrapped: wrapped{rt}, limiter: cfg.rateLimiter}
+	}
+	if cfg.pacer != nil {
+		// the visitor decides to navigate before the limiter releases it
+		rt = &pacingTransport{wrapped: wrapped{rt}, pacer: cfg.pacer}
+	}
+	if cfg.cache != nil {
+		// responses served from the cache skip the network entirely
+		rt = &cacheTransport{wrapped: wrapped{rt}, cache: cfg.cache}
+	}
+
+	rt = &decompressTransport{
+		wrapped:     wrapped{rt},
+		maxBodySize: cfg.maxBodySize,
+		limits:      cfg.decompressionLimits,
+		stats:       cfg.decompressionStats,
+	}
+	if cfg.sniff {
+		// types are sniffed from the decoded body
+		rt = &sniffTransport{wrapped: wrapped{rt}}
+	}
+	if cfg.charset {
+		// charsets are found in the decoded body, by its sniffed type
+		rt = &charsetTransport{wrapped: wrapped{rt}}
+	}
+	if cfg.refreshMaxDelay != nil {
+		// refreshes are found in the decoded document
+		rt = &refreshTransport{wrapped: wrapped{rt}, maxDelay: *cfg.refreshMaxDelay}
+	}
+	if cfg.cookieGates != nil {
+		rt = &cookieGateTransport{wrapped: wrapped{rt}, jar: cfg.jar, gates: *cfg.cookieGates}
+	}
+	if len(cfg.middleware) > 0 {
+		rt = &clientMiddleware{wrapped: wrapped{rt}, chain: chain(rt, cfg.middleware)}
+	}
+
+	return &http.Client{
+		Transport:     rt,
+		Jar:           cfg.jar,
+		CheckRedirect: cfg.checkRedirect,
+	}, nil
+}
+
+// browserCheckRedirect follows up to maxRedirects redirects, matching the limit
+// enforced by Chromium and Firefox.
+func browserCheckRedirect(_ *http.Request, via []*http.Request) error {
+	// via holds the requests already sent, one more than the redirects
+	// followed before this one
+	if len(via) > maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	return nil
+}
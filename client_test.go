@@ -0,0 +1,61 @@
+package mimic
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// hopRoundTripper redirects /N to /N+1 until /hops, which it answers.
+type hopRoundTripper struct {
+	hops int
+}
+
+func (rt hopRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	n, _ := strconv.Atoi(strings.TrimPrefix(req.URL.Path, "/"))
+	if n >= rt.hops {
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusFound,
+		Header:     http.Header{"Location": {"/" + strconv.Itoa(n+1)}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func TestRedirectLimit(t *testing.T) {
+	tests := []struct {
+		hops    int
+		wantErr bool
+	}{
+		{maxRedirects, false},
+		{maxRedirects + 1, true},
+	}
+
+	for _, tt := range tests {
+		tr := newTestTransport(t)
+		tr.transport = hopRoundTripper{hops: tt.hops}
+		client, err := newClient(tr, &clientConfig{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := client.Get("https://example.com/0")
+		if tt.wantErr {
+			if err == nil || !strings.Contains(err.Error(), "stopped after 20 redirects") {
+				t.Errorf("%d hops: err = %v, want the redirect limit", tt.hops, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d hops: %v", tt.hops, err)
+		}
+		res.Body.Close()
+		if got := res.Request.URL.Path; got != "/"+strconv.Itoa(tt.hops) {
+			t.Errorf("%d hops: ended at %s", tt.hops, got)
+		}
+	}
+}
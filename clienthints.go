@@ -15,44 +15,86 @@ var (
 	}
 )
 
-func formatBrand(brand Brand, majorVersion string) string {
-	return fmt.Sprintf(`"%s";v="%s"`, brand, majorVersion)
+// GreaseStrategy selects how the GREASE brand in sec-ch-ua is generated.
+type GreaseStrategy int
+
+const (
+	// GreaseAuto uses whichever algorithm the claimed Chromium major ships with.
+	GreaseAuto GreaseStrategy = iota
+
+	// GreaseUpdated uses the "Not?A_Brand" algorithm Chromium enabled by default
+	// in 105 (https://github.com/WICG/ua-client-hints/pull/310).
+	GreaseUpdated
+
+	// GreaseLegacy uses the " Not A;Brand" algorithm from before 105, which newer
+	// versions also fall back to when the UserAgentClientHintsGREASEUpdateEnabled
+	// enterprise policy is disabled.
+	GreaseLegacy
+)
+
+// BrandVersion is one entry of the sec-ch-ua brand list.
+type BrandVersion struct {
+	Brand   string
+	Version string
+}
+
+func (b BrandVersion) String() string {
+	return fmt.Sprintf(`"%s";v="%s"`, b.Brand, b.Version)
 }
 
-func greasedBrand(seed int, majorVersionNumber int, permutedOrder []int) string {
+func greasedBrand(seed int, majorVersionNumber int, strategy GreaseStrategy, permutedOrder []int) BrandVersion {
 	var brand, version string
 
 	switch {
-	case majorVersionNumber <= 102, majorVersionNumber == 104:
+	case strategy == GreaseLegacy,
+		strategy == GreaseAuto && (majorVersionNumber <= 102 || majorVersionNumber == 104):
 		brand = fmt.Sprintf("%sNot%sA%sBrand", legacyGreasyChars[permutedOrder[0]], legacyGreasyChars[permutedOrder[1]], legacyGreasyChars[permutedOrder[2]])
 		version = "99"
-	case majorVersionNumber == 103:
+	case strategy == GreaseAuto && majorVersionNumber == 103:
 		brand = fmt.Sprintf("%sNot%sA%sBrand", greasyChars[(seed%(len(greasyChars)-1))+1], greasyChars[(seed+1)%len(greasyChars)], greasyChars[(seed+2)%len(greasyChars)])
 		version = greasyVersion[seed%len(greasyVersion)]
-	default: // >=105
+	default: // >=105 or GreaseUpdated
 		// https://github.com/WICG/ua-client-hints/pull/310
 		brand = fmt.Sprintf("Not%sA%sBrand", greasyChars[seed%len(greasyChars)], greasyChars[(seed+1)%len(greasyChars)])
 		version = greasyVersion[seed%len(greasyVersion)]
 	}
 
-	return formatBrand(Brand(brand), version)
-
+	return BrandVersion{Brand: brand, Version: version}
 }
 
-func clientHintUA(brand Brand, majorVersion string, majorVersionNumber int) string {
-	seed := majorVersionNumber
+// greaseSeed returns the seed Chromium uses for the brand list: the major version.
+func greaseSeed(majorVersionNumber int) int {
 	if majorVersionNumber <= 102 {
 		// legacy behavior (maybe a bug?)
-		seed = 0
+		return 0
 	}
+	return majorVersionNumber
+}
 
+// clientHintBrands returns the sec-ch-ua brand list in the order Chromium emits it.
+// The list is generated by Chromium's shared embedder code, so derivatives like
+// Edge and Brave get the same permutation with hint, their own brand, in
+// Chrome's place.
+//
+// The seed drives both the permutation and the GREASE brand characters; real
+// Chromium always uses greaseSeed(majorVersionNumber).
+func clientHintBrands(hint BrandVersion, majorVersion string, majorVersionNumber int, seed int, strategy GreaseStrategy) []BrandVersion {
 	order := greasyOrders[seed%len(greasyOrders)]
 
-	greased := make([]string, 3)
+	brands := make([]BrandVersion, 3)
 
-	greased[order[0]] = greasedBrand(seed, majorVersionNumber, order)
-	greased[order[1]] = formatBrand("Chromium", majorVersion)
-	greased[order[2]] = formatBrand(brand, majorVersion)
+	brands[order[0]] = greasedBrand(seed, majorVersionNumber, strategy, order)
+	brands[order[1]] = BrandVersion{Brand: "Chromium", Version: majorVersion}
+	brands[order[2]] = hint
 
-	return strings.Join(greased, ", ")
+	return brands
+}
+
+// formatBrandList renders a brand list as a sec-ch-ua header value.
+func formatBrandList(brands []BrandVersion) string {
+	formatted := make([]string, len(brands))
+	for i, b := range brands {
+		formatted[i] = b.String()
+	}
+	return strings.Join(formatted, ", ")
 }
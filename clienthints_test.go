@@ -1,6 +1,10 @@
 package mimic
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -29,10 +33,421 @@ func TestClientHintUA(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		ua := clientHintUA(BrandChrome, majorStr, majorNum)
+		hint := BrandVersion{Brand: string(BrandChrome), Version: majorStr}
+		ua := formatBrandList(clientHintBrands(hint, majorStr, majorNum, greaseSeed(majorNum), GreaseAuto))
 
 		if ua != test.clientHintUa {
 			t.Errorf("version %s: want %s; got %s", test.version, test.clientHintUa, ua)
 		}
 	}
 }
+
+func TestClientHintUAEdge(t *testing.T) {
+	tests := []struct {
+		version      string
+		clientHintUa string
+	}{
+		{"100.0.0.0", `" Not A;Brand";v="99", "Chromium";v="100", "Microsoft Edge";v="100"`},
+		{"120.0.0.0", `"Not_A Brand";v="8", "Chromium";v="120", "Microsoft Edge";v="120"`},
+		{"137.0.0.0", `"Microsoft Edge";v="137", "Chromium";v="137", "Not/A)Brand";v="24"`},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandEdge, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ua := formatBrandList(spec.Brands()); ua != test.clientHintUa {
+			t.Errorf("version %s: want %s; got %s", test.version, test.clientHintUa, ua)
+		}
+	}
+}
+
+func TestWithBrandList(t *testing.T) {
+	brands := []BrandVersion{
+		{Brand: "Chromium", Version: "137"},
+		{Brand: "Microsoft Edge", Version: "137"},
+		{Brand: "Not/A)Brand", Version: "24"},
+	}
+
+	spec, err := Chromium(BrandEdge, "137.0.0.0", WithBrandList(brands...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := spec.buildHeaders(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `"Chromium";v="137", "Microsoft Edge";v="137", "Not/A)Brand";v="24"`
+	if got := headers.Get("sec-ch-ua"); got != want {
+		t.Errorf("want %s; got %s", want, got)
+	}
+}
+
+func TestGreaseOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		version      string
+		opts         []SpecOption
+		clientHintUa string
+	}{
+		{
+			name:         "legacy strategy on 137",
+			version:      "137.0.0.0",
+			opts:         []SpecOption{WithGreaseStrategy(GreaseLegacy)},
+			clientHintUa: `"Google Chrome";v="137", "Chromium";v="137", ";Not A Brand";v="99"`,
+		},
+		{
+			name:         "updated strategy on 100",
+			version:      "100.0.0.0",
+			opts:         []SpecOption{WithGreaseStrategy(GreaseUpdated)},
+			clientHintUa: `"Not A(Brand";v="8", "Chromium";v="100", "Google Chrome";v="100"`,
+		},
+		{
+			name:         "fixed seed matches the seed's own major",
+			version:      "137.0.0.0",
+			opts:         []SpecOption{WithGreaseSeed(120)},
+			clientHintUa: `"Not_A Brand";v="8", "Chromium";v="137", "Google Chrome";v="137"`,
+		},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandChrome, test.version, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ua := formatBrandList(spec.Brands()); ua != test.clientHintUa {
+			t.Errorf("%s: want %s; got %s", test.name, test.clientHintUa, ua)
+		}
+	}
+}
+
+func TestLegacyChromiumHeaders(t *testing.T) {
+	tests := []struct {
+		version  string
+		hints    []string
+		noHints  []string
+		document string
+	}{
+		{"88.0.4324.190", nil, []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform"}, "image/avif"},
+		{"91.0.4472.124", []string{"sec-ch-ua", "sec-ch-ua-mobile"}, []string{"sec-ch-ua-platform"}, "v=b3;q=0.9"},
+		{"99.0.4844.51", []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform"}, nil, "v=b3;q=0.9"},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandChrome, test.version, WithWindowsVersion("6.1"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers, err := spec.buildHeaders(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := "Mozilla/5.0 (Windows NT 6.1; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/" + test.version + " Safari/537.36"
+		if ua := headers.Get("user-agent"); ua != want {
+			t.Errorf("%s: want %s; got %s", test.version, want, ua)
+		}
+		for _, key := range test.hints {
+			if headers.Get(key) == "" {
+				t.Errorf("%s: want %s", test.version, key)
+			}
+		}
+		for _, key := range test.noHints {
+			if headers.Get(key) != "" {
+				t.Errorf("%s: want no %s; got %s", test.version, key, headers.Get(key))
+			}
+		}
+		if !strings.Contains(spec.fetch.document, test.document) {
+			t.Errorf("%s: want %s in the document accept; got %s", test.version, test.document, spec.fetch.document)
+		}
+	}
+
+	if _, err := Chromium(BrandChrome, "120.0.0.0", WithWindowsVersion("6.1")); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion for Windows 7 after the user agent freeze; got %v", err)
+	}
+}
+
+func TestChromiumFullVersion(t *testing.T) {
+	tests := []struct {
+		version    string
+		fullPrefix string
+		uaVersion  string
+	}{
+		{"137.0.0.0", "137.0.7151.", "137.0.0.0"},
+		{"137.0.7151.104", "137.0.7151.104", "137.0.0.0"},
+		{"99.0.0.0", "99.0.4844.", ""},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandChrome, test.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		full := spec.FullVersion()
+		if !strings.HasPrefix(full, test.fullPrefix) || strings.HasSuffix(full, ".0.0") {
+			t.Errorf("%s: want a full version starting %s; got %s", test.version, test.fullPrefix, full)
+		}
+
+		headers, err := spec.buildHeaders(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		uaVersion := test.uaVersion
+		if uaVersion == "" {
+			uaVersion = full
+		}
+		if ua := headers.Get("user-agent"); !strings.Contains(ua, "Chrome/"+uaVersion+" ") {
+			t.Errorf("%s: want Chrome/%s in the user agent; got %s", test.version, uaVersion, ua)
+		}
+
+		// the build is picked once, so every request reports the same one
+		for range 3 {
+			hints, err := spec.ClientHints(PlatformWindows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := hints.Get("sec-ch-ua-full-version"), `"`+full+`"`; got != want {
+				t.Errorf("%s: want sec-ch-ua-full-version %s; got %s", test.version, want, got)
+			}
+		}
+	}
+
+	spec, err := Chromium(BrandChrome, "137.0.7151.104")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints, err := spec.ClientHints(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"Google Chrome";v="137.0.7151.104", "Chromium";v="137.0.7151.104", "Not/A)Brand";v="24.0.0.0"`
+	if got := hints.Get("sec-ch-ua-full-version-list"); got != want {
+		t.Errorf("want sec-ch-ua-full-version-list %s; got %s", want, got)
+	}
+
+	spec, err = Chromium(BrandEdge, "137.0.7151.104")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hints, err = spec.ClientHints(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hints.Get("sec-ch-ua-full-version-list"); !strings.Contains(got, `"Microsoft Edge";v="137.0.0.0"`) {
+		t.Errorf("want Edge at its major in sec-ch-ua-full-version-list; got %s", got)
+	}
+}
+
+func TestChromiumArch(t *testing.T) {
+	tests := []struct {
+		version   string
+		arch      Arch
+		platform  Platform
+		uaToken   string
+		hintArch  string
+		bitness   string
+		wow64     string
+		supported bool
+	}{
+		{"137.0.0.0", ArchX64, PlatformWindows, "(Windows NT 10.0; Win64; x64)", `"x86"`, `"64"`, "?0", true},
+		{"137.0.0.0", ArchARM64, PlatformWindows, "(Windows NT 10.0; Win64; x64)", `"arm"`, `"64"`, "?0", true},
+		{"137.0.0.0", ArchARM64, PlatformMac, "(Macintosh; Intel Mac OS X 10_15_7)", `"arm"`, `"64"`, "?0", true},
+		{"137.0.0.0", ArchX86, PlatformWindows, "(Windows NT 10.0; Win64; x64)", `"x86"`, `"32"`, "?1", true},
+		{"99.0.4844.51", ArchX86, PlatformWindows, "(Windows NT 10.0; WOW64)", `"x86"`, `"32"`, "", true},
+		{"109.0.5414.120", ArchARM64, PlatformWindows, "(Windows NT 10.0)", `"arm"`, `"64"`, "?0", true},
+		{"109.0.5414.120", ArchARM64, PlatformLinux, "(X11; Linux aarch64)", `"arm"`, `"64"`, "?0", true},
+		{"137.0.0.0", ArchX86, PlatformMac, "", "", "", "", false},
+	}
+
+	for _, test := range tests {
+		name := fmt.Sprintf("%s %s on %s", test.version, test.arch, test.platform)
+
+		spec, err := Chromium(BrandChrome, test.version, WithArch(test.arch))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers, err := spec.buildHeaders(test.platform)
+		hints, hintsErr := spec.ClientHints(test.platform)
+		if !test.supported {
+			if !errors.Is(err, ErrUnsupportedPlatform) || !errors.Is(hintsErr, ErrUnsupportedPlatform) {
+				t.Errorf("%s: want ErrUnsupportedPlatform; got %v and %v", name, err, hintsErr)
+			}
+			continue
+		}
+		if err != nil || hintsErr != nil {
+			t.Fatal(err, hintsErr)
+		}
+
+		if ua := headers.Get("user-agent"); !strings.Contains(ua, test.uaToken) {
+			t.Errorf("%s: want %s in the user agent; got %s", name, test.uaToken, ua)
+		}
+		for key, want := range map[string]string{
+			"sec-ch-ua-arch":    test.hintArch,
+			"sec-ch-ua-bitness": test.bitness,
+			"sec-ch-ua-wow64":   test.wow64,
+		} {
+			if got := hints.Get(key); got != want {
+				t.Errorf("%s: want %s %q; got %q", name, key, want, got)
+			}
+		}
+	}
+
+	if _, err := Chromium(BrandChrome, "137.0.0.0", WithArch("mips")); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("want ErrUnsupportedPlatform for an unknown arch; got %v", err)
+	}
+}
+
+func TestCustomBrands(t *testing.T) {
+	tests := []struct {
+		brand Brand
+		ua    string
+		hint  BrandVersion
+	}{
+		{
+			BrandWhale("4.29.282.14"),
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Whale/4.29.282.14 Safari/537.36",
+			BrandVersion{Brand: "Whale", Version: "4"},
+		},
+		{
+			BrandCustomChromium("Opera", "OPR/115.0.0.0", "Opera"),
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 OPR/115.0.0.0",
+			BrandVersion{Brand: "Opera", Version: "115"},
+		},
+		{
+			BrandCustomChromium("Vivaldi", "", "Vivaldi"),
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36",
+			BrandVersion{Brand: "Vivaldi", Version: "130"},
+		},
+		{
+			BrandEdge,
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0",
+			BrandVersion{Brand: "Microsoft Edge", Version: "130"},
+		},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(test.brand, "130.0.0.0")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers, err := spec.buildHeaders(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ua := headers.Get("user-agent"); ua != test.ua {
+			t.Errorf("%s: want %s; got %s", test.brand, test.ua, ua)
+		}
+		if !slices.Contains(spec.Brands(), test.hint) {
+			t.Errorf("%s: want %v in %v", test.brand, test.hint, spec.Brands())
+		}
+	}
+
+	// a Brand is its own description, so another with the same name does
+	// not change it
+	opera := BrandCustomChromium("Opera", "OPR/115.0.0.0", "Opera")
+	BrandCustomChromium("Opera", "OPR/115.0.0.0", "Opera GX")
+	spec, err := Chromium(opera, "130.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (BrandVersion{Brand: "Opera", Version: "115"}); !slices.Contains(spec.Brands(), want) {
+		t.Errorf("want %v in %v", want, spec.Brands())
+	}
+}
+
+func TestChromiumPreferences(t *testing.T) {
+	tests := []struct {
+		version       string
+		prefs         Preferences
+		colorScheme   string
+		reducedMotion string
+	}{
+		{"137.0.0.0", Preferences{}, `"light"`, `"no-preference"`},
+		{"137.0.0.0", Preferences{ColorScheme: ColorSchemeDark, ReducedMotion: true}, `"dark"`, `"reduce"`},
+		{"100.0.4896.60", Preferences{ColorScheme: ColorSchemeDark}, `"dark"`, ""},
+		{"92.0.4515.107", Preferences{ColorScheme: ColorSchemeDark}, "", ""},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandChrome, test.version, WithPreferences(test.prefs))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hints, err := spec.ClientHints(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := hints.Get("sec-ch-prefers-color-scheme"); got != test.colorScheme {
+			t.Errorf("%s %+v: want color scheme %q; got %q", test.version, test.prefs, test.colorScheme, got)
+		}
+		if got := hints.Get("sec-ch-prefers-reduced-motion"); got != test.reducedMotion {
+			t.Errorf("%s %+v: want reduced motion %q; got %q", test.version, test.prefs, test.reducedMotion, got)
+		}
+
+		headers, err := spec.buildHeaders(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if headers.Get("sec-ch-prefers-color-scheme") != "" {
+			t.Errorf("%s: want preference hints only on request", test.version)
+		}
+	}
+}
+
+func TestChromiumDevice(t *testing.T) {
+	tests := []struct {
+		version  string
+		device   *Device
+		platform Platform
+		memory   string
+		width    string
+		prefixed bool
+	}{
+		{"137.0.0.0", nil, PlatformWindows, "8", "1920", true},
+		{"137.0.0.0", nil, PlatformMac, "8", "1512", true},
+		{"137.0.0.0", &DeviceBudgetLaptop, PlatformWindows, "4", "1366", true},
+		{"137.0.0.0", &Device{Memory: 3, ViewportWidth: 1280}, PlatformLinux, "4", "1280", true},
+		{"137.0.0.0", &Device{Memory: 0.1, ViewportWidth: 800}, PlatformLinux, "0.25", "800", true},
+		{"99.0.4844.51", &DeviceLaptop, PlatformWindows, "8", "1536", false},
+	}
+
+	for _, test := range tests {
+		var opts []SpecOption
+		if test.device != nil {
+			opts = append(opts, WithDevice(*test.device))
+		}
+		spec, err := Chromium(BrandChrome, test.version, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hints, err := spec.ClientHints(test.platform)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		name := fmt.Sprintf("%s on %s", test.version, test.platform)
+		if got := hints.Get("device-memory"); got != test.memory {
+			t.Errorf("%s: want device-memory %q; got %q", name, test.memory, got)
+		}
+		if got := hints.Get("viewport-width"); got != test.width {
+			t.Errorf("%s: want viewport-width %q; got %q", name, test.width, got)
+		}
+
+		prefixed := hints.Get("sec-ch-device-memory") == test.memory && hints.Get("sec-ch-viewport-width") == test.width
+		if prefixed != test.prefixed || !test.prefixed && hints.Get("sec-ch-device-memory") != "" {
+			t.Errorf("%s: want sec-ch- prefixed hints %t; got %q, %q", name, test.prefixed, hints.Get("sec-ch-device-memory"), hints.Get("sec-ch-viewport-width"))
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// fingerprintURL echoes the TLS and HTTP/2 fingerprint of the calling client.
+const fingerprintURL = "https://tls.peet.ws/api/clean"
+
+// Fingerprint is the response from fingerprintURL.
+type Fingerprint struct {
+	Ja3           string `json:"ja3"`
+	Ja3Hash       string `json:"ja3_hash"`
+	Ja4           string `json:"ja4"`
+	Ja4R          string `json:"ja4_r"`
+	Akamai        string `json:"akamai"`
+	AkamaiHash    string `json:"akamai_hash"`
+	Peetprint     string `json:"peetprint"`
+	PeetprintHash string `json:"peetprint_hash"`
+}
+
+// fetchFingerprint requests fingerprintURL through client.
+func fetchFingerprint(client *http.Client) (*Fingerprint, error) {
+	res, err := client.Get(fingerprintURL)
+	if err != nil {
+		return nil, fmt.Errorf("requesting fingerprint: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting fingerprint: unexpected status: %d", res.StatusCode)
+	}
+
+	var fp Fingerprint
+	if err := json.NewDecoder(res.Body).Decode(&fp); err != nil {
+		return nil, fmt.Errorf("decoding fingerprint: %w", err)
+	}
+
+	return &fp, nil
+}
+
+// printFingerprint writes fp as aligned text or, with asJSON, as JSON.
+func printFingerprint(w io.Writer, fp *Fingerprint, asJSON bool) error {
+	if asJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fp)
+	}
+
+	_, err := fmt.Fprintf(w,
+		"JA3:       %s\nJA3 Hash:  %s\nJA4:       %s\nJA4-R:     %s\nAkamai:    %s\nAkamai #:  %s\nPeetprint: %s\nPeet #:    %s\n",
+		fp.Ja3, fp.Ja3Hash, fp.Ja4, fp.Ja4R, fp.Akamai, fp.AkamaiHash, fp.Peetprint, fp.PeetprintHash,
+	)
+	return err
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aarock1234/mimic"
+)
+
+// runHello implements "mimic hello <file>".
+func runHello(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("mimic hello", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mimic hello <file>\n\n")
+		fmt.Fprintf(fs.Output(), "Converts a captured ClientHello, or a pcap or pcapng capture containing one,\n")
+		fmt.Fprintf(fs.Output(), "into a ClientHello document for mimic.LoadCustomHello. Use - to read stdin.\n")
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return fmt.Errorf("reading capture: %w", err)
+	}
+
+	hello, err := mimic.ParseCapturedHello(data)
+	if err != nil {
+		return err
+	}
+
+	doc, err := json.Marshal(hello)
+	if err != nil {
+		return fmt.Errorf("encoding hello: %w", err)
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, doc, "", "  "); err != nil {
+		return fmt.Errorf("encoding hello: %w", err)
+	}
+	out.WriteByte('\n')
+
+	_, err = out.WriteTo(stdout)
+	return err
+}
@@ -0,0 +1,257 @@
+// Command mimic sends HTTP requests with a browser's TLS, HTTP/2, and header
+// fingerprint, using a curl-like interface.
+//
+//	mimic --browser chrome --version 137 --platform win https://example.com
+//	mimic -X POST -H "content-type: application/json" -d '{"a":1}' https://example.com/api
+//	mimic --browser firefox --fingerprint
+//	mimic probe https://example.com
+//	mimic hello capture.pcapng > hello.json
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aarock1234/mimic"
+	http "github.com/saucesteals/fhttp"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		fmt.Fprintf(os.Stderr, "mimic: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// headerFlags collects repeated -H values.
+type headerFlags []string
+
+func (h *headerFlags) String() string {
+	return strings.Join(*h, ", ")
+}
+
+func (h *headerFlags) Set(value string) error {
+	*h = append(*h, value)
+	return nil
+}
+
+type options struct {
+	method      string
+	headers     headerFlags
+	data        string
+	proxy       string
+	output      string
+	include     bool
+	browser     string
+	version     string
+	platform    string
+	fingerprint bool
+	json        bool
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) > 0 && args[0] == "probe" {
+		return runProbe(args[1:], stdout)
+	}
+	if len(args) > 0 && args[0] == "hello" {
+		return runHello(args[1:], stdout)
+	}
+
+	var opts options
+	fs := newFlagSet(&opts)
+
+	target, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	client, err := newClient(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.fingerprint {
+		fp, err := fetchFingerprint(client)
+		if err != nil {
+			return err
+		}
+		return printFingerprint(stdout, fp, opts.json)
+	}
+
+	if target == "" {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	req, err := newRequest(opts, target)
+	if err != nil {
+		return err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return writeResponse(stdout, res, opts)
+}
+
+// newFlagSet returns the flags of a request, parsed into opts.
+func newFlagSet(opts *options) *flag.FlagSet {
+	fs := flag.NewFlagSet("mimic", flag.ContinueOnError)
+	fs.StringVar(&opts.method, "X", "", "request method (default GET, or POST with -d)")
+	fs.Var(&opts.headers, "H", "request header as \"name: value\" (repeatable)")
+	fs.StringVar(&opts.data, "d", "", "request body, or @file to read it from a file")
+	fs.StringVar(&opts.proxy, "proxy", "", "proxy URL (http, https, or socks5)")
+	fs.StringVar(&opts.output, "o", "", "write the response body to a file instead of stdout")
+	fs.BoolVar(&opts.include, "i", false, "include the response status line and headers in the output")
+	fs.StringVar(&opts.browser, "browser", "chrome", "browser to mimic: chrome, edge, brave, safari, or firefox")
+	fs.StringVar(&opts.version, "version", "", "browser version, either full (137.0.0.0) or major (137)")
+	fs.StringVar(&opts.platform, "platform", "", "platform to mimic: win, mac, linux, ios, or ipados")
+	fs.BoolVar(&opts.fingerprint, "fingerprint", false, "print the fingerprint seen by "+fingerprintURL+" instead of making a request")
+	fs.BoolVar(&opts.json, "json", false, "print output as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mimic [flags] <url>\n\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseInterspersed parses flags that may appear before or after the URL, the
+// way curl accepts them, and returns the URL.
+func parseInterspersed(fs *flag.FlagSet, args []string) (string, error) {
+	var target string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return "", err
+		}
+
+		if fs.NArg() == 0 {
+			return target, nil
+		}
+
+		if target != "" {
+			return "", fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		}
+
+		target = fs.Arg(0)
+		args = fs.Args()[1:]
+	}
+}
+
+func newClient(opts options) (*http.Client, error) {
+	spec, err := newSpec(opts.browser, opts.version)
+	if err != nil {
+		return nil, err
+	}
+
+	var transportOpts []mimic.TransportOption
+	if opts.proxy != "" {
+		proxyURL, err := url.Parse(opts.proxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing proxy: %w", err)
+		}
+		transportOpts = append(transportOpts, mimic.WithProxy(proxyURL))
+	}
+
+	return mimic.NewClient(spec, platformFor(opts.browser, opts.platform),
+		mimic.WithTransportOptions(transportOpts...),
+	)
+}
+
+func newRequest(opts options, target string) (*http.Request, error) {
+	var body io.Reader
+	if opts.data != "" {
+		data := []byte(opts.data)
+		if path, ok := strings.CutPrefix(opts.data, "@"); ok {
+			var err error
+			data, err = os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("reading body: %w", err)
+			}
+		}
+		body = strings.NewReader(string(data))
+	}
+
+	method := opts.method
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	req, err := http.NewRequest(method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	// the Transport sends the browser's own accept headers
+	if body != nil {
+		req.Header.Set("content-type", "application/x-www-form-urlencoded")
+	}
+
+	for _, h := range opts.headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q: want \"name: value\"", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	return req, nil
+}
+
+// jsonResponse is the --json output for a request.
+type jsonResponse struct {
+	Status  int                 `json:"status"`
+	Proto   string              `json:"proto"`
+	Headers map[string][]string `json:"headers"`
+	Body    string              `json:"body"`
+}
+
+func writeResponse(stdout io.Writer, res *http.Response, opts options) error {
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("reading response: %w", err)
+	}
+
+	if opts.output != "" {
+		if err := os.WriteFile(opts.output, body, 0o644); err != nil {
+			return fmt.Errorf("writing output: %w", err)
+		}
+		body = nil
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonResponse{
+			Status:  res.StatusCode,
+			Proto:   res.Proto,
+			Headers: res.Header,
+			Body:    string(body),
+		})
+	}
+
+	if opts.include {
+		fmt.Fprintf(stdout, "%s %s\n", res.Proto, res.Status)
+		if err := res.Header.Write(stdout); err != nil {
+			return fmt.Errorf("writing headers: %w", err)
+		}
+		fmt.Fprintln(stdout)
+	}
+
+	_, err = stdout.Write(body)
+	return err
+}
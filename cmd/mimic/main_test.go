@@ -0,0 +1,81 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestParseInterspersed(t *testing.T) {
+	var opts options
+	fs := newFlagSet(&opts)
+	fs.SetOutput(io.Discard)
+
+	target, err := parseInterspersed(fs, []string{"-X", "PUT", "https://example.com/", "-H", "a: 1", "-i", "-H", "b:2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "https://example.com/" {
+		t.Errorf("target = %q", target)
+	}
+	if opts.method != "PUT" || !opts.include || len(opts.headers) != 2 {
+		t.Errorf("want flags on both sides of the URL parsed; got %+v", opts)
+	}
+
+	if _, err := parseInterspersed(newFlagSet(&options{}), []string{"https://a.test/", "https://b.test/"}); err == nil {
+		t.Error("want an error for a second URL")
+	}
+}
+
+func TestNewRequest(t *testing.T) {
+	body := filepath.Join(t.TempDir(), "body")
+	if err := os.WriteFile(body, []byte("a=1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := newRequest(options{data: "@" + body, headers: headerFlags{"X-Test:  yes "}}, "https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST with a body", req.Method)
+	}
+	if got, _ := io.ReadAll(req.Body); string(got) != "a=1" {
+		t.Errorf("body = %q, want the file's contents", got)
+	}
+	if req.Header.Get("X-Test") != "yes" || req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("header = %v", req.Header)
+	}
+	for _, name := range []string{"Accept", "Accept-Encoding", "Accept-Language"} {
+		if v := req.Header.Get(name); v != "" {
+			t.Errorf("%s = %q, want it left to the Transport", name, v)
+		}
+	}
+
+	req, err = newRequest(options{}, "https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.MethodGet || req.Body != nil {
+		t.Errorf("want a bodiless GET by default; got %s", req.Method)
+	}
+
+	if _, err := newRequest(options{headers: headerFlags{"no-colon"}}, "https://example.com/"); err == nil {
+		t.Error("want an error for a header without a colon")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	if _, err := newClient(options{browser: "chrome", proxy: "socks5://127.0.0.1:1080"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newClient(options{browser: "chrome", proxy: "://bad"}); err == nil {
+		t.Error("want an error for an unparsable proxy")
+	}
+	if _, err := newClient(options{browser: "netscape"}); err == nil {
+		t.Error("want an error for an unknown browser")
+	}
+}
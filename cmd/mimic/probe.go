@@ -0,0 +1,221 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aarock1234/mimic"
+	http "github.com/saucesteals/fhttp"
+)
+
+// identity is one browser and platform combination to probe with.
+type identity struct {
+	browser  string
+	platform mimic.Platform
+}
+
+// probeIdentities covers every browser on every platform it supports.
+var probeIdentities = []identity{
+	{"chrome", mimic.PlatformWindows},
+	{"chrome", mimic.PlatformMac},
+	{"chrome", mimic.PlatformLinux},
+	{"edge", mimic.PlatformWindows},
+	{"edge", mimic.PlatformMac},
+	{"brave", mimic.PlatformWindows},
+	{"safari", mimic.PlatformMac},
+	{"safari", mimic.PlatformIOS},
+	{"safari", mimic.PlatformIPadOS},
+	{"firefox", mimic.PlatformWindows},
+	{"firefox", mimic.PlatformMac},
+	{"firefox", mimic.PlatformLinux},
+}
+
+// probeResult is one row of probe output.
+type probeResult struct {
+	Browser    string         `json:"browser"`
+	Version    string         `json:"version"`
+	Platform   mimic.Platform `json:"platform"`
+	Ja3Hash    string         `json:"ja3_hash,omitempty"`
+	Ja4        string         `json:"ja4,omitempty"`
+	AkamaiHash string         `json:"akamai_hash,omitempty"`
+	Status     int            `json:"status,omitempty"`
+	Blocked    bool           `json:"blocked"`
+	Error      string         `json:"error,omitempty"`
+}
+
+// probeVersions collects repeated --version values of the form
+// browser=version[,version...].
+type probeVersions map[string][]string
+
+func (v probeVersions) String() string {
+	var parts []string
+	for browser, versions := range v {
+		parts = append(parts, browser+"="+strings.Join(versions, ","))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (v probeVersions) Set(value string) error {
+	browser, versions, ok := strings.Cut(value, "=")
+	if !ok || versions == "" {
+		return fmt.Errorf("invalid version %q: want \"browser=version[,version...]\"", value)
+	}
+	if _, ok := defaultVersions[browser]; !ok {
+		return fmt.Errorf("unknown browser %q: want chrome, edge, brave, safari, or firefox", browser)
+	}
+
+	for version := range strings.SplitSeq(versions, ",") {
+		v[browser] = append(v[browser], strings.TrimSpace(version))
+	}
+	return nil
+}
+
+// runProbe implements "mimic probe [flags] [url]".
+func runProbe(args []string, stdout io.Writer) error {
+	var opts options
+	versions := probeVersions{}
+
+	fs := newProbeFlagSet(&opts, versions)
+	target, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+
+	var results []probeResult
+	for _, id := range probeIdentities {
+		opts.browser = id.browser
+		opts.platform = string(id.platform)
+		for _, version := range versionsFor(versions, id.browser) {
+			opts.version = version
+			results = append(results, probe(opts, target))
+		}
+	}
+
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	}
+
+	return printProbeTable(stdout, results, target != "")
+}
+
+// newProbeFlagSet returns the flags of "mimic probe", parsed into opts and
+// versions.
+func newProbeFlagSet(opts *options, versions probeVersions) *flag.FlagSet {
+	fs := flag.NewFlagSet("mimic probe", flag.ContinueOnError)
+	fs.StringVar(&opts.proxy, "proxy", "", "proxy URL (http, https, or socks5)")
+	fs.Var(versions, "version", "versions of a browser to probe as \"browser=version[,version...]\" (repeatable)")
+	fs.BoolVar(&opts.json, "json", false, "print results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: mimic probe [flags] [url]\n\n")
+		fmt.Fprintf(fs.Output(), "Requests %s and, if given, url with every supported browser\n", fingerprintURL)
+		fmt.Fprintf(fs.Output(), "and platform, reporting each fingerprint and whether url blocked it.\n\n")
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// versionsFor returns the versions of browser to probe: those given with
+// --version, or its default version.
+func versionsFor(versions probeVersions, browser string) []string {
+	if v := versions[browser]; len(v) > 0 {
+		return v
+	}
+	return []string{defaultVersions[browser]}
+}
+
+// probe fetches the fingerprint for one identity and, if target is set, checks
+// whether target accepts it.
+func probe(opts options, target string) probeResult {
+	result := probeResult{
+		Browser:  opts.browser,
+		Version:  opts.version,
+		Platform: mimic.Platform(opts.platform),
+	}
+
+	client, err := newClient(opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	fp, err := fetchFingerprint(client)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Ja3Hash = fp.Ja3Hash
+	result.Ja4 = fp.Ja4
+	result.AkamaiHash = fp.AkamaiHash
+
+	if target == "" {
+		return result
+	}
+
+	req, err := newRequest(opts, target)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	checkTarget(client, req, &result)
+	return result
+}
+
+// checkTarget sends req and records the response status in result and whether
+// it blocked the request. A request that fails without a response, on DNS, TLS,
+// or a timeout, is recorded as an error, not a block.
+func checkTarget(client *http.Client, req *http.Request, result *probeResult) {
+	res, err := client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return
+	}
+	res.Body.Close()
+
+	result.Status = res.StatusCode
+	result.Blocked = isBlocked(res)
+}
+
+// isBlocked reports whether res is one that bot protection commonly answers
+// with: a 403, 429, or 503, or a challenge page.
+func isBlocked(res *http.Response) bool {
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return true
+	}
+
+	// Cloudflare marks its challenge pages, whatever their status
+	return res.Header.Get("cf-mitigated") == "challenge"
+}
+
+func printProbeTable(w io.Writer, results []probeResult, withTarget bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if withTarget {
+		fmt.Fprintln(tw, "BROWSER\tVERSION\tPLATFORM\tJA3 HASH\tJA4\tAKAMAI HASH\tSTATUS\tBLOCKED\tERROR")
+	} else {
+		fmt.Fprintln(tw, "BROWSER\tVERSION\tPLATFORM\tJA3 HASH\tJA4\tAKAMAI HASH")
+	}
+
+	for _, r := range results {
+		if r.Error != "" && r.Ja3Hash == "" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %s\n", r.Browser, r.Version, r.Platform, r.Error)
+			continue
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s", r.Browser, r.Version, r.Platform, r.Ja3Hash, r.Ja4, r.AkamaiHash)
+		if withTarget {
+			fmt.Fprintf(tw, "\t%d\t%t\t%s", r.Status, r.Blocked, r.Error)
+		}
+		fmt.Fprintln(tw)
+	}
+
+	return tw.Flush()
+}
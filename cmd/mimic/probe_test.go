@@ -0,0 +1,88 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestProbeVersions(t *testing.T) {
+	var opts options
+	versions := probeVersions{}
+	fs := newProbeFlagSet(&opts, versions)
+	fs.SetOutput(io.Discard)
+
+	target, err := parseInterspersed(fs, []string{"--version", "chrome=120,137", "https://example.com/", "--version", "firefox=128"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "https://example.com/" {
+		t.Errorf("target = %q", target)
+	}
+
+	if got := versionsFor(versions, "chrome"); !slices.Equal(got, []string{"120", "137"}) {
+		t.Errorf("chrome versions = %v", got)
+	}
+	if got := versionsFor(versions, "firefox"); !slices.Equal(got, []string{"128"}) {
+		t.Errorf("firefox versions = %v", got)
+	}
+	if got := versionsFor(versions, "safari"); !slices.Equal(got, []string{defaultVersions["safari"]}) {
+		t.Errorf("safari versions = %v, want the default", got)
+	}
+
+	for _, value := range []string{"137", "chrome=", "netscape=4"} {
+		if err := (probeVersions{}).Set(value); err == nil {
+			t.Errorf("%q: want an error", value)
+		}
+	}
+}
+
+// stubRoundTripper answers every request with res, or fails with err.
+type stubRoundTripper struct {
+	res *http.Response
+	err error
+}
+
+func (s stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	res := *s.res
+	res.Request = req
+	res.Body = io.NopCloser(strings.NewReader(""))
+	return &res, nil
+}
+
+func TestCheckTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		rt      stubRoundTripper
+		status  int
+		blocked bool
+		err     bool
+	}{
+		{"ok", stubRoundTripper{res: &http.Response{StatusCode: 200, Header: http.Header{}}}, 200, false, false},
+		{"forbidden", stubRoundTripper{res: &http.Response{StatusCode: 403, Header: http.Header{}}}, 403, true, false},
+		{"rate limited", stubRoundTripper{res: &http.Response{StatusCode: 429, Header: http.Header{}}}, 429, true, false},
+		{"challenge", stubRoundTripper{res: &http.Response{StatusCode: 200, Header: http.Header{"Cf-Mitigated": {"challenge"}}}}, 200, true, false},
+		{"transport error", stubRoundTripper{err: errors.New("tls: handshake failure")}, 0, false, true},
+	}
+
+	for _, test := range tests {
+		req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result probeResult
+		checkTarget(&http.Client{Transport: test.rt}, req, &result)
+
+		if result.Status != test.status || result.Blocked != test.blocked || (result.Error != "") != test.err {
+			t.Errorf("%s: got status %d, blocked %t, error %q", test.name, result.Status, result.Blocked, result.Error)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aarock1234/mimic"
+)
+
+// defaultVersions are used when --version is not set.
+var defaultVersions = map[string]string{
+	"chrome":  "137.0.0.0",
+	"edge":    "137.0.0.0",
+	"brave":   "137.0.0.0",
+	"safari":  "18.3",
+	"firefox": "134.0",
+}
+
+// defaultPlatforms are used when --platform is not set.
+var defaultPlatforms = map[string]mimic.Platform{
+	"chrome":  mimic.PlatformWindows,
+	"edge":    mimic.PlatformWindows,
+	"brave":   mimic.PlatformWindows,
+	"safari":  mimic.PlatformMac,
+	"firefox": mimic.PlatformWindows,
+}
+
+// newSpec creates the spec for a browser name, expanding a bare major version
+// like "137" into the full form the browser reports.
+func newSpec(browser, version string) (*mimic.ClientSpec, error) {
+	if version == "" {
+		version = defaultVersions[browser]
+	}
+
+	switch browser {
+	case "chrome", "edge", "brave":
+		if !strings.Contains(version, ".") {
+			version += ".0.0.0"
+		}
+
+		brand := map[string]mimic.Brand{
+			"chrome": mimic.BrandChrome,
+			"edge":   mimic.BrandEdge,
+			"brave":  mimic.BrandBrave,
+		}[browser]
+
+		return mimic.Chromium(brand, version)
+	case "safari":
+		if !strings.Contains(version, ".") {
+			version += ".0"
+		}
+		return mimic.Safari(version)
+	case "firefox":
+		if !strings.Contains(version, ".") {
+			version += ".0"
+		}
+		return mimic.Firefox(version)
+	default:
+		return nil, fmt.Errorf("unknown browser %q: want chrome, edge, brave, safari, or firefox", browser)
+	}
+}
+
+// platformFor returns the platform to use for browser, falling back to its default.
+func platformFor(browser, platform string) mimic.Platform {
+	if platform == "" {
+		return defaultPlatforms[browser]
+	}
+	return mimic.Platform(platform)
+}
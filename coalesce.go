@@ -0,0 +1,199 @@
+package mimic
+
+import (
+	"context"
+	"crypto/x509"
+	"net"
+	"net/url"
+	"sync"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+// WithCoalescing sets whether HTTP/2 connections are shared across hostnames the
+// way Chrome and Firefox share them: a request to a host with no connection of
+// its own reuses an open connection whose server IP is among the host's
+// addresses and whose certificate covers the host.
+//
+// Coalescing resolves hostnames with the system resolver before dialing, so it
+// is on by default only when mimic creates the base transport. Enable it for a
+// transport set with WithBaseTransport only if that transport dials through the
+// same resolver. It never applies to requests sent through a proxy.
+func WithCoalescing(enabled bool) TransportOption {
+	return func(c *transportConfig) {
+		c.coalesce = &enabled
+	}
+}
+
+// coalescingPool wraps the HTTP/2 connection pool fhttp builds for an
+// http.Transport, falling back to a connection to another host when the pool
+// has none for the requested one.
+type coalescingPool struct {
+	inner http2.ClientConnPool
+
+	// closer reaches inner's idle connection closer, which fhttp only exposes
+	// through http2.Transport.CloseIdleConnections.
+	closer *http2.Transport
+	proxy  func(*http.Request) (*url.URL, error)
+
+	// lookupIP resolves a hostname; tests replace it.
+	lookupIP func(ctx context.Context, host string) ([]net.IP, error)
+
+	mu sync.Mutex
+	// pending holds the handshake of the newest connection to each address
+	// until the pool first returns its ClientConn.
+	pending map[string]coalesceConn
+	conns   map[*http2.ClientConn]coalesceConn
+}
+
+// coalesceConn is what decides whether a connection can serve another host.
+type coalesceConn struct {
+	ip   net.IP
+	cert *x509.Certificate
+}
+
+// enableCoalescing installs a coalescingPool on t2, the HTTP/2 transport
+// configured for t.
+func enableCoalescing(t *http.Transport, t2 *http2.Transport) *coalescingPool {
+	p := &coalescingPool{
+		inner:  t2.ConnPool,
+		closer: &http2.Transport{ConnPool: t2.ConnPool},
+		proxy:  t.Proxy,
+		lookupIP: func(ctx context.Context, host string) ([]net.IP, error) {
+			return net.DefaultResolver.LookupIP(ctx, "ip", host)
+		},
+		pending: make(map[string]coalesceConn),
+		conns:   make(map[*http2.ClientConn]coalesceConn),
+	}
+	t2.ConnPool = p
+
+	upgrade := t.TLSNextProto["h2"]
+	t.TLSNextProto["h2"] = func(authority string, c *utls.UConn) http.RoundTripper {
+		p.handshake(authority, c)
+		return upgrade(authority, c)
+	}
+
+	return p
+}
+
+// handshake records the server IP and certificate of a new connection.
+func (p *coalescingPool) handshake(authority string, c *utls.UConn) {
+	addr, ok := c.RemoteAddr().(*net.TCPAddr)
+	certs := c.ConnectionState().PeerCertificates
+	if !ok || len(certs) == 0 {
+		return
+	}
+
+	if _, _, err := net.SplitHostPort(authority); err != nil {
+		authority = net.JoinHostPort(authority, "443")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending[authority] = coalesceConn{ip: addr.IP, cert: certs[0]}
+}
+
+// GetClientConn returns a connection to addr if the pool has one, and otherwise
+// an open connection to another host that can serve req's host.
+func (p *coalescingPool) GetClientConn(req *http.Request, addr string) (*http2.ClientConn, error) {
+	cc, err := p.inner.GetClientConn(req, addr)
+	if err == nil {
+		p.track(cc, addr)
+		return cc, nil
+	}
+
+	if coalesced := p.coalesce(req, addr); coalesced != nil {
+		return coalesced, nil
+	}
+
+	return nil, err
+}
+
+// MarkDead forgets cc and removes it from the pool.
+func (p *coalescingPool) MarkDead(cc *http2.ClientConn) {
+	p.mu.Lock()
+	delete(p.conns, cc)
+	p.mu.Unlock()
+
+	p.inner.MarkDead(cc)
+}
+
+// closeIdleConnections closes the pool's idle connections. The pool is no
+// longer the one fhttp's own CloseIdleConnections knows how to close.
+func (p *coalescingPool) closeIdleConnections() {
+	p.closer.CloseIdleConnections()
+}
+
+// track associates cc with the handshake of the newest connection to addr the
+// first time the pool returns it.
+func (p *coalescingPool) track(cc *http2.ClientConn, addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.conns[cc]; ok {
+		return
+	}
+	if conn, ok := p.pending[addr]; ok {
+		p.conns[cc] = conn
+		delete(p.pending, addr)
+	}
+}
+
+// coalesce returns an open connection that can serve req, or nil.
+func (p *coalescingPool) coalesce(req *http.Request, addr string) *http2.ClientConn {
+	if req.URL.Scheme != "https" {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	empty := len(p.conns) == 0
+	p.mu.Unlock()
+	if empty {
+		return nil
+	}
+
+	if p.proxy != nil {
+		if proxyURL, err := p.proxy(req); err != nil || proxyURL != nil {
+			return nil
+		}
+	}
+
+	ips, err := p.lookupIP(req.Context(), host)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []*http2.ClientConn
+	p.mu.Lock()
+	for cc, conn := range p.conns {
+		if containsIP(ips, conn.ip) && conn.cert.VerifyHostname(host) == nil {
+			candidates = append(candidates, cc)
+		}
+	}
+	p.mu.Unlock()
+
+	for _, cc := range candidates {
+		if cc.CanTakeNewRequest() {
+			return cc
+		}
+	}
+
+	return nil
+}
+
+func containsIP(ips []net.IP, ip net.IP) bool {
+	for _, candidate := range ips {
+		if candidate.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestCoalescing(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every hostname dials the test server, so only the pool decides whether a
+	// request gets a new connection
+	base := &http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+		},
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base), WithCoalescing(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	tr.pool.lookupIP = func(_ context.Context, host string) ([]net.IP, error) {
+		return []net.IP{net.IPv4(127, 0, 0, 1)}, nil
+	}
+
+	get := func(host string) string {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, "https://"+net.JoinHostPort(host, port), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		if res.ProtoMajor != 2 {
+			t.Fatalf("want http/2; got %s", res.Proto)
+		}
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(body)
+	}
+
+	first := get("127.0.0.1")
+
+	if got := get("example.com"); got != first {
+		t.Errorf("want example.com to reuse the connection to 127.0.0.1; got %s and %s", first, got)
+	}
+
+	if got := get("example.org"); got == first {
+		t.Error("want example.org, which the certificate does not cover, to get its own connection")
+	}
+
+	tr.CloseIdleConnections()
+	if got := get("127.0.0.1"); got == first {
+		t.Error("want CloseIdleConnections to close the coalesced connection")
+	}
+}
+
+func TestCoalescingDefault(t *testing.T) {
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.pool == nil {
+		t.Error("want coalescing on for the default base transport")
+	}
+
+	clone, err := tr.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.pool == nil || clone.pool == tr.pool {
+		t.Error("want clone to coalesce with its own pool")
+	}
+
+	custom, err := NewTransport(spec, PlatformLinux, WithBaseTransport(&http.Transport{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if custom.pool != nil {
+		t.Error("want coalescing off for a custom base transport")
+	}
+}
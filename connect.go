@@ -0,0 +1,97 @@
+package mimic
+
+import (
+	"context"
+	"net"
+	"strings"
+)
+
+// WithConnectTo dials to instead of from, like curl's --connect-to, while
+// requests keep from's hostname for SNI, :authority, Host, and cookies. It is the way to reach a virtual host at another address, such
+// as a staging server or a specific edge node, without the mismatched SNI and
+// :authority that setting req.Host produces.
+//
+// from is "host" or "host:port" and to is "host" or "host:port"; a missing port
+// matches any port or keeps the original one. Call it once per mapping. It
+// does not apply to requests sent through a proxy, which dial the proxy, and
+// it turns connection coalescing off unless WithCoalescing turns it back on.
+func WithConnectTo(from, to string) TransportOption {
+	return func(c *transportConfig) {
+		if c.connectTo == nil {
+			c.connectTo = make(connectTo)
+		}
+		c.connectTo[strings.ToLower(from)] = to
+	}
+}
+
+// connectTo maps a dial address, or just its host, to the address to dial.
+type connectTo map[string]string
+
+func (m connectTo) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, m.target(addr))
+	}
+}
+
+// target returns the address to dial for addr.
+func (m connectTo) target(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	host = strings.ToLower(host)
+
+	to, ok := m[net.JoinHostPort(host, port)]
+	if !ok {
+		if to, ok = m[host]; !ok {
+			return addr
+		}
+	}
+
+	if _, _, err := net.SplitHostPort(to); err != nil {
+		return net.JoinHostPort(strings.Trim(to, "[]"), port)
+	}
+	return to
+}
+
+// AddressRewriter returns the network and address to dial in place of network
+// and addr, the host and port a request would dial.
+type AddressRewriter func(network, addr string) (newNetwork, newAddr string)
+
+// WithAddressRewriter dials the network and address rewrite returns in place
+// of each one the Transport would dial, while requests keep their URL's host
+// for SNI, :authority, Host, and cookies. Use it to reach a server through a
+// unix domain socket, a sidecar, or a test harness; see UnixSocket. Through a
+// proxy, rewrite is given the proxy's address.
+//
+// Like WithConnectTo, it turns connection coalescing off unless
+// WithCoalescing turns it back on, and mappings from WithConnectTo are
+// applied before rewrite is called.
+func WithAddressRewriter(rewrite AddressRewriter) TransportOption {
+	return func(c *transportConfig) {
+		c.rewriteAddr = rewrite
+	}
+}
+
+// UnixSocket returns an AddressRewriter that dials the unix domain socket at
+// path for every address.
+func UnixSocket(path string) AddressRewriter {
+	return func(string, string) (string, string) {
+		return "unix", path
+	}
+}
+
+func (rewrite AddressRewriter) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		network, addr = rewrite(network, addr)
+		return dial(ctx, network, addr)
+	}
+}
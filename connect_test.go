@@ -0,0 +1,120 @@
+package mimic
+
+import (
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestWithConnectTo(t *testing.T) {
+	var host, serverName string
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		host, serverName = r.Host, r.TLS.ServerName
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	transport, err := NewTransport(spec, PlatformWindows,
+		WithBaseTransport(base),
+		WithConnectTo("VHost.test:443", server.Listener.Addr().String()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://vhost.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if host != "vhost.test" || serverName != "vhost.test" {
+		t.Errorf("want :authority and SNI vhost.test; got %q and %q", host, serverName)
+	}
+}
+
+func TestConnectToTarget(t *testing.T) {
+	m := connectTo{
+		"a.test:443": "10.0.0.1:8443",
+		"b.test":     "10.0.0.2",
+		"c.test":     "[::1]",
+	}
+
+	tests := map[string]string{
+		"a.test:443": "10.0.0.1:8443",
+		"a.test:80":  "a.test:80",
+		"b.test:80":  "10.0.0.2:80",
+		"c.test:443": net.JoinHostPort("::1", "443"),
+		"d.test:443": "d.test:443",
+	}
+	for addr, want := range tests {
+		if got := m.target(addr); got != want {
+			t.Errorf("target(%q) = %q; want %q", addr, got, want)
+		}
+	}
+}
+
+func TestWithAddressRewriterUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "mimic.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	var host, serverName string
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		host, serverName = r.Host, r.TLS.ServerName
+	}))
+	server.Listener.Close()
+	server.Listener = listener
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	transport, err := NewTransport(spec, PlatformWindows,
+		WithBaseTransport(base),
+		WithAddressRewriter(UnixSocket(socket)),
+		WithHostPolicy(DenyPrivateNetworks),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://sidecar.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if host != "sidecar.test" || serverName != "sidecar.test" {
+		t.Errorf("want :authority and SNI sidecar.test; got %q and %q", host, serverName)
+	}
+}
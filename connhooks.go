@@ -0,0 +1,209 @@
+package mimic
+
+import (
+	"context"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+	"github.com/saucesteals/fhttp/httptrace"
+)
+
+// ConnHooks are called as connections made by a Transport move through their
+// lifecycle. Every event carries the ID the connection was given when it was
+// dialed, so events for one connection can be correlated. Hooks run
+// synchronously on the connection's goroutine and must not block. Any hook may
+// be nil.
+type ConnHooks struct {
+	OnDial         func(DialEvent)
+	OnTLSHandshake func(TLSHandshakeEvent)
+	OnH2Preface    func(H2PrefaceEvent)
+	OnClose        func(CloseEvent)
+
+	// traced, when set, reports whether to trace handshakes for now
+	traced func() bool
+}
+
+// DialEvent describes a finished dial to the server or proxy.
+type DialEvent struct {
+	ConnID     uint64
+	Network    string
+	Addr       string
+	LocalAddr  net.Addr // nil if the dial failed
+	RemoteAddr net.Addr // nil if the dial failed
+	Duration   time.Duration
+	Err        error
+}
+
+// TLSHandshakeEvent describes a finished TLS handshake with the server. It is
+// only reported for handshakes made on behalf of a request.
+type TLSHandshakeEvent struct {
+	ConnID   uint64
+	Conn     ConnInfo // TLS details; Proto is empty
+	Duration time.Duration
+	Err      error
+}
+
+// H2PrefaceEvent is reported when a connection negotiates HTTP/2, just before
+// the client preface, SETTINGS, and connection WINDOW_UPDATE are written.
+type H2PrefaceEvent struct {
+	ConnID         uint64
+	Authority      string
+	Settings       []http2.Setting
+	ConnectionFlow uint32 // ConnectionFlowDefault when fhttp's default is sent
+}
+
+// CloseEvent describes a closed connection.
+type CloseEvent struct {
+	ConnID       uint64
+	RemoteAddr   net.Addr
+	Lifetime     time.Duration
+	BytesRead    int64
+	BytesWritten int64
+	Err          error // returned by Close
+}
+
+// WithConnHooks calls hooks as connections are dialed, handshaken, start
+// HTTP/2, and close, for per-connection logging, metrics, or pooling and ban
+// policies. Hooks see connections to the proxy when one is used.
+func WithConnHooks(hooks ConnHooks) TransportOption {
+	return func(c *transportConfig) {
+		c.connHooks = &hooks
+	}
+}
+
+// connIDs numbers connections across all Transports.
+var connIDs atomic.Uint64
+
+// hookTrace carries the ID of the connection a request dialed from the dialer to
+// the request's TLS handshake trace.
+type hookTrace struct {
+	connID atomic.Uint64
+}
+
+// context attaches the TLS handshake hook to ctx, if there is one.
+func (h *ConnHooks) context(ctx context.Context) context.Context {
+	if h.OnTLSHandshake == nil || h.traced != nil && !h.traced() {
+		return ctx
+	}
+
+	trace := &hookTrace{}
+	var start time.Time
+	ctx = context.WithValue(ctx, connHookKey, trace)
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeStart: func() {
+			start = time.Now()
+		},
+		TLSHandshakeDone: func(state utls.ConnectionState, err error) {
+			h.OnTLSHandshake(TLSHandshakeEvent{
+				ConnID:   trace.connID.Load(),
+				Conn:     newConnInfo(&state),
+				Duration: time.Since(start),
+				Err:      err,
+			})
+		},
+	})
+}
+
+func (h *ConnHooks) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		id := connIDs.Add(1)
+		if trace, ok := ctx.Value(connHookKey).(*hookTrace); ok {
+			trace.connID.Store(id)
+		}
+
+		start := time.Now()
+		conn, err := dial(ctx, network, addr)
+		if h.OnDial != nil {
+			event := DialEvent{ConnID: id, Network: network, Addr: addr, Duration: time.Since(start), Err: err}
+			if err == nil {
+				event.LocalAddr = conn.LocalAddr()
+				event.RemoteAddr = conn.RemoteAddr()
+			}
+			h.OnDial(event)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return &hookConn{Conn: conn, id: id, hooks: h, opened: time.Now()}, nil
+	}
+}
+
+// install wraps t's HTTP/2 upgrade to report the preface. It must be called
+// after the HTTP/2 transport is configured and after any other wrapper of the
+// upgrade, such as coalescing.
+func (h *ConnHooks) install(t *http.Transport, opts *HTTP2Options) {
+	if h.OnH2Preface == nil {
+		return
+	}
+
+	upgrade := t.TLSNextProto["h2"]
+	if upgrade == nil {
+		return
+	}
+
+	t.TLSNextProto["h2"] = func(authority string, c *utls.UConn) http.RoundTripper {
+		var id uint64
+		if hc, ok := c.NetConn().(*hookConn); ok {
+			id = hc.id
+		}
+		h.OnH2Preface(H2PrefaceEvent{
+			ConnID:         id,
+			Authority:      authority,
+			Settings:       opts.Settings,
+			ConnectionFlow: opts.ConnectionFlow,
+		})
+		return upgrade(authority, c)
+	}
+}
+
+// hookConn counts traffic and reports its own close.
+type hookConn struct {
+	net.Conn
+	id     uint64
+	hooks  *ConnHooks
+	opened time.Time
+
+	read    atomic.Int64
+	written atomic.Int64
+	close   sync.Once
+}
+
+func (c *hookConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+func (c *hookConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(int64(n))
+	return n, err
+}
+
+func (c *hookConn) Close() error {
+	err := c.Conn.Close()
+	c.close.Do(func() {
+		if c.hooks.OnClose == nil {
+			return
+		}
+		c.hooks.OnClose(CloseEvent{
+			ConnID:       c.id,
+			RemoteAddr:   c.Conn.RemoteAddr(),
+			Lifetime:     time.Since(c.opened),
+			BytesRead:    c.read.Load(),
+			BytesWritten: c.written.Load(),
+			Err:          err,
+		})
+	})
+	return err
+}
@@ -0,0 +1,86 @@
+package mimic
+
+import (
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestConnHooks(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		mu        sync.Mutex
+		dial      DialEvent
+		handshake TLSHandshakeEvent
+		preface   H2PrefaceEvent
+		closed    = make(chan CloseEvent, 1)
+	)
+	hooks := ConnHooks{
+		OnDial: func(e DialEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			dial = e
+		},
+		OnTLSHandshake: func(e TLSHandshakeEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			handshake = e
+		},
+		OnH2Preface: func(e H2PrefaceEvent) {
+			mu.Lock()
+			defer mu.Unlock()
+			preface = e
+		},
+		OnClose: func(e CloseEvent) {
+			closed <- e
+		},
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	transport, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base), WithConnHooks(hooks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	mu.Lock()
+	if dial.ConnID == 0 || dial.Err != nil || dial.RemoteAddr == nil {
+		t.Errorf("want a successful dial event; got %+v", dial)
+	}
+	if handshake.ConnID != dial.ConnID || handshake.Conn.ALPN != "h2" {
+		t.Errorf("want the handshake of connection %d negotiating h2; got %+v", dial.ConnID, handshake)
+	}
+	if preface.ConnID != dial.ConnID || len(preface.Settings) == 0 {
+		t.Errorf("want the preface of connection %d with settings; got %+v", dial.ConnID, preface)
+	}
+	mu.Unlock()
+
+	transport.CloseIdleConnections()
+	e := <-closed
+	if e.ConnID != dial.ConnID || e.BytesRead == 0 || e.BytesWritten == 0 {
+		t.Errorf("want the close of connection %d with traffic counted; got %+v", dial.ConnID, e)
+	}
+}
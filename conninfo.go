@@ -0,0 +1,98 @@
+package mimic
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// ConnInfo describes the negotiated connection a response was received on.
+type ConnInfo struct {
+	// Proto is the HTTP protocol of the response, such as "HTTP/2.0".
+	Proto string
+
+	// ALPN is the application protocol negotiated during the TLS handshake,
+	// such as "h2" or "http/1.1". It is empty if ALPN was not negotiated.
+	ALPN string
+
+	// TLSVersion is the negotiated TLS version, such as tls.VersionTLS13.
+	TLSVersion uint16
+
+	// CipherSuite is the negotiated cipher suite ID.
+	CipherSuite uint16
+
+	// Resumed reports whether the TLS session was resumed from a previous connection.
+	Resumed bool
+
+	// ServerName is the SNI value sent in the ClientHello.
+	ServerName string
+
+	// PeerCertificates is the certificate chain presented by the server, leaf first.
+	PeerCertificates []*x509.Certificate
+
+	// VerifiedChains are the chains the presented certificates were verified
+	// against, each leaf first and ending in a trusted root. It is empty when
+	// verification was skipped.
+	VerifiedChains [][]*x509.Certificate
+
+	// OCSPResponse is the DER-encoded OCSP response the server stapled to the
+	// handshake, or nil if it stapled none.
+	OCSPResponse []byte
+
+	// SignedCertificateTimestamps are the SCTs the server sent in the TLS
+	// handshake. SCTs embedded in the certificate are counted by
+	// EmbeddedSCTCount.
+	SignedCertificateTimestamps [][]byte
+}
+
+// TLSVersionName returns the name of the negotiated TLS version, such as "TLS 1.3".
+func (c ConnInfo) TLSVersionName() string {
+	return tls.VersionName(c.TLSVersion)
+}
+
+// CipherSuiteName returns the standard name of the negotiated cipher suite,
+// such as "TLS_AES_128_GCM_SHA256".
+func (c ConnInfo) CipherSuiteName() string {
+	return tls.CipherSuiteName(c.CipherSuite)
+}
+
+// EmbeddedSCTCount returns the number of SCTs embedded in the leaf
+// certificate, which together with SignedCertificateTimestamps decides whether
+// a certificate meets a Certificate Transparency policy.
+func (c ConnInfo) EmbeddedSCTCount() int {
+	if len(c.PeerCertificates) == 0 {
+		return 0
+	}
+	return countEmbeddedSCTs(c.PeerCertificates[0])
+}
+
+// ConnInfoFromResponse returns details of the TLS connection res was received on,
+// so callers can verify at runtime that a request really negotiated the expected
+// protocol and parameters. It returns false if res was not received over TLS.
+func ConnInfoFromResponse(res *http.Response) (ConnInfo, bool) {
+	if res == nil || res.TLS == nil {
+		return ConnInfo{}, false
+	}
+
+	info := newConnInfo(res.TLS)
+	info.Proto = res.Proto
+	return info, true
+}
+
+// newConnInfo returns the TLS details of state, leaving Proto empty.
+func newConnInfo(state *utls.ConnectionState) ConnInfo {
+	return ConnInfo{
+		ALPN:             state.NegotiatedProtocol,
+		TLSVersion:       state.Version,
+		CipherSuite:      state.CipherSuite,
+		Resumed:          state.DidResume,
+		ServerName:       state.ServerName,
+		PeerCertificates: state.PeerCertificates,
+		VerifiedChains:   state.VerifiedChains,
+		OCSPResponse:     state.OCSPResponse,
+
+		SignedCertificateTimestamps: state.SignedCertificateTimestamps,
+	}
+}
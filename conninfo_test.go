@@ -0,0 +1,110 @@
+package mimic
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestConnInfoFromResponse(t *testing.T) {
+	leaf := &x509.Certificate{Extensions: []pkix.Extension{sctListExtension(t, 3)}}
+	root := &x509.Certificate{}
+
+	res := &http.Response{
+		Proto: "HTTP/2.0",
+		TLS: &utls.ConnectionState{
+			Version:                     utls.VersionTLS13,
+			NegotiatedProtocol:          "h2",
+			PeerCertificates:            []*x509.Certificate{leaf},
+			VerifiedChains:              [][]*x509.Certificate{{leaf, root}},
+			OCSPResponse:                []byte{0x30},
+			SignedCertificateTimestamps: [][]byte{{1}},
+		},
+	}
+
+	info, ok := ConnInfoFromResponse(res)
+	if !ok {
+		t.Fatal("want conn info for a TLS response")
+	}
+	if len(info.VerifiedChains) != 1 || info.VerifiedChains[0][1] != root {
+		t.Error("want the verified chain")
+	}
+	if len(info.OCSPResponse) != 1 || len(info.SignedCertificateTimestamps) != 1 {
+		t.Error("want the stapled OCSP response and handshake SCTs")
+	}
+	if n := info.EmbeddedSCTCount(); n != 3 {
+		t.Errorf("want 3 embedded SCTs; got %d", n)
+	}
+
+	if _, ok := ConnInfoFromResponse(&http.Response{}); ok {
+		t.Error("want no conn info without TLS")
+	}
+}
+
+func TestConnInfoFromResponseHTTP2(t *testing.T) {
+	var conns atomic.Int32
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	// the second request reuses the first one's connection
+	for i := range 2 {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		info, ok := ConnInfoFromResponse(res)
+		if !ok {
+			t.Fatalf("request %d: want conn info for a TLS response", i)
+		}
+		if info.Proto != "HTTP/2.0" || info.ALPN != "h2" {
+			t.Errorf("request %d: want HTTP/2 negotiated with ALPN; got %s over %q", i, info.Proto, info.ALPN)
+		}
+		if info.TLSVersion != utls.VersionTLS13 {
+			t.Errorf("request %d: want TLS 1.3; got %s", i, info.TLSVersionName())
+		}
+		if info.Resumed {
+			t.Errorf("request %d: want a full handshake without a session cache", i)
+		}
+		if len(info.PeerCertificates) == 0 || len(info.VerifiedChains) != 0 {
+			t.Errorf("request %d: want the server's certificate, unverified", i)
+		}
+	}
+
+	if got := conns.Load(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+}
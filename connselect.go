@@ -0,0 +1,146 @@
+package mimic
+
+import (
+	"fmt"
+	"sync"
+
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+// ConnSelector returns the key of the connection pool a request is sent from.
+// Requests with different keys never share a connection, so an identity's
+// connections, and the TLS sessions they resume, are never reused for
+// another's. Requests with the same key reuse connections as the browser
+// would.
+type ConnSelector func(req *http.Request) string
+
+// WithConnSelector sends each request from the connection pool selector
+// picks for it. The Transport keeps one pool per key for as long as it lives,
+// so keys should name identities, such as sessions or accounts, and not vary
+// per request.
+//
+// Requests through different proxies never share a connection, with or
+// without a selector: fhttp keys HTTP/2 connections by the server's address
+// alone, so a request through one proxy could otherwise be sent on a
+// connection made through another. Clone and WithPlatform across TLS
+// fingerprints give the new Transport pools of its own. Engines pool
+// connections themselves, so selector is not used with WithEngine.
+func WithConnSelector(selector ConnSelector) TransportOption {
+	return func(c *transportConfig) {
+		c.connSelector = selector
+	}
+}
+
+// ConnPerSession is a ConnSelector that gives the requests of each session ID
+// set with WithSessionID a pool of their own. Requests without one share the
+// Transport's pool.
+func ConnPerSession(req *http.Request) string {
+	id, _ := SessionID(req.Context())
+	return id
+}
+
+// connPartition is one of a Transport's connection pools.
+type connPartition struct {
+	base      *http.Transport
+	h2        *http2.Transport
+	transport http.RoundTripper
+	pool      *coalescingPool
+	priority  *streamPriority
+	streams   *streamLimiter
+}
+
+// newConnPartition returns a pool for spec on platform p, with a new base
+// transport cloned from t's.
+func (t *Transport) newConnPartition(spec *ClientSpec, p Platform) (*connPartition, error) {
+	base := t.base.Clone()
+	// the cloned TLSNextProto would hand h2 connections to t's pool
+	base.TLSNextProto = nil
+
+	t2, err := spec.configureTransport(base, p)
+	if err != nil {
+		return nil, fmt.Errorf("configuring transport: %w", err)
+	}
+	t2.ReadIdleTimeout, t2.PingTimeout = t.ping, t.pingTimeout
+
+	part := &connPartition{base: base, h2: t2}
+	if t.pool != nil {
+		part.pool = enableCoalescing(base, t2)
+	}
+	t.connHooks.install(base, spec.http2Options)
+
+	part.transport = base
+	part.priority = newStreamPriority(t2, spec.fetch.priority)
+	if n := spec.http2Options.MaxConcurrentStreams; n > 0 {
+		part.streams = newStreamLimiter(int(n))
+	}
+	if t.engine != nil {
+		part.transport = t.engine
+		part.priority = nil
+		part.streams = nil
+	}
+	part.transport = chain(part.transport, t.middleware)
+
+	return part, nil
+}
+
+func (p *connPartition) closeIdleConnections() {
+	p.base.CloseIdleConnections()
+	if p.pool != nil {
+		p.pool.closeIdleConnections()
+	}
+}
+
+// connPartitions are the pools a Transport sends requests from besides its
+// own, by key.
+type connPartitions struct {
+	mu    sync.Mutex
+	parts map[string]*connPartition
+}
+
+func newConnPartitions() *connPartitions {
+	return &connPartitions{parts: make(map[string]*connPartition)}
+}
+
+// partition returns the pool sent, a copy of req, is sent from: t's own, or
+// nil, when neither a proxy nor t's selector sets it apart.
+func (t *Transport) partition(req, sent *http.Request) (*connPartition, error) {
+	var key string
+	if t.base.Proxy != nil {
+		proxy, err := t.base.Proxy(sent)
+		if err != nil {
+			return nil, err
+		}
+		key = proxyKey(proxy)
+	}
+	if t.connSelector != nil {
+		if id := t.connSelector(req); id != "" {
+			key += " " + id
+		}
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	t.partitions.mu.Lock()
+	defer t.partitions.mu.Unlock()
+
+	if part, ok := t.partitions.parts[key]; ok {
+		return part, nil
+	}
+	part, err := t.newConnPartition(t.spec, t.platform)
+	if err != nil {
+		return nil, err
+	}
+	t.partitions.parts[key] = part
+	return part, nil
+}
+
+func (ps *connPartitions) closeIdleConnections() {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	for _, part := range ps.parts {
+		part.closeIdleConnections()
+	}
+}
@@ -0,0 +1,185 @@
+package mimic
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// countingServer returns an HTTP/2 test server and the number of
+// connections it has accepted.
+func countingServer(t *testing.T) (*stdhttptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var conns atomic.Int32
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server, &conns
+}
+
+func TestConnPerSession(t *testing.T) {
+	server, conns := countingServer(t)
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithConnSelector(ConnPerSession), WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	for _, session := range []string{"a", "b", "a", "", "b", ""} {
+		ctx := context.Background()
+		if session != "" {
+			ctx = WithSessionID(ctx, session)
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	if got := conns.Load(); got != 3 {
+		t.Errorf("connections = %d, want one per session and one without", got)
+	}
+}
+
+// proxyKeyCtx selects the test proxy a request is sent through.
+type proxyKeyCtx struct{}
+
+func TestConnPerProxy(t *testing.T) {
+	server, conns := countingServer(t)
+
+	proxies := map[string]*url.URL{
+		"a": startConnectProxy(t),
+		"b": startConnectProxy(t),
+	}
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			return proxies[req.Context().Value(proxyKeyCtx{}).(string)], nil
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	for _, proxy := range []string{"a", "b", "a", "b"} {
+		ctx := context.WithValue(context.Background(), proxyKeyCtx{}, proxy)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	if got := conns.Load(); got != 2 {
+		t.Errorf("connections = %d, want one per proxy", got)
+	}
+}
+
+func TestConnPartitionClosesBodyOnProxyError(t *testing.T) {
+	errProxy := errors.New("no proxy")
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		Proxy: func(*http.Request) (*url.URL, error) {
+			return nil, errProxy
+		},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := &closeRecorder{Reader: strings.NewReader("a=1")}
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); !errors.Is(err, errProxy) || !body.closed {
+		t.Errorf("want the body closed when no pool is picked; got %v, closed %t", err, body.closed)
+	}
+}
+
+// startConnectProxy starts an HTTP proxy that only tunnels CONNECT requests.
+func startConnectProxy(t *testing.T) *url.URL {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+
+				req, err := stdhttp.ReadRequest(bufio.NewReader(conn))
+				if err != nil || req.Method != stdhttp.MethodConnect {
+					return
+				}
+				upstream, err := net.Dial("tcp", req.Host)
+				if err != nil {
+					return
+				}
+				defer upstream.Close()
+
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go io.Copy(upstream, conn)
+				io.Copy(conn, upstream)
+			}()
+		}
+	}()
+
+	return &url.URL{Scheme: "http", Host: ln.Addr().String()}
+}
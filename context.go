@@ -0,0 +1,46 @@
+package mimic
+
+import "context"
+
+// contextKey is the type of the context keys mimic defines, so they cannot
+// collide with keys from other packages.
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	sessionIDKey
+	bodyStallTimeoutKey
+	maxBodySizeKey
+	headerOrderKey
+	decodedKey
+	fetchIntentKey     // the request's *fetchIntent
+	banTargetKey       // the banTarget a request dials
+	connHookKey        // the hookTrace for a request
+	criticalCHRetryKey // marks a request retried for Critical-CH
+)
+
+// WithRequestID returns a copy of ctx carrying id, which mimic reports with
+// the request in hooks such as RateLimiter.OnThrottle. Use it to correlate a
+// request's log lines, traces, and metrics.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID set by WithRequestID.
+func RequestID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey).(string)
+	return id, ok
+}
+
+// WithSessionID returns a copy of ctx carrying id, which mimic reports with
+// the request in hooks such as RateLimiter.OnThrottle. Use it to tell apart
+// requests from different browser profiles sharing a process.
+func WithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, id)
+}
+
+// SessionID returns the session ID set by WithSessionID.
+func SessionID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sessionIDKey).(string)
+	return id, ok
+}
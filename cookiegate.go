@@ -0,0 +1,192 @@
+package mimic
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+	"golang.org/x/net/html"
+)
+
+// maxGateScan bounds how much of a document is searched for a cookie gate.
+// Gate pages are small; a document longer than this is a real page.
+const maxGateScan = 64 << 10
+
+var (
+	// gateCookie matches a string literal assigned to document.cookie and not
+	// concatenated with anything.
+	gateCookie = regexp.MustCompile(`document\.cookie\s*=\s*(?:"([^"\\]*)"|'([^'\\]*)')\s*(?:[;,)}\n]|$)`)
+
+	// gateCookieAssign matches any assignment to document.cookie.
+	gateCookieAssign = regexp.MustCompile(`document\.cookie\s*=[^=]`)
+
+	// gateNavigation matches a script reloading the page or navigating to a
+	// string literal.
+	gateNavigation = regexp.MustCompile(`location\.reload\(|location(?:\.href)?\s*=\s*(?:"([^"\\]*)"|'([^'\\]*)')|location\.(?:replace|assign)\(\s*(?:"([^"\\]*)"|'([^'\\]*)')\s*\)`)
+)
+
+// CookieGates configures WithCookieGates.
+type CookieGates struct {
+	// OnChallenge is called with responses the client cannot get past on its
+	// own: bot management challenges DetectChallenge recognizes, scripts that
+	// set a cookie the gate handler cannot read, reported with the vendor
+	// "unknown", and gates that answer again after their cookie is set,
+	// reported with the vendor "cookie-gate". The response is then returned
+	// to the caller as is. OnChallenge must not read or close its body.
+	OnChallenge func(res *http.Response, challenge Challenge)
+}
+
+// WithCookieGates makes the client pass simple cookie gates on GET requests
+// for HTML documents: pages whose inline script sets a cookie to a string
+// literal and then reloads the page or navigates to a string literal URL, as
+// a browser running the script would. The cookies are stored in the client's
+// jar and the navigation is handed to the client as a 303 redirect, so it is
+// sent with the browser's headers and the new cookies, shares the redirect
+// limit and CheckRedirect, and shows up in RedirectsFromResponse.
+//
+// Gates that redirect with Set-Cookie need no handler, since the client
+// follows redirects with its jar, and gates that use a meta refresh are
+// passed by WithMetaRefresh.
+func WithCookieGates(gates CookieGates) ClientOption {
+	return func(c *clientConfig) {
+		c.cookieGates = &gates
+	}
+}
+
+// cookieGateTransport passes cookie gates.
+type cookieGateTransport struct {
+	wrapped
+	jar   http.CookieJar
+	gates CookieGates
+}
+
+func (t *cookieGateTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet || (res.StatusCode >= 300 && res.StatusCode < 400) || !isHTML(res.Header) {
+		return res, nil
+	}
+
+	scanned, err := io.ReadAll(io.LimitReader(res.Body, maxGateScan+1))
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+	res.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(scanned), res.Body), body: res.Body}
+
+	if challenge, ok := DetectChallenge(res, scanned); ok {
+		t.surface(res, challenge)
+		return res, nil
+	}
+	if len(scanned) > maxGateScan {
+		return res, nil
+	}
+
+	gate, ok := findCookieGate(scanned)
+	if !ok {
+		return res, nil
+	}
+	if len(gate.cookies) == 0 {
+		t.surface(res, Challenge{Vendor: "unknown"})
+		return res, nil
+	}
+
+	location, err := req.URL.Parse(gate.target)
+	if err != nil || (location.Scheme != "http" && location.Scheme != "https") {
+		return res, nil
+	}
+	if repeatedGate(req, location) {
+		t.surface(res, Challenge{Vendor: "cookie-gate"})
+		return res, nil
+	}
+
+	t.jar.SetCookies(req.URL, gate.cookies)
+
+	// the script runs once the document has loaded
+	_, err = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Status = "303 See Other"
+	res.StatusCode = http.StatusSeeOther
+	res.Header.Set("Location", location.String())
+	res.Header.Del("Content-Length")
+	res.ContentLength = 0
+	res.Body = http.NoBody
+
+	return res, nil
+}
+
+func (t *cookieGateTransport) surface(res *http.Response, challenge Challenge) {
+	if t.gates.OnChallenge != nil {
+		t.gates.OnChallenge(res, challenge)
+	}
+}
+
+// repeatedGate reports whether req was itself sent by passing a gate that
+// navigated to location, meaning the gate did not accept its cookie.
+func repeatedGate(req *http.Request, location *url.URL) bool {
+	prev := req.Response
+	return prev != nil && prev.StatusCode == http.StatusSeeOther && prev.Request != nil &&
+		prev.Request.URL.String() == req.URL.String() && req.URL.String() == location.String()
+}
+
+// cookieGate is what a gate page's script does: set cookies, then navigate
+// to target, "" for a reload.
+type cookieGate struct {
+	cookies []*http.Cookie
+	target  string
+}
+
+// findCookieGate looks for a gate in the inline scripts of doc. It reports a
+// gate with no cookies when a script navigates after setting a cookie that is
+// not a string literal.
+func findCookieGate(doc []byte) (cookieGate, bool) {
+	var script strings.Builder
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	var inScript bool
+	for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
+		switch tt {
+		case html.StartTagToken:
+			name, _ := z.TagName()
+			inScript = string(name) == "script"
+		case html.EndTagToken:
+			inScript = false
+		case html.TextToken:
+			if inScript {
+				script.Write(z.Text())
+				script.WriteByte('\n')
+			}
+		}
+	}
+	src := script.String()
+
+	nav := gateNavigation.FindStringSubmatch(src)
+	if nav == nil || !gateCookieAssign.MatchString(src) {
+		return cookieGate{}, false
+	}
+
+	var gate cookieGate
+	for _, group := range nav[1:] {
+		if group != "" {
+			gate.target = group
+			break
+		}
+	}
+
+	header := make(http.Header)
+	for _, m := range gateCookie.FindAllStringSubmatch(src, -1) {
+		header.Add("Set-Cookie", m[1]+m[2])
+	}
+	gate.cookies = (&http.Response{Header: header}).Cookies()
+
+	return gate, true
+}
@@ -0,0 +1,104 @@
+package mimic
+
+import (
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestFindCookieGate(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		ok      bool
+		cookies int
+		target  string
+	}{
+		{"reload", `<script>document.cookie = "pass=1; path=/"; location.reload();</script>`, true, 1, ""},
+		{"navigate", `<script>document.cookie='a=1';document.cookie='b=2';window.location.href = '/home';</script>`, true, 2, "/home"},
+		{"replace", `<script>document.cookie="a=1"; location.replace("/next")</script>`, true, 1, "/next"},
+		{"computed", `<script>document.cookie = "a=" + token(); location.reload()</script>`, true, 0, ""},
+		{"no navigation", `<script>document.cookie = "seen=1";</script>`, false, 0, ""},
+		{"comparison", `<script>if (document.cookie == "") location.reload()</script>`, false, 0, ""},
+		{"outside script", `<p>document.cookie = "a=1"; location.reload()</p>`, false, 0, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate, ok := findCookieGate([]byte(tt.doc))
+			if ok != tt.ok || len(gate.cookies) != tt.cookies || gate.target != tt.target {
+				t.Errorf("got %v with %d cookies to %q, want %v with %d to %q", ok, len(gate.cookies), gate.target, tt.ok, tt.cookies, tt.target)
+			}
+		})
+	}
+}
+
+func TestCookieGates(t *testing.T) {
+	gate := []byte(`<html><script>document.cookie = "pass=1; path=/"; location.reload();</script></html>`)
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if _, err := r.Cookie("pass"); err != nil || r.URL.Path == "/stuck" {
+			w.WriteHeader(stdhttp.StatusForbidden)
+			w.Write(gate)
+			return
+		}
+		w.Write([]byte("welcome"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var challenges []Challenge
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithCookieGates(CookieGates{
+			OnChallenge: func(res *http.Response, c Challenge) {
+				challenges = append(challenges, c)
+			},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if string(body) != "welcome" {
+		t.Errorf("body = %q, want welcome", body)
+	}
+	if chain := RedirectsFromResponse(res); len(chain) != 1 || chain[0].StatusCode != http.StatusSeeOther {
+		t.Errorf("want the gate as one 303 hop; got %+v", chain)
+	}
+
+	res, err = client.Get(server.URL + "/stuck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusForbidden {
+		t.Errorf("status = %d, want the gate returned", res.StatusCode)
+	}
+	if len(challenges) != 1 || challenges[0].Vendor != "cookie-gate" {
+		t.Errorf("challenges = %v, want one cookie-gate", challenges)
+	}
+}
+
+func TestCookieGatesCloseIdleConnections(t *testing.T) {
+	testClientClosesIdle(t, WithCookieGates(CookieGates{}))
+}
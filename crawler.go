@@ -0,0 +1,89 @@
+package mimic
+
+import (
+	"fmt"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// crawlerOrder is the header order crawler requests are built with.
+var crawlerOrder = []string{"user-agent", "from", "accept", "accept-language", "accept-encoding", "referer", "cookie"}
+
+// Googlebot creates a ClientSpec that mimics Google's crawler, which renders
+// pages with an evergreen Chromium build. Version is the Chrome version in its
+// user agent (e.g., "137.0.7151.119"). Use PlatformLinux for the desktop
+// crawler and PlatformAndroid for the smartphone crawler.
+//
+// It is meant for testing a site's own bot handling. Sites verify Googlebot by
+// reverse DNS, which requests from anywhere else fail.
+func Googlebot(version string, opts ...SpecOption) (*ClientSpec, error) {
+	const bot = "compatible; Googlebot/2.1; +http://www.google.com/bot.html"
+	return crawler("googlebot", version, map[Platform]string{
+		PlatformLinux:   fmt.Sprintf("Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; %s) Chrome/%s Safari/537.36", bot, version),
+		PlatformAndroid: fmt.Sprintf("Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Mobile Safari/537.36 (%s)", version, bot),
+	}, "googlebot(at)googlebot.com", opts)
+}
+
+// Bingbot creates a ClientSpec that mimics Microsoft's crawler, which renders
+// pages with an evergreen Chromium build. Version is the Chrome version in its
+// user agent. Use PlatformLinux for the desktop crawler and PlatformAndroid for
+// the mobile crawler.
+//
+// It is meant for testing a site's own bot handling. Sites verify Bingbot by
+// reverse DNS, which requests from anywhere else fail.
+func Bingbot(version string, opts ...SpecOption) (*ClientSpec, error) {
+	const bot = "compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm"
+	return crawler("bingbot", version, map[Platform]string{
+		PlatformLinux:   fmt.Sprintf("Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; %s) Chrome/%s Safari/537.36", bot, version),
+		PlatformAndroid: fmt.Sprintf("Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Mobile Safari/537.36 (%s)", version, bot),
+	}, "", opts)
+}
+
+// crawler builds the spec of a Chromium-based crawler with the given user agent
+// per platform. Crawlers send a fixed Accept and Accept-Encoding on every
+// request, a From address when they have one, and neither client hints nor
+// fetch metadata.
+func crawler(name, version string, uas map[Platform]string, from string, opts []SpecOption) (*ClientSpec, error) {
+	_, majorNum, err := parseMajorVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	if majorNum < crawlerMinMajor {
+		return nil, &VersionTooOldError{Browser: name, Min: crawlerMinMajor, Got: majorNum}
+	}
+
+	const (
+		accept         = "text/html,application/xhtml+xml,application/signed-exchange;v=b3,application/xml;q=0.9,*/*;q=0.8"
+		acceptEncoding = "gzip, deflate, br"
+	)
+
+	headers := make(map[Platform]http.Header, len(uas))
+	for p, ua := range uas {
+		h := http.Header{
+			"User-Agent":      {ua},
+			"Accept":          {accept},
+			"Accept-Encoding": {acceptEncoding},
+		}
+		if from != "" {
+			h.Set("From", from)
+		}
+		headers[p] = h
+	}
+
+	spec, err := chromiumEmbedder(name, version, version, headers, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	spec.fetch = &fetchHeaders{
+		document:        accept,
+		image:           "image/*,*/*;q=0.8",
+		acceptEncoding:  acceptEncoding,
+		navigationOrder: crawlerOrder,
+		fetchOrder:      crawlerOrder,
+	}
+	spec.quicParameters = nil
+
+	return spec, nil
+}
@@ -0,0 +1,47 @@
+package mimic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCrawlerSpecs(t *testing.T) {
+	googlebot, err := Googlebot("137.0.7151.119")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := googlebot.buildHeaders(PlatformAndroid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua := headers.Get("User-Agent"); !strings.Contains(ua, "Mobile Safari/537.36 (compatible; Googlebot/2.1;") {
+		t.Errorf("want the smartphone crawler user agent; got %q", ua)
+	}
+	if headers.Get("From") != "googlebot(at)googlebot.com" {
+		t.Errorf("want From; got %q", headers.Get("From"))
+	}
+	if headers.Get("sec-ch-ua") != "" {
+		t.Error("want no client hints")
+	}
+
+	bingbot, err := Bingbot("137.0.7151.119")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err = bingbot.buildHeaders(PlatformLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua := headers.Get("User-Agent"); !strings.Contains(ua, "bingbot/2.0") || headers.Get("From") != "" {
+		t.Errorf("want the bingbot user agent and no From; got %v", headers)
+	}
+
+	if _, err := NewTransport(bingbot, PlatformWindows); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("want ErrUnsupportedPlatform on Windows; got %v", err)
+	}
+	if _, err := Googlebot("90.0.0.0"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion before 100; got %v", err)
+	}
+}
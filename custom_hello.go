@@ -0,0 +1,196 @@
+package mimic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+// CustomHello is a TLS ClientHello defined in a JSON document rather than Go
+// code, for fingerprints no built-in profile covers. Use it with
+// WithCustomHello.
+//
+// The document uses utls's JSON format, which names cipher suites, groups,
+// signature algorithms, and extensions by their IANA names, plus an optional
+// shuffle_extensions flag that permutes the extensions on every connection as
+// Chrome 106+ does:
+//
+//	{
+//	  "cipher_suites": ["GREASE", "TLS_AES_128_GCM_SHA256", ...],
+//	  "compression_methods": ["NULL"],
+//	  "extensions": [
+//	    {"name": "GREASE"},
+//	    {"name": "server_name"},
+//	    {"name": "supported_groups", "named_group_list": ["GREASE", "x25519", "secp256r1"]},
+//	    {"name": "key_share", "client_shares": [{"group": "GREASE", "key_exchange": [0]}, {"group": "x25519"}]},
+//	    {"name": "encrypted_client_hello"},
+//	    ...
+//	  ],
+//	  "shuffle_extensions": true
+//	}
+type CustomHello struct {
+	spec *tlsSpec
+}
+
+// ParseCustomHello parses and validates a ClientHello document. Unknown names
+// are rejected, and the hello is built once so a document utls cannot send
+// fails here rather than on the first connection.
+func ParseCustomHello(data []byte) (*CustomHello, error) {
+	var doc struct {
+		utls.ClientHelloSpecJSONUnmarshaler
+		// Extensions shadows the embedded field so each extension can be
+		// read on its own, see parseHelloExtension.
+		Extensions        []json.RawMessage `json:"extensions"`
+		ShuffleExtensions bool              `json:"shuffle_extensions"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing custom hello: %w", err)
+	}
+
+	if doc.CipherSuites == nil || len(doc.CipherSuites.CipherSuites()) == 0 {
+		return nil, errors.New("parsing custom hello: no cipher_suites")
+	}
+	if doc.Extensions == nil {
+		return nil, errors.New("parsing custom hello: no extensions")
+	}
+	if doc.CompressionMethods == nil {
+		doc.CompressionMethods = &utls.CompressionMethodsJSONUnmarshaler{}
+	}
+
+	template := utls.ClientHelloSpec{
+		CipherSuites:       doc.CipherSuites.CipherSuites(),
+		CompressionMethods: doc.CompressionMethods.CompressionMethods(),
+		TLSVersMin:         doc.TLSVersMin,
+		TLSVersMax:         doc.TLSVersMax,
+	}
+	for _, raw := range doc.Extensions {
+		ext, err := parseHelloExtension(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing custom hello: %w", err)
+		}
+		template.Extensions = append(template.Extensions, ext)
+	}
+
+	spec := &tlsSpec{template: template, shuffle: doc.ShuffleExtensions}
+	if len(spec.template.CompressionMethods) == 0 {
+		spec.template.CompressionMethods = []uint8{0}
+	}
+
+	if err := validateHello(spec.New()); err != nil {
+		return nil, fmt.Errorf("validating custom hello: %w", err)
+	}
+
+	return &CustomHello{spec: spec}, nil
+}
+
+// parseHelloExtension parses one extension of a ClientHello document. utls
+// has no JSON form for encrypted_client_hello, so it is read here as the
+// GREASE ECH extension Chromium sends when it has no ECH config, and its
+// group names lack the hybrid post-quantum groups, so the extensions that
+// list groups are read here too.
+func parseHelloExtension(raw json.RawMessage) (utls.TLSExtension, error) {
+	var ext struct {
+		Name           string   `json:"name"`
+		NamedGroupList []string `json:"named_group_list"`
+		ClientShares   []struct {
+			Group       string  `json:"group"`
+			KeyExchange []uint8 `json:"key_exchange"`
+		} `json:"client_shares"`
+	}
+	if err := json.Unmarshal(raw, &ext); err != nil {
+		return nil, err
+	}
+
+	switch ext.Name {
+	case extensionNameECH:
+		return utls.BoringGREASEECH(), nil
+	case "supported_groups":
+		curves := make([]utls.CurveID, 0, len(ext.NamedGroupList))
+		for _, name := range ext.NamedGroupList {
+			curve, err := groupByName(name)
+			if err != nil {
+				return nil, err
+			}
+			curves = append(curves, curve)
+		}
+		return &utls.SupportedCurvesExtension{Curves: curves}, nil
+	case "key_share":
+		shares := make([]utls.KeyShare, 0, len(ext.ClientShares))
+		for _, share := range ext.ClientShares {
+			curve, err := groupByName(share.Group)
+			if err != nil {
+				return nil, err
+			}
+			shares = append(shares, utls.KeyShare{Group: curve, Data: share.KeyExchange})
+		}
+		return &utls.KeyShareExtension{KeyShares: shares}, nil
+	}
+
+	var exts utls.TLSExtensionsJSONUnmarshaler
+	if err := json.Unmarshal(append(append([]byte("["), raw...), ']'), &exts); err != nil {
+		return nil, err
+	}
+	return exts.Extensions()[0], nil
+}
+
+// hybridGroups are the post-quantum key exchange groups browsers send, which
+// are missing from utls's names.
+var hybridGroups = map[string]utls.CurveID{
+	"X25519MLKEM768":        utls.X25519MLKEM768,
+	"X25519Kyber768Draft00": utls.X25519Kyber768Draft00,
+}
+
+// groupByName returns the named group called name in a ClientHello document.
+func groupByName(name string) (utls.CurveID, error) {
+	if name == "GREASE" {
+		return utls.GREASE_PLACEHOLDER, nil
+	}
+	if curve, ok := hybridGroups[name]; ok {
+		return curve, nil
+	}
+	if id, ok := dicttls.DictSupportedGroupsNameIndexed[name]; ok {
+		return utls.CurveID(id), nil
+	}
+	return 0, fmt.Errorf("unknown named group: %s", name)
+}
+
+// LoadCustomHello reads and parses a ClientHello document from a file.
+func LoadCustomHello(path string) (*CustomHello, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading custom hello: %w", err)
+	}
+	return ParseCustomHello(data)
+}
+
+// ClientHelloSpec returns a fresh copy of the ClientHello spec.
+func (h *CustomHello) ClientHelloSpec() *utls.ClientHelloSpec {
+	return h.spec.New()
+}
+
+// WithCustomHello sends h as the TLS ClientHello on every platform instead of
+// the browser's own. The HTTP/2 settings and headers are still the browser's,
+// so h should be a hello that browser could plausibly send.
+func WithCustomHello(h *CustomHello) SpecOption {
+	return func(c *specConfig) {
+		c.customHello = h
+	}
+}
+
+// validateHello builds the ClientHello for spec without sending it.
+func validateHello(spec *utls.ClientHelloSpec) error {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn := utls.UClient(client, &utls.Config{ServerName: "example.com"}, utls.HelloCustom)
+	if err := conn.ApplyPreset(spec); err != nil {
+		return err
+	}
+	return conn.BuildHandshakeState()
+}
@@ -0,0 +1,105 @@
+package mimic
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// chrome102Hello is utls's HelloChrome_102 as a document.
+const chrome102Hello = `{
+  "cipher_suites": [
+    "GREASE", "TLS_AES_128_GCM_SHA256", "TLS_AES_256_GCM_SHA384", "TLS_CHACHA20_POLY1305_SHA256",
+    "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256",
+    "TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384",
+    "TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256", "TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256",
+    "TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA", "TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA",
+    "TLS_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_AES_256_GCM_SHA384",
+    "TLS_RSA_WITH_AES_128_CBC_SHA", "TLS_RSA_WITH_AES_256_CBC_SHA"
+  ],
+  "compression_methods": ["NULL"],
+  "extensions": [
+    {"name": "GREASE"},
+    {"name": "server_name"},
+    {"name": "extended_master_secret"},
+    {"name": "renegotiation_info"},
+    {"name": "supported_groups", "named_group_list": ["GREASE", "x25519", "secp256r1", "secp384r1"]},
+    {"name": "ec_point_formats", "ec_point_format_list": ["uncompressed"]},
+    {"name": "session_ticket"},
+    {"name": "application_layer_protocol_negotiation", "protocol_name_list": ["h2", "http/1.1"]},
+    {"name": "status_request"},
+    {"name": "signature_algorithms", "supported_signature_algorithms": [
+      "ecdsa_secp256r1_sha256", "rsa_pss_rsae_sha256", "rsa_pkcs1_sha256", "ecdsa_secp384r1_sha384",
+      "rsa_pss_rsae_sha384", "rsa_pkcs1_sha384", "rsa_pss_rsae_sha512", "rsa_pkcs1_sha512"
+    ]},
+    {"name": "signed_certificate_timestamp"},
+    {"name": "key_share", "client_shares": [{"group": "GREASE", "key_exchange": [0]}, {"group": "x25519"}]},
+    {"name": "psk_key_exchange_modes", "ke_modes": ["psk_dhe_ke"]},
+    {"name": "supported_versions", "versions": ["GREASE", "TLS 1.3", "TLS 1.2"]},
+    {"name": "compress_certificate", "algorithms": ["brotli"]},
+    {"name": "application_settings", "supported_protocols": ["h2"]},
+    {"name": "GREASE"},
+    {"name": "padding", "len": 0}
+  ],
+  "shuffle_extensions": true
+}`
+
+func TestParseCustomHello(t *testing.T) {
+	hello, err := ParseCustomHello([]byte(chrome102Hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := utls.UTLSIdToSpec(utls.HelloChrome_102)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := hello.ClientHelloSpec()
+	if !slices.Equal(got.CipherSuites, want.CipherSuites) {
+		t.Errorf("want cipher suites %v; got %v", want.CipherSuites, got.CipherSuites)
+	}
+	if len(got.Extensions) != len(want.Extensions) {
+		t.Errorf("want %d extensions; got %d", len(want.Extensions), len(got.Extensions))
+	}
+	if !hello.spec.shuffle {
+		t.Error("want shuffle_extensions to enable shuffling")
+	}
+
+	for _, doc := range []string{
+		`{"extensions": [{"name": "server_name"}]}`,
+		`{"cipher_suites": ["TLS_NOT_A_SUITE"], "extensions": []}`,
+		`{"cipher_suites": ["TLS_AES_128_GCM_SHA256"], "extensions": [{"name": "not_an_extension"}]}`,
+		`{"cipher_suites": ["TLS_AES_128_GCM_SHA256"]}`,
+	} {
+		if _, err := ParseCustomHello([]byte(doc)); err == nil {
+			t.Errorf("%s: want error", doc)
+		}
+	}
+}
+
+func TestWithCustomHello(t *testing.T) {
+	hello, err := ParseCustomHello([]byte(chrome102Hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := Safari("18.0", WithCustomHello(hello))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helloSpec, err := spec.ClientHelloSpec(PlatformIOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(helloSpec.CipherSuites) != 16 {
+		t.Errorf("want the custom hello's 16 cipher suites; got %d", len(helloSpec.CipherSuites))
+	}
+
+	if _, err := spec.ClientHelloSpec(PlatformWindows); err == nil || !strings.Contains(err.Error(), "safari") {
+		t.Errorf("want Safari's platform check kept; got %v", err)
+	}
+}
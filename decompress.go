@@ -0,0 +1,194 @@
+package mimic
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	http "github.com/saucesteals/fhttp"
+)
+
+// decompressTransport decodes compressed response bodies regardless of which
+// accept-encoding value the request carried, the way browsers do.
+//
+// fhttp already decodes gzip, deflate, and br in some cases (always over HTTP/2,
+// and over HTTP/1.1 only when accept-encoding mentions gzip), but it never decodes
+// zstd and leaves Content-Encoding in place over HTTP/2. This wrapper fills those
+// gaps so callers always receive a decoded body with consistent headers.
+//
+// It also applies the body size limit, to the decoded body, and the
+// decompression limits and stats, to encoded bodies.
+type decompressTransport struct {
+	wrapped
+	maxBodySize int64
+	limits      DecompressionLimits
+	stats       *DecompressionStats
+}
+
+// RoundTrip executes the request and decodes the response body if needed.
+func (t *decompressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == http.MethodHead || res.Body == nil || res.Body == http.NoBody {
+		return res, nil
+	}
+	defer limitBody(req, res, t.maxBodySize)
+
+	raw := res.Header.Values("Content-Encoding")
+	encoding := strings.ToLower(strings.TrimSpace(strings.Join(raw, ", ")))
+	if encoding == "" || encoding == "identity" {
+		return res, nil
+	}
+
+	// fhttp marks every HTTP/2 body as uncompressed, but only decodes one
+	// Content-Encoding that is exactly a token it knows
+	decodedByFHTTP := res.Uncompressed && len(raw) == 1 && fhttpDecoders[raw[0]]
+	if decodedByFHTTP {
+		// fhttp leaves the encoded length in place
+		length, err := strconv.ParseInt(res.Header.Get("Content-Length"), 10, 64)
+		if err != nil {
+			length = -1
+		}
+		t.meter(res, encoding, func() int64 { return length })
+	} else {
+		body, encoded, ok := decodeBody(res.Body, encoding)
+		if !ok {
+			return res, nil
+		}
+		res.Body = body
+		t.meter(res, encoding, encoded)
+	}
+
+	res.Header.Del("Content-Encoding")
+	res.Header.Del("Content-Length")
+	res.ContentLength = -1
+	res.Uncompressed = true
+	reportDecoded(req.Context())
+
+	return res, nil
+}
+
+// meter wraps the decoded body of res to apply the decompression limits and
+// stats, if any are set. encoded returns the encoded bytes read so far.
+func (t *decompressTransport) meter(res *http.Response, encoding string, encoded func() int64) {
+	if t.limits == (DecompressionLimits{}) && t.stats == nil {
+		return
+	}
+	res.Body = newMeteredBody(res.Body, encoding, encoded, t.limits, t.stats)
+}
+
+// fhttpDecoders are the Content-Encoding values fhttp decodes itself.
+var fhttpDecoders = map[string]bool{"gzip": true, "br": true, "deflate": true}
+
+// decodeBody returns body decoded from encoding, a lowercased Content-Encoding
+// list of the codings applied in order, and a function returning the encoded
+// bytes read so far. It reports false, leaving body alone, if any coding is
+// unknown.
+func decodeBody(body io.ReadCloser, encoding string) (io.ReadCloser, func() int64, bool) {
+	var codings []string
+	for coding := range strings.SplitSeq(encoding, ",") {
+		switch coding = strings.TrimSpace(coding); coding {
+		case "", "identity":
+		case "x-gzip":
+			codings = append(codings, "gzip")
+		default:
+			if _, ok := decoders[coding]; !ok {
+				return body, nil, false
+			}
+			codings = append(codings, coding)
+		}
+	}
+	if len(codings) == 0 {
+		return body, nil, false
+	}
+
+	// the last coding applied is the first to undo
+	var first *decodingReader
+	for i := len(codings) - 1; i >= 0; i-- {
+		d := &decodingReader{body: body, newDecoder: decoders[codings[i]]}
+		if first == nil {
+			first = d
+		}
+		body = d
+	}
+	return body, func() int64 { return first.encoded.n }, true
+}
+
+// decoders maps a Content-Encoding token to a constructor for its decoder.
+var decoders = map[string]func(io.Reader) (io.ReadCloser, error){
+	"gzip": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	"deflate": newDeflateReader,
+	"br": func(r io.Reader) (io.ReadCloser, error) {
+		return io.NopCloser(brotli.NewReader(r)), nil
+	},
+	"zstd": func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	},
+}
+
+// newDeflateReader accepts both zlib-wrapped and raw deflate streams. The
+// "deflate" coding is supposed to be zlib, but enough servers send raw deflate
+// that every browser accepts either.
+func newDeflateReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+
+	isZlib := header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+	if isZlib {
+		return zlib.NewReader(br)
+	}
+
+	return flate.NewReader(br), nil
+}
+
+// decodingReader lazily creates its decoder on the first call to Read so that
+// RoundTrip does not block waiting for body bytes.
+type decodingReader struct {
+	body       io.ReadCloser
+	newDecoder func(io.Reader) (io.ReadCloser, error)
+	decoder    io.ReadCloser
+	encoded    countingReader // the body, as the decoder reads it
+	err        error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	if d.err != nil {
+		return 0, d.err
+	}
+
+	if d.decoder == nil {
+		d.encoded.r = d.body
+		d.decoder, d.err = d.newDecoder(&d.encoded)
+		if d.err != nil {
+			return 0, d.err
+		}
+	}
+
+	return d.decoder.Read(p)
+}
+
+func (d *decodingReader) Close() error {
+	if d.decoder != nil {
+		d.decoder.Close()
+	}
+	return d.body.Close()
+}
@@ -0,0 +1,177 @@
+package mimic
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// encodedRoundTripper answers every request with body, sent with encoding as
+// its Content-Encoding, as fhttp hands over bodies it does not decode.
+type encodedRoundTripper struct {
+	encoding string
+	body     []byte
+}
+
+func (rt encodedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Content-Encoding": {rt.encoding},
+			"Content-Length":   {strconv.Itoa(len(rt.body))},
+		},
+		Body:          io.NopCloser(bytes.NewReader(rt.body)),
+		ContentLength: int64(len(rt.body)),
+		Request:       req,
+	}, nil
+}
+
+func TestDecompressEncodings(t *testing.T) {
+	want := bytes.Repeat([]byte("mimic decodes what browsers decode. "), 100)
+
+	encode := func(newWriter func(io.Writer) io.WriteCloser) []byte {
+		var b bytes.Buffer
+		w := newWriter(&b)
+		w.Write(want)
+		w.Close()
+		return b.Bytes()
+	}
+	gzipped := encode(func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"gzip", "gzip", gzipped},
+		{"uppercase", " GZIP ", gzipped},
+		{"zlib deflate", "deflate", encode(func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) })},
+		{"raw deflate", "deflate", encode(func(w io.Writer) io.WriteCloser {
+			fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+			return fw
+		})},
+		{"br", "br", encode(func(w io.Writer) io.WriteCloser { return brotli.NewWriter(w) })},
+		{"zstd", "Zstd", encode(func(w io.Writer) io.WriteCloser {
+			zw, _ := zstd.NewWriter(w)
+			return zw
+		})},
+		{"identity", "identity", want},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &decompressTransport{wrapped: wrapped{encodedRoundTripper{encoding: tt.encoding, body: tt.body}}}
+			req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := rt.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			got, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("body = %q..., want the decoded body", got[:min(len(got), 32)])
+			}
+
+			if tt.encoding == "identity" {
+				return
+			}
+			if res.Header.Get("Content-Encoding") != "" || res.Header.Get("Content-Length") != "" || res.ContentLength != -1 {
+				t.Errorf("want the encoding and encoded length removed; got %v, length %d", res.Header, res.ContentLength)
+			}
+		})
+	}
+}
+
+func TestDecompressHTTP2(t *testing.T) {
+	want := bytes.Repeat([]byte("mimic decodes what browsers decode. "), 100)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(want)
+	zw.Close()
+
+	var stacked bytes.Buffer
+	bw := brotli.NewWriter(&stacked)
+	bw.Write(gz.Bytes())
+	bw.Close()
+
+	bodies := map[string][]byte{"GZIP": gz.Bytes(), "x-gzip": gz.Bytes(), "gzip, br": stacked.Bytes()}
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		w.Header().Set("Content-Encoding", encoding)
+		w.Write(bodies[encoding])
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for encoding := range bodies {
+		res, err := client.Get(server.URL + "/?encoding=" + url.QueryEscape(encoding))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", encoding, err)
+		}
+
+		if res.ProtoMajor != 2 {
+			t.Fatalf("%s: want HTTP/2; got %s", encoding, res.Proto)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: body = %q..., want the decoded body", encoding, got[:min(len(got), 32)])
+		}
+		if res.Header.Get("Content-Encoding") != "" {
+			t.Errorf("%s: want the encoding removed; got %v", encoding, res.Header)
+		}
+	}
+}
+
+func TestDecompressUnknownEncoding(t *testing.T) {
+	rt := &decompressTransport{wrapped: wrapped{encodedRoundTripper{encoding: "compress", body: []byte("x")}}}
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("Content-Encoding") != "compress" {
+		t.Errorf("want an unknown encoding left in place; got %v", res.Header)
+	}
+}
@@ -0,0 +1,136 @@
+package mimic
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// decompressionRatioFloor is how many bytes a body is decoded to before
+// MaxRatio applies, since the first bytes of a body can decode to far more
+// than the average.
+const decompressionRatioFloor = 1 << 20
+
+// DecompressionLimits bound what a compressed response body may decode to,
+// failing reads past either with a *DecompressionBombError. WithMaxBodySize
+// bounds every body; these bound encoded ones only, so a client can allow
+// large downloads while refusing small responses that expand without end.
+type DecompressionLimits struct {
+	// MaxRatio is the most decoded bytes a body may have per encoded byte,
+	// checked once it has decoded to 1 MiB. Zero is no limit.
+	//
+	// Over HTTP/2, fhttp decodes gzip, deflate, and br before mimic sees
+	// the body, so their encoded size is taken from Content-Length, and
+	// MaxRatio does not apply to those sent without one.
+	MaxRatio float64
+
+	// MaxSize is the most bytes a body may decode to. Zero is no limit.
+	MaxSize int64
+}
+
+// WithDecompressionLimits fails reads of compressed response bodies that
+// decode past limits.
+func WithDecompressionLimits(limits DecompressionLimits) ClientOption {
+	return func(c *clientConfig) {
+		c.decompressionLimits = limits
+	}
+}
+
+// DecompressionStats counts the compressed response bodies a Client decodes,
+// and their encoded and decoded bytes as they are read. The encoded bytes of
+// an HTTP/2 body fhttp decodes are its Content-Length, counted when it is
+// first read, or nothing when it has none, as for
+// DecompressionLimits.MaxRatio. Its methods are safe for concurrent use.
+type DecompressionStats struct {
+	bodies       atomic.Int64
+	compressed   atomic.Int64
+	decompressed atomic.Int64
+}
+
+// WithDecompressionStats counts the compressed response bodies the client
+// decodes into stats, which may be shared by several clients.
+func WithDecompressionStats(stats *DecompressionStats) ClientOption {
+	return func(c *clientConfig) {
+		c.decompressionStats = stats
+	}
+}
+
+// Bodies returns the number of compressed bodies decoded.
+func (s *DecompressionStats) Bodies() int64 {
+	return s.bodies.Load()
+}
+
+// CompressedBytes returns the encoded bytes read.
+func (s *DecompressionStats) CompressedBytes() int64 {
+	return s.compressed.Load()
+}
+
+// DecompressedBytes returns the bytes the encoded bytes decoded to.
+func (s *DecompressionStats) DecompressedBytes() int64 {
+	return s.decompressed.Load()
+}
+
+// meteredBody counts a decoded body into stats and fails reads past limits.
+type meteredBody struct {
+	body     io.ReadCloser
+	encoding string
+	// encoded returns the encoded bytes read so far, or -1 when they are
+	// unknown
+	encoded func() int64
+	limits  DecompressionLimits
+	stats   *DecompressionStats
+
+	decoded int64
+	counted int64 // encoded bytes added to stats
+	err     error
+}
+
+func newMeteredBody(body io.ReadCloser, encoding string, encoded func() int64, limits DecompressionLimits, stats *DecompressionStats) *meteredBody {
+	if stats != nil {
+		stats.bodies.Add(1)
+	}
+	return &meteredBody{body: body, encoding: encoding, encoded: encoded, limits: limits, stats: stats}
+}
+
+func (b *meteredBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+
+	n, err := b.body.Read(p)
+	b.decoded += int64(n)
+	encoded := b.encoded()
+	if b.stats != nil {
+		b.stats.decompressed.Add(int64(n))
+		if encoded > b.counted {
+			b.stats.compressed.Add(encoded - b.counted)
+			b.counted = encoded
+		}
+	}
+
+	limits := b.limits
+	switch {
+	case limits.MaxSize > 0 && b.decoded > limits.MaxSize:
+		b.err = &DecompressionBombError{Encoding: b.encoding, Encoded: encoded, Decoded: b.decoded, MaxSize: limits.MaxSize}
+		return n - int(b.decoded-limits.MaxSize), b.err
+	case limits.MaxRatio > 0 && encoded > 0 && b.decoded > decompressionRatioFloor && float64(b.decoded) > limits.MaxRatio*float64(encoded):
+		b.err = &DecompressionBombError{Encoding: b.encoding, Encoded: encoded, Decoded: b.decoded, MaxRatio: limits.MaxRatio}
+		return n, b.err
+	}
+	return n, err
+}
+
+func (b *meteredBody) Close() error {
+	return b.body.Close()
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,111 @@
+package mimic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestDecompressionLimits(t *testing.T) {
+	var gzipped, zstded bytes.Buffer
+	zw := gzip.NewWriter(&gzipped)
+	zw.Write(make([]byte, 8<<20))
+	zw.Close()
+	enc, err := zstd.NewWriter(&zstded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.Write(make([]byte, 8<<20))
+	enc.Close()
+
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		body := gzipped.Bytes()
+		if r.URL.Path == "/zstd" {
+			body = zstded.Bytes()
+		}
+		w.Header().Set("Content-Encoding", r.URL.Path[1:])
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Write(body)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := func() *http.Transport {
+		return &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	}
+	get := func(client *http.Client, path string) (int, error) {
+		res, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		got, err := io.ReadAll(res.Body)
+		return len(got), err
+	}
+
+	// fhttp decodes gzip, so its size comes from Content-Length; mimic
+	// decodes zstd and counts it
+	stats := &DecompressionStats{}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base())),
+		WithDecompressionLimits(DecompressionLimits{MaxRatio: 100}),
+		WithDecompressionStats(stats),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/gzip", "/zstd"} {
+		_, err := get(client, path)
+		var bomb *DecompressionBombError
+		if !errors.As(err, &bomb) || !errors.Is(err, ErrDecompressionBomb) {
+			t.Fatalf("%s: err = %v, want a DecompressionBombError", path, err)
+		}
+		if bomb.MaxRatio != 100 || bomb.Encoded <= 0 || bomb.Decoded <= 100*bomb.Encoded {
+			t.Errorf("%s: err = %+v, want the ratio passed", path, bomb)
+		}
+	}
+	if stats.Bodies() != 2 || stats.CompressedBytes() <= 0 || stats.DecompressedBytes() <= 100*stats.CompressedBytes() {
+		t.Errorf("stats = %d bodies, %d compressed, %d decompressed", stats.Bodies(), stats.CompressedBytes(), stats.DecompressedBytes())
+	}
+
+	client, err = NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base())),
+		WithDecompressionLimits(DecompressionLimits{MaxSize: 1 << 20}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := get(client, "/zstd")
+	if !errors.Is(err, ErrDecompressionBomb) || n != 1<<20 {
+		t.Errorf("read %d bytes, %v, want 1 MiB then a DecompressionBombError", n, err)
+	}
+
+	stats = &DecompressionStats{}
+	client, err = NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base())),
+		WithDecompressionStats(stats),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := get(client, "/zstd"); err != nil || n != 8<<20 {
+		t.Fatalf("read %d bytes, %v, want the whole body", n, err)
+	}
+	if stats.CompressedBytes() != int64(zstded.Len()) || stats.DecompressedBytes() != 8<<20 {
+		t.Errorf("stats = %d compressed, %d decompressed, want %d and %d", stats.CompressedBytes(), stats.DecompressedBytes(), zstded.Len(), 8<<20)
+	}
+}
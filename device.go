@@ -0,0 +1,80 @@
+package mimic
+
+import (
+	"math"
+	"strconv"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Device is the hardware a browser runs on, as far as client hints reveal it.
+type Device struct {
+	// Memory is the device's RAM in GiB. Browsers round it to a power of two
+	// between 0.25 and 8, as navigator.deviceMemory does, so 16 is sent as 8.
+	// Zero is sent as 8.
+	Memory float64
+
+	// ViewportWidth is the width of the browser window's layout viewport in
+	// CSS pixels, the screen width scaled by the display's scale factor for a
+	// maximized window.
+	ViewportWidth int
+}
+
+var (
+	// DeviceDesktop is a desktop with 16 GiB of RAM and a maximized window on
+	// a 1080p display.
+	DeviceDesktop = Device{Memory: 16, ViewportWidth: 1920}
+
+	// DeviceLaptop is a laptop with 8 GiB of RAM and a 1080p display at 125%
+	// scale, the Windows default for its size.
+	DeviceLaptop = Device{Memory: 8, ViewportWidth: 1536}
+
+	// DeviceBudgetLaptop is a laptop with 4 GiB of RAM and a 1366x768 display.
+	DeviceBudgetLaptop = Device{Memory: 4, ViewportWidth: 1366}
+
+	// DeviceMacBook is a 14-inch MacBook Pro with 16 GiB of RAM at its default
+	// scaled resolution.
+	DeviceMacBook = Device{Memory: 16, ViewportWidth: 1512}
+)
+
+// WithDevice sets the hardware the browser runs on. Chromium specs answer the
+// device-memory and viewport-width hints, and their sec-ch- prefixed names
+// from 100, with it, only to origins that ask for them with Accept-CH. The
+// default is DeviceMacBook on PlatformMac and DeviceDesktop elsewhere. Only
+// applies to Chromium specs.
+func WithDevice(d Device) SpecOption {
+	return func(c *specConfig) {
+		c.device = &d
+	}
+}
+
+// deviceHints adds the device hints Chromium at majorNum answers with on
+// platform p to h.
+func deviceHints(h http.Header, majorNum int, p Platform, device *Device) {
+	d := DeviceDesktop
+	switch {
+	case device != nil:
+		d = *device
+	case p == PlatformMac:
+		d = DeviceMacBook
+	}
+
+	memory := strconv.FormatFloat(deviceMemory(d.Memory), 'f', -1, 64)
+	width := strconv.Itoa(d.ViewportWidth)
+
+	h.Set("device-memory", memory)
+	h.Set("viewport-width", width)
+	if majorNum >= 100 {
+		h.Set("sec-ch-device-memory", memory)
+		h.Set("sec-ch-viewport-width", width)
+	}
+}
+
+// deviceMemory returns the value browsers report for gib of RAM: the nearest
+// power of two, between 0.25 and 8.
+func deviceMemory(gib float64) float64 {
+	if gib <= 0 {
+		return 8
+	}
+	return min(max(math.Exp2(math.Round(math.Log2(gib))), 0.25), 8)
+}
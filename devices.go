@@ -0,0 +1,250 @@
+package mimic
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+// Living-room platforms, each only supported by its own device spec.
+const (
+	PlatformPlayStation Platform = "playstation"
+	PlatformTizen       Platform = "tizen"
+	PlatformWebOS       Platform = "webos"
+)
+
+// tizenChromium maps Tizen releases to the Chromium build their browser runs.
+var tizenChromium = map[string]string{
+	"6.5": "85.0.4183.93",
+	"7.0": "94.0.4606.31",
+	"8.0": "108.0.5359.1",
+	"9.0": "120.0.6099.5",
+}
+
+// webOSChromium maps webOS TV releases to the Chromium build their browser runs.
+var webOSChromium = map[string]string{
+	"22": "87.0.4280.88",
+	"23": "94.0.4606.128",
+	"24": "108.0.5359.211",
+	"25": "120.0.6099.270",
+}
+
+// SamsungTV creates a ClientSpec that mimics the browser on Samsung smart TVs,
+// a Chromium build that Tizen ships. Version is the Tizen release ("6.5", "7.0",
+// "8.0", or "9.0"), which fixes the Chromium version. Use it with PlatformTizen.
+//
+// The TV browser does not send sec-ch-ua client hint headers.
+func SamsungTV(tizen string, opts ...SpecOption) (*ClientSpec, error) {
+	chromium, ok := tizenChromium[tizen]
+	if !ok {
+		return nil, fmt.Errorf("tizen %s: %w", tizen, ErrUnsupportedVersion)
+	}
+
+	ua := fmt.Sprintf("Mozilla/5.0 (SMART-TV; LINUX; Tizen %s) AppleWebKit/537.36 (KHTML, like Gecko) %s/%s TV Safari/537.36", tizen, chromium, tizen)
+	return chromiumEmbedder("tizen", tizen, chromium, map[Platform]http.Header{
+		PlatformTizen: {"User-Agent": {ua}},
+	}, opts)
+}
+
+// LGTV creates a ClientSpec that mimics the browser on LG smart TVs, a Chromium
+// build that webOS ships. Version is the webOS TV release ("22", "23", "24", or
+// "25"), which fixes the Chromium version. Use it with PlatformWebOS.
+//
+// The TV browser does not send sec-ch-ua client hint headers.
+func LGTV(webOS string, opts ...SpecOption) (*ClientSpec, error) {
+	chromium, ok := webOSChromium[webOS]
+	if !ok {
+		return nil, fmt.Errorf("webos %s: %w", webOS, ErrUnsupportedVersion)
+	}
+
+	ua := fmt.Sprintf("Mozilla/5.0 (Web0S; Linux/SmartTV) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/%s Safari/537.36 WebAppManager", chromium)
+	return chromiumEmbedder("webos", webOS, chromium, map[Platform]http.Header{
+		PlatformWebOS: {"User-Agent": {ua}},
+	}, opts)
+}
+
+// chromiumEmbedder builds the spec of a client on the given Chromium build that
+// runs only on the platforms in headers and sends their default headers, with
+// no client hints.
+func chromiumEmbedder(browser, version, chromium string, headers map[Platform]http.Header, opts []SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
+	_, majorNum, err := parseMajorVersion(chromium)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := cfg.newTLSSpec(chromiumTLSHelloID(majorNum))
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", browser, version, err)
+	}
+
+	fetch := chromiumFetchHeaders(majorNum)
+	fetch.navigationOrder = withoutClientHints(fetch.navigationOrder)
+	fetch.fetchOrder = withoutClientHints(fetch.fetchOrder)
+
+	return &ClientSpec{
+		version:      version,
+		http2Options: cfg.http2Options(chromiumHTTP2Options(majorNum)),
+		timeouts:     chromiumTimeouts(),
+		requireSCTs:  true,
+		tlsSpecFor: cfg.tlsSpecFor(func(p Platform) (*tlsSpec, error) {
+			if _, ok := headers[p]; !ok {
+				return nil, &PlatformError{Browser: browser, Platform: p}
+			}
+			return ts, nil
+		}),
+		buildHeaders: deviceBuildHeaders(browser, headers),
+		fetch:        fetch,
+
+		quicParameters: chromiumQUICParameters,
+	}, nil
+}
+
+// withoutClientHints returns order without the sec-ch-ua headers.
+func withoutClientHints(order []string) []string {
+	return slices.DeleteFunc(slices.Clone(order), func(key string) bool {
+		return strings.HasPrefix(key, "sec-ch-ua")
+	})
+}
+
+// helloPlayStation5 names the PS5 browser's hello in errors. utls has no
+// parrot for it; ps5TLSSpec builds it.
+var helloPlayStation5 = utls.ClientHelloID{Client: "PlayStation", Version: "5"}
+
+// PlayStation5 creates a ClientSpec that mimics the PS5 system browser at the
+// given system software version (e.g., "24.06"). The browser is Sony's WebKit
+// port, which uses curl and OpenSSL for networking, so its TLS and HTTP/2
+// fingerprints are curl's rather than Safari's. Use it with
+// PlatformPlayStation.
+func PlayStation5(firmware string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
+	if _, _, err := parseMajorVersion(firmware); err != nil {
+		return nil, err
+	}
+
+	ts, err := cfg.editTLSSpec(helloPlayStation5, ps5TLSSpec())
+	if err != nil {
+		return nil, fmt.Errorf("playstation %s: %w", firmware, err)
+	}
+
+	ua := fmt.Sprintf("Mozilla/5.0 (PlayStation; PlayStation 5/%s) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/15.4 Safari/605.1.15", firmware)
+
+	return &ClientSpec{
+		version:      firmware,
+		http2Options: cfg.http2Options(curlHTTP2Options()),
+		timeouts:     safariTimeouts(),
+		tlsSpecFor: cfg.tlsSpecFor(func(p Platform) (*tlsSpec, error) {
+			if p != PlatformPlayStation {
+				return nil, &PlatformError{Browser: "playstation", Platform: p}
+			}
+			return ts, nil
+		}),
+		buildHeaders: deviceBuildHeaders("playstation", map[Platform]http.Header{
+			PlatformPlayStation: {"User-Agent": {ua}},
+		}),
+		fetch: &fetchHeaders{
+			document:        "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+			image:           "image/webp,image/png,image/svg+xml,image/*;q=0.8,video/*;q=0.8,*/*;q=0.5",
+			acceptEncoding:  "gzip, deflate, br",
+			navigationOrder: safariNavigationOrder,
+			fetchOrder:      safariFetchOrder,
+		},
+	}, nil
+}
+
+// ps5TLSSpec is OpenSSL 1.1.1's default ClientHello as curl sends it.
+func ps5TLSSpec() *tlsSpec {
+	return &tlsSpec{template: utls.ClientHelloSpec{
+		TLSVersMin: utls.VersionTLS12,
+		TLSVersMax: utls.VersionTLS13,
+		CipherSuites: []uint16{
+			utls.TLS_AES_256_GCM_SHA384,
+			utls.TLS_CHACHA20_POLY1305_SHA256,
+			utls.TLS_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+			utls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_256_GCM_SHA384,
+			utls.TLS_RSA_WITH_AES_128_GCM_SHA256,
+			utls.TLS_RSA_WITH_AES_256_CBC_SHA,
+			utls.TLS_RSA_WITH_AES_128_CBC_SHA,
+			utls.FAKE_TLS_EMPTY_RENEGOTIATION_INFO_SCSV,
+		},
+		CompressionMethods: []uint8{0},
+		Extensions: []utls.TLSExtension{
+			&utls.SNIExtension{},
+			&utls.SupportedPointsExtension{SupportedPoints: []uint8{0, 1, 2}},
+			&utls.SupportedCurvesExtension{Curves: []utls.CurveID{
+				utls.X25519, utls.CurveP256, utls.CurveID(30), utls.CurveP521, utls.CurveP384,
+			}},
+			&utls.SessionTicketExtension{},
+			&utls.ALPNExtension{AlpnProtocols: []string{"h2", "http/1.1"}},
+			&utls.GenericExtension{Id: 22}, // encrypt_then_mac
+			&utls.ExtendedMasterSecretExtension{},
+			&utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: []utls.SignatureScheme{
+				utls.ECDSAWithP256AndSHA256,
+				utls.ECDSAWithP384AndSHA384,
+				utls.ECDSAWithP521AndSHA512,
+				utls.Ed25519,
+				0x0808, // ed448
+				utls.PSSWithSHA256,
+				utls.PSSWithSHA384,
+				utls.PSSWithSHA512,
+				0x0809, // rsa_pss_pss_sha256
+				0x080a, // rsa_pss_pss_sha384
+				0x080b, // rsa_pss_pss_sha512
+				utls.PKCS1WithSHA256,
+				utls.PKCS1WithSHA384,
+				utls.PKCS1WithSHA512,
+			}},
+			&utls.SupportedVersionsExtension{Versions: []uint16{utls.VersionTLS13, utls.VersionTLS12}},
+			&utls.PSKKeyExchangeModesExtension{Modes: []uint8{utls.PskModeDHE}},
+			&utls.KeyShareExtension{KeyShares: []utls.KeyShare{{Group: utls.X25519}}},
+		},
+	}}
+}
+
+// curlHTTP2Options are the HTTP/2 settings curl sends through nghttp2, with
+// its 32 MiB stream and connection windows.
+func curlHTTP2Options() *HTTP2Options {
+	return &HTTP2Options{
+		PseudoHeaderOrder: []string{":method", ":path", ":scheme", ":authority"},
+		Settings: []http2.Setting{
+			{ID: http2.SettingMaxConcurrentStreams, Val: 100},
+			{ID: http2.SettingInitialWindowSize, Val: 33554432},
+			{ID: http2.SettingEnablePush, Val: 0},
+		},
+		InitialWindowSize: 33554432,
+		HeaderTableSize:   4096,
+		ConnectionFlow:    33488897,
+		HeaderPriority: &http2.PriorityParam{
+			Exclusive: false,
+			Weight:    15, // nghttp2's default weight of 16
+		},
+	}
+}
+
+// deviceBuildHeaders returns a header builder for a client that only runs on
+// the platforms in headers, returning a copy of each platform's headers.
+func deviceBuildHeaders(browser string, headers map[Platform]http.Header) func(Platform) (http.Header, error) {
+	return func(p Platform) (http.Header, error) {
+		h, ok := headers[p]
+		if !ok {
+			return nil, &PlatformError{Browser: browser, Platform: p}
+		}
+		return h.Clone(), nil
+	}
+}
@@ -0,0 +1,56 @@
+package mimic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDeviceSpecs(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     func() (*ClientSpec, error)
+		platform Platform
+		ua       string
+	}{
+		{"ps5", func() (*ClientSpec, error) { return PlayStation5("24.06") }, PlatformPlayStation, "PlayStation 5/24.06"},
+		{"tizen", func() (*ClientSpec, error) { return SamsungTV("6.5") }, PlatformTizen, "Tizen 6.5) AppleWebKit/537.36 (KHTML, like Gecko) 85.0.4183.93/6.5 TV"},
+		{"webos", func() (*ClientSpec, error) { return LGTV("24") }, PlatformWebOS, "Web0S; Linux/SmartTV"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := tt.spec()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hello, err := spec.ClientHelloSpec(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := validateHello(hello); err != nil {
+				t.Errorf("want a buildable ClientHello; got %v", err)
+			}
+
+			headers, err := spec.buildHeaders(tt.platform)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ua := headers.Get("user-agent"); !strings.Contains(ua, tt.ua) {
+				t.Errorf("want user agent containing %q; got %q", tt.ua, ua)
+			}
+			if headers.Get("sec-ch-ua") != "" {
+				t.Error("want no client hints")
+			}
+
+			if _, err := NewTransport(spec, PlatformWindows); !errors.Is(err, ErrUnsupportedPlatform) {
+				t.Errorf("want ErrUnsupportedPlatform on Windows; got %v", err)
+			}
+		})
+	}
+
+	if _, err := SamsungTV("5.0"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion for an unknown Tizen release; got %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// DownloadOption configures Download.
+type DownloadOption func(*downloadConfig)
+
+type downloadConfig struct {
+	maxResumes int
+	progress   func(written, total int64)
+}
+
+// WithMaxResumes sets how many times Download re-requests the remaining bytes
+// after a connection failure. The default is 5.
+func WithMaxResumes(n int) DownloadOption {
+	return func(c *downloadConfig) {
+		c.maxResumes = n
+	}
+}
+
+// WithProgress sets a callback invoked after every write with the number of bytes
+// written so far and the total size, or -1 if the size is unknown.
+func WithProgress(fn func(written, total int64)) DownloadOption {
+	return func(c *downloadConfig) {
+		c.progress = fn
+	}
+}
+
+// Download performs req with client and copies the response body to w. If the
+// connection fails mid-body, the remaining bytes are re-requested with a Range
+// header and an If-Range validator, the way browser download managers resume.
+// Every segment is sent through the same client, so each one carries the same
+// fingerprint. It returns the number of bytes written.
+//
+// Byte offsets are only meaningful for the unencoded entity, so every segment is
+// requested with accept-encoding: identity. A server that encodes the body anyway
+// cannot be resumed, even when the client decoded it. Resuming also requires the
+// server to send a strong ETag or a Last-Modified date; otherwise the first failure
+// is returned.
+func Download(ctx context.Context, client *http.Client, req *http.Request, w io.Writer, opts ...DownloadOption) (int64, error) {
+	cfg := &downloadConfig{maxResumes: 5}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	// the client's decompression layer strips Content-Encoding, so it reports
+	// decoding here instead
+	var decoded atomic.Bool
+	req = req.Clone(withDecodeReport(ctx, &decoded))
+	req.Header.Set("accept-encoding", "identity")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("requesting download: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return 0, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	total := res.ContentLength
+	validator := resumeValidator(res)
+	if decoded.Load() {
+		validator = ""
+	}
+
+	var written int64
+	for resumes := 0; ; resumes++ {
+		n, err := copyWithProgress(w, res.Body, written, total, cfg.progress)
+		res.Body.Close()
+		written += n
+
+		if err == nil {
+			return written, nil
+		}
+
+		var writeErr *downloadWriteError
+		if errors.As(err, &writeErr) {
+			return written, fmt.Errorf("writing download: %w", writeErr.err)
+		}
+
+		if ctx.Err() != nil || resumes >= cfg.maxResumes || validator == "" {
+			return written, fmt.Errorf("reading download body: %w", err)
+		}
+
+		res, err = resumeDownload(client, req, written, validator, &decoded)
+		if err != nil {
+			return written, err
+		}
+	}
+}
+
+// resumeValidator returns the value to send in If-Range, or "" if the response
+// cannot be resumed safely.
+func resumeValidator(res *http.Response) string {
+	// byte offsets into an encoded body do not match the entity
+	if encodedBody(res) {
+		return ""
+	}
+
+	// If-Range requires a strong validator
+	if etag := res.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+
+	return res.Header.Get("Last-Modified")
+}
+
+// encodedBody reports whether res has a Content-Encoding other than identity.
+func encodedBody(res *http.Response) bool {
+	encoding := res.Header.Get("Content-Encoding")
+	return encoding != "" && encoding != "identity"
+}
+
+// resumeDownload requests the bytes of req from offset onward, failing if the
+// server does not continue exactly where the previous response stopped, or sends
+// the rest encoded.
+func resumeDownload(client *http.Client, req *http.Request, offset int64, validator string, decoded *atomic.Bool) (*http.Response, error) {
+	resumeReq := req.Clone(req.Context())
+	resumeReq.Header.Set("range", fmt.Sprintf("bytes=%d-", offset))
+	resumeReq.Header.Set("if-range", validator)
+
+	res, err := client.Do(resumeReq)
+	if err != nil {
+		return nil, fmt.Errorf("resuming download at %d: %w", offset, err)
+	}
+
+	if res.StatusCode != http.StatusPartialContent {
+		res.Body.Close()
+		return nil, fmt.Errorf("resuming download at %d: status %d: %w", offset, res.StatusCode, ErrResumeRejected)
+	}
+
+	if decoded.Load() || encodedBody(res) {
+		res.Body.Close()
+		return nil, fmt.Errorf("resuming download at %d: encoded response: %w", offset, ErrResumeRejected)
+	}
+
+	start, err := contentRangeStart(res.Header.Get("Content-Range"))
+	if err != nil || start != offset {
+		res.Body.Close()
+		return nil, fmt.Errorf("resuming download at %d: content-range %q: %w", offset, res.Header.Get("Content-Range"), ErrResumeRejected)
+	}
+
+	return res, nil
+}
+
+// withDecodeReport returns a copy of ctx in which the client's decompression
+// layer sets decoded when it decodes a response body.
+func withDecodeReport(ctx context.Context, decoded *atomic.Bool) context.Context {
+	return context.WithValue(ctx, decodedKey, decoded)
+}
+
+// reportDecoded records that a response to a request with ctx was decoded, if
+// withDecodeReport asked for it.
+func reportDecoded(ctx context.Context) {
+	if decoded, ok := ctx.Value(decodedKey).(*atomic.Bool); ok {
+		decoded.Store(true)
+	}
+}
+
+// contentRangeStart parses the first byte position from a header like
+// "bytes 100-999/1000".
+func contentRangeStart(header string) (int64, error) {
+	spec, ok := strings.CutPrefix(header, "bytes ")
+	if !ok {
+		return 0, fmt.Errorf("parsing content-range %q", header)
+	}
+
+	start, _, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, fmt.Errorf("parsing content-range %q", header)
+	}
+
+	return strconv.ParseInt(start, 10, 64)
+}
+
+// copyWithProgress copies src to dst, reporting the running total (starting at
+// offset) to progress after each write.
+func copyWithProgress(dst io.Writer, src io.Reader, offset, total int64, progress func(written, total int64)) (int64, error) {
+	buf := make([]byte, 32*1024)
+
+	var written int64
+	for {
+		n, readErr := src.Read(buf)
+		if n > 0 {
+			wn, err := dst.Write(buf[:n])
+			written += int64(wn)
+			if err != nil {
+				return written, &downloadWriteError{err: err}
+			}
+			if progress != nil {
+				progress(offset+written, total)
+			}
+		}
+
+		if readErr == io.EOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+// downloadWriteError marks a failure writing to the destination, which must not
+// trigger a resume.
+type downloadWriteError struct {
+	err error
+}
+
+func (e *downloadWriteError) Error() string {
+	return e.err.Error()
+}
+
+func (e *downloadWriteError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,152 @@
+package mimic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"math/rand/v2"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptest"
+)
+
+func TestDownloadResumes(t *testing.T) {
+	body := strings.Repeat("mimic", 1000)
+	cut := len(body) / 2
+
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		w.Header().Set("ETag", `"v1"`)
+
+		if requests == 1 {
+			// send half the body, then drop the connection
+			conn, buf, err := w.(http.Hijacker).Hijack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fmt.Fprintf(buf, "HTTP/1.1 200 OK\r\nETag: \"v1\"\r\nContent-Length: %d\r\n\r\n%s", len(body), body[:cut])
+			buf.Flush()
+			conn.Close()
+			return
+		}
+
+		if got := r.Header.Get("If-Range"); got != `"v1"` {
+			t.Errorf("if-range: want %q; got %q", `"v1"`, got)
+		}
+
+		want := fmt.Sprintf("bytes=%d-", cut)
+		if got := r.Header.Get("Range"); got != want {
+			t.Errorf("range: want %q; got %q", want, got)
+		}
+
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", cut, len(body)-1, len(body)))
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte(body[cut:]))
+	}))
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var progress int64
+	var buf bytes.Buffer
+	n, err := Download(context.Background(), server.Client(), req, &buf, WithProgress(func(written, _ int64) {
+		progress = written
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != int64(len(body)) || progress != n {
+		t.Errorf("written: want %d; got %d (progress %d)", len(body), n, progress)
+	}
+
+	if buf.String() != body {
+		t.Error("downloaded body does not match")
+	}
+
+	if requests != 2 {
+		t.Errorf("requests: want 2; got %d", requests)
+	}
+}
+
+func TestDownloadEncodedResume(t *testing.T) {
+	entity := make([]byte, 32<<10)
+	for i := range entity {
+		entity[i] = byte(rand.Uint32())
+	}
+	body := hex.EncodeToString(entity)
+
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte(body))
+	zw.Close()
+	encoded := gz.Bytes()
+
+	// the server ignores accept-encoding and serves ranges of the gzip file,
+	// as servers of precompressed static files do
+	var requests atomic.Int32
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("ETag", `"v1"`)
+
+		if r.Header.Get("Range") != "" {
+			stdhttp.ServeContent(w, r, "", time.Time{}, bytes.NewReader(encoded))
+			return
+		}
+
+		w.Write(encoded[:len(encoded)/2])
+		w.(stdhttp.Flusher).Flush()
+		panic(stdhttp.ErrAbortHandler)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	n, err := Download(context.Background(), client, req, &buf)
+	if err == nil {
+		t.Fatal("want the interrupted download to fail")
+	}
+
+	if n != int64(buf.Len()) || !strings.HasPrefix(body, buf.String()) {
+		t.Errorf("want a prefix of the decoded body; got %d bytes that are not", buf.Len())
+	}
+
+	if got := requests.Load(); got != 1 {
+		t.Errorf("requests: want 1, with no resume of the decoded body; got %d", got)
+	}
+}
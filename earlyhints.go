@@ -0,0 +1,80 @@
+package mimic
+
+import (
+	"context"
+	"net/textproto"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptrace"
+)
+
+// InformationalResponse is a 1xx response the server sent before the final
+// response, such as 103 Early Hints.
+type InformationalResponse struct {
+	StatusCode int
+	Header     http.Header
+}
+
+// Link is one entry of a Link header, such as a preload hint.
+type Link struct {
+	// URL is the target of the link, exactly as written between the angle brackets.
+	URL string
+
+	// Params holds the link parameters, such as "rel" and "as", with lowercase names.
+	Params map[string]string
+}
+
+// Links parses the response's Link headers. Browsers act on the preload and
+// preconnect links of 103 Early Hints while waiting for the final response.
+func (r InformationalResponse) Links() []Link {
+	return ParseLinks(strings.Join(r.Header.Values("Link"), ","))
+}
+
+// WithInformationalResponses returns a copy of ctx that calls fn for every 1xx
+// informational response received for a request made with it, over HTTP/1.1 or
+// HTTP/2. Without it, informational responses are silently discarded.
+//
+//	ctx := mimic.WithInformationalResponses(ctx, func(r mimic.InformationalResponse) {
+//	    if r.StatusCode == http.StatusEarlyHints {
+//	        for _, link := range r.Links() { ... }
+//	    }
+//	})
+//	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+func WithInformationalResponses(ctx context.Context, fn func(InformationalResponse)) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			fn(InformationalResponse{StatusCode: code, Header: http.Header(header).Clone()})
+			return nil
+		},
+	})
+}
+
+// ParseLinks parses a Link header value (RFC 8288) into its links. Malformed
+// entries are skipped.
+func ParseLinks(value string) []Link {
+	var links []Link
+
+	for _, entry := range splitQuoted(value, ',') {
+		params := splitQuoted(entry, ';')
+
+		target := strings.TrimSpace(params[0])
+		if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+
+		link := Link{URL: target[1 : len(target)-1], Params: make(map[string]string)}
+		for _, param := range params[1:] {
+			name, val, _ := strings.Cut(param, "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+			link.Params[name] = strings.Trim(strings.TrimSpace(val), `"`)
+		}
+
+		links = append(links, link)
+	}
+
+	return links
+}
@@ -0,0 +1,76 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptest"
+)
+
+func TestWithInformationalResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 103 Early Hints\r\n" +
+			"Link: </style.css>; rel=preload; as=style\r\n" +
+			"Link: <https://cdn.example.com/a,b.js>; rel=preload; as=script; crossorigin\r\n\r\n")
+		buf.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok")
+		buf.Flush()
+	}))
+	defer server.Close()
+
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	var got []InformationalResponse
+	ctx := WithInformationalResponses(context.Background(), func(r InformationalResponse) {
+		got = append(got, r)
+	})
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if body, _ := io.ReadAll(res.Body); res.StatusCode != http.StatusOK || string(body) != "ok" {
+		t.Fatalf("want final 200 ok; got %d %q", res.StatusCode, body)
+	}
+
+	if len(got) != 1 || got[0].StatusCode != http.StatusEarlyHints {
+		t.Fatalf("want one 103 response; got %+v", got)
+	}
+
+	links := got[0].Links()
+	if len(links) != 2 {
+		t.Fatalf("want 2 links; got %+v", links)
+	}
+	if links[0].URL != "/style.css" || links[0].Params["as"] != "style" {
+		t.Errorf("link 0: got %+v", links[0])
+	}
+	if links[1].URL != "https://cdn.example.com/a,b.js" || links[1].Params["rel"] != "preload" {
+		t.Errorf("link 1: got %+v", links[1])
+	}
+	if _, ok := links[1].Params["crossorigin"]; !ok {
+		t.Errorf("link 1: want crossorigin param; got %+v", links[1].Params)
+	}
+}
@@ -0,0 +1,161 @@
+package mimic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// ECHOutcome is how a Transport recovered from a server rejecting ECH.
+type ECHOutcome string
+
+const (
+	// ECHRetried means the server sent retry configs and the request was
+	// sent again with them.
+	ECHRetried ECHOutcome = "retried"
+	// ECHDisabled means the server sent no retry configs, having no ECH keys,
+	// and the request was sent again without ECH, naming the host in the
+	// outer SNI.
+	ECHDisabled ECHOutcome = "disabled"
+)
+
+// ECHRejection describes a server rejecting ECH and how the Transport
+// recovered. RequestID and SessionID come from the rejected request's
+// context.
+type ECHRejection struct {
+	Host            string
+	Outcome         ECHOutcome
+	RetryConfigList []byte
+	Err             error
+	RequestID       string
+	SessionID       string
+}
+
+// WithECH encrypts the ClientHello with Encrypted Client Hello using
+// configList, a serialized ECHConfigList as published in a DNS HTTPS record,
+// in place of the GREASE ECH extension the browser sends without one. Specs
+// whose hello has no ECH extension, such as Safari's, send it unencrypted.
+//
+// When a server rejects ECH the Transport recovers as browsers do rather than
+// failing: if the server sent retry configs, the request is sent again once
+// with them, and if it sent none, it is sent again without ECH. Later
+// requests to that host go out the same way directly. onReject, if not nil,
+// is called each time a host is switched. A request with a body is retried
+// only if it sets GetBody.
+//
+// The server's certificate for its public name is verified before its retry
+// configs are trusted. Set EncryptedClientHelloRejectionVerify in the base
+// transport's TLSClientConfig to verify it differently.
+func WithECH(configList []byte, onReject func(ECHRejection)) TransportOption {
+	return func(c *transportConfig) {
+		c.echConfigList = configList
+		c.onECHReject = onReject
+	}
+}
+
+// echRecovery holds the transports a Transport switches hosts to when ECH is
+// rejected.
+type echRecovery struct {
+	transport *Transport
+	onReject  func(ECHRejection)
+
+	mu sync.Mutex
+	// variants are the transports sending each retry config list, keyed by
+	// the list, with "" for the one sending no ECH
+	variants map[string]*Transport
+	hosts    map[string]*Transport
+}
+
+func newECHRecovery(t *Transport, onReject func(ECHRejection)) *echRecovery {
+	return &echRecovery{
+		transport: t,
+		onReject:  onReject,
+		variants:  make(map[string]*Transport),
+		hosts:     make(map[string]*Transport),
+	}
+}
+
+// switched returns the transport req's host was switched to, or nil.
+func (e *echRecovery) switched(req *http.Request) *Transport {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.hosts[strings.ToLower(req.URL.Host)]
+}
+
+// isECHRejection reports whether err is a server rejecting ECH.
+func isECHRejection(err error) bool {
+	var rejection *utls.ECHRejectionError
+	return errors.As(err, &rejection)
+}
+
+// retry sends req again after err, a rejection of ECH, the way the server's
+// answer calls for. It returns err if req cannot be sent again.
+func (e *echRecovery) retry(req *http.Request, err error) (*http.Response, error) {
+	var rejection *utls.ECHRejectionError
+	if !errors.As(err, &rejection) || req.Context().Err() != nil {
+		return nil, err
+	}
+
+	retry, ok := replayable(req)
+	if !ok {
+		return nil, err
+	}
+
+	outcome := ECHRetried
+	if len(rejection.RetryConfigList) == 0 {
+		outcome = ECHDisabled
+	}
+
+	variant, variantErr := e.variant(rejection.RetryConfigList)
+	if variantErr != nil {
+		return nil, fmt.Errorf("configuring ech retry: %w", variantErr)
+	}
+
+	e.mu.Lock()
+	e.hosts[strings.ToLower(req.URL.Host)] = variant
+	e.mu.Unlock()
+
+	if e.onReject != nil {
+		event := ECHRejection{
+			Host:            req.URL.Hostname(),
+			Outcome:         outcome,
+			RetryConfigList: rejection.RetryConfigList,
+			Err:             err,
+		}
+		event.RequestID, _ = RequestID(req.Context())
+		event.SessionID, _ = SessionID(req.Context())
+		e.onReject(event)
+	}
+
+	return variant.RoundTrip(retry)
+}
+
+// variant returns the transport sending configList, or no ECH if it is empty.
+// A variant does not recover from rejections itself, as browsers retry only
+// once.
+func (e *echRecovery) variant(configList []byte) (*Transport, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if v, ok := e.variants[string(configList)]; ok {
+		return v, nil
+	}
+
+	v, err := e.transport.rebuild(e.transport.spec, e.transport.platform)
+	if err != nil {
+		return nil, err
+	}
+	v.ech = nil
+	if len(configList) == 0 {
+		configList = nil
+	}
+	v.base.TLSClientConfig.EncryptedClientHelloConfigList = configList
+
+	e.variants[string(configList)] = v
+	return v, nil
+}
@@ -0,0 +1,121 @@
+package mimic
+
+import (
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// echKey returns an X25519 ECH key with the given config ID for the public
+// name example.com.
+func echKey(t *testing.T, id byte) tls.EncryptedClientHelloKey {
+	t.Helper()
+
+	key, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pub := key.PublicKey().Bytes()
+	contents := []byte{id, 0x00, 0x20}
+	contents = binary.BigEndian.AppendUint16(contents, uint16(len(pub)))
+	contents = append(contents, pub...)
+	// HKDF-SHA256 with AES-128-GCM
+	contents = append(contents, 0x00, 0x04, 0x00, 0x01, 0x00, 0x01)
+	contents = append(contents, 0, byte(len("example.com")))
+	contents = append(contents, "example.com"...)
+	contents = append(contents, 0x00, 0x00)
+
+	config := binary.BigEndian.AppendUint16(nil, 0xfe0d)
+	config = binary.BigEndian.AppendUint16(config, uint16(len(contents)))
+	config = append(config, contents...)
+
+	return tls.EncryptedClientHelloKey{Config: config, PrivateKey: key.Bytes(), SendAsRetry: true}
+}
+
+// echConfigList returns the ECHConfigList of key alone.
+func echConfigList(key tls.EncryptedClientHelloKey) []byte {
+	list := binary.BigEndian.AppendUint16(nil, uint16(len(key.Config)))
+	return append(list, key.Config...)
+}
+
+func TestECH(t *testing.T) {
+	stale := echKey(t, 1)
+	current := echKey(t, 2)
+
+	tests := []struct {
+		name string
+		keys []tls.EncryptedClientHelloKey
+		want ECHOutcome
+	}{
+		{"retry configs", []tls.EncryptedClientHelloKey{current}, ECHRetried},
+		{"no ech", nil, ECHDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				if r.TLS.ECHAccepted {
+					w.Write([]byte("accepted"))
+				}
+			}))
+			server.EnableHTTP2 = true
+			server.TLS = &tls.Config{EncryptedClientHelloKeys: tt.keys}
+			server.StartTLS()
+			defer server.Close()
+
+			spec, err := Chromium(BrandChrome, "137.0.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var rejections []ECHRejection
+			base := &http.Transport{TLSClientConfig: &utls.Config{
+				InsecureSkipVerify:                  true,
+				EncryptedClientHelloRejectionVerify: func(utls.ConnectionState) error { return nil },
+			}}
+			tr, err := NewTransport(spec, PlatformWindows,
+				WithBaseTransport(base),
+				WithECH(echConfigList(stale), func(r ECHRejection) {
+					rejections = append(rejections, r)
+				}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			client := &http.Client{Transport: tr}
+
+			for range 2 {
+				res, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatal(err)
+				}
+				body, _ := io.ReadAll(res.Body)
+				res.Body.Close()
+
+				if accepted := string(body) == "accepted"; accepted != (tt.want == ECHRetried) {
+					t.Errorf("want ech accepted %t; got %t", tt.want == ECHRetried, accepted)
+				}
+			}
+
+			// the second request goes out the recovered way directly
+			if len(rejections) != 1 {
+				t.Fatalf("want 1 rejection; got %d", len(rejections))
+			}
+			if got := rejections[0]; got.Outcome != tt.want || got.Host != "127.0.0.1" {
+				t.Errorf("want outcome %q for 127.0.0.1; got %q for %s", tt.want, got.Outcome, got.Host)
+			}
+			if tt.want == ECHRetried && len(rejections[0].RetryConfigList) == 0 {
+				t.Error("want retry configs")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package mimic
+
+import (
+	"crypto/tls"
+	stdhttp "net/http"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// Engine sends requests on the wire for a Transport. The Transport shapes each
+// request first, adding the browser's default headers, header order, and fetch
+// metadata; the engine owns connections, TLS, and HTTP framing.
+//
+// The default engine is the fhttp transport configured with the browser's TLS
+// and HTTP/2 fingerprint. Any other engine carries only the fingerprint it
+// implements itself, so use one for traffic that does not need to look like the
+// browser, or to try a different HTTP/2 implementation.
+type Engine interface {
+	RoundTrip(req *http.Request) (*http.Response, error)
+	CloseIdleConnections()
+}
+
+// WithEngine sends requests through e instead of the fhttp transport. Options
+// that configure the fhttp transport, such as WithBaseTransport, WithProxy, and
+// WithCoalescing, have no effect on e. Clones and platform variants of the
+// Transport share e and its connections.
+func WithEngine(e Engine) TransportOption {
+	return func(c *transportConfig) {
+		c.engine = e
+	}
+}
+
+// NewStdlibEngine returns an Engine backed by a net/http transport. Requests
+// keep their headers and values but not their order, and the TLS and HTTP/2
+// fingerprints are Go's. If t is nil, a clone of http.DefaultTransport is used.
+func NewStdlibEngine(t *stdhttp.Transport) Engine {
+	if t == nil {
+		t = stdhttp.DefaultTransport.(*stdhttp.Transport).Clone()
+	}
+	return &stdlibEngine{transport: t}
+}
+
+// stdlibEngine converts between fhttp and net/http requests and responses.
+type stdlibEngine struct {
+	transport *stdhttp.Transport
+}
+
+func (e *stdlibEngine) RoundTrip(req *http.Request) (*http.Response, error) {
+	out := &stdhttp.Request{
+		Method:           req.Method,
+		URL:              req.URL,
+		Proto:            "HTTP/1.1",
+		ProtoMajor:       1,
+		ProtoMinor:       1,
+		Header:           stdlibHeader(req.Header),
+		Body:             req.Body,
+		GetBody:          req.GetBody,
+		ContentLength:    req.ContentLength,
+		TransferEncoding: req.TransferEncoding,
+		Close:            req.Close,
+		Host:             req.Host,
+		Trailer:          stdlibHeader(req.Trailer),
+	}
+
+	res, err := e.transport.RoundTrip(out.WithContext(req.Context()))
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:           res.Status,
+		StatusCode:       res.StatusCode,
+		Proto:            res.Proto,
+		ProtoMajor:       res.ProtoMajor,
+		ProtoMinor:       res.ProtoMinor,
+		Header:           http.Header(res.Header),
+		Body:             res.Body,
+		ContentLength:    res.ContentLength,
+		TransferEncoding: res.TransferEncoding,
+		Close:            res.Close,
+		Uncompressed:     res.Uncompressed,
+		Trailer:          http.Header(res.Trailer),
+		Request:          req,
+		TLS:              utlsConnectionState(res.TLS),
+	}, nil
+}
+
+func (e *stdlibEngine) CloseIdleConnections() {
+	e.transport.CloseIdleConnections()
+}
+
+// stdlibHeader copies h without fhttp's ordering keys, which net/http would
+// otherwise send as headers.
+func stdlibHeader(h http.Header) stdhttp.Header {
+	if h == nil {
+		return nil
+	}
+
+	out := make(stdhttp.Header, len(h))
+	for key, values := range h {
+		if key == http.HeaderOrderKey || key == http.PHeaderOrderKey {
+			continue
+		}
+		out[key] = values
+	}
+	return out
+}
+
+// utlsConnectionState converts the fields of a crypto/tls connection state that
+// mimic and its callers read.
+func utlsConnectionState(cs *tls.ConnectionState) *utls.ConnectionState {
+	if cs == nil {
+		return nil
+	}
+
+	return &utls.ConnectionState{
+		Version:                     cs.Version,
+		HandshakeComplete:           cs.HandshakeComplete,
+		DidResume:                   cs.DidResume,
+		CipherSuite:                 cs.CipherSuite,
+		NegotiatedProtocol:          cs.NegotiatedProtocol,
+		ServerName:                  cs.ServerName,
+		PeerCertificates:            cs.PeerCertificates,
+		VerifiedChains:              cs.VerifiedChains,
+		SignedCertificateTimestamps: cs.SignedCertificateTimestamps,
+		OCSPResponse:                cs.OCSPResponse,
+		ECHAccepted:                 cs.ECHAccepted,
+	}
+}
@@ -0,0 +1,69 @@
+package mimic
+
+import (
+	"crypto/tls"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestStdlibEngine(t *testing.T) {
+	var got stdhttp.Header
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		got = r.Header
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := NewStdlibEngine(&stdhttp.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	})
+	transport, err := NewTransport(spec, PlatformWindows, WithEngine(engine))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer transport.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.ProtoMajor != 2 {
+		t.Errorf("want HTTP/2; got %s", res.Proto)
+	}
+	if res.TLS == nil || res.TLS.PeerCertificates == nil {
+		t.Error("want the TLS connection state converted")
+	}
+	if ua := got.Get("User-Agent"); ua == "" || ua == "Go-http-client/2.0" {
+		t.Errorf("want the browser's user agent; got %q", ua)
+	}
+	for key := range got {
+		if key == http.HeaderOrderKey || key == http.PHeaderOrderKey {
+			t.Errorf("want ordering key %q dropped", key)
+		}
+	}
+
+	clone, err := transport.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clone.transport != engine {
+		t.Error("want clones to share the engine")
+	}
+}
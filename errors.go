@@ -0,0 +1,332 @@
+package mimic
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+var (
+	ErrUnsupportedVersion  = errors.New("unsupported version")
+	ErrUnsupportedPlatform = errors.New("unsupported platform")
+	ErrBodyStalled         = errors.New("response body stalled")
+	ErrSessionClosed       = errors.New("session closed")
+	ErrQUICNotSupported    = errors.New("quic not supported")
+	ErrHostBanned          = errors.New("host banned")
+	ErrBodyTooLarge        = errors.New("response body too large")
+	ErrDecompressionBomb   = errors.New("response body decompression bomb")
+	ErrHostRefused         = errors.New("host refused by policy")
+
+	// ErrFetchBlocked is returned by Fetch when the browser would refuse a
+	// request or redirect, such as a cross-origin request in same-origin mode.
+	ErrFetchBlocked = errors.New("fetch blocked")
+
+	// ErrResumeRejected is returned by Download when a server refuses to
+	// continue an interrupted download, usually because the resource changed
+	// since the first request.
+	ErrResumeRejected = errors.New("resume rejected")
+
+	// ErrNoClientHello is returned when a capture holds no TLS ClientHello.
+	ErrNoClientHello = errors.New("no client hello found")
+)
+
+// VersionTooOldError is returned when a browser version is below the minimum
+// mimic supports for that browser. It matches ErrUnsupportedVersion.
+type VersionTooOldError struct {
+	Browser string
+	Min     int
+	Got     int
+}
+
+func (e *VersionTooOldError) Error() string {
+	return fmt.Sprintf("%s %d: %s: minimum is %d", e.Browser, e.Got, ErrUnsupportedVersion, e.Min)
+}
+
+func (e *VersionTooOldError) Is(target error) bool {
+	return target == ErrUnsupportedVersion
+}
+
+// PlatformError is returned when a browser does not run on the requested platform,
+// such as Chromium on iOS. It matches ErrUnsupportedPlatform.
+type PlatformError struct {
+	Browser  string
+	Platform Platform
+}
+
+func (e *PlatformError) Error() string {
+	return fmt.Sprintf("%s on %s: %s", e.Browser, e.Platform, ErrUnsupportedPlatform)
+}
+
+func (e *PlatformError) Is(target error) bool {
+	return target == ErrUnsupportedPlatform
+}
+
+// TLSSpecError is returned when the linked utls version cannot produce the
+// ClientHello spec for a hello ID.
+type TLSSpecError struct {
+	HelloID utls.ClientHelloID
+	Err     error
+}
+
+func (e *TLSSpecError) Error() string {
+	return fmt.Sprintf("resolving tls spec for %s %s: %s", e.HelloID.Client, e.HelloID.Version, e.Err)
+}
+
+func (e *TLSSpecError) Unwrap() error {
+	return e.Err
+}
+
+// SelfTestError is returned by SelfTest when the linked utls or fhttp no
+// longer send the fingerprints mimic was built against. Each entry of Drift
+// names a spec and what it sends differently.
+type SelfTestError struct {
+	Drift []string
+}
+
+func (e *SelfTestError) Error() string {
+	return "fingerprint self-test: " + strings.Join(e.Drift, "; ")
+}
+
+// CertificatePolicyError is returned during the TLS handshake when a verified
+// certificate breaks a rule the mimicked browser enforces. See
+// WithBrowserCertificatePolicy.
+type CertificatePolicyError struct {
+	Subject string
+	Reason  string
+}
+
+func (e *CertificatePolicyError) Error() string {
+	return fmt.Sprintf("certificate %q rejected: %s", e.Subject, e.Reason)
+}
+
+// StrictModeError is returned by Transport.RoundTrip, before the request is
+// sent, when strict mode is on and the request would break the mimicked
+// browser's identity. See WithStrictMode.
+type StrictModeError struct {
+	Header string // empty when the URL is at fault
+	Reason string
+}
+
+func (e *StrictModeError) Error() string {
+	if e.Header == "" {
+		return "strict mode: " + e.Reason
+	}
+	return fmt.Sprintf("strict mode: %s: %s", e.Header, e.Reason)
+}
+
+// HeaderOrderError is returned by Transport.RoundTrip, before the request is
+// sent, when the order set with WithHeaderOrder names headers that are neither
+// sent nor ordered by the browser, which are most likely typos, or names a
+// header twice.
+type HeaderOrderError struct {
+	Unknown    []string
+	Duplicates []string
+}
+
+func (e *HeaderOrderError) Error() string {
+	var problems []string
+	if len(e.Unknown) > 0 {
+		problems = append(problems, "unknown headers "+strings.Join(e.Unknown, ", "))
+	}
+	if len(e.Duplicates) > 0 {
+		problems = append(problems, "repeated headers "+strings.Join(e.Duplicates, ", "))
+	}
+	return "header order: " + strings.Join(problems, "; ")
+}
+
+// DecompressionBombError is returned by a read of a compressed response body
+// that decodes past a limit set with WithDecompressionLimits. It matches
+// ErrDecompressionBomb.
+type DecompressionBombError struct {
+	Encoding string
+	Encoded  int64 // encoded bytes read, or -1 when unknown
+	Decoded  int64
+	MaxRatio float64 // set when the ratio was passed
+	MaxSize  int64   // set when the size was passed
+}
+
+func (e *DecompressionBombError) Error() string {
+	if e.MaxSize > 0 {
+		return fmt.Sprintf("%s: %s body decoded past %d bytes", ErrDecompressionBomb, e.Encoding, e.MaxSize)
+	}
+	return fmt.Sprintf("%s: %s body decoded from %d to %d bytes, over %g times", ErrDecompressionBomb, e.Encoding, e.Encoded, e.Decoded, e.MaxRatio)
+}
+
+func (e *DecompressionBombError) Is(target error) bool {
+	return target == ErrDecompressionBomb
+}
+
+// BodyStallError is returned by a response body read that received no bytes
+// for the stall timeout. It is distinct from the request's context deadline,
+// which fails reads with context.DeadlineExceeded however fast the body
+// arrives. It matches ErrBodyStalled.
+type BodyStallError struct {
+	Timeout  time.Duration
+	Received int64 // body bytes read before the stall
+}
+
+func (e *BodyStallError) Error() string {
+	return fmt.Sprintf("%s: no data for %s after %d bytes", ErrBodyStalled, e.Timeout, e.Received)
+}
+
+func (e *BodyStallError) Is(target error) bool {
+	return target == ErrBodyStalled
+}
+
+// BodyTooLargeError is returned by a response body read that passes the
+// limit set with WithMaxBodySize. It matches ErrBodyTooLarge.
+type BodyTooLargeError struct {
+	Limit int64
+}
+
+func (e *BodyTooLargeError) Error() string {
+	return fmt.Sprintf("%s: over %d bytes", ErrBodyTooLarge, e.Limit)
+}
+
+func (e *BodyTooLargeError) Is(target error) bool {
+	return target == ErrBodyTooLarge
+}
+
+// BannedError is returned by Transport.RoundTrip for a request to a host its
+// BanList bans. It matches ErrHostBanned.
+type BannedError struct {
+	Host  string
+	Until time.Time
+}
+
+func (e *BannedError) Error() string {
+	return fmt.Sprintf("%s: %s until %s", e.Host, ErrHostBanned, e.Until.Format(time.RFC3339))
+}
+
+func (e *BannedError) Is(target error) bool {
+	return target == ErrHostBanned
+}
+
+// HostPolicyError is returned by Transport.RoundTrip when the policy set with
+// WithHostPolicy refuses every address of the host it would dial. It matches
+// ErrHostRefused and unwraps to the policy's error.
+type HostPolicyError struct {
+	Host string
+	IP   netip.Addr
+	Err  error
+}
+
+func (e *HostPolicyError) Error() string {
+	return fmt.Sprintf("%s (%s): %s: %s", e.Host, e.IP, ErrHostRefused, e.Err)
+}
+
+func (e *HostPolicyError) Is(target error) bool {
+	return target == ErrHostRefused
+}
+
+func (e *HostPolicyError) Unwrap() error {
+	return e.Err
+}
+
+// DialError is returned by Transport.RoundTrip when the connection to the server
+// or proxy could not be established, including DNS failures.
+type DialError struct {
+	Err error
+}
+
+func (e *DialError) Error() string {
+	return "dial: " + e.Err.Error()
+}
+
+func (e *DialError) Unwrap() error {
+	return e.Err
+}
+
+// HandshakeError is returned by Transport.RoundTrip when the TLS handshake
+// failed, including alerts, certificate verification failures, and timeouts.
+type HandshakeError struct {
+	Err error
+}
+
+func (e *HandshakeError) Error() string {
+	return "tls handshake: " + e.Err.Error()
+}
+
+func (e *HandshakeError) Unwrap() error {
+	return e.Err
+}
+
+// ProtocolError is returned by Transport.RoundTrip when the server violated or
+// aborted the HTTP/2 protocol, such as with RST_STREAM or GOAWAY. Code is the
+// error code of the reset stream or connection: the one the server sent, or
+// the one the transport sent on finding the violation.
+type ProtocolError struct {
+	Code http2.ErrCode
+	Err  error
+}
+
+func (e *ProtocolError) Error() string {
+	return "http2: " + e.Err.Error()
+}
+
+func (e *ProtocolError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps a RoundTrip error in DialError, HandshakeError, or
+// ProtocolError when its cause can be identified. Other errors, including
+// context cancellation, are returned unchanged.
+func classifyError(err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	var (
+		opErr        *net.OpError
+		dnsErr       *net.DNSError
+		recordErr    utls.RecordHeaderError
+		alertErr     utls.AlertError
+		verifyErr    *utls.CertificateVerificationError
+		unknownCAErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		certErr      x509.CertificateInvalidError
+		echErr       *utls.ECHRejectionError
+		policyErr    *CertificatePolicyError
+		streamErr    http2.StreamError
+		connErr      http2.ConnectionError
+		goAwayErr    http2.GoAwayError
+	)
+
+	switch {
+	case errors.As(err, &streamErr):
+		return &ProtocolError{Code: streamErr.Code, Err: err}
+	case errors.As(err, &connErr):
+		return &ProtocolError{Code: http2.ErrCode(connErr), Err: err}
+	case errors.As(err, &goAwayErr):
+		return &ProtocolError{Code: goAwayErr.ErrCode, Err: err}
+	case errors.As(err, &recordErr), errors.As(err, &alertErr), errors.As(err, &verifyErr),
+		errors.As(err, &unknownCAErr), errors.As(err, &hostnameErr), errors.As(err, &certErr),
+		errors.As(err, &echErr), errors.As(err, &policyErr):
+		return &HandshakeError{Err: err}
+	case errors.As(err, &dnsErr):
+		return &DialError{Err: err}
+	case errors.As(err, &opErr):
+		switch opErr.Op {
+		case "dial", "proxyconnect":
+			return &DialError{Err: err}
+		case "local error", "remote error":
+			// utls reports sent and received alerts as OpErrors with these ops
+			return &HandshakeError{Err: err}
+		}
+	}
+
+	// fhttp's handshake timeout error is unexported
+	if strings.Contains(err.Error(), "TLS handshake timeout") {
+		return &HandshakeError{Err: err}
+	}
+
+	return err
+}
@@ -0,0 +1,94 @@
+package mimic
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/saucesteals/fhttp/http2"
+)
+
+func TestVersionTooOldError(t *testing.T) {
+	_, err := Chromium(BrandChrome, "80.0.0.0")
+
+	var versionErr *VersionTooOldError
+	if !errors.As(err, &versionErr) {
+		t.Fatalf("want VersionTooOldError; got %v", err)
+	}
+
+	if versionErr.Min != 83 || versionErr.Got != 80 {
+		t.Errorf("want min 83, got 80; got min %d, got %d", versionErr.Min, versionErr.Got)
+	}
+
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Error("want error to match ErrUnsupportedVersion")
+	}
+}
+
+func TestPlatformError(t *testing.T) {
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = NewTransport(spec, PlatformIOS)
+
+	var platformErr *PlatformError
+	if !errors.As(err, &platformErr) || platformErr.Platform != PlatformIOS {
+		t.Fatalf("want PlatformError for ios; got %v", err)
+	}
+
+	if !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Error("want error to match ErrUnsupportedPlatform")
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	plain := errors.New("plain")
+
+	tests := []struct {
+		name string
+		err  error
+		want any
+	}{
+		{"dial", &net.OpError{Op: "dial", Err: plain}, &DialError{}},
+		{"dns", &net.DNSError{Err: "no such host"}, &DialError{}},
+		{"alert", &net.OpError{Op: "remote error", Err: plain}, &HandshakeError{}},
+		{"stream reset", http2.StreamError{Code: http2.ErrCodeCancel}, &ProtocolError{Code: http2.ErrCodeCancel}},
+		{"connection error", http2.ConnectionError(http2.ErrCodeFlowControl), &ProtocolError{Code: http2.ErrCodeFlowControl}},
+		{"goaway", http2.GoAwayError{ErrCode: http2.ErrCodeProtocol}, &ProtocolError{Code: http2.ErrCodeProtocol}},
+		{"other", plain, nil},
+	}
+
+	for _, test := range tests {
+		got := classifyError(test.err)
+
+		switch test.want.(type) {
+		case *DialError:
+			var target *DialError
+			if !errors.As(got, &target) {
+				t.Errorf("%s: want DialError; got %T", test.name, got)
+			}
+		case *HandshakeError:
+			var target *HandshakeError
+			if !errors.As(got, &target) {
+				t.Errorf("%s: want HandshakeError; got %T", test.name, got)
+			}
+		case *ProtocolError:
+			var target *ProtocolError
+			if !errors.As(got, &target) {
+				t.Errorf("%s: want ProtocolError; got %T", test.name, got)
+			} else if want := test.want.(*ProtocolError).Code; target.Code != want {
+				t.Errorf("%s: Code = %v, want %v", test.name, target.Code, want)
+			}
+		default:
+			if got != test.err {
+				t.Errorf("%s: want error unchanged; got %T", test.name, got)
+			}
+		}
+
+		if !errors.Is(got, test.err) && got != test.err {
+			t.Errorf("%s: classified error does not wrap the original", test.name)
+		}
+	}
+}
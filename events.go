@@ -0,0 +1,255 @@
+package mimic
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptrace"
+)
+
+// eventBuffer is the buffer of each channel returned by Transport.Events.
+const eventBuffer = 256
+
+// Event is something that happened in a Transport: a ConnOpened,
+// HandshakeCompleted, RequestSent, ChallengeDetected, or ConnClosed.
+type Event interface {
+	isEvent()
+}
+
+// ConnOpened is reported when a connection to the server or proxy is dialed.
+type ConnOpened struct {
+	Time       time.Time
+	ConnID     uint64
+	Network    string
+	Addr       string
+	LocalAddr  net.Addr
+	RemoteAddr net.Addr
+	Duration   time.Duration
+}
+
+// HandshakeCompleted is reported when a TLS handshake with the server
+// finishes, successfully or not.
+type HandshakeCompleted struct {
+	Time     time.Time
+	ConnID   uint64
+	Conn     ConnInfo // TLS details; Proto is empty
+	Duration time.Duration
+	Err      error
+}
+
+// RequestSent is reported once a request's headers are written to its
+// connection, with the headers the browser sends.
+type RequestSent struct {
+	Time      time.Time
+	RequestID string // set with WithRequestID
+	ConnID    uint64
+	Method    string
+	URL       *url.URL
+	Header    http.Header
+}
+
+// ChallengeDetected is reported for a response DetectChallenge recognizes
+// from its status and headers as a bot management challenge or block.
+type ChallengeDetected struct {
+	Time       time.Time
+	RequestID  string // set with WithRequestID
+	URL        *url.URL
+	StatusCode int
+	Challenge  Challenge
+}
+
+// ConnClosed is reported when a connection is closed.
+type ConnClosed struct {
+	Time         time.Time
+	ConnID       uint64
+	RemoteAddr   net.Addr
+	Lifetime     time.Duration
+	BytesRead    int64
+	BytesWritten int64
+}
+
+func (ConnOpened) isEvent()         {}
+func (HandshakeCompleted) isEvent() {}
+func (RequestSent) isEvent()        {}
+func (ChallengeDetected) isEvent()  {}
+func (ConnClosed) isEvent()         {}
+
+// Events returns a channel that receives the Transport's events from now on,
+// for monitoring or adapting to what the Transport sees without wrapping it.
+// Each call returns a new channel. Events are dropped rather than holding up
+// the Transport when the channel's buffer of 256 is full, so receive them
+// promptly. Call StopEvents when done with the channel.
+//
+// Transports made by Clone and WithPlatform report to the same channels.
+// Connection events are not reported for connections an Engine makes.
+func (t *Transport) Events() <-chan Event {
+	return t.events.subscribe()
+}
+
+// StopEvents stops sending events to ch, a channel returned by Events, and
+// closes it.
+func (t *Transport) StopEvents(ch <-chan Event) {
+	t.events.unsubscribe(ch)
+}
+
+// eventBus sends events to subscribed channels.
+type eventBus struct {
+	mu   sync.Mutex
+	subs []chan Event
+	n    atomic.Int32
+}
+
+func (b *eventBus) subscribe() <-chan Event {
+	ch := make(chan Event, eventBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.subs = append(b.subs, ch)
+	b.n.Store(int32(len(b.subs)))
+	return ch
+}
+
+func (b *eventBus) unsubscribe(ch <-chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, sub := range b.subs {
+		if sub == ch {
+			b.subs = append(b.subs[:i], b.subs[i+1:]...)
+			b.n.Store(int32(len(b.subs)))
+			close(sub)
+			return
+		}
+	}
+}
+
+// active reports whether anyone is subscribed, so events need not be built.
+func (b *eventBus) active() bool {
+	return b.n.Load() > 0
+}
+
+func (b *eventBus) emit(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, sub := range b.subs {
+		select {
+		case sub <- e:
+		default:
+		}
+	}
+}
+
+// hooks returns connection hooks that report to b and then call user's, if
+// any.
+func (b *eventBus) hooks(user *ConnHooks) *ConnHooks {
+	var u ConnHooks
+	if user != nil {
+		u = *user
+	}
+
+	h := &ConnHooks{
+		OnDial: func(e DialEvent) {
+			if e.Err == nil && b.active() {
+				b.emit(ConnOpened{
+					Time:       time.Now(),
+					ConnID:     e.ConnID,
+					Network:    e.Network,
+					Addr:       e.Addr,
+					LocalAddr:  e.LocalAddr,
+					RemoteAddr: e.RemoteAddr,
+					Duration:   e.Duration,
+				})
+			}
+			if u.OnDial != nil {
+				u.OnDial(e)
+			}
+		},
+		OnTLSHandshake: func(e TLSHandshakeEvent) {
+			if b.active() {
+				b.emit(HandshakeCompleted{
+					Time:     time.Now(),
+					ConnID:   e.ConnID,
+					Conn:     e.Conn,
+					Duration: e.Duration,
+					Err:      e.Err,
+				})
+			}
+			if u.OnTLSHandshake != nil {
+				u.OnTLSHandshake(e)
+			}
+		},
+		OnH2Preface: u.OnH2Preface,
+		OnClose: func(e CloseEvent) {
+			if b.active() {
+				b.emit(ConnClosed{
+					Time:         time.Now(),
+					ConnID:       e.ConnID,
+					RemoteAddr:   e.RemoteAddr,
+					Lifetime:     e.Lifetime,
+					BytesRead:    e.BytesRead,
+					BytesWritten: e.BytesWritten,
+				})
+			}
+			if u.OnClose != nil {
+				u.OnClose(e)
+			}
+		},
+	}
+	// the handshake is only traced while someone wants it
+	h.traced = func() bool {
+		return u.OnTLSHandshake != nil || b.active()
+	}
+	return h
+}
+
+// context returns a copy of ctx that reports a RequestSent for sent, with
+// header, once its headers are written.
+func (b *eventBus) context(ctx context.Context, sent *http.Request, header http.Header) context.Context {
+	var connID uint64
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			conn := info.Conn
+			if uc, ok := conn.(*utls.UConn); ok {
+				conn = uc.NetConn()
+			}
+			if hc, ok := conn.(*hookConn); ok {
+				connID = hc.id
+			}
+		},
+		WroteHeaders: func() {
+			id, _ := RequestID(ctx)
+			b.emit(RequestSent{
+				Time:      time.Now(),
+				RequestID: id,
+				ConnID:    connID,
+				Method:    sent.Method,
+				URL:       sent.URL,
+				Header:    sentHeader(header),
+			})
+		},
+	})
+}
+
+// responseReceived reports res if it is a challenge.
+func (b *eventBus) responseReceived(req *http.Request, res *http.Response) {
+	challenge, ok := DetectChallenge(res, nil)
+	if !ok {
+		return
+	}
+	id, _ := RequestID(req.Context())
+	b.emit(ChallengeDetected{
+		Time:       time.Now(),
+		RequestID:  id,
+		URL:        req.URL,
+		StatusCode: res.StatusCode,
+		Challenge:  challenge,
+	})
+}
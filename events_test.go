@@ -0,0 +1,79 @@
+package mimic
+
+import (
+	"context"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestTransportEvents(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(stdhttp.StatusForbidden)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	transport, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := transport.Events()
+
+	req, err := http.NewRequestWithContext(WithRequestID(context.Background(), "req-1"), http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	transport.CloseIdleConnections()
+
+	var got []Event
+	timeout := time.After(5 * time.Second)
+	for len(got) < 5 {
+		select {
+		case e := <-events:
+			got = append(got, e)
+		case <-timeout:
+			t.Fatalf("got %d events, want 5: %#v", len(got), got)
+		}
+	}
+
+	opened, ok := got[0].(ConnOpened)
+	if !ok || opened.ConnID == 0 {
+		t.Fatalf("event 0 = %#v, want ConnOpened", got[0])
+	}
+	if e, ok := got[1].(HandshakeCompleted); !ok || e.ConnID != opened.ConnID || e.Err != nil {
+		t.Errorf("event 1 = %#v, want a successful HandshakeCompleted", got[1])
+	}
+	if e, ok := got[2].(RequestSent); !ok || e.RequestID != "req-1" || e.ConnID != opened.ConnID || e.Header.Get("User-Agent") == "" {
+		t.Errorf("event 2 = %#v, want RequestSent with the browser's headers", got[2])
+	}
+	if e, ok := got[3].(ChallengeDetected); !ok || e.Challenge.Vendor != "cloudflare" || e.StatusCode != http.StatusForbidden {
+		t.Errorf("event 3 = %#v, want a cloudflare ChallengeDetected", got[3])
+	}
+	if e, ok := got[4].(ConnClosed); !ok || e.ConnID != opened.ConnID {
+		t.Errorf("event 4 = %#v, want ConnClosed", got[4])
+	}
+
+	transport.StopEvents(events)
+	if _, ok := <-events; ok {
+		t.Error("want StopEvents to close the channel")
+	}
+}
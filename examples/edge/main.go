@@ -1,12 +1,13 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"log/slog"
 	"os"
 
 	"github.com/aarock1234/mimic"
-	_ "github.com/aarock1234/mimic/internal/logger"
+	_ "github.com/aarock1234/mimic/examples/internal/logger"
 	http "github.com/saucesteals/fhttp"
 )
 
@@ -33,19 +34,16 @@ func main() {
 
 	client := &http.Client{Transport: transport}
 
-	req, _ := http.NewRequest(http.MethodGet, "https://tls.peet.ws/api/clean", nil)
-
-	req.Header.Add("rtt", "50")
-	req.Header.Add("accept", "text/html,*/*")
-	req.Header.Add("x-requested-with", "XMLHttpRequest")
-	req.Header.Add("downlink", "3.9")
-	req.Header.Add("ect", "4g")
-	req.Header.Add("sec-fetch-site", "same-origin")
-	req.Header.Add("sec-fetch-mode", "cors")
-	req.Header.Add("sec-fetch-dest", "empty")
-	req.Header.Add("accept-encoding", "gzip, deflate, br")
-	req.Header.Add("accept-language", "en,en_US;q=0.9")
-	// mimic automatically sets: user-agent, sec-ch-ua, sec-ch-ua-mobile, sec-ch-ua-platform
+	// the builder sets edge's headers for an XMLHttpRequest from the page at the
+	// referrer, in edge's order: user-agent, sec-ch-ua, accept, sec-fetch-*, ...
+	req, err := mimic.NewXHRRequest(context.Background(), spec, mimic.PlatformWindows,
+		http.MethodGet, "https://tls.peet.ws/api/clean", "https://tls.peet.ws/", nil)
+	if err != nil {
+		slog.Error("failed to create request", "error", err)
+		return
+	}
+
+	req.Header.Set("accept-language", "en,en_US;q=0.9")
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,24 @@
+module github.com/aarock1234/mimic/examples
+
+go 1.24
+
+require (
+	github.com/aarock1234/mimic v0.0.0
+	github.com/lmittmann/tint v1.1.3
+	github.com/mattn/go-colorable v0.1.14
+	github.com/mattn/go-isatty v0.0.20
+	github.com/saucesteals/fhttp v1.0.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.0.6 // indirect
+	github.com/cloudflare/circl v1.5.0 // indirect
+	github.com/klauspost/compress v1.17.4 // indirect
+	github.com/refraction-networking/utls v1.7.4-0.20250519154908-0557f61cb0b8 // indirect
+	golang.org/x/crypto v0.36.0 // indirect
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/sys v0.31.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
+
+replace github.com/aarock1234/mimic => ../
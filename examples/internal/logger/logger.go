@@ -9,6 +9,8 @@ import (
 	"github.com/lmittmann/tint"
 	"github.com/mattn/go-colorable"
 	"github.com/mattn/go-isatty"
+
+	"github.com/aarock1234/mimic"
 )
 
 const (
@@ -19,10 +21,14 @@ type ContextHandler struct {
 	slog.Handler
 }
 
-// Handle overrides the default Handle method to add context values.
+// Handle overrides the default Handle method to add the request and session
+// IDs set with mimic.WithRequestID and mimic.WithSessionID.
 func (h *ContextHandler) Handle(ctx context.Context, r slog.Record) error {
-	if key, ok := ctx.Value("idempotency_key").(string); ok {
-		r.AddAttrs(slog.String("idempotency_key", key))
+	if id, ok := mimic.RequestID(ctx); ok {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if id, ok := mimic.SessionID(ctx); ok {
+		r.AddAttrs(slog.String("session_id", id))
 	}
 
 	return h.Handler.Handle(ctx, r)
@@ -6,7 +6,7 @@ import (
 	"os"
 
 	"github.com/aarock1234/mimic"
-	_ "github.com/aarock1234/mimic/internal/logger"
+	_ "github.com/aarock1234/mimic/examples/internal/logger"
 	http "github.com/saucesteals/fhttp"
 )
 
@@ -0,0 +1,259 @@
+// Package experiment compares how far specs get past a site's bot management.
+// It sends each arm's navigation requests to a set of URLs, classifies every
+// response with mimic.DetectChallenge, and reports each arm's success rate.
+//
+//	report, err := experiment.Run(ctx, []experiment.Arm{
+//	    {Name: "chrome", Spec: chrome, Platform: mimic.PlatformWindows},
+//	    {Name: "firefox", Spec: firefox, Platform: mimic.PlatformWindows},
+//	}, []string{"https://example.com/"}, experiment.WithTrials(20))
+//	if err != nil {
+//	    return err
+//	}
+//	for _, s := range report.Summary() {
+//	    fmt.Printf("%s: %.0f%%\n", s.Arm, 100*s.SuccessRate())
+//	}
+package experiment
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"slices"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+
+	"github.com/aarock1234/mimic"
+)
+
+// maxBodyScan bounds how much of each response body is read for challenge
+// markers.
+const maxBodyScan = 256 << 10
+
+// Arm is one configuration under test.
+type Arm struct {
+	Name     string
+	Spec     *mimic.ClientSpec
+	Platform mimic.Platform
+
+	// Options are added to the options set with WithClientOptions for this
+	// arm's clients.
+	Options []mimic.ClientOption
+}
+
+// Outcome classifies a trial.
+type Outcome string
+
+const (
+	// OutcomeSuccess is a response below 400 that is not a challenge.
+	OutcomeSuccess Outcome = "success"
+	// OutcomeChallenge is a challenge the client would have to solve.
+	OutcomeChallenge Outcome = "challenge"
+	// OutcomeBlocked is a block page, or a 403 or 429 from an unrecognized
+	// product.
+	OutcomeBlocked Outcome = "blocked"
+	// OutcomeError is a failed request or any other status.
+	OutcomeError Outcome = "error"
+)
+
+// Trial is one request from one arm.
+type Trial struct {
+	Arm        string
+	URL        string
+	Outcome    Outcome
+	StatusCode int
+	// Vendor is the product that challenged or blocked the request, if
+	// recognized.
+	Vendor   string
+	Duration time.Duration
+	Err      error
+}
+
+// Summary counts one arm's outcomes.
+type Summary struct {
+	Arm        string
+	Trials     int
+	Successes  int
+	Challenges int
+	Blocks     int
+	Errors     int
+}
+
+// SuccessRate returns the fraction of trials that succeeded.
+func (s Summary) SuccessRate() float64 {
+	if s.Trials == 0 {
+		return 0
+	}
+	return float64(s.Successes) / float64(s.Trials)
+}
+
+// Report is the result of an experiment.
+type Report struct {
+	Trials   []Trial
+	Duration time.Duration
+}
+
+// Summary returns each arm's counts, highest success rate first. Arms with the
+// same rate keep the order they were run in.
+func (r *Report) Summary() []Summary {
+	var summaries []Summary
+	index := make(map[string]int)
+	for _, trial := range r.Trials {
+		i, ok := index[trial.Arm]
+		if !ok {
+			i = len(summaries)
+			index[trial.Arm] = i
+			summaries = append(summaries, Summary{Arm: trial.Arm})
+		}
+
+		s := &summaries[i]
+		s.Trials++
+		switch trial.Outcome {
+		case OutcomeSuccess:
+			s.Successes++
+		case OutcomeChallenge:
+			s.Challenges++
+		case OutcomeBlocked:
+			s.Blocks++
+		default:
+			s.Errors++
+		}
+	}
+
+	slices.SortStableFunc(summaries, func(a, b Summary) int {
+		return cmp.Compare(b.SuccessRate(), a.SuccessRate())
+	})
+	return summaries
+}
+
+// Option configures Run.
+type Option func(*config)
+
+type config struct {
+	trials     int
+	interval   time.Duration
+	clientOpts []mimic.ClientOption
+}
+
+// WithTrials sets how many times each arm requests each URL, 1 by default.
+func WithTrials(n int) Option {
+	return func(c *config) {
+		c.trials = max(n, 1)
+	}
+}
+
+// WithInterval waits d between requests, so trials do not trip rate limits
+// that would count against whichever arm runs later.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) {
+		c.interval = d
+	}
+}
+
+// WithClientOptions sets options for every arm's clients, such as a proxy.
+func WithClientOptions(opts ...mimic.ClientOption) Option {
+	return func(c *config) {
+		c.clientOpts = opts
+	}
+}
+
+// Run requests each URL with each arm, as a navigation from a fresh client so
+// no trial inherits another's cookies or connections. Arms take turns on each
+// URL, so changes in the site's behavior over the run are spread across them.
+//
+// Per-trial failures are recorded in the report. Run returns an error only if
+// ctx is done before every trial ran.
+func Run(ctx context.Context, arms []Arm, urls []string, opts ...Option) (*Report, error) {
+	cfg := &config{trials: 1}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	start := time.Now()
+	report := &Report{Trials: make([]Trial, 0, cfg.trials*len(urls)*len(arms))}
+
+	for range cfg.trials {
+		for _, rawURL := range urls {
+			for _, arm := range arms {
+				if len(report.Trials) > 0 && cfg.interval > 0 {
+					select {
+					case <-ctx.Done():
+					case <-time.After(cfg.interval):
+					}
+				}
+				if err := ctx.Err(); err != nil {
+					report.Duration = time.Since(start)
+					return report, err
+				}
+
+				report.Trials = append(report.Trials, run(ctx, cfg, arm, rawURL))
+			}
+		}
+	}
+
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+func run(ctx context.Context, cfg *config, arm Arm, rawURL string) Trial {
+	trial := Trial{Arm: arm.Name, URL: rawURL}
+	start := time.Now()
+	defer func() {
+		trial.Duration = time.Since(start)
+	}()
+
+	res, body, err := fetch(ctx, cfg, arm, rawURL)
+	if err != nil {
+		trial.Outcome = OutcomeError
+		trial.Err = err
+		return trial
+	}
+	trial.StatusCode = res.StatusCode
+
+	challenge, found := mimic.DetectChallenge(res, body)
+	switch {
+	case found && challenge.Blocked:
+		trial.Outcome = OutcomeBlocked
+		trial.Vendor = challenge.Vendor
+	case found:
+		trial.Outcome = OutcomeChallenge
+		trial.Vendor = challenge.Vendor
+	case res.StatusCode == http.StatusForbidden, res.StatusCode == http.StatusTooManyRequests:
+		trial.Outcome = OutcomeBlocked
+	case res.StatusCode < 400:
+		trial.Outcome = OutcomeSuccess
+	default:
+		trial.Outcome = OutcomeError
+	}
+
+	return trial
+}
+
+// fetch requests rawURL with a new client for arm, returning the response and
+// the start of its body.
+func fetch(ctx context.Context, cfg *config, arm Arm, rawURL string) (*http.Response, []byte, error) {
+	client, err := mimic.NewClient(arm.Spec, arm.Platform, slices.Concat(cfg.clientOpts, arm.Options)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := mimic.NewNavigationRequest(ctx, arm.Spec, arm.Platform, rawURL, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodyScan))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return res, body, nil
+}
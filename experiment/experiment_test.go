@@ -0,0 +1,86 @@
+package experiment
+
+import (
+	"context"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aarock1234/mimic"
+)
+
+func TestRun(t *testing.T) {
+	// challenges Firefox and blocks Safari, like a WAF keyed on the user agent
+	server := stdhttptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch ua := r.UserAgent(); {
+		case strings.Contains(ua, "Firefox"):
+			w.Header().Set("Cf-Mitigated", "challenge")
+			w.WriteHeader(stdhttp.StatusForbidden)
+		case strings.Contains(ua, "Chrome"):
+			w.Write([]byte("ok"))
+		default:
+			w.WriteHeader(stdhttp.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	chrome, err := mimic.Chromium(mimic.BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firefox, err := mimic.Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	safari, err := mimic.Safari("18.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Run(context.Background(), []Arm{
+		{Name: "firefox", Spec: firefox, Platform: mimic.PlatformWindows},
+		{Name: "safari", Spec: safari, Platform: mimic.PlatformMac},
+		{Name: "chrome", Spec: chrome, Platform: mimic.PlatformWindows},
+		{Name: "broken", Spec: chrome, Platform: mimic.PlatformIOS},
+	}, []string{server.URL + "/a", server.URL + "/b"}, WithTrials(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Trials) != 16 {
+		t.Fatalf("want 16 trials; got %d", len(report.Trials))
+	}
+	if got := report.Trials[0]; got.Outcome != OutcomeChallenge || got.Vendor != "cloudflare" {
+		t.Errorf("want a cloudflare challenge; got %+v", got)
+	}
+
+	want := []Summary{
+		{Arm: "chrome", Trials: 4, Successes: 4},
+		{Arm: "firefox", Trials: 4, Challenges: 4},
+		{Arm: "safari", Trials: 4, Blocks: 4},
+		{Arm: "broken", Trials: 4, Errors: 4},
+	}
+	got := report.Summary()
+	if len(got) != len(want) {
+		t.Fatalf("want %+v; got %+v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("want %+v; got %+v", want[i], got[i])
+		}
+	}
+	if rate := got[0].SuccessRate(); rate != 1 {
+		t.Errorf("want chrome success rate 1; got %v", rate)
+	}
+}
+
+func TestRunCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Run(ctx, []Arm{{Name: "chrome"}}, []string{"https://example.com"})
+	if err == nil || len(report.Trials) != 0 {
+		t.Errorf("want no trials and an error; got %d trials and %v", len(report.Trials), err)
+	}
+}
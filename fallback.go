@@ -0,0 +1,127 @@
+package mimic
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptrace"
+)
+
+// WithFallback retries requests whose TLS handshake fails with a transport for
+// spec, the way browsers fall back when a middlebox cannot handle their
+// ClientHello, such as one that drops hellos too large for a single segment or
+// strips ECH. Once a host has needed the fallback, later requests to it use the
+// fallback directly.
+//
+// onFallback, if not nil, is called with the request and the handshake error
+// each time a host is downgraded. Certificate errors and canceled requests
+// never fall back, and requests with a body fall back only if they set GetBody.
+func WithFallback(spec *ClientSpec, onFallback func(req *http.Request, err error)) TransportOption {
+	return func(c *transportConfig) {
+		c.fallbackSpec = spec
+		c.onFallback = onFallback
+	}
+}
+
+// fallback is the transport a Transport downgrades to and the hosts that have
+// needed it.
+type fallback struct {
+	transport  *Transport
+	onFallback func(req *http.Request, err error)
+
+	mu    sync.Mutex
+	hosts map[string]bool
+}
+
+func newFallback(t *Transport, onFallback func(req *http.Request, err error)) *fallback {
+	return &fallback{transport: t, onFallback: onFallback, hosts: make(map[string]bool)}
+}
+
+// downgraded reports whether req's host has needed the fallback before.
+func (f *fallback) downgraded(req *http.Request) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.hosts[strings.ToLower(req.URL.Host)]
+}
+
+// retry sends req with the fallback transport if err came from a failed
+// handshake that a different ClientHello might get past. Otherwise it returns
+// err.
+func (f *fallback) retry(req *http.Request, handshake *handshakeWatch, err error) (*http.Response, error) {
+	if !handshake.failed.Load() || req.Context().Err() != nil || isCertificateError(err) {
+		return nil, err
+	}
+
+	retry, ok := replayable(req)
+	if !ok {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	f.hosts[strings.ToLower(req.URL.Host)] = true
+	f.mu.Unlock()
+
+	if f.onFallback != nil {
+		f.onFallback(req, err)
+	}
+
+	return f.transport.RoundTrip(retry)
+}
+
+// replayable returns req ready to be sent again, with a fresh body from
+// GetBody. It reports false if req has a body it cannot replay.
+func replayable(req *http.Request) (*http.Request, bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return req, true
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	retry := req.Clone(req.Context())
+	retry.Body = body
+	return retry, true
+}
+
+// handshakeWatch records whether a TLS handshake made for a request failed,
+// which tells a reset or timeout during the handshake apart from one after the
+// request was sent.
+type handshakeWatch struct {
+	failed atomic.Bool
+}
+
+func (w *handshakeWatch) context(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		TLSHandshakeDone: func(_ utls.ConnectionState, err error) {
+			if err != nil {
+				w.failed.Store(true)
+			}
+		},
+	})
+}
+
+// isCertificateError reports whether err is a certificate verification
+// failure, which no other ClientHello would fix.
+func isCertificateError(err error) bool {
+	var (
+		verifyErr    *utls.CertificateVerificationError
+		unknownCAErr x509.UnknownAuthorityError
+		hostnameErr  x509.HostnameError
+		certErr      x509.CertificateInvalidError
+		policyErr    *CertificatePolicyError
+	)
+
+	return errors.As(err, &verifyErr) || errors.As(err, &unknownCAErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &certErr) || errors.As(err, &policyErr)
+}
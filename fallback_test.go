@@ -0,0 +1,146 @@
+package mimic
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// intolerantListener resets connections whose first TLS record is larger than
+// limit, like a middlebox that cannot handle a hello spanning two segments.
+type intolerantListener struct {
+	net.Listener
+	limit  int
+	resets atomic.Int32
+}
+
+func (l *intolerantListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		r := bufio.NewReader(conn)
+		header, err := r.Peek(tlsRecordHeaderLen)
+		if err == nil && int(binary.BigEndian.Uint16(header[3:5])) > l.limit {
+			l.resets.Add(1)
+			conn.(*net.TCPConn).SetLinger(0)
+			conn.Close()
+			continue
+		}
+
+		return &peekedConn{Conn: conn, r: r}, nil
+	}
+}
+
+type peekedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *peekedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+func TestFallback(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Write([]byte(r.UserAgent()))
+	}))
+	listener := &intolerantListener{Listener: server.Listener, limit: 1400}
+	server.Listener = listener
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fallbackSpec, err := Chromium(BrandChrome, "120.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var downgrades int
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	tr, err := NewTransport(spec, PlatformWindows,
+		WithBaseTransport(base),
+		WithFallback(fallbackSpec, func(req *http.Request, err error) {
+			downgrades++
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	for range 2 {
+		req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ua, err := io.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.Contains(string(ua), "Chrome/120") {
+			t.Errorf("want the fallback spec's user agent; got %q", ua)
+		}
+	}
+
+	if downgrades != 1 || listener.resets.Load() != 1 {
+		t.Errorf("want one downgrade after one reset; got %d downgrades and %d resets", downgrades, listener.resets.Load())
+	}
+}
+
+func TestFallbackCertificateError(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var downgraded bool
+	tr, err := NewTransport(spec, PlatformWindows, WithFallback(spec, func(*http.Request, error) {
+		downgraded = true
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tr.RoundTrip(req); err == nil {
+		t.Fatal("want untrusted certificate to fail")
+	}
+	if downgraded {
+		t.Error("want certificate errors not to fall back")
+	}
+}
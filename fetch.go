@@ -0,0 +1,487 @@
+package mimic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+	"golang.org/x/net/publicsuffix"
+)
+
+// FetchProfile is the kind of request a page makes, which decides the headers
+// a browser derives for it and the defaults of the other FetchOptions.
+type FetchProfile int
+
+const (
+	// ProfileFetch is a fetch() call from a page script.
+	ProfileFetch FetchProfile = iota
+
+	// ProfileXHR is an XMLHttpRequest from a page script.
+	ProfileXHR
+
+	// ProfileNavigation is a top-level navigation, such as following a link
+	// or submitting a form.
+	ProfileNavigation
+
+	// ProfileImage is an <img> load.
+	ProfileImage
+
+	// ProfileScript is a classic <script src> load.
+	ProfileScript
+
+	// ProfileStyle is a <link rel="stylesheet"> load.
+	ProfileStyle
+)
+
+// FetchDestination is a request's destination as reported in sec-fetch-dest.
+type FetchDestination string
+
+const (
+	DestinationDocument FetchDestination = "document"
+	DestinationEmpty    FetchDestination = "empty"
+	DestinationImage    FetchDestination = "image"
+	DestinationScript   FetchDestination = "script"
+	DestinationStyle    FetchDestination = "style"
+)
+
+// FetchMode is the Fetch API request mode.
+type FetchMode string
+
+const (
+	ModeCORS       FetchMode = "cors"
+	ModeNoCORS     FetchMode = "no-cors"
+	ModeSameOrigin FetchMode = "same-origin"
+	ModeNavigate   FetchMode = "navigate"
+)
+
+// FetchCredentials is the Fetch API credentials mode, which decides whether
+// cookies are sent and stored.
+type FetchCredentials string
+
+const (
+	CredentialsOmit       FetchCredentials = "omit"
+	CredentialsSameOrigin FetchCredentials = "same-origin"
+	CredentialsInclude    FetchCredentials = "include"
+)
+
+// FetchRedirect is the Fetch API redirect mode.
+type FetchRedirect string
+
+const (
+	RedirectFollow FetchRedirect = "follow"
+	RedirectError  FetchRedirect = "error"
+	RedirectManual FetchRedirect = "manual"
+)
+
+// Reload is how the page a request belongs to is being loaded, which decides
+// the cache headers the browser sends.
+type Reload int
+
+const (
+	// LoadNormal sends no cache headers.
+	LoadNormal Reload = iota
+
+	// LoadReload is the reload button: the page is revalidated with
+	// "cache-control: max-age=0", and its subresources load normally, as
+	// Chrome loads them.
+	LoadReload
+
+	// LoadHardReload bypasses the cache, sending "pragma: no-cache" and
+	// "cache-control: no-cache" for the page and its subresources.
+	LoadHardReload
+)
+
+// FetchOptions describes a request by intent. Zero fields take the profile's
+// defaults: the method is GET, navigations use navigate mode and include
+// credentials, subresources use no-cors mode, and fetch() and XMLHttpRequest
+// use cors mode with same-origin credentials. Redirects are followed.
+type FetchOptions struct {
+	Method      string
+	Header      http.Header
+	Body        io.Reader
+	Profile     FetchProfile
+	Mode        FetchMode
+	Credentials FetchCredentials
+	Redirect    FetchRedirect
+
+	// Referrer is the URL of the page making the request. It sets Origin,
+	// Referer, and sec-fetch-site, and decides which requests are same-origin.
+	// Leave it empty for a navigation the user started from the address bar.
+	Referrer string
+
+	// Reload is how the page is being loaded, LoadNormal by default.
+	Reload Reload
+}
+
+// Fetch sends a request the way a page would, deriving the headers a browser
+// adds for the profile, such as accept and sec-fetch-*, and applying its
+// mode, credentials, and redirect policy. Headers set in opts.Header take
+// precedence. The headers are set by the mimic Transport beneath client,
+// which recomputes them for every redirect; with another transport only the
+// policies apply.
+func Fetch(ctx context.Context, client *http.Client, rawURL string, opts FetchOptions) (*http.Response, error) {
+	req, intent, err := newFetchRequest(ctx, rawURL, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return sendFetch(client, req, intent)
+}
+
+// sendFetch sends req, a request carrying intent, with client under intent's
+// policies.
+func sendFetch(client *http.Client, req *http.Request, intent *fetchIntent) (*http.Response, error) {
+	if err := intent.check(req.URL); err != nil {
+		return nil, err
+	}
+
+	next := client.CheckRedirect
+	if next == nil {
+		next = browserCheckRedirect
+	}
+
+	c := *client
+	c.CheckRedirect = intent.checkRedirect(next)
+
+	return c.Do(req)
+}
+
+// newFetchRequest returns a request for rawURL carrying opts as its intent.
+func newFetchRequest(ctx context.Context, rawURL string, opts FetchOptions) (*http.Request, *fetchIntent, error) {
+	intent, err := newFetchIntent(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	method := opts.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	req, err := http.NewRequestWithContext(withFetchIntent(ctx, intent), method, rawURL, opts.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	for key, values := range opts.Header {
+		req.Header[key] = values
+	}
+
+	return req, intent, nil
+}
+
+// fetchIntent carries a Fetch call's options to the Transport, which derives
+// the request's headers from it on every hop.
+type fetchIntent struct {
+	dest        FetchDestination
+	mode        FetchMode
+	credentials FetchCredentials
+	redirect    FetchRedirect
+	reload      Reload
+	initiator   *url.URL
+	form        *formSubmission
+
+	mu   sync.Mutex
+	site string
+}
+
+func newFetchIntent(opts FetchOptions) (*fetchIntent, error) {
+	intent := &fetchIntent{
+		mode:        opts.Mode,
+		credentials: opts.Credentials,
+		redirect:    opts.Redirect,
+		reload:      opts.Reload,
+	}
+
+	var mode FetchMode
+	var credentials FetchCredentials
+	switch opts.Profile {
+	case ProfileFetch, ProfileXHR:
+		intent.dest, mode, credentials = DestinationEmpty, ModeCORS, CredentialsSameOrigin
+	case ProfileNavigation:
+		intent.dest, mode, credentials = DestinationDocument, ModeNavigate, CredentialsInclude
+	case ProfileImage:
+		intent.dest, mode, credentials = DestinationImage, ModeNoCORS, CredentialsInclude
+	case ProfileScript:
+		intent.dest, mode, credentials = DestinationScript, ModeNoCORS, CredentialsInclude
+	case ProfileStyle:
+		intent.dest, mode, credentials = DestinationStyle, ModeNoCORS, CredentialsInclude
+	default:
+		return nil, fmt.Errorf("unknown fetch profile %d", opts.Profile)
+	}
+
+	if intent.mode == "" {
+		intent.mode = mode
+	}
+	if intent.credentials == "" {
+		intent.credentials = credentials
+	}
+	if intent.redirect == "" {
+		intent.redirect = RedirectFollow
+	}
+
+	if opts.Referrer != "" {
+		initiator, err := url.Parse(opts.Referrer)
+		if err != nil {
+			return nil, fmt.Errorf("parsing referrer: %w", err)
+		}
+		intent.initiator = initiator
+	}
+
+	return intent, nil
+}
+
+func withFetchIntent(ctx context.Context, intent *fetchIntent) context.Context {
+	return context.WithValue(ctx, fetchIntentKey, intent)
+}
+
+func fetchIntentFrom(ctx context.Context) *fetchIntent {
+	intent, _ := ctx.Value(fetchIntentKey).(*fetchIntent)
+	return intent
+}
+
+// check reports whether the browser would send a request to u.
+func (f *fetchIntent) check(u *url.URL) error {
+	if f.mode == ModeSameOrigin && f.initiator != nil && !sameOrigin(f.initiator, u) {
+		return fmt.Errorf("%w: %s is cross-origin in same-origin mode", ErrFetchBlocked, u.Redacted())
+	}
+	return nil
+}
+
+func (f *fetchIntent) checkRedirect(next func(*http.Request, []*http.Request) error) func(*http.Request, []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		switch f.redirect {
+		case RedirectError:
+			return fmt.Errorf("%w: redirect to %s in error mode", ErrFetchBlocked, req.URL.Redacted())
+		case RedirectManual:
+			return http.ErrUseLastResponse
+		}
+
+		if err := f.check(req.URL); err != nil {
+			return err
+		}
+		return next(req, via)
+	}
+}
+
+// sendsCredentials reports whether cookies go with a request to u.
+func (f *fetchIntent) sendsCredentials(u *url.URL) bool {
+	switch f.credentials {
+	case CredentialsOmit:
+		return false
+	case CredentialsSameOrigin:
+		return f.initiator == nil || sameOrigin(f.initiator, u)
+	default:
+		return true
+	}
+}
+
+// setHeaders adds the headers the browser derives for a request to u to
+// header, keeping any already set but those that depend on the hops taken.
+func (f *fetchIntent) setHeaders(header http.Header, method string, u *url.URL, fh *fetchHeaders) {
+	setDefault := func(key, value string) {
+		if value != "" && header.Get(key) == "" {
+			header.Set(key, value)
+		}
+	}
+
+	setDefault("Accept", fh.accept(f.dest))
+	setDefault("Accept-Encoding", fh.acceptEncoding)
+
+	if f.mode == ModeNavigate {
+		setDefault("Upgrade-Insecure-Requests", "1")
+	}
+
+	switch {
+	case f.reload == LoadHardReload:
+		setDefault("Pragma", "no-cache")
+		setDefault("Cache-Control", "no-cache")
+	case f.reload == LoadReload && f.mode == ModeNavigate:
+		setDefault("Cache-Control", "max-age=0")
+	case fh.revalidatePost && f.mode == ModeNavigate && method == http.MethodPost:
+		setDefault("Cache-Control", "max-age=0")
+	}
+
+	// a redirect that turns a form's POST into a GET drops its body, and
+	// with it the body's type
+	if f.form != nil && (method == http.MethodGet || method == http.MethodHead) {
+		header.Del("Content-Type")
+	}
+
+	// the site and origin depend on the hops the request has taken, so they
+	// replace the values the client carries over from the previous hop
+	site := f.siteFor(u)
+	if fh.metadata && potentiallyTrustworthy(u) {
+		header.Set("Sec-Fetch-Site", site)
+		setDefault("Sec-Fetch-Mode", string(f.mode))
+		if f.mode == ModeNavigate {
+			setDefault("Sec-Fetch-User", "?1")
+		}
+		setDefault("Sec-Fetch-Dest", string(f.dest))
+	}
+
+	if f.initiator == nil {
+		return
+	}
+
+	corsCrossOrigin := f.mode == ModeCORS && !sameOrigin(f.initiator, u)
+	if corsCrossOrigin || (method != http.MethodGet && method != http.MethodHead) {
+		header.Set("Origin", origin(f.initiator))
+	}
+
+	// the referrer is the page's, not the previous hop's
+	if referrer := strictOriginWhenCrossOrigin(f.initiator, u); referrer != "" {
+		header.Set("Referer", referrer)
+	} else {
+		header.Del("Referer")
+	}
+}
+
+// siteFor returns the sec-fetch-site value for a request to u, which is the
+// least related site any hop of the request has reached.
+func (f *fetchIntent) siteFor(u *url.URL) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.initiator == nil {
+		f.site = "none"
+		return f.site
+	}
+
+	site := "cross-site"
+	switch {
+	case sameOrigin(f.initiator, u):
+		site = "same-origin"
+	case sameSite(f.initiator, u):
+		site = "same-site"
+	}
+
+	if siteRank[site] > siteRank[f.site] {
+		f.site = site
+	}
+	return f.site
+}
+
+var siteRank = map[string]int{"same-origin": 1, "same-site": 2, "cross-site": 3}
+
+func sameOrigin(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && a.Hostname() == b.Hostname() && portOf(a) == portOf(b)
+}
+
+func sameSite(a, b *url.URL) bool {
+	return a.Scheme == b.Scheme && registrableDomain(a.Hostname()) == registrableDomain(b.Hostname())
+}
+
+func registrableDomain(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
+
+func portOf(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	switch u.Scheme {
+	case "https":
+		return "443"
+	case "http":
+		return "80"
+	}
+	return ""
+}
+
+func origin(u *url.URL) string {
+	return u.Scheme + "://" + u.Host
+}
+
+// potentiallyTrustworthy reports whether u is a secure context, the only kind
+// browsers send fetch metadata headers to.
+func potentiallyTrustworthy(u *url.URL) bool {
+	if u.Scheme == "https" {
+		return true
+	}
+	host := u.Hostname()
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// strictOriginWhenCrossOrigin returns the Referer browsers send by default:
+// the full page URL to the same origin, only its origin elsewhere, and nothing
+// when leaving HTTPS for HTTP.
+func strictOriginWhenCrossOrigin(page, u *url.URL) string {
+	if page.Scheme == "https" && u.Scheme != "https" {
+		return ""
+	}
+	if sameOrigin(page, u) {
+		ref := *page
+		ref.User = nil
+		ref.Fragment = ""
+		ref.RawFragment = ""
+		return ref.String()
+	}
+	return origin(page) + "/"
+}
+
+// fetchHeaders are the headers a browser version derives from a request's
+// destination.
+type fetchHeaders struct {
+	document       string
+	image          string
+	acceptEncoding string
+
+	// metadata reports whether the browser sends sec-fetch-* headers
+	metadata bool
+
+	// navigationOrder and fetchOrder are the browser's header orders for
+	// navigations and for fetch() and XMLHttpRequest, in lowercase
+	navigationOrder []string
+	fetchOrder      []string
+
+	// formBoundary makes the browser's multipart form boundaries, and is
+	// nil for browsers that make them as Chromium and Safari do
+	formBoundary func() string
+
+	// revalidatePost reports whether the browser sends
+	// "cache-control: max-age=0" with form POSTs, as Chromium does
+	revalidatePost bool
+
+	// priority is the HTTP/2 priority of requests to each destination, for
+	// browsers that prioritize by destination
+	priority map[FetchDestination]http2.PriorityParam
+}
+
+// boundary returns a multipart form boundary as the browser makes them.
+func (fh *fetchHeaders) boundary() string {
+	if fh.formBoundary != nil {
+		return fh.formBoundary()
+	}
+	return webkitBoundary()
+}
+
+// accept returns the accept header for dest. Browsers agree on every
+// destination but documents and images.
+func (fh *fetchHeaders) accept(dest FetchDestination) string {
+	switch dest {
+	case DestinationDocument:
+		return fh.document
+	case DestinationImage:
+		return fh.image
+	case DestinationStyle:
+		return "text/css,*/*;q=0.1"
+	default:
+		return "*/*"
+	}
+}
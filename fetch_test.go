@@ -0,0 +1,353 @@
+package mimic
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	stdhttptest "net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// redirectRoundTripper redirects requests for /redirect to their "to" query
+// parameter and answers everything else with an empty 200.
+type redirectRoundTripper struct{}
+
+func (redirectRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Path == "/redirect" {
+		return &http.Response{
+			StatusCode: http.StatusFound,
+			Header:     http.Header{"Location": {req.URL.Query().Get("to")}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Set-Cookie": {"a=1"}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func newFetchClient(t *testing.T, spec *ClientSpec) *http.Client {
+	t.Helper()
+
+	tr, err := NewTransport(spec, PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.transport = redirectRoundTripper{}
+
+	return &http.Client{Transport: tr}
+}
+
+func TestFetchHeaders(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newFetchClient(t, spec)
+
+	tests := []struct {
+		name string
+		url  string
+		opts FetchOptions
+		want map[string]string
+	}{
+		{
+			name: "address bar navigation",
+			url:  "https://www.example.com/",
+			opts: FetchOptions{Profile: ProfileNavigation},
+			want: map[string]string{
+				"accept":                    spec.fetch.document,
+				"accept-encoding":           "gzip, deflate, br, zstd",
+				"upgrade-insecure-requests": "1",
+				"sec-fetch-site":            "none",
+				"sec-fetch-mode":            "navigate",
+				"sec-fetch-user":            "?1",
+				"sec-fetch-dest":            "document",
+				"referer":                   "",
+				"origin":                    "",
+			},
+		},
+		{
+			name: "cross-origin fetch",
+			url:  "https://api.example.com/items",
+			opts: FetchOptions{Method: http.MethodPost, Referrer: "https://www.example.com/shop?q=1#top"},
+			want: map[string]string{
+				"accept":         "*/*",
+				"sec-fetch-site": "same-site",
+				"sec-fetch-mode": "cors",
+				"sec-fetch-user": "",
+				"sec-fetch-dest": "empty",
+				"referer":        "https://www.example.com/",
+				"origin":         "https://www.example.com",
+			},
+		},
+		{
+			name: "same-origin image",
+			url:  "https://www.example.com/logo.png",
+			opts: FetchOptions{Profile: ProfileImage, Referrer: "https://www.example.com/shop?q=1#top"},
+			want: map[string]string{
+				"accept":         spec.fetch.image,
+				"sec-fetch-site": "same-origin",
+				"sec-fetch-mode": "no-cors",
+				"sec-fetch-dest": "image",
+				"referer":        "https://www.example.com/shop?q=1",
+				"origin":         "",
+			},
+		},
+		{
+			name: "caller's header wins",
+			url:  "https://www.example.com/data",
+			opts: FetchOptions{Header: http.Header{"Accept": {"application/json"}}},
+			want: map[string]string{
+				"accept": "application/json",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		res, err := Fetch(context.Background(), client, tt.url, tt.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+
+		for key, want := range tt.want {
+			if got := res.Request.Header.Get(key); got != want {
+				t.Errorf("%s: want %s %q; got %q", tt.name, key, want, got)
+			}
+		}
+	}
+}
+
+func TestFetchCredentials(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newFetchClient(t, spec)
+
+	tests := []struct {
+		url         string
+		credentials FetchCredentials
+		want        bool
+	}{
+		{"https://www.example.com/", "", true},
+		{"https://api.example.com/", "", false},
+		{"https://api.example.com/", CredentialsInclude, true},
+		{"https://www.example.com/", CredentialsOmit, false},
+	}
+
+	for _, tt := range tests {
+		res, err := Fetch(context.Background(), client, tt.url, FetchOptions{
+			Header:      http.Header{"Cookie": {"sid=1"}},
+			Credentials: tt.credentials,
+			Referrer:    "https://www.example.com/",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sent := res.Request.Header.Get("Cookie") != ""
+		stored := res.Header.Get("Set-Cookie") != ""
+		if sent != tt.want || stored != tt.want {
+			t.Errorf("%s with %q credentials: want cookies %v; got sent %v, stored %v", tt.url, tt.credentials, tt.want, sent, stored)
+		}
+	}
+}
+
+func TestFetchRedirect(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newFetchClient(t, spec)
+
+	const page = "https://www.example.com/"
+	const bounce = "https://www.example.com/redirect?to=https://tracker.test/redirect?to=https://www.example.com/final"
+
+	res, err := Fetch(context.Background(), client, bounce, FetchOptions{Profile: ProfileNavigation, Referrer: page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Request.URL.Path != "/final" {
+		t.Fatalf("want redirects followed; got %s", res.Request.URL)
+	}
+	if got := res.Request.Header.Get("sec-fetch-site"); got != "cross-site" {
+		t.Errorf("want cross-site kept after a cross-site hop; got %q", got)
+	}
+	if got := res.Request.Header.Get("referer"); got != page {
+		t.Errorf("want the page as referrer on every hop; got %q", got)
+	}
+
+	res, err = Fetch(context.Background(), client, bounce, FetchOptions{Redirect: RedirectManual, Referrer: page})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusFound {
+		t.Errorf("want the redirect returned in manual mode; got %d", res.StatusCode)
+	}
+
+	if _, err := Fetch(context.Background(), client, bounce, FetchOptions{Redirect: RedirectError, Referrer: page}); !errors.Is(err, ErrFetchBlocked) {
+		t.Errorf("want ErrFetchBlocked in error mode; got %v", err)
+	}
+
+	if _, err := Fetch(context.Background(), client, bounce, FetchOptions{Mode: ModeSameOrigin, Referrer: page}); !errors.Is(err, ErrFetchBlocked) {
+		t.Errorf("want ErrFetchBlocked redirecting cross-origin in same-origin mode; got %v", err)
+	}
+
+	// a client without a redirect policy gets the browser's limit
+	plain := &http.Client{Transport: hopRoundTripper{hops: maxRedirects + 1}}
+	if _, err := Fetch(context.Background(), plain, "https://example.com/0", FetchOptions{}); err == nil || !strings.Contains(err.Error(), "stopped after 20 redirects") {
+		t.Errorf("want the redirect limit without a CheckRedirect; got %v", err)
+	}
+}
+
+func TestFetchMetadataVersions(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"14.1", false},
+		{"15.6", false},
+		{"16.0", false},
+		{"16.3.1", false},
+		{"16.4", true},
+		{"18.3", true},
+	}
+
+	for _, tt := range tests {
+		spec, err := Safari(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := Fetch(context.Background(), newFetchClient(t, spec), "https://example.com/", FetchOptions{Profile: ProfileNavigation})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := res.Request.Header.Get("sec-fetch-mode") != ""
+		if got != tt.want {
+			t.Errorf("safari %s: want fetch metadata %v; got %v", tt.version, tt.want, got)
+		}
+		if accept := res.Request.Header.Get("accept"); !strings.HasPrefix(accept, "text/html") {
+			t.Errorf("safari %s: want a document accept header; got %q", tt.version, accept)
+		}
+	}
+}
+
+// serveHeaderNames answers HTTP/1.1 requests over TLS with an empty 200, and
+// sends the lowercase header names of each in the order they came in on the
+// wire, which HTTP/2 servers and net/http do not keep.
+func serveHeaderNames(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+
+	certs := stdhttptest.NewUnstartedServer(nil)
+	certs.StartTLS()
+	cert := certs.TLS.Certificates[0]
+	certs.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		NextProtos:   []string{"http/1.1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	names := make(chan []string, 8)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					if _, err := r.ReadString('\n'); err != nil {
+						return
+					}
+					var got []string
+					for {
+						line, err := r.ReadString('\n')
+						if err != nil {
+							return
+						}
+						name, _, ok := strings.Cut(strings.TrimRight(line, "\r\n"), ":")
+						if !ok {
+							break
+						}
+						got = append(got, strings.ToLower(name))
+					}
+					names <- got
+					conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n"))
+				}
+			}(conn)
+		}
+	}()
+
+	return "https://" + ln.Addr().String() + "/", names
+}
+
+func TestFetchHeaderOrder(t *testing.T) {
+	url, names := serveHeaderNames(t)
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	tests := []struct {
+		name  string
+		opts  FetchOptions
+		order []string
+	}{
+		{"navigation", FetchOptions{Profile: ProfileNavigation}, spec.fetch.navigationOrder},
+		{"xhr", FetchOptions{Profile: ProfileXHR, Referrer: "https://www.example.com/"}, spec.fetch.fetchOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := Fetch(context.Background(), client, url, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			// the headers the browser orders come in its order, ahead of the rest
+			got := <-names
+			var ordered []string
+			for i, name := range got {
+				if !slices.Contains(tt.order, name) {
+					if rest := got[i:]; slices.ContainsFunc(rest, func(n string) bool { return slices.Contains(tt.order, n) }) {
+						t.Fatalf("order = %v, want the browser's headers first", got)
+					}
+					break
+				}
+				ordered = append(ordered, name)
+			}
+			want := slices.DeleteFunc(slices.Clone(tt.order), func(n string) bool { return !slices.Contains(ordered, n) })
+			if len(ordered) < 5 || !slices.Equal(ordered, want) {
+				t.Errorf("order = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package mimic
 
 import (
 	"fmt"
+	"slices"
+	"time"
 
 	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
 	http "github.com/saucesteals/fhttp"
 	"github.com/saucesteals/fhttp/http2"
 )
@@ -18,28 +21,34 @@ import (
 // dependency tree. This is not supported by the underlying HTTP/2 transport, so the
 // Akamai PRIORITY section of the fingerprint will differ from real Firefox.
 // The TLS, SETTINGS, WINDOW_UPDATE, and pseudo-header order are all matched.
-func Firefox(version string) (*ClientSpec, error) {
+func Firefox(version string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
 	_, majorNum, err := parseMajorVersion(version)
 	if err != nil {
 		return nil, err
 	}
 
-	if majorNum < 55 {
-		return nil, fmt.Errorf("firefox %s: %w", version, ErrUnsupportedVersion)
+	if majorNum < firefoxMinMajor {
+		return nil, &VersionTooOldError{Browser: "firefox", Min: firefoxMinMajor, Got: majorNum}
 	}
 
-	helloID := firefoxTLSHelloID(majorNum)
-	if err := validateTLSHelloID(helloID); err != nil {
+	ts, err := cfg.newTLSSpec(firefoxTLSHelloID(majorNum), firefoxTLSEdits(majorNum)...)
+	if err != nil {
 		return nil, fmt.Errorf("firefox %s: %w", version, err)
 	}
 
 	return &ClientSpec{
 		version:      version,
-		http2Options: firefoxHTTP2Options(),
-		tlsSpecFn: func(_ Platform) (func() *utls.ClientHelloSpec, error) {
-			return newTLSSpecFunc(helloID), nil
-		},
-		buildHeaders: firefoxBuildHeaders(version),
+		http2Options: cfg.http2Options(firefoxHTTP2Options()),
+		timeouts:     firefoxTimeouts(),
+		tlsSpecFor: cfg.tlsSpecFor(func(_ Platform) (*tlsSpec, error) {
+			return ts, nil
+		}),
+		buildHeaders: cfg.buildHeaders(firefoxBuildHeaders(version), firefoxAcceptLanguage),
+		fetch:        firefoxFetchHeaders(majorNum),
+
+		quicParameters: firefoxQUICParameters(majorNum),
 	}, nil
 }
 
@@ -51,9 +60,11 @@ func firefoxTLSHelloID(majorNum int) utls.ClientHelloID {
 		return utls.HelloFirefox_56
 	case majorNum < 65:
 		return utls.HelloFirefox_63
-	case majorNum < 99:
+	case majorNum < 78:
 		return utls.HelloFirefox_65
 	case majorNum < 102:
+		// 78 dropped the DHE suites for RSA AES-GCM, the last change before
+		// the 99 hello
 		return utls.HelloFirefox_99
 	case majorNum < 105:
 		return utls.HelloFirefox_102
@@ -64,6 +75,52 @@ func firefoxTLSHelloID(majorNum int) utls.ClientHelloID {
 	}
 }
 
+// firefoxTLSEdits returns the changes a version made on top of its hello ID:
+//   - 77 enabled delegated_credentials by default, one release before the
+//     cipher suite change the 99 hello already has.
+//   - 132 enabled the X25519MLKEM768 hybrid key exchange by default, offering
+//     and sharing it ahead of X25519. 121-131, including the 128 ESR, send the
+//     120 hello unchanged.
+func firefoxTLSEdits(majorNum int) []tlsOverride {
+	switch {
+	case majorNum == 77:
+		return []tlsOverride{firefoxDelegatedCredentials}
+	case majorNum >= 132:
+		return []tlsOverride{firefoxMLKEM}
+	default:
+		return nil
+	}
+}
+
+func firefoxDelegatedCredentials(spec *utls.ClientHelloSpec) error {
+	dc := &utls.FakeDelegatedCredentialsExtension{
+		SupportedSignatureAlgorithms: []utls.SignatureScheme{
+			utls.ECDSAWithP256AndSHA256,
+			utls.ECDSAWithP384AndSHA384,
+			utls.ECDSAWithP521AndSHA512,
+			utls.ECDSAWithSHA1,
+		},
+	}
+
+	i := slices.IndexFunc(spec.Extensions, func(ext utls.TLSExtension) bool {
+		return extensionID(ext) == dicttls.ExtType_status_request
+	})
+	spec.Extensions = slices.Insert(spec.Extensions, i+1, utls.TLSExtension(dc))
+	return nil
+}
+
+func firefoxMLKEM(spec *utls.ClientHelloSpec) error {
+	for _, ext := range spec.Extensions {
+		switch ext := ext.(type) {
+		case *utls.SupportedCurvesExtension:
+			ext.Curves = slices.Insert(slices.Clone(ext.Curves), 0, utls.X25519MLKEM768)
+		case *utls.KeyShareExtension:
+			ext.KeyShares = slices.Insert(slices.Clone(ext.KeyShares), 0, utls.KeyShare{Group: utls.X25519MLKEM768})
+		}
+	}
+	return nil
+}
+
 func firefoxHTTP2Options() *HTTP2Options {
 	return &HTTP2Options{
 		// Firefox's unique pseudo-header order: method, path, authority, scheme
@@ -76,7 +133,11 @@ func firefoxHTTP2Options() *HTTP2Options {
 		InitialWindowSize: 131072,
 		HeaderTableSize:   65536,
 		ConnectionFlow:    12517377,
-		// Firefox requests use stream 13 as their priority leader with weight 42.
+		UploadChunkSize:   16384,
+		// network.http.http2.default-concurrent caps the server's limit
+		MaxConcurrentStreams: 100,
+		// Firefox navigations use stream 13 as their priority leader with weight
+		// 42; requests from Fetch take their destination's from firefoxPriorities.
 		// Real Firefox also sends standalone PRIORITY frames at connection start,
 		// but those are not supported by the underlying HTTP/2 transport.
 		HeaderPriority: &http2.PriorityParam{
@@ -87,6 +148,82 @@ func firefoxHTTP2Options() *HTTP2Options {
 	}
 }
 
+var (
+	firefoxNavigationOrder = []string{
+		"user-agent", "accept", "accept-language", "accept-encoding", "content-type", "content-length", "origin",
+		"referer", "cookie", "upgrade-insecure-requests", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site", "sec-fetch-user",
+		"if-modified-since", "if-none-match", "priority", "pragma", "cache-control", "te",
+	}
+	firefoxFetchOrder = []string{
+		"user-agent", "accept", "accept-language", "accept-encoding", "referer", "content-type",
+		"content-length", "origin", "cookie", "sec-fetch-dest", "sec-fetch-mode", "sec-fetch-site",
+		"if-modified-since", "if-none-match", "priority", "pragma", "cache-control", "te",
+	}
+)
+
+// firefoxFetchHeaders follows Firefox adding AVIF in 92, fetch metadata in 90,
+// zstd in 126, its geckoformboundary multipart boundaries in 127, and
+// trimming the navigation and image accept headers in 128.
+func firefoxFetchHeaders(majorNum int) *fetchHeaders {
+	fh := &fetchHeaders{
+		document:        "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8",
+		image:           "image/webp,*/*",
+		acceptEncoding:  "gzip, deflate, br",
+		metadata:        majorNum >= 90,
+		navigationOrder: firefoxNavigationOrder,
+		fetchOrder:      firefoxFetchOrder,
+	}
+
+	switch {
+	case majorNum >= 128:
+		fh.document = "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8"
+		fh.image = "image/avif,image/webp,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	case majorNum >= 92:
+		fh.document = "text/html,application/xhtml+xml,application/xml;q=0.9,image/avif,image/webp,*/*;q=0.8"
+		fh.image = "image/avif,image/webp,*/*"
+	}
+
+	if majorNum >= 126 {
+		fh.acceptEncoding = "gzip, deflate, br, zstd"
+	}
+	fh.formBoundary = legacyGeckoBoundary
+	if majorNum >= 127 {
+		fh.formBoundary = geckoBoundary
+	}
+	fh.priority = firefoxPriorities
+	return fh
+}
+
+// firefoxPriorities place each destination in the group Firefox's class of
+// service gives it: navigations under the urgent-start group (stream 13),
+// stylesheets and scripts under leaders (3), images under followers (5), and
+// fetch() and XMLHttpRequest under unblocked (7). The weight is 22 minus the
+// request's nsISupportsPriority, so highest is 42, high 32, and normal 22; the
+// wire weight is one less.
+var firefoxPriorities = map[FetchDestination]http2.PriorityParam{
+	DestinationDocument: {StreamDep: 13, Weight: 41},
+	DestinationStyle:    {StreamDep: 3, Weight: 41},
+	DestinationScript:   {StreamDep: 3, Weight: 31},
+	DestinationImage:    {StreamDep: 5, Weight: 21},
+	DestinationEmpty:    {StreamDep: 7, Weight: 21},
+}
+
+// firefoxTimeouts mirrors Firefox's network.http.connection-timeout,
+// network.http.tls-handshake-timeout, and network.http.response.timeout prefs,
+// network.http.http2.timeout for idle connections, and
+// network.http.http2.ping-threshold and network.http.http2.ping-timeout.
+func firefoxTimeouts() Timeouts {
+	return Timeouts{
+		Connect:        90 * time.Second,
+		TLSHandshake:   30 * time.Second,
+		ResponseHeader: 300 * time.Second,
+		BodyStall:      300 * time.Second,
+		Idle:           170 * time.Second,
+		Ping:           58 * time.Second,
+		PingTimeout:    8 * time.Second,
+	}
+}
+
 // firefoxBuildHeaders returns a function that generates Firefox-appropriate default headers
 // for a given platform. Firefox does not send sec-ch-ua client hint headers.
 func firefoxBuildHeaders(version string) func(Platform) (http.Header, error) {
@@ -102,7 +239,7 @@ func firefoxBuildHeaders(version string) func(Platform) (http.Header, error) {
 		case PlatformLinux:
 			uaPlatform = "X11; Linux x86_64"
 		default:
-			return nil, fmt.Errorf("firefox on %s: %w", p, ErrUnsupportedPlatform)
+			return nil, &PlatformError{Browser: "firefox", Platform: p}
 		}
 
 		ua := fmt.Sprintf(
@@ -112,6 +249,38 @@ func firefoxBuildHeaders(version string) func(Platform) (http.Header, error) {
 
 		h := http.Header{}
 		h.Set("user-agent", ua)
+		// Firefox advertises that it accepts trailers, which gRPC-style servers
+		// rely on; the transport populates Response.Trailer when they arrive.
+		h.Set("te", "trailers")
 		return h, nil
 	}
 }
+
+// firefoxQUICParameters returns a builder for the transport parameters neqo,
+// Firefox's QUIC stack, sends in its fixed order. HTTP/3 is on by default from
+// 88; earlier versions get nil.
+func firefoxQUICParameters(majorNum int) func() utls.TransportParameters {
+	if majorNum < 88 {
+		return nil
+	}
+
+	return func() utls.TransportParameters {
+		return utls.TransportParameters{
+			utls.InitialMaxStreamDataBidiLocal(12582912),
+			utls.InitialMaxStreamDataBidiRemote(1048576),
+			utls.InitialMaxStreamDataUni(1048576),
+			utls.InitialMaxData(25165824),
+			utls.InitialMaxStreamsBidi(16),
+			utls.InitialMaxStreamsUni(16),
+			utls.MaxIdleTimeout(30000),
+			utls.ActiveConnectionIDLimit(8),
+			utls.InitialSourceConnectionID{},
+			&utls.VersionInformation{
+				ChoosenVersion:    utls.VERSION_1,
+				AvailableVersions: []uint32{utls.VERSION_1, utls.VERSION_2},
+			},
+			&utls.GREASEQUICBit{},
+			&utls.GREASETransportParameter{},
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package mimic
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// FormEncoding is the enctype of an HTML form, which decides how its fields
+// are encoded in the body of a POST.
+type FormEncoding string
+
+const (
+	FormURLEncoded FormEncoding = "application/x-www-form-urlencoded"
+	FormMultipart  FormEncoding = "multipart/form-data"
+	FormTextPlain  FormEncoding = "text/plain"
+)
+
+// Form is the entry list of an HTML form: its fields in document order, as a
+// browser submits them. A name may appear more than once. The zero value is
+// an empty form.
+type Form struct {
+	entries []formEntry
+}
+
+type formEntry struct {
+	name  string
+	value string
+	file  *formFile
+}
+
+type formFile struct {
+	filename    string
+	contentType string
+	content     []byte
+}
+
+// Add appends a field named name with value.
+func (f *Form) Add(name, value string) {
+	f.entries = append(f.entries, formEntry{name: name, value: value})
+}
+
+// AddFile appends a file input named name holding a file called filename.
+// An empty contentType is sent as application/octet-stream, and a file input
+// with no file chosen is an empty filename with no content.
+func (f *Form) AddFile(name, filename, contentType string, content []byte) {
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	f.entries = append(f.entries, formEntry{name: name, file: &formFile{
+		filename:    filename,
+		contentType: contentType,
+		content:     content,
+	}})
+}
+
+// Encode returns the body a browser sends for f with enctype, and its
+// Content-Type. Line breaks in names and values are sent as CRLF. URL encoding
+// escapes every byte but letters, digits, and *-._, and sends spaces as +.
+// Multipart names and filenames are sent as UTF-8 with ", CR, and LF
+// percent-encoded, and never as filename*, which browsers do not send. The
+// multipart boundary is in the style of Chromium and Safari; Firefox's is
+// used when the request is sent through a Firefox Transport. An unknown
+// enctype is URL encoded, as browsers treat it.
+func (f *Form) Encode(enctype FormEncoding) (body []byte, contentType string) {
+	return f.encode(enctype, webkitBoundary)
+}
+
+func (f *Form) encode(enctype FormEncoding, boundary func() string) ([]byte, string) {
+	switch enctype {
+	case FormMultipart:
+		b := boundary()
+		return f.multipart(b), string(FormMultipart) + "; boundary=" + b
+	case FormTextPlain:
+		return f.textPlain(), string(FormTextPlain)
+	default:
+		return []byte(f.urlEncoded()), string(FormURLEncoded)
+	}
+}
+
+// urlEncoded serializes f as application/x-www-form-urlencoded. A file input
+// contributes its filename.
+func (f *Form) urlEncoded() string {
+	var b strings.Builder
+	for i, e := range f.entries {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		formURLEscape(&b, normalizeNewlines(e.name))
+		b.WriteByte('=')
+		formURLEscape(&b, normalizeNewlines(e.textValue()))
+	}
+	return b.String()
+}
+
+func (f *Form) textPlain() []byte {
+	var b bytes.Buffer
+	for _, e := range f.entries {
+		b.WriteString(normalizeNewlines(e.name))
+		b.WriteByte('=')
+		b.WriteString(normalizeNewlines(e.textValue()))
+		b.WriteString("\r\n")
+	}
+	return b.Bytes()
+}
+
+func (f *Form) multipart(boundary string) []byte {
+	var b bytes.Buffer
+	for _, e := range f.entries {
+		b.WriteString("--" + boundary + "\r\n")
+		b.WriteString(`Content-Disposition: form-data; name="` + multipartEscape(normalizeNewlines(e.name)) + `"`)
+		if e.file == nil {
+			b.WriteString("\r\n\r\n")
+			b.WriteString(normalizeNewlines(e.value))
+		} else {
+			b.WriteString(`; filename="` + multipartEscape(e.file.filename) + `"` + "\r\n")
+			b.WriteString("Content-Type: " + e.file.contentType + "\r\n\r\n")
+			b.Write(e.file.content)
+		}
+		b.WriteString("\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.Bytes()
+}
+
+func (e formEntry) textValue() string {
+	if e.file != nil {
+		return e.file.filename
+	}
+	return e.value
+}
+
+// formURLEscape writes s to b in the application/x-www-form-urlencoded byte
+// serializer's encoding, which differs from url.QueryEscape in keeping * and
+// escaping ~.
+func formURLEscape(b *strings.Builder, s string) {
+	const hex = "0123456789ABCDEF"
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ':
+			b.WriteByte('+')
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '*', c == '-', c == '.', c == '_':
+			b.WriteByte(c)
+		default:
+			b.WriteByte('%')
+			b.WriteByte(hex[c>>4])
+			b.WriteByte(hex[c&0xf])
+		}
+	}
+}
+
+var multipartEscaper = strings.NewReplacer(`"`, "%22", "\r", "%0D", "\n", "%0A")
+
+// multipartEscape escapes a name or filename for a Content-Disposition
+// parameter the way the HTML standard's multipart encoding does.
+func multipartEscape(s string) string {
+	return multipartEscaper.Replace(s)
+}
+
+// normalizeNewlines replaces every lone CR and lone LF in s with CRLF.
+func normalizeNewlines(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+// boundaryChars are the characters WebKit and Blink draw boundaries from,
+// with A and B doubled to fill 64 entries.
+const boundaryChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789AB"
+
+// webkitBoundary returns a multipart boundary as Chromium and Safari make
+// them.
+func webkitBoundary() string {
+	b := []byte("----WebKitFormBoundary")
+	for range 16 {
+		b = append(b, boundaryChars[rand.IntN(len(boundaryChars))])
+	}
+	return string(b)
+}
+
+// geckoBoundary returns a multipart boundary as Firefox 127 and later make
+// them.
+func geckoBoundary() string {
+	return fmt.Sprintf("----geckoformboundary%016x%016x", rand.Uint64(), rand.Uint64())
+}
+
+// legacyGeckoBoundary returns a multipart boundary as Firefox made them
+// before 127: dashes and three random integers.
+func legacyGeckoBoundary() string {
+	b := []byte("---------------------------")
+	for range 3 {
+		b = strconv.AppendInt(b, int64(rand.Int32()), 10)
+	}
+	return string(b)
+}
+
+// formSubmission is the form a request submits, which the Transport encodes
+// again when its browser makes boundaries of another style.
+type formSubmission struct {
+	form    *Form
+	enctype FormEncoding
+}
+
+// rebound returns the body of s in fh's multipart boundary style, and its
+// Content-Type, when req carries it in the style of Chromium and Safari and
+// fh's browser makes boundaries of another.
+func (s *formSubmission) rebound(req *http.Request, header http.Header, fh *fetchHeaders) ([]byte, string, bool) {
+	if s.enctype != FormMultipart || fh.formBoundary == nil || req.Body == nil || req.Body == http.NoBody {
+		return nil, "", false
+	}
+	if !strings.Contains(header.Get("Content-Type"), "boundary=----WebKitFormBoundary") {
+		return nil, "", false
+	}
+
+	body, contentType := s.form.encode(FormMultipart, fh.formBoundary)
+	return body, contentType, true
+}
+
+// newFormRequest returns a POST navigation to rawURL submitting form from the
+// page at referrer, with its multipart boundary made by boundary.
+func newFormRequest(ctx context.Context, rawURL, referrer string, enctype FormEncoding, form *Form, boundary func() string) (*http.Request, *fetchIntent, error) {
+	body, contentType := form.encode(enctype, boundary)
+	req, intent, err := newFetchRequest(ctx, rawURL, FetchOptions{
+		Method:   http.MethodPost,
+		Header:   http.Header{"Content-Type": {contentType}},
+		Body:     bytes.NewReader(body),
+		Profile:  ProfileNavigation,
+		Referrer: referrer,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	intent.form = &formSubmission{form: form, enctype: enctype}
+	return req, intent, nil
+}
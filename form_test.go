@@ -0,0 +1,146 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestFormEncode(t *testing.T) {
+	var form Form
+	form.Add("q", "a b*~é")
+	form.Add("note\n", "1\r2")
+	form.AddFile(`up"load`, "résumé\n.txt", "", []byte("data"))
+
+	if got, want := form.urlEncoded(), "q=a+b*%7E%C3%A9&note%0D%0A=1%0D%0A2&up%22load=r%C3%A9sum%C3%A9%0D%0A.txt"; got != want {
+		t.Errorf("urlencoded = %q, want %q", got, want)
+	}
+
+	if got, want := string(form.textPlain()), "q=a b*~é\r\nnote\r\n=1\r\n2\r\nup\"load=résumé\r\n.txt\r\n"; got != want {
+		t.Errorf("text/plain = %q, want %q", got, want)
+	}
+
+	want := "--B\r\n" +
+		"Content-Disposition: form-data; name=\"q\"\r\n\r\na b*~é\r\n" +
+		"--B\r\n" +
+		"Content-Disposition: form-data; name=\"note%0D%0A\"\r\n\r\n1\r\n2\r\n" +
+		"--B\r\n" +
+		"Content-Disposition: form-data; name=\"up%22load\"; filename=\"résumé%0A.txt\"\r\n" +
+		"Content-Type: application/octet-stream\r\n\r\ndata\r\n" +
+		"--B--\r\n"
+	if got := string(form.multipart("B")); got != want {
+		t.Errorf("multipart = %q, want %q", got, want)
+	}
+
+	_, contentType := form.Encode(FormMultipart)
+	if !strings.HasPrefix(contentType, "multipart/form-data; boundary=----WebKitFormBoundary") || len(contentType) != len("multipart/form-data; boundary=----WebKitFormBoundary")+16 {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+}
+
+func TestPageSubmit(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]*stdhttp.Request)
+
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		r.ParseMultipartForm(1 << 20)
+		mu.Lock()
+		seen[r.URL.Path] = r
+		mu.Unlock()
+
+		if r.URL.Path == "/login" {
+			stdhttp.Redirect(w, r, "/done", stdhttp.StatusSeeOther)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<form>")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	chrome, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firefox, err := Firefox("138.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var form Form
+	form.Add("user", "a b")
+	form.Add("pass", "x&y")
+
+	tests := []struct {
+		name     string
+		spec     *ClientSpec
+		method   string
+		enctype  FormEncoding
+		boundary string
+	}{
+		{"chrome urlencoded", chrome, "POST", FormURLEncoded, ""},
+		{"firefox multipart", firefox, "post", FormMultipart, "----geckoformboundary"},
+		{"chrome get", chrome, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+			session, err := NewSession(tt.spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer session.Close()
+
+			ctx := context.Background()
+			page, err := session.Navigate(ctx, server.URL+"/form")
+			if err != nil {
+				t.Fatal(err)
+			}
+			done, err := page.Submit(ctx, "login?old=1", tt.method, tt.enctype, &form)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done.URL.Path != "/done" || done.Referrer != page.URL.String() {
+				t.Errorf("want the redirect followed from the form page; got %s from %q", done.URL, done.Referrer)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			login := seen["/login"]
+			if tt.method == "" {
+				if login.Method != "GET" || login.URL.RawQuery != "user=a+b&pass=x%26y" {
+					t.Errorf("want the fields as the query; got %s %s", login.Method, login.URL)
+				}
+				return
+			}
+
+			if login.Method != "POST" || login.Header.Get("Origin") != server.URL || login.Header.Get("Referer") != page.URL.String() {
+				t.Errorf("want a POST from the page's origin; got %s %v", login.Method, login.Header)
+			}
+			if login.PostForm.Get("user") != "a b" || login.PostForm.Get("pass") != "x&y" {
+				t.Errorf("want the fields in the body; got %v", login.PostForm)
+			}
+			if got := login.Header.Get("Content-Type"); !strings.Contains(got, tt.boundary) {
+				t.Errorf("Content-Type = %q, want a %s boundary", got, tt.boundary)
+			}
+			if got, want := login.Header.Get("Cache-Control"), map[bool]string{true: "max-age=0"}[tt.spec == chrome]; got != want {
+				t.Errorf("Cache-Control = %q, want %q", got, want)
+			}
+
+			redirected := seen["/done"]
+			if redirected.Method != "GET" || redirected.Header.Get("Content-Type") != "" || redirected.Header.Get("Origin") != "" {
+				t.Errorf("want the redirect sent as a GET without the form's headers; got %s %v", redirected.Method, redirected.Header)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package mimic
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+// chromiumBuild is the build number of a Chromium major's release branch and
+// the patch numbers of its first and last stable releases.
+type chromiumBuild struct {
+	build                 int
+	firstPatch, lastPatch int
+}
+
+// chromiumBuilds are the stable releases of each Chromium major.
+var chromiumBuilds = map[int]chromiumBuild{
+	83:  {4103, 61, 116},
+	84:  {4147, 89, 135},
+	85:  {4183, 83, 121},
+	86:  {4240, 75, 198},
+	87:  {4280, 66, 141},
+	88:  {4324, 96, 190},
+	89:  {4389, 72, 128},
+	90:  {4430, 72, 212},
+	91:  {4472, 77, 164},
+	92:  {4515, 107, 159},
+	93:  {4577, 63, 82},
+	94:  {4606, 54, 81},
+	95:  {4638, 54, 69},
+	96:  {4664, 45, 110},
+	97:  {4692, 71, 99},
+	98:  {4758, 80, 102},
+	99:  {4844, 51, 84},
+	100: {4896, 60, 127},
+	101: {4951, 41, 67},
+	102: {5005, 61, 115},
+	103: {5060, 53, 134},
+	104: {5112, 79, 102},
+	105: {5195, 52, 127},
+	106: {5249, 61, 119},
+	107: {5304, 62, 121},
+	108: {5359, 71, 125},
+	109: {5414, 74, 120},
+	110: {5481, 77, 178},
+	111: {5563, 64, 147},
+	112: {5615, 49, 138},
+	113: {5672, 63, 127},
+	114: {5735, 90, 199},
+	115: {5790, 98, 171},
+	116: {5845, 96, 188},
+	117: {5938, 62, 149},
+	118: {5993, 70, 118},
+	119: {6045, 105, 199},
+	120: {6099, 62, 225},
+	121: {6167, 85, 184},
+	122: {6261, 57, 129},
+	123: {6312, 58, 122},
+	124: {6367, 60, 207},
+	125: {6422, 60, 142},
+	126: {6478, 55, 182},
+	127: {6533, 72, 119},
+	128: {6613, 84, 137},
+	129: {6668, 58, 100},
+	130: {6723, 58, 117},
+	131: {6778, 69, 205},
+	132: {6834, 83, 160},
+	133: {6943, 53, 142},
+	134: {6998, 35, 178},
+	135: {7049, 41, 115},
+	136: {7103, 48, 114},
+	137: {7151, 55, 122},
+	138: {7204, 49, 184},
+	139: {7258, 66, 155},
+	140: {7339, 80, 208},
+	141: {7390, 54, 123},
+	142: {7444, 52, 176},
+}
+
+// chromiumBuildsPerMajor is how far the build number has advanced per major
+// in recent releases, used to place majors newer than chromiumBuilds.
+const chromiumBuildsPerMajor = 55
+
+// chromiumFullVersion returns the full version a Chromium at version reports.
+// A version with a build number is returned as given. For a reduced version
+// like "137.0.0.0", a patch is picked at random from the major's stable
+// releases; callers pick once per spec, so a session reports one build
+// throughout.
+func chromiumFullVersion(version string, majorNum int) string {
+	parts := strings.Split(version, ".")
+	if len(parts) == 4 && parts[2] != "0" {
+		return version
+	}
+
+	b, ok := chromiumBuilds[majorNum]
+	if !ok {
+		latest := 0
+		for major := range chromiumBuilds {
+			latest = max(latest, major)
+		}
+		if majorNum < latest {
+			return fmt.Sprintf("%d.0.0.0", majorNum)
+		}
+		b = chromiumBuilds[latest]
+		b.build += (majorNum - latest) * chromiumBuildsPerMajor
+	}
+
+	patch := b.firstPatch + rand.IntN(b.lastPatch-b.firstPatch+1)
+	return fmt.Sprintf("%d.0.%d.%d", majorNum, b.build, patch)
+}
+
+// fullVersionList returns brands with the full versions they report in
+// sec-ch-ua-full-version-list: Chromium's full version for Chromium and
+// Chrome, and the major with zeroes for the rest, which include the GREASE
+// brand and derivatives with builds of their own.
+func fullVersionList(brands []BrandVersion, fullVersion string) []BrandVersion {
+	list := make([]BrandVersion, len(brands))
+	for i, b := range brands {
+		switch {
+		case b.Brand == "Chromium" || b.Brand == string(BrandChrome):
+			b.Version = fullVersion
+		case !strings.Contains(b.Version, "."):
+			b.Version += ".0.0.0"
+		}
+		list[i] = b
+	}
+	return list
+}
@@ -0,0 +1,96 @@
+package mimic
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// WithHeaderOrder returns a shallow copy of req whose headers a Transport
+// sends in order, instead of the browser's order it uses when none is set.
+//
+// Unlike setting http.HeaderOrderKey, which fhttp matches against lowercase
+// names only and otherwise ignores without a word, order is checked when the
+// request is sent, against the headers it carries by then, including the
+// ones the Transport adds. Names are matched whatever their case. A name that
+// is neither sent nor in the browser's own order, most likely a typo, or a
+// name listed twice, fails the request with a *HeaderOrderError. Headers left
+// out of order go where the browser puts them: after the header they follow
+// in its order, or first when nothing before them is sent.
+func WithHeaderOrder(req *http.Request, order []string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), headerOrderKey, slices.Clone(order)))
+}
+
+func headerOrderFrom(ctx context.Context) ([]string, bool) {
+	order, ok := ctx.Value(headerOrderKey).([]string)
+	return order, ok
+}
+
+// mergeHeaderOrder returns order, lowercased, with the headers of header it
+// leaves out placed as in browserOrder. Names that are neither in header nor
+// in known are unknown.
+func mergeHeaderOrder(order []string, header http.Header, browserOrder []string, known ...[]string) ([]string, error) {
+	sent := make(map[string]bool, len(header))
+	for key := range header {
+		if key != http.HeaderOrderKey && key != http.PHeaderOrderKey {
+			sent[strings.ToLower(key)] = true
+		}
+	}
+
+	var orderErr HeaderOrderError
+	merged := make([]string, 0, len(order)+len(sent))
+	placed := make(map[string]bool, len(order)+len(sent))
+	for _, name := range order {
+		name = strings.ToLower(name)
+		switch {
+		case placed[name]:
+			if !slices.Contains(orderErr.Duplicates, name) {
+				orderErr.Duplicates = append(orderErr.Duplicates, name)
+			}
+			continue
+		case !sent[name] && !slices.ContainsFunc(known, func(k []string) bool { return slices.Contains(k, name) }):
+			orderErr.Unknown = append(orderErr.Unknown, name)
+		}
+		merged = append(merged, name)
+		placed[name] = true
+	}
+	if orderErr.Unknown != nil || orderErr.Duplicates != nil {
+		return nil, &orderErr
+	}
+
+	// each header left out goes after the nearest header before it in the
+	// browser's order that is already placed
+	for i, name := range browserOrder {
+		if !sent[name] || placed[name] {
+			continue
+		}
+		at := 0
+		for _, prev := range slices.Backward(browserOrder[:i]) {
+			if placed[prev] {
+				at = slices.Index(merged, prev) + 1
+				break
+			}
+		}
+		merged = slices.Insert(merged, at, name)
+		placed[name] = true
+	}
+
+	// headers the browser does not order go last, as fhttp sends them
+	var rest []string
+	for name := range sent {
+		if !placed[name] {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	return append(merged, rest...), nil
+}
+
+// browserHeaderOrder returns the headers of header in browserOrder, with the
+// headers it does not list last, sorted, as fhttp sends them.
+func browserHeaderOrder(header http.Header, browserOrder []string) []string {
+	order, _ := mergeHeaderOrder(nil, header, browserOrder)
+	return order
+}
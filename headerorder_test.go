@@ -0,0 +1,73 @@
+package mimic
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestWithHeaderOrder(t *testing.T) {
+	tr := newTestTransport(t)
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("x-trace", "1")
+	req.Header.Set("accept", "*/*")
+
+	// cookie is not sent, but chromium orders it
+	res, err := tr.RoundTrip(WithHeaderOrder(req, []string{"Accept", "User-Agent", "cookie"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "accept", "user-agent", "cookie", "x-trace"}
+	if got := res.Request.Header[http.HeaderOrderKey]; !slices.Equal(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+
+	_, err = tr.RoundTrip(WithHeaderOrder(req, []string{"accept", "user_agent", "Accept"}))
+	var orderErr *HeaderOrderError
+	if !errors.As(err, &orderErr) {
+		t.Fatalf("err = %v, want a *HeaderOrderError", err)
+	}
+	if !slices.Equal(orderErr.Unknown, []string{"user_agent"}) || !slices.Equal(orderErr.Duplicates, []string{"accept"}) {
+		t.Errorf("err = %+v, want user_agent unknown and accept repeated", orderErr)
+	}
+
+	body := &closeRecorder{Reader: strings.NewReader("a=1")}
+	post, err := http.NewRequest(http.MethodPost, "https://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(WithHeaderOrder(post, []string{"user_agent"})); !errors.As(err, &orderErr) || !body.closed {
+		t.Errorf("want the body of a misordered request closed; got %v, closed %t", err, body.closed)
+	}
+}
+
+func TestRoundTripDefaultOrder(t *testing.T) {
+	tr := newTestTransport(t)
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-Trace", "1")
+
+	req.Header.Set("Accept", "*/*")
+
+	want := []string{"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "user-agent", "accept", "x-trace"}
+	for range 5 {
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := res.Request.Header[http.HeaderOrderKey]; !slices.Equal(got, want) {
+			t.Fatalf("order = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package mimic
+
+import (
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// HeaderRule changes the headers of requests to the hosts it matches, such as
+// adding an API key only for an API host.
+type HeaderRule struct {
+	// Host is the hostname the rule applies to. A leading "*." matches its
+	// subdomains but not the host itself, so "*.example.com" matches
+	// "api.example.com" and "a.b.example.com" but not "example.com".
+	Host string
+
+	// Set replaces headers, whether the request or the browser's defaults set
+	// them. A replaced header keeps its place in the header order.
+	Set http.Header
+
+	// Add sets headers the request leaves unset. Headers the browser's order
+	// does not name are sent after the ones it does.
+	Add http.Header
+
+	// Remove deletes headers, including the browser's defaults.
+	Remove []string
+}
+
+// WithHeaderRules applies rules to the headers of each request whose host they
+// match, in the order given, after the browser's default headers are added and
+// before the header order is decided.
+func WithHeaderRules(rules ...HeaderRule) TransportOption {
+	return func(c *transportConfig) {
+		c.headerRules = append(c.headerRules, rules...)
+	}
+}
+
+// matches reports whether r applies to requests for host.
+func (r HeaderRule) matches(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	pattern := strings.TrimSuffix(strings.ToLower(r.Host), ".")
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return host == pattern
+}
+
+// apply changes header as r describes. Values are shared with r, capped so
+// appending to them copies them.
+func (r HeaderRule) apply(header http.Header) {
+	for _, key := range r.Remove {
+		delete(header, http.CanonicalHeaderKey(key))
+	}
+	for key, values := range r.Set {
+		header[http.CanonicalHeaderKey(key)] = values[:len(values):len(values)]
+	}
+	for key, values := range r.Add {
+		key = http.CanonicalHeaderKey(key)
+		if existing := header[key]; len(existing) > 0 && existing[0] != "" {
+			continue
+		}
+		header[key] = values[:len(values):len(values)]
+	}
+}
+
+// applyHeaderRules applies each of rules that matches host to header.
+func applyHeaderRules(rules []HeaderRule, header http.Header, host string) {
+	for _, rule := range rules {
+		if rule.matches(host) {
+			rule.apply(header)
+		}
+	}
+}
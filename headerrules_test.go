@@ -0,0 +1,81 @@
+package mimic
+
+import (
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestHeaderRuleMatches(t *testing.T) {
+	tests := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"api.example.com", "api.example.com", true},
+		{"api.example.com", "API.example.com.", true},
+		{"api.example.com", "example.com", false},
+		{"*.example.com", "api.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+	}
+
+	for _, test := range tests {
+		if got := (HeaderRule{Host: test.pattern}).matches(test.host); got != test.want {
+			t.Errorf("%s against %s: want %t; got %t", test.pattern, test.host, test.want, got)
+		}
+	}
+}
+
+func TestRoundTripHeaderRules(t *testing.T) {
+	tr := newTestTransport(t)
+	tr.headerRules = []HeaderRule{
+		{
+			Host:   "api.example.com",
+			Add:    http.Header{"X-Api-Key": {"secret"}, "X-Client": {"default"}},
+			Set:    http.Header{"accept": {"application/json"}},
+			Remove: []string{"sec-ch-ua-mobile"},
+		},
+		{Host: "*.example.com", Set: http.Header{"X-Client": {"override"}}},
+	}
+
+	send := func(rawURL string, header http.Header) http.Header {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header = header
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.Request.Header
+	}
+
+	h := send("https://api.example.com/v1", http.Header{"X-Api-Key": {"mine"}})
+	if got := h.Get("X-Api-Key"); got != "mine" {
+		t.Errorf("want Add to keep the request's value; got %q", got)
+	}
+	if got := h.Get("Accept"); got != "application/json" {
+		t.Errorf("want Set to replace the default; got %q", got)
+	}
+	if _, ok := h["Sec-Ch-Ua-Mobile"]; ok {
+		t.Error("want Remove to delete the default")
+	}
+	if got := h.Get("X-Client"); got != "override" {
+		t.Errorf("want later rules applied last; got %q", got)
+	}
+
+	h = send("https://www.example.com/", http.Header{})
+	if h.Get("X-Api-Key") != "" || h.Get("X-Client") != "override" || h.Get("Sec-Ch-Ua-Mobile") == "" {
+		t.Error("want only the wildcard rule applied to www.example.com")
+	}
+
+	h = send("https://example.com/", http.Header{})
+	if h.Get("X-Client") != "" {
+		t.Error("want no rules applied to example.com")
+	}
+}
@@ -0,0 +1,581 @@
+package mimic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+// extensionNameECH names the encrypted_client_hello extension in a
+// ClientHello document.
+const extensionNameECH = "encrypted_client_hello"
+
+// ParseCapturedHello builds a CustomHello from a ClientHello captured off the
+// wire, so any client that can be captured once can be cloned. Data is either
+// a pcap or pcapng capture, in which case the first ClientHello sent over TCP
+// is used, or the ClientHello itself: the TLS record, the bare handshake
+// message, or either as hex, as Wireshark copies it.
+//
+// Per-connection values are not kept: GREASE values, key shares, the ECH
+// payload, and the padding length are generated afresh on every connection,
+// as the browser does. One capture cannot tell whether the client shuffles its
+// extensions, so the hello sends them in the captured order. Marshal the
+// result to get a ClientHello document to edit or load with LoadCustomHello.
+func ParseCapturedHello(data []byte) (*CustomHello, error) {
+	record, err := clientHelloRecord(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing captured hello: %w", err)
+	}
+
+	fingerprinter := utls.Fingerprinter{}
+	template, err := fingerprinter.RawClientHello(record)
+	if err != nil {
+		return nil, fmt.Errorf("parsing captured hello: %w", err)
+	}
+
+	for i, ext := range template.Extensions {
+		if _, ok := ext.(*utls.UtlsPaddingExtension); ok {
+			// pad by the browser's rule, not to the captured hello's length
+			template.Extensions[i] = &utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle}
+		}
+	}
+
+	spec := &tlsSpec{template: *template}
+	if err := validateHello(spec.New()); err != nil {
+		return nil, fmt.Errorf("validating captured hello: %w", err)
+	}
+
+	return &CustomHello{spec: spec}, nil
+}
+
+// LoadCapturedHello reads a captured ClientHello or a capture containing one
+// from a file and parses it with ParseCapturedHello.
+func LoadCapturedHello(path string) (*CustomHello, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading captured hello: %w", err)
+	}
+	return ParseCapturedHello(data)
+}
+
+// MarshalJSON encodes h as a ClientHello document that ParseCustomHello
+// reads back into the same hello.
+func (h *CustomHello) MarshalJSON() ([]byte, error) {
+	template := h.spec.template
+
+	doc := struct {
+		CipherSuites       []string         `json:"cipher_suites"`
+		CompressionMethods []string         `json:"compression_methods"`
+		Extensions         []helloExtension `json:"extensions"`
+		TLSVersMin         uint16           `json:"min_vers,omitempty"`
+		TLSVersMax         uint16           `json:"max_vers,omitempty"`
+		ShuffleExtensions  bool             `json:"shuffle_extensions,omitempty"`
+	}{
+		CipherSuites:       make([]string, 0, len(template.CipherSuites)),
+		CompressionMethods: make([]string, 0, len(template.CompressionMethods)),
+		Extensions:         make([]helloExtension, 0, len(template.Extensions)),
+		TLSVersMin:         template.TLSVersMin,
+		TLSVersMax:         template.TLSVersMax,
+		ShuffleExtensions:  h.spec.shuffle,
+	}
+
+	for _, suite := range template.CipherSuites {
+		name, err := lookupName(dicttls.DictCipherSuiteValueIndexed, suite, "cipher suite")
+		if err != nil {
+			return nil, err
+		}
+		doc.CipherSuites = append(doc.CipherSuites, name)
+	}
+	for _, method := range template.CompressionMethods {
+		name, err := lookupName(dicttls.DictCompMethValueIndexed, method, "compression method")
+		if err != nil {
+			return nil, err
+		}
+		doc.CompressionMethods = append(doc.CompressionMethods, name)
+	}
+	for _, ext := range template.Extensions {
+		m, err := marshalHelloExtension(ext)
+		if err != nil {
+			return nil, err
+		}
+		doc.Extensions = append(doc.Extensions, m)
+	}
+
+	return json.Marshal(doc)
+}
+
+// marshalHelloExtension returns ext in the form of a ClientHello document.
+func marshalHelloExtension(ext utls.TLSExtension) (helloExtension, error) {
+	switch e := ext.(type) {
+	case *utls.UtlsGREASEExtension:
+		return helloExtension{"name": "GREASE"}, nil
+	case *utls.SNIExtension:
+		return helloExtension{"name": "server_name"}, nil
+	case *utls.StatusRequestExtension:
+		return helloExtension{"name": "status_request"}, nil
+	case *utls.StatusRequestV2Extension:
+		return helloExtension{"name": "status_request_v2"}, nil
+	case *utls.SCTExtension:
+		return helloExtension{"name": "signed_certificate_timestamp"}, nil
+	case *utls.ExtendedMasterSecretExtension:
+		return helloExtension{"name": "extended_master_secret"}, nil
+	case *utls.SessionTicketExtension:
+		return helloExtension{"name": "session_ticket"}, nil
+	case *utls.RenegotiationInfoExtension:
+		return helloExtension{"name": "renegotiation_info"}, nil
+	case *utls.UtlsPaddingExtension:
+		return helloExtension{"name": "padding", "len": 0}, nil
+	case *utls.FakePreSharedKeyExtension, *utls.UtlsPreSharedKeyExtension:
+		return helloExtension{"name": "pre_shared_key"}, nil
+	case *utls.GREASEEncryptedClientHelloExtension:
+		return helloExtension{"name": extensionNameECH}, nil
+	case *utls.SupportedCurvesExtension:
+		groups, err := curveNames(e.Curves)
+		if err != nil {
+			return nil, err
+		}
+		return helloExtension{"name": "supported_groups", "named_group_list": groups}, nil
+	case *utls.SupportedPointsExtension:
+		formats := make([]string, 0, len(e.SupportedPoints))
+		for _, point := range e.SupportedPoints {
+			name, err := lookupName(dicttls.DictECPointFormatValueIndexed, point, "point format")
+			if err != nil {
+				return nil, err
+			}
+			formats = append(formats, name)
+		}
+		return helloExtension{"name": "ec_point_formats", "ec_point_format_list": formats}, nil
+	case *utls.SignatureAlgorithmsExtension:
+		schemes, err := schemeNames(e.SupportedSignatureAlgorithms)
+		if err != nil {
+			return nil, err
+		}
+		return helloExtension{"name": "signature_algorithms", "supported_signature_algorithms": schemes}, nil
+	case *utls.SignatureAlgorithmsCertExtension:
+		schemes, err := schemeNames(e.SupportedSignatureAlgorithms)
+		if err != nil {
+			return nil, err
+		}
+		return helloExtension{"name": "signature_algorithms_cert", "supported_signature_algorithms": schemes}, nil
+	case *utls.FakeDelegatedCredentialsExtension:
+		schemes, err := schemeNames(e.SupportedSignatureAlgorithms)
+		if err != nil {
+			return nil, err
+		}
+		return helloExtension{"name": "delegated_credentials", "supported_signature_algorithms": schemes}, nil
+	case *utls.ALPNExtension:
+		return helloExtension{"name": "application_layer_protocol_negotiation", "protocol_name_list": e.AlpnProtocols}, nil
+	case *utls.ApplicationSettingsExtension:
+		return helloExtension{"name": "application_settings", "supported_protocols": e.SupportedProtocols}, nil
+	case *utls.ApplicationSettingsExtensionNew:
+		return helloExtension{"name": "application_settings_new", "supported_protocols": e.SupportedProtocols}, nil
+	case *utls.UtlsCompressCertExtension:
+		algorithms := make([]string, 0, len(e.Algorithms))
+		for _, alg := range e.Algorithms {
+			name, err := lookupName(dicttls.DictCertificateCompressionAlgorithmValueIndexed, uint16(alg), "certificate compression algorithm")
+			if err != nil {
+				return nil, err
+			}
+			algorithms = append(algorithms, name)
+		}
+		return helloExtension{"name": "compress_certificate", "algorithms": algorithms}, nil
+	case *utls.FakeRecordSizeLimitExtension:
+		return helloExtension{"name": "record_size_limit", "record_size_limit": e.Limit}, nil
+	case *utls.KeyShareExtension:
+		shares := make([]map[string]any, 0, len(e.KeyShares))
+		for _, share := range e.KeyShares {
+			group, err := groupName(share.Group)
+			if err != nil {
+				return nil, err
+			}
+			s := map[string]any{"group": group}
+			if share.Group == utls.GREASE_PLACEHOLDER {
+				// GREASE shares carry a placeholder key that is sent as is
+				s["key_exchange"] = []int{0}
+			}
+			shares = append(shares, s)
+		}
+		return helloExtension{"name": "key_share", "client_shares": shares}, nil
+	case *utls.PSKKeyExchangeModesExtension:
+		modes := make([]string, 0, len(e.Modes))
+		for _, mode := range e.Modes {
+			name, err := lookupName(dicttls.DictPSKKeyExchangeModeValueIndexed, mode, "psk key exchange mode")
+			if err != nil {
+				return nil, err
+			}
+			modes = append(modes, name)
+		}
+		return helloExtension{"name": "psk_key_exchange_modes", "ke_modes": modes}, nil
+	case *utls.SupportedVersionsExtension:
+		versions := make([]string, 0, len(e.Versions))
+		for _, v := range e.Versions {
+			name, ok := tlsVersionNames[v]
+			if !ok {
+				return nil, fmt.Errorf("unknown tls version %#04x", v)
+			}
+			versions = append(versions, name)
+		}
+		return helloExtension{"name": "supported_versions", "versions": versions}, nil
+	default:
+		return nil, fmt.Errorf("extension %T has no document form", ext)
+	}
+}
+
+// helloExtension is an extension of a ClientHello document. It encodes with
+// its name first, so documents read the way they are written by hand.
+type helloExtension map[string]any
+
+func (e helloExtension) MarshalJSON() ([]byte, error) {
+	name, err := json.Marshal(e["name"])
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString(`{"name":`)
+	buf.Write(name)
+	for _, key := range slices.Sorted(maps.Keys(e)) {
+		if key == "name" {
+			continue
+		}
+		value, err := json.Marshal(e[key])
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(buf, ",%q:", key)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// tlsVersionNames are the names supported_versions uses in a ClientHello
+// document.
+var tlsVersionNames = map[uint16]string{
+	utls.GREASE_PLACEHOLDER: "GREASE",
+	utls.VersionTLS13:       "TLS 1.3",
+	utls.VersionTLS12:       "TLS 1.2",
+	utls.VersionTLS11:       "TLS 1.1",
+	utls.VersionTLS10:       "TLS 1.0",
+}
+
+func curveNames(curves []utls.CurveID) ([]string, error) {
+	names := make([]string, 0, len(curves))
+	for _, curve := range curves {
+		name, err := groupName(curve)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// groupName returns the name of curve in a ClientHello document.
+func groupName(curve utls.CurveID) (string, error) {
+	for name, hybrid := range hybridGroups {
+		if curve == hybrid {
+			return name, nil
+		}
+	}
+	return lookupName(dicttls.DictSupportedGroupsValueIndexed, uint16(curve), "named group")
+}
+
+func schemeNames(schemes []utls.SignatureScheme) ([]string, error) {
+	names := make([]string, 0, len(schemes))
+	for _, scheme := range schemes {
+		name, err := lookupName(dicttls.DictSignatureSchemeValueIndexed, uint16(scheme), "signature scheme")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// lookupName returns the IANA name of id, or "GREASE" for GREASE values.
+func lookupName[T uint8 | uint16](dict map[T]string, id T, kind string) (string, error) {
+	if uint16(id) == utls.GREASE_PLACEHOLDER {
+		return "GREASE", nil
+	}
+	name, ok := dict[id]
+	if !ok {
+		return "", fmt.Errorf("unknown %s %#x", kind, id)
+	}
+	return name, nil
+}
+
+// Capture file magic numbers, as written on a little-endian host.
+const (
+	pcapMagic      = 0xa1b2c3d4
+	pcapMagicNanos = 0xa1b23c4d
+	pcapngMagic    = 0x0a0d0d0a
+)
+
+// clientHelloRecord finds the ClientHello in data and returns it as a single
+// TLS record.
+func clientHelloRecord(data []byte) ([]byte, error) {
+	if len(data) >= 4 {
+		magic := binary.LittleEndian.Uint32(data)
+		switch {
+		case magic == pcapngMagic:
+			return helloFromPackets(data, readPcapng)
+		case magic == pcapMagic || magic == pcapMagicNanos ||
+			binary.BigEndian.Uint32(data) == pcapMagic || binary.BigEndian.Uint32(data) == pcapMagicNanos:
+			return helloFromPackets(data, readPcap)
+		}
+	}
+
+	if text := strings.Join(strings.Fields(string(data)), ""); text != "" {
+		if decoded, err := hex.DecodeString(text); err == nil {
+			data = decoded
+		}
+	}
+
+	if hello, ok := helloFromStream(data); ok {
+		return hello, nil
+	}
+	if len(data) > 4 && data[0] == 1 {
+		// a bare handshake message; wrap it in the record it was sent in
+		return helloRecord(0x0301, data), nil
+	}
+	return nil, ErrNoClientHello
+}
+
+// helloRecord wraps a ClientHello handshake message in a TLS record.
+func helloRecord(version uint16, handshake []byte) []byte {
+	record := make([]byte, 5, 5+len(handshake))
+	record[0] = 0x16
+	binary.BigEndian.PutUint16(record[1:], version)
+	binary.BigEndian.PutUint16(record[3:], uint16(min(len(handshake), 0xffff)))
+	return append(record, handshake...)
+}
+
+// helloFromStream returns the ClientHello at the start of a TCP stream as a
+// single record, joining a hello split across several records. It reports
+// false if the stream does not start with a ClientHello or ends before it.
+func helloFromStream(stream []byte) ([]byte, bool) {
+	if len(stream) < 5 || stream[0] != 0x16 {
+		return nil, false
+	}
+	version := binary.BigEndian.Uint16(stream[1:])
+
+	var handshake []byte
+	for len(stream) >= 5 && stream[0] == 0x16 {
+		n := int(binary.BigEndian.Uint16(stream[3:]))
+		if len(stream) < 5+n {
+			return nil, false
+		}
+		handshake = append(handshake, stream[5:5+n]...)
+		stream = stream[5+n:]
+
+		if len(handshake) >= 4 {
+			if handshake[0] != 1 {
+				return nil, false
+			}
+			size := 4 + (int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3]))
+			if len(handshake) >= size {
+				return helloRecord(version, handshake[:size]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// packet is one captured frame and the link type it was captured on.
+type packet struct {
+	linkType uint32
+	data     []byte
+}
+
+// helloFromPackets reassembles the TCP streams in a capture read by read and
+// returns the first ClientHello sent on one of them.
+func helloFromPackets(data []byte, read func([]byte, func(packet)) error) ([]byte, error) {
+	type flow struct {
+		next   uint32
+		stream []byte
+	}
+	flows := make(map[string]*flow)
+
+	var hello []byte
+	err := read(data, func(p packet) {
+		if hello != nil {
+			return
+		}
+		key, seq, payload, ok := tcpPayload(p)
+		if !ok || len(payload) == 0 {
+			return
+		}
+
+		f, ok := flows[key]
+		if !ok {
+			if payload[0] != 0x16 {
+				return
+			}
+			f = &flow{next: seq}
+			flows[key] = f
+		}
+		if seq != f.next {
+			// a retransmission or a segment captured out of order
+			return
+		}
+		f.stream = append(f.stream, payload...)
+		f.next += uint32(len(payload))
+
+		if record, ok := helloFromStream(f.stream); ok {
+			hello = record
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if hello == nil {
+		return nil, ErrNoClientHello
+	}
+	return hello, nil
+}
+
+// readPcap calls fn with each packet of a pcap capture.
+func readPcap(data []byte, fn func(packet)) error {
+	if len(data) < 24 {
+		return errors.New("truncated pcap header")
+	}
+	order := binary.ByteOrder(binary.LittleEndian)
+	if m := binary.BigEndian.Uint32(data); m == pcapMagic || m == pcapMagicNanos {
+		order = binary.BigEndian
+	}
+	linkType := order.Uint32(data[20:]) & 0x0fffffff
+
+	for data = data[24:]; len(data) >= 16; {
+		n := int(order.Uint32(data[8:]))
+		if len(data) < 16+n {
+			return errors.New("truncated pcap record")
+		}
+		fn(packet{linkType: linkType, data: data[16 : 16+n]})
+		data = data[16+n:]
+	}
+	return nil
+}
+
+// readPcapng calls fn with each packet of a pcapng capture.
+func readPcapng(data []byte, fn func(packet)) error {
+	var order binary.ByteOrder = binary.LittleEndian
+	var links []uint32
+
+	for len(data) >= 12 {
+		if binary.LittleEndian.Uint32(data) == pcapngMagic {
+			// a section header sets the byte order of the blocks after it
+			if binary.BigEndian.Uint32(data[8:]) == 0x1a2b3c4d {
+				order = binary.BigEndian
+			} else {
+				order = binary.LittleEndian
+			}
+			links = links[:0]
+		}
+
+		blockType, n := order.Uint32(data), int(order.Uint32(data[4:]))
+		if n < 12 || n > len(data) {
+			return errors.New("truncated pcapng block")
+		}
+		body := data[8 : n-4]
+
+		switch blockType {
+		case 1: // interface description
+			if len(body) >= 2 {
+				links = append(links, uint32(order.Uint16(body)))
+			}
+		case 3: // simple packet, always on the first interface
+			if len(body) >= 4 && len(links) > 0 {
+				size := min(int(order.Uint32(body)), len(body)-4)
+				fn(packet{linkType: links[0], data: body[4 : 4+size]})
+			}
+		case 6: // enhanced packet
+			if len(body) >= 20 {
+				iface, size := int(order.Uint32(body)), int(order.Uint32(body[12:]))
+				if iface < len(links) && 20+size <= len(body) {
+					fn(packet{linkType: links[iface], data: body[20 : 20+size]})
+				}
+			}
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// tcpPayload decodes a captured frame down to TCP and returns its flow, its
+// sequence number, and its payload.
+func tcpPayload(p packet) (flow string, seq uint32, payload []byte, ok bool) {
+	ip := p.data
+	switch p.linkType {
+	case 1: // Ethernet
+		if len(ip) < 14 {
+			return "", 0, nil, false
+		}
+		etherType, rest := binary.BigEndian.Uint16(ip[12:]), ip[14:]
+		for (etherType == 0x8100 || etherType == 0x88a8) && len(rest) >= 4 {
+			etherType, rest = binary.BigEndian.Uint16(rest[2:]), rest[4:]
+		}
+		ip = rest
+	case 0, 108: // BSD loopback
+		if len(ip) < 4 {
+			return "", 0, nil, false
+		}
+		ip = ip[4:]
+	case 113: // Linux cooked capture
+		if len(ip) < 16 {
+			return "", 0, nil, false
+		}
+		ip = ip[16:]
+	case 276: // Linux cooked capture v2
+		if len(ip) < 20 {
+			return "", 0, nil, false
+		}
+		ip = ip[20:]
+	case 12, 14, 101, 228, 229: // raw IP
+	default:
+		return "", 0, nil, false
+	}
+
+	var src, dst, tcp []byte
+	switch {
+	case len(ip) >= 20 && ip[0]>>4 == 4:
+		headerLen, total := int(ip[0]&0x0f)*4, int(binary.BigEndian.Uint16(ip[2:]))
+		if ip[9] != 6 || headerLen < 20 || total < headerLen || total > len(ip) {
+			return "", 0, nil, false
+		}
+		src, dst, tcp = ip[12:16], ip[16:20], ip[headerLen:total]
+	case len(ip) >= 40 && ip[0]>>4 == 6:
+		total := 40 + int(binary.BigEndian.Uint16(ip[4:]))
+		if ip[6] != 6 || total > len(ip) {
+			return "", 0, nil, false
+		}
+		src, dst, tcp = ip[8:24], ip[24:40], ip[40:total]
+	default:
+		return "", 0, nil, false
+	}
+
+	if len(tcp) < 20 {
+		return "", 0, nil, false
+	}
+	headerLen := int(tcp[12]>>4) * 4
+	if headerLen < 20 || headerLen > len(tcp) {
+		return "", 0, nil, false
+	}
+
+	var key bytes.Buffer
+	key.Write(src)
+	key.Write(tcp[0:2])
+	key.Write(dst)
+	key.Write(tcp[2:4])
+	return key.String(), binary.BigEndian.Uint32(tcp[4:]), tcp[headerLen:], true
+}
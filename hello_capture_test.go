@@ -0,0 +1,189 @@
+package mimic
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"slices"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// captureHello returns the ClientHello record utls sends for id.
+func captureHello(t *testing.T, id utls.ClientHelloID) []byte {
+	t.Helper()
+
+	client, server := net.Pipe()
+	defer server.Close()
+
+	conn := utls.UClient(client, &utls.Config{ServerName: "example.com"}, id)
+	go func() {
+		conn.Handshake()
+		conn.Close()
+	}()
+
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(server, header); err != nil {
+		t.Fatal(err)
+	}
+	record := make([]byte, 5+int(binary.BigEndian.Uint16(header[3:])))
+	copy(record, header)
+	if _, err := io.ReadFull(server, record[5:]); err != nil {
+		t.Fatal(err)
+	}
+	return record
+}
+
+func extensionTypes(exts []utls.TLSExtension) []reflect.Type {
+	types := make([]reflect.Type, len(exts))
+	for i, ext := range exts {
+		types[i] = reflect.TypeOf(ext)
+	}
+	return types
+}
+
+func TestParseCapturedHello(t *testing.T) {
+	for _, id := range []utls.ClientHelloID{chromiumTLSHelloID(137), utls.HelloFirefox_120, utls.HelloIOS_14} {
+		t.Run(id.Str(), func(t *testing.T) {
+			record := captureHello(t, id)
+
+			hello, err := ParseCapturedHello(record)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want, err := utls.UTLSIdToSpec(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := hello.ClientHelloSpec()
+			if !slices.Equal(got.CipherSuites, want.CipherSuites) {
+				t.Errorf("want cipher suites %v; got %v", want.CipherSuites, got.CipherSuites)
+			}
+			if len(got.Extensions) != len(want.Extensions) {
+				t.Errorf("want %d extensions; got %d", len(want.Extensions), len(got.Extensions))
+			}
+
+			// the document loads back into the same hello
+			doc, err := json.Marshal(hello)
+			if err != nil {
+				t.Fatal(err)
+			}
+			loaded, err := ParseCustomHello(doc)
+			if err != nil {
+				t.Fatalf("%s: %v", doc, err)
+			}
+			if want, got := extensionTypes(got.Extensions), extensionTypes(loaded.ClientHelloSpec().Extensions); !slices.Equal(want, got) {
+				t.Errorf("want extensions %v; got %v", want, got)
+			}
+
+			// the handshake message alone, and both as hex, parse the same
+			for _, data := range [][]byte{record[5:], []byte(hex.EncodeToString(record)), []byte(hex.EncodeToString(record[5:]))} {
+				other, err := ParseCapturedHello(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(other.ClientHelloSpec().CipherSuites, got.CipherSuites) {
+					t.Error("want the same hello from every form")
+				}
+			}
+		})
+	}
+
+	if _, err := ParseCapturedHello([]byte("not a hello")); !errors.Is(err, ErrNoClientHello) {
+		t.Errorf("want ErrNoClientHello; got %v", err)
+	}
+}
+
+// tcpFrame returns an Ethernet frame carrying payload in a TCP segment from
+// port 50000 to 443.
+func tcpFrame(seq uint32, payload []byte) []byte {
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], 50000)
+	binary.BigEndian.PutUint16(tcp[2:], 443)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12] = 5 << 4
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 20, 20+len(tcp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)))
+	ip[9] = 6
+	copy(ip[12:], []byte{10, 0, 0, 1, 93, 184, 215, 14})
+	ip = append(ip, tcp...)
+
+	frame := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(frame[12:], 0x0800)
+	return append(frame, ip...)
+}
+
+func TestParseCapturedHelloPcap(t *testing.T) {
+	record := captureHello(t, chromiumTLSHelloID(137))
+
+	// the hello spans two segments, the first of them retransmitted, after
+	// an unrelated packet on the same flow
+	frames := [][]byte{
+		tcpFrame(1, nil),
+		tcpFrame(1, record[:1000]),
+		tcpFrame(1, record[:1000]),
+		tcpFrame(1001, record[1000:]),
+	}
+
+	var pcap bytes.Buffer
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, pcapMagic)
+	binary.LittleEndian.PutUint32(header[20:], 1)
+	pcap.Write(header)
+	for _, frame := range frames {
+		rec := make([]byte, 16)
+		binary.LittleEndian.PutUint32(rec[8:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(rec[12:], uint32(len(frame)))
+		pcap.Write(rec)
+		pcap.Write(frame)
+	}
+
+	var pcapng bytes.Buffer
+	block := func(blockType uint32, body []byte) {
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+		b := binary.LittleEndian.AppendUint32(nil, blockType)
+		b = binary.LittleEndian.AppendUint32(b, uint32(12+len(body)))
+		b = append(b, body...)
+		b = binary.LittleEndian.AppendUint32(b, uint32(12+len(body)))
+		pcapng.Write(b)
+	}
+	block(pcapngMagic, []byte{0x4d, 0x3c, 0x2b, 0x1a, 1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	block(1, []byte{1, 0, 0, 0, 0, 0, 0, 0})
+	for _, frame := range frames {
+		body := make([]byte, 20, 20+len(frame))
+		binary.LittleEndian.PutUint32(body[12:], uint32(len(frame)))
+		binary.LittleEndian.PutUint32(body[16:], uint32(len(frame)))
+		block(6, append(body, frame...))
+	}
+
+	want, err := ParseCapturedHello(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, data := range map[string][]byte{"pcap": pcap.Bytes(), "pcapng": pcapng.Bytes()} {
+		hello, err := ParseCapturedHello(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want, got := extensionTypes(want.ClientHelloSpec().Extensions), extensionTypes(hello.ClientHelloSpec().Extensions); !slices.Equal(want, got) {
+			t.Errorf("%s: want extensions %v; got %v", name, want, got)
+		}
+	}
+
+	if _, err := ParseCapturedHello(pcap.Bytes()[:24]); !errors.Is(err, ErrNoClientHello) {
+		t.Errorf("empty capture: want ErrNoClientHello; got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package mimic
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"sync"
+)
+
+// tlsRecordHeaderLen is the length of a TLS record header: type, version, length.
+const tlsRecordHeaderLen = 5
+
+// HelloFragmentation controls how the TLS ClientHello is written to the socket.
+//
+// Browsers send the ClientHello as a single TLS record in a single write, and
+// the kernel splits it into TCP segments at the path MSS. Large post-quantum
+// hellos span two segments that way, which is what the zero value reproduces.
+// Setting the fields changes the record and segment boundaries that some DPI
+// keys on.
+type HelloFragmentation struct {
+	// RecordSize caps the handshake bytes in each TLS record the ClientHello
+	// is split into. A value of 0 sends one record.
+	RecordSize int
+
+	// SegmentSize caps the bytes in each write of the ClientHello to the
+	// socket. With TCP_NODELAY, which Go sets by default, each write leaves as
+	// its own segment. A value of 0 writes the records at once.
+	SegmentSize int
+}
+
+// WithHelloFragmentation splits the ClientHello of every connection the
+// transport dials as f describes. It wraps the base transport's DialContext,
+// so it has no effect on a base transport that sets DialTLSContext.
+func WithHelloFragmentation(f HelloFragmentation) TransportOption {
+	return func(c *transportConfig) {
+		c.helloFragmentation = &f
+	}
+}
+
+// dialContext wraps dial so the connections it returns write their
+// ClientHello as f describes.
+func (f HelloFragmentation) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &helloConn{Conn: conn, fragmentation: f}, nil
+	}
+}
+
+// helloConn splits the first ClientHello record written to it. Writes before
+// it, such as a proxy CONNECT request, and after it pass through unchanged.
+type helloConn struct {
+	net.Conn
+	fragmentation HelloFragmentation
+
+	mu   sync.Mutex
+	done bool
+}
+
+func (c *helloConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.done || !isClientHelloRecord(p) {
+		return c.Conn.Write(p)
+	}
+	c.done = true
+
+	recordLen := tlsRecordHeaderLen + int(binary.BigEndian.Uint16(p[3:5]))
+	out := append(fragmentRecord(p[:recordLen], c.fragmentation.RecordSize), p[recordLen:]...)
+
+	segment := c.fragmentation.SegmentSize
+	if segment <= 0 {
+		segment = len(out)
+	}
+
+	for written := 0; written < len(out); {
+		n, err := c.Conn.Write(out[written:min(written+segment, len(out))])
+		if err != nil {
+			// the split bytes do not map back onto p, and the handshake fails
+			// on any short write anyway
+			return 0, err
+		}
+		written += n
+	}
+
+	return len(p), nil
+}
+
+// isClientHelloRecord reports whether p starts with a complete TLS handshake
+// record holding a ClientHello.
+func isClientHelloRecord(p []byte) bool {
+	if len(p) < tlsRecordHeaderLen+1 || p[0] != 0x16 || p[1] != 0x03 || p[5] != 0x01 {
+		return false
+	}
+	return len(p) >= tlsRecordHeaderLen+int(binary.BigEndian.Uint16(p[3:5]))
+}
+
+// fragmentRecord splits a TLS record into records carrying at most size bytes
+// of its payload each, under the same type and version.
+func fragmentRecord(record []byte, size int) []byte {
+	payload := record[tlsRecordHeaderLen:]
+	if size <= 0 || len(payload) <= size {
+		return append([]byte(nil), record...)
+	}
+
+	out := make([]byte, 0, len(payload)+(len(payload)/size+1)*tlsRecordHeaderLen)
+	for len(payload) > 0 {
+		n := min(size, len(payload))
+		out = append(out, record[0], record[1], record[2], byte(n>>8), byte(n))
+		out = append(out, payload[:n]...)
+		payload = payload[n:]
+	}
+	return out
+}
@@ -0,0 +1,122 @@
+package mimic
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// recordingConn records the size of each write and the bytes written.
+type recordingConn struct {
+	net.Conn
+
+	mu      sync.Mutex
+	writes  []int
+	written bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	c.writes = append(c.writes, len(p))
+	c.written.Write(p)
+	c.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+func TestHelloFragmentation(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	var conn *recordingConn
+	base := &http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			c, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			conn = &recordingConn{Conn: c}
+			return conn, nil
+		},
+	}
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fragmentation := HelloFragmentation{RecordSize: 500, SegmentSize: 300}
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base), WithHelloFragmentation(fragmentation))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+
+	// walk the records of the ClientHello, which Chrome 137's post-quantum key
+	// share makes larger than 1500 bytes
+	data := conn.written.Bytes()
+	total := 4 + (int(data[6])<<16 | int(data[7])<<8 | int(data[8]))
+	var helloLen, records int
+	for helloLen < total {
+		if len(data) < tlsRecordHeaderLen || data[0] != 0x16 {
+			t.Fatalf("want handshake record %d; got %x", records, data[:min(len(data), tlsRecordHeaderLen)])
+		}
+		n := int(binary.BigEndian.Uint16(data[3:5]))
+		if n > fragmentation.RecordSize {
+			t.Fatalf("want records of at most %d bytes; got %d", fragmentation.RecordSize, n)
+		}
+		helloLen += n
+		records++
+		data = data[tlsRecordHeaderLen+n:]
+	}
+	if records < 4 {
+		t.Errorf("want the hello split into at least 4 records; got %d", records)
+	}
+
+	for i, n := range conn.writes[:records] {
+		if n > fragmentation.SegmentSize {
+			t.Errorf("write %d: want at most %d bytes; got %d", i, fragmentation.SegmentSize, n)
+		}
+	}
+}
+
+func TestFragmentRecord(t *testing.T) {
+	record := append([]byte{0x16, 0x03, 0x01, 0x00, 0x05}, "hello"...)
+
+	if got := fragmentRecord(record, 0); !bytes.Equal(got, record) {
+		t.Errorf("want record unchanged; got %x", got)
+	}
+
+	want := []byte{
+		0x16, 0x03, 0x01, 0x00, 0x02, 'h', 'e',
+		0x16, 0x03, 0x01, 0x00, 0x02, 'l', 'l',
+		0x16, 0x03, 0x01, 0x00, 0x01, 'o',
+	}
+	if got := fragmentRecord(record, 2); !bytes.Equal(got, want) {
+		t.Errorf("want %x; got %x", want, got)
+	}
+}
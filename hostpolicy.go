@@ -0,0 +1,116 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which
+// netip.Addr.IsPrivate does not cover.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// HostPolicy decides whether a Transport may connect to host at ip, returning
+// an error to refuse.
+type HostPolicy func(host string, ip netip.Addr) error
+
+// WithHostPolicy checks every connection the Transport dials against policy
+// before dialing it. Host names are resolved first and policy is asked about
+// each address; the connection is made to the first address it allows, so a
+// name cannot resolve to an allowed address for the check and a refused one
+// for the dial. A refused dial fails the request with a HostPolicyError.
+//
+// Through a proxy, policy is asked about the proxy, since the proxy resolves
+// and dials the request's host. WithConnectTo and WithAddressRewriter are
+// applied first, so policy sees the address actually dialed; unix domain
+// sockets are not checked.
+func WithHostPolicy(policy HostPolicy) TransportOption {
+	return func(c *transportConfig) {
+		c.hostPolicy = policy
+	}
+}
+
+// DenyPrivateNetworks is a HostPolicy that refuses addresses a public
+// service should not be made to reach on a caller's behalf: loopback,
+// private, carrier-grade NAT, link-local, including cloud metadata endpoints
+// such as 169.254.169.254, multicast, and unspecified addresses.
+func DenyPrivateNetworks(host string, ip netip.Addr) error {
+	ip = ip.Unmap()
+	switch {
+	case ip.IsLoopback():
+		return errors.New("loopback address")
+	case ip.IsPrivate(), sharedAddressSpace.Contains(ip):
+		return errors.New("private address")
+	case ip.IsLinkLocalUnicast(), ip.IsLinkLocalMulticast():
+		return errors.New("link-local address")
+	case ip.IsMulticast(), ip.IsInterfaceLocalMulticast():
+		return errors.New("multicast address")
+	case ip.IsUnspecified():
+		return errors.New("unspecified address")
+	}
+	return nil
+}
+
+// dialContext resolves the host of each dialed address, and dials the first
+// of its addresses policy allows.
+func (policy HostPolicy) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if !strings.HasPrefix(network, "tcp") && !strings.HasPrefix(network, "udp") {
+			// unix sockets are named by the Transport's owner, not by requests
+			return dial(ctx, network, addr)
+		}
+
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		var ips []netip.Addr
+		if ip, err := netip.ParseAddr(host); err == nil {
+			ips = []netip.Addr{ip}
+		} else {
+			ipNetwork := "ip"
+			switch network {
+			case "tcp4":
+				ipNetwork = "ip4"
+			case "tcp6":
+				ipNetwork = "ip6"
+			}
+			ips, err = net.DefaultResolver.LookupNetIP(ctx, ipNetwork, host)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var refused error
+		var dialErr error
+		for _, ip := range ips {
+			if err := policy(host, ip); err != nil {
+				if refused == nil {
+					refused = &HostPolicyError{Host: host, IP: ip, Err: err}
+				}
+				continue
+			}
+
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.Unmap().String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+
+		switch {
+		case dialErr != nil:
+			return nil, dialErr
+		case refused != nil:
+			return nil, refused
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+}
@@ -0,0 +1,87 @@
+package mimic
+
+import (
+	"errors"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"net/netip"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestDenyPrivateNetworks(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.215.14":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"192.168.0.1":     false,
+		"100.64.0.1":      false,
+		"169.254.169.254": false,
+		"::1":             false,
+		"fd00:ec2::254":   false,
+		"::ffff:10.0.0.1": false,
+		"0.0.0.0":         false,
+		"224.0.0.1":       false,
+	}
+
+	for addr, allowed := range tests {
+		err := DenyPrivateNetworks("example.com", netip.MustParseAddr(addr))
+		if (err == nil) != allowed {
+			t.Errorf("%s: err = %v, want allowed %v", addr, err, allowed)
+		}
+	}
+}
+
+func TestRoundTripHostPolicy(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		policy HostPolicy
+		want   error
+	}{
+		{"deny", DenyPrivateNetworks, ErrHostRefused},
+		{"allow", func(string, netip.Addr) error { return nil }, nil},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+			transport, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base), WithHostPolicy(tt.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := transport.RoundTrip(req)
+			if res != nil {
+				res.Body.Close()
+			}
+
+			if tt.want == nil {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			var policyErr *HostPolicyError
+			if !errors.Is(err, tt.want) || !errors.As(err, &policyErr) || policyErr.IP.String() != "127.0.0.1" {
+				t.Errorf("want a HostPolicyError for 127.0.0.1; got %v", err)
+			}
+		})
+	}
+}
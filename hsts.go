@@ -0,0 +1,254 @@
+package mimic
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// maxHSTSAge is the longest policy Chrome keeps, whatever max-age a server sends.
+const maxHSTSAge = 365 * 24 * time.Hour
+
+// PreloadedTLDs is a snapshot of the top-level domains on Chromium's HSTS
+// preload list. Every host under them is HTTPS-only in all major browsers.
+var PreloadedTLDs = []string{
+	"android", "app", "bank", "boo", "chrome", "dad", "day", "dev", "eat",
+	"esq", "fly", "foo", "gle", "gmail", "google", "hangout", "ing",
+	"insurance", "meet", "meme", "mov", "new", "nexus", "page", "phd", "prof",
+	"rsvp", "search", "youtube", "zip",
+}
+
+// HSTSStore remembers which hosts must only be reached over HTTPS, the way
+// browsers do: from the Strict-Transport-Security headers of HTTPS responses,
+// and from a preload list that never expires.
+// The zero value is an empty store ready to use.
+type HSTSStore struct {
+	mu      sync.Mutex
+	dynamic map[string]hstsPolicy
+	preload map[string]hstsPolicy
+}
+
+type hstsPolicy struct {
+	includeSubdomains bool
+	expires           time.Time
+}
+
+// WithHSTS upgrades requests for http:// URLs to https:// before they are sent
+// when store has a policy for the host, and records the
+// Strict-Transport-Security headers of HTTPS responses in store. As in
+// browsers, the header is ignored on plaintext responses and for IP addresses.
+func WithHSTS(store *HSTSStore) TransportOption {
+	return func(c *transportConfig) {
+		c.hsts = store
+	}
+}
+
+// ParseHSTS parses a Strict-Transport-Security header value. A max-age of 0
+// means the host's policy should be removed; longer ones are capped at a year,
+// as Chrome caps them.
+func ParseHSTS(value string) (maxAge time.Duration, includeSubdomains bool, err error) {
+	seen := make(map[string]bool)
+	hasMaxAge := false
+
+	for _, directive := range splitQuoted(value, ';') {
+		name, val, _ := strings.Cut(directive, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		val = strings.Trim(strings.TrimSpace(val), `"`)
+		if name == "" {
+			continue
+		}
+
+		if seen[name] {
+			return 0, false, fmt.Errorf("duplicate directive %q", name)
+		}
+		seen[name] = true
+
+		switch name {
+		case "max-age":
+			seconds, err := strconv.ParseInt(val, 10, 64)
+			if err != nil || seconds < 0 {
+				return 0, false, fmt.Errorf("invalid max-age %q", val)
+			}
+			maxAge = time.Duration(min(seconds, int64(maxHSTSAge/time.Second))) * time.Second
+			hasMaxAge = true
+		case "includesubdomains":
+			includeSubdomains = true
+		}
+	}
+
+	if !hasMaxAge {
+		return 0, false, errors.New("missing max-age")
+	}
+
+	return maxAge, includeSubdomains, nil
+}
+
+// Preload adds hosts that are HTTPS-only, along with all of their subdomains,
+// for as long as the store exists. Pass PreloadedTLDs for the TLDs browsers
+// preload, or load Chromium's full list with LoadChromiumPreload.
+func (s *HSTSStore) Preload(hosts ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.preload == nil {
+		s.preload = make(map[string]hstsPolicy, len(hosts))
+	}
+	for _, host := range hosts {
+		s.preload[normalizeHSTSHost(host)] = hstsPolicy{includeSubdomains: true}
+	}
+}
+
+// LoadChromiumPreload adds the force-https entries of Chromium's
+// transport_security_state_static.json preload list to the store.
+func (s *HSTSStore) LoadChromiumPreload(r io.Reader) error {
+	// the file is JSON with // line comments
+	var (
+		src     strings.Builder
+		scanner = bufio.NewScanner(r)
+	)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+		src.WriteString(line)
+		src.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading preload list: %w", err)
+	}
+
+	var list struct {
+		Entries []struct {
+			Name              string `json:"name"`
+			Mode              string `json:"mode"`
+			IncludeSubdomains bool   `json:"include_subdomains"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal([]byte(src.String()), &list); err != nil {
+		return fmt.Errorf("decoding preload list: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.preload == nil {
+		s.preload = make(map[string]hstsPolicy, len(list.Entries))
+	}
+	for _, entry := range list.Entries {
+		if entry.Mode != "force-https" {
+			continue
+		}
+		s.preload[normalizeHSTSHost(entry.Name)] = hstsPolicy{includeSubdomains: entry.IncludeSubdomains}
+	}
+
+	return nil
+}
+
+// Match reports whether requests to host must use HTTPS.
+func (s *HSTSStore) Match(host string) bool {
+	host = normalizeHSTSHost(host)
+	if net.ParseIP(host) != nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	candidate, exact := host, true
+	for {
+		if s.matchLocked(s.preload, candidate, exact, now) || s.matchLocked(s.dynamic, candidate, exact, now) {
+			return true
+		}
+
+		_, parent, ok := strings.Cut(candidate, ".")
+		if !ok {
+			return false
+		}
+		candidate, exact = parent, false
+	}
+}
+
+// matchLocked reports whether policies has an unexpired policy for host that
+// applies to it directly, or to its subdomains when exact is false. The caller
+// must hold s.mu.
+func (s *HSTSStore) matchLocked(policies map[string]hstsPolicy, host string, exact bool, now time.Time) bool {
+	policy, ok := policies[host]
+	if !ok {
+		return false
+	}
+
+	if !policy.expires.IsZero() && !now.Before(policy.expires) {
+		delete(policies, host)
+		return false
+	}
+
+	return exact || policy.includeSubdomains
+}
+
+// Clear forgets the policy host sent. Preloaded hosts are not affected.
+func (s *HSTSStore) Clear(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.dynamic, normalizeHSTSHost(host))
+}
+
+// record updates the store from a response's Strict-Transport-Security
+// header. Invalid headers are ignored, as browsers ignore them.
+func (s *HSTSStore) record(res *http.Response, now time.Time) {
+	value := res.Header.Get("Strict-Transport-Security")
+	if value == "" || res.TLS == nil || res.Request == nil {
+		return
+	}
+
+	host := normalizeHSTSHost(res.Request.URL.Hostname())
+	if net.ParseIP(host) != nil {
+		return
+	}
+
+	maxAge, includeSubdomains, err := ParseHSTS(value)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if maxAge == 0 {
+		delete(s.dynamic, host)
+		return
+	}
+
+	if s.dynamic == nil {
+		s.dynamic = make(map[string]hstsPolicy)
+	}
+	s.dynamic[host] = hstsPolicy{includeSubdomains: includeSubdomains, expires: now.Add(maxAge)}
+}
+
+// hstsUpgrade returns u with the https scheme. The default HTTP port becomes the
+// default HTTPS port; any other port is kept.
+func hstsUpgrade(u *url.URL) *url.URL {
+	upgraded := *u
+	upgraded.Scheme = "https"
+	if u.Port() == "80" {
+		upgraded.Host = u.Hostname()
+	}
+	return &upgraded
+}
+
+func normalizeHSTSHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,125 @@
+package mimic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestParseHSTS(t *testing.T) {
+	tests := []struct {
+		value             string
+		maxAge            time.Duration
+		includeSubdomains bool
+		wantErr           bool
+	}{
+		{value: "max-age=31536000", maxAge: 365 * 24 * time.Hour},
+		{value: `max-age="600"; includeSubDomains; preload`, maxAge: 10 * time.Minute, includeSubdomains: true},
+		{value: "max-age=0", maxAge: 0},
+		{value: "max-age=63072000", maxAge: maxHSTSAge},
+		{value: "includeSubDomains", wantErr: true},
+		{value: "max-age=1; max-age=2", wantErr: true},
+		{value: "max-age=-1", wantErr: true},
+	}
+
+	for _, test := range tests {
+		maxAge, includeSubdomains, err := ParseHSTS(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%q: want error %t; got %v", test.value, test.wantErr, err)
+			continue
+		}
+		if maxAge != test.maxAge || includeSubdomains != test.includeSubdomains {
+			t.Errorf("%q: want %v, %t; got %v, %t", test.value, test.maxAge, test.includeSubdomains, maxAge, includeSubdomains)
+		}
+	}
+}
+
+func TestHSTSStore(t *testing.T) {
+	var store HSTSStore
+	store.Preload(PreloadedTLDs...)
+
+	if !store.Match("example.dev") || store.Match("example.com") {
+		t.Error("want only the preloaded TLD to match")
+	}
+
+	record := func(rawURL, value string) {
+		req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.record(&http.Response{
+			Header:  http.Header{"Strict-Transport-Security": {value}},
+			Request: req,
+			TLS:     &utls.ConnectionState{},
+		}, time.Now())
+	}
+
+	record("https://example.com", "max-age=3600")
+	if !store.Match("EXAMPLE.com.") || store.Match("www.example.com") {
+		t.Error("want the policy to cover only example.com")
+	}
+
+	record("https://example.com", "max-age=3600; includeSubDomains")
+	if !store.Match("www.example.com") {
+		t.Error("want includeSubDomains to cover www.example.com")
+	}
+
+	record("https://example.com", "max-age=0")
+	if store.Match("example.com") {
+		t.Error("want max-age=0 to remove the policy")
+	}
+
+	record("https://127.0.0.1", "max-age=3600")
+	if store.Match("127.0.0.1") {
+		t.Error("want IP addresses ignored")
+	}
+
+	const preload = `{
+  // comment
+  "entries": [
+    { "name": "preloaded.test", "policy": "custom", "mode": "force-https", "include_subdomains": true },
+    { "name": "pinned.test", "policy": "custom" }
+  ]
+}`
+	if err := store.LoadChromiumPreload(strings.NewReader(preload)); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Match("a.preloaded.test") || store.Match("pinned.test") {
+		t.Error("want only force-https entries loaded")
+	}
+}
+
+func TestRoundTripHSTS(t *testing.T) {
+	var store HSTSStore
+	store.Preload("example.dev")
+
+	tr := newTestTransport(t)
+	tr.hsts = &store
+
+	for _, test := range []struct{ url, want string }{
+		{"http://example.dev/path", "https://example.dev/path"},
+		{"http://example.dev:80/", "https://example.dev/"},
+		{"http://example.dev:8080/", "https://example.dev:8080/"},
+		{"http://example.com/", "http://example.com/"},
+	} {
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := res.Request.URL.String(); got != test.want {
+			t.Errorf("%s: want %s sent; got %s", test.url, test.want, got)
+		}
+		if req.URL.String() != test.url {
+			t.Errorf("%s: want caller's URL unchanged; got %s", test.url, req.URL)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// closeIdler is implemented by transports that can drop their idle connections.
+type closeIdler interface {
+	CloseIdleConnections()
+}
+
+// canceler is implemented by transports that support the deprecated
+// CancelRequest method.
+type canceler interface {
+	CancelRequest(req *http.Request)
+}
+
+// wrapped is embedded by the round trippers that wrap another. It holds the
+// wrapped transport and passes CloseIdleConnections and CancelRequest through
+// to it, so they reach the Transport under every wrapper.
+type wrapped struct {
+	transport http.RoundTripper
+}
+
+func (w wrapped) CloseIdleConnections() {
+	if c, ok := w.transport.(closeIdler); ok {
+		c.CloseIdleConnections()
+	}
+}
+
+func (w wrapped) CancelRequest(req *http.Request) {
+	if c, ok := w.transport.(canceler); ok {
+		c.CancelRequest(req)
+	}
+}
+
+// CloseIdleConnections closes connections in the pool that are not in use,
+// including idle HTTP/2 connections, without interrupting requests in flight.
+// Call it when rotating identities or shutting down so pools drain gracefully.
+func (t *Transport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	if t.engine != nil {
+		t.engine.CloseIdleConnections()
+	}
+	if t.pool != nil {
+		t.pool.closeIdleConnections()
+	}
+	if t.partitions != nil {
+		t.partitions.closeIdleConnections()
+	}
+}
+
+// Abort cancels an in-flight request the way a browser aborts a fetch. An
+// HTTP/2 request's stream is reset with CANCEL, leaving the connection and its
+// other streams open, and an HTTP/1.1 request's connection is closed. It works
+// until the response body is read to the end or closed; the request or body
+// read then fails with context.Canceled.
+func (t *Transport) Abort(req *http.Request) {
+	for _, cancel := range t.requests.cancels(req) {
+		cancel()
+	}
+}
+
+// CancelRequest cancels an in-flight request, as Abort does.
+//
+// Deprecated: Use Abort, or Request.WithContext to create a request with a
+// cancelable context.
+func (t *Transport) CancelRequest(req *http.Request) {
+	t.Abort(req)
+}
+
+// BaseTransport returns the underlying fhttp transport, for settings mimic does
+// not expose. The TLS and HTTP/2 configuration on it is what makes requests look
+// like the browser, so change those with care.
+func (t *Transport) BaseTransport() *http.Transport {
+	return t.base
+}
+
+// requestTracker maps requests passed to RoundTrip to the copies sent to the
+// base transport, each with a func canceling its context, so a request can be
+// aborted by the request the caller holds.
+type requestTracker struct {
+	mu       sync.Mutex
+	inflight map[*http.Request][]sentRequest
+}
+
+// sentRequest is a copy of a request sent to the base transport.
+type sentRequest struct {
+	req    *http.Request
+	cancel context.CancelFunc
+}
+
+func newRequestTracker() *requestTracker {
+	return &requestTracker{inflight: make(map[*http.Request][]sentRequest)}
+}
+
+// add tracks sent, returning it with a context Abort cancels.
+func (r *requestTracker) add(req, sent *http.Request) *http.Request {
+	ctx, cancel := context.WithCancel(sent.Context())
+	sent = sent.WithContext(ctx)
+
+	r.mu.Lock()
+	r.inflight[req] = append(r.inflight[req], sentRequest{req: sent, cancel: cancel})
+	r.mu.Unlock()
+
+	return sent
+}
+
+// remove stops tracking sent once it is done, releasing its context.
+func (r *requestTracker) remove(req, sent *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	copies := r.inflight[req]
+	i := slices.IndexFunc(copies, func(c sentRequest) bool { return c.req == sent })
+	if i < 0 {
+		return
+	}
+	copies[i].cancel()
+	copies = slices.Delete(copies, i, i+1)
+	if len(copies) == 0 {
+		delete(r.inflight, req)
+	} else {
+		r.inflight[req] = copies
+	}
+}
+
+func (r *requestTracker) sent(req *http.Request) []*http.Request {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var sent []*http.Request
+	for _, c := range r.inflight[req] {
+		sent = append(sent, c.req)
+	}
+	return sent
+}
+
+func (r *requestTracker) cancels(req *http.Request) []context.CancelFunc {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var cancels []context.CancelFunc
+	for _, c := range r.inflight[req] {
+		cancels = append(cancels, c.cancel)
+	}
+	return cancels
+}
+
+// trackedBody stops tracking its request once the body is read to the end,
+// fails, or is closed.
+type trackedBody struct {
+	io.ReadCloser
+	tracker *requestTracker
+	req     *http.Request
+	sent    *http.Request
+	done    atomic.Bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.finish()
+	}
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *trackedBody) finish() {
+	if b.done.CompareAndSwap(false, true) {
+		b.tracker.remove(b.req, b.sent)
+	}
+}
@@ -0,0 +1,209 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/httptest"
+)
+
+func TestTransportCancelRequest(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("partial"))
+		w.(http.Flusher).Flush()
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	tr.CancelRequest(req)
+
+	if _, err := io.ReadAll(res.Body); err == nil {
+		t.Fatal("want body read to fail after CancelRequest")
+	}
+
+	if sent := tr.requests.sent(req); len(sent) != 0 {
+		t.Errorf("want request untracked after body failed; got %d copies", len(sent))
+	}
+}
+
+func TestTransportAbortHTTP2(t *testing.T) {
+	canceled := make(chan struct{}, 1)
+	var conns atomic.Int32
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path != "/slow" {
+			return
+		}
+		w.Write([]byte("partial"))
+		w.(stdhttp.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+			canceled <- struct{}{}
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/slow", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr.Abort(req)
+
+	if _, err := io.ReadAll(res.Body); !errors.Is(err, context.Canceled) {
+		t.Fatalf("body read error = %v, want context.Canceled", err)
+	}
+	res.Body.Close()
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not see the stream canceled")
+	}
+
+	// the stream was reset, not the connection
+	next, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = tr.RoundTrip(next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if got := conns.Load(); got != 1 {
+		t.Errorf("connections = %d, want 1", got)
+	}
+}
+
+func TestTransportBaseTransport(t *testing.T) {
+	spec, err := Safari("18.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{}
+	tr, err := NewTransport(spec, PlatformMac, WithBaseTransport(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tr.BaseTransport() != base {
+		t.Error("want BaseTransport to return the configured base transport")
+	}
+
+	client, err := NewClient(spec, PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.Transport.(closeIdler); !ok {
+		t.Error("want client transport to pass CloseIdleConnections through")
+	}
+}
+
+// testClientClosesIdle checks that CloseIdleConnections on a client built with
+// opts reaches its Transport, so the next request dials again.
+func testClientClosesIdle(t *testing.T, opts ...ClientOption) {
+	t.Helper()
+
+	var conns atomic.Int32
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "ok")
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+		if state == stdhttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	opts = append([]ClientOption{WithTransportOptions(WithBaseTransport(base))}, opts...)
+	client, err := NewClient(spec, PlatformWindows, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	for range 2 {
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+
+		client.CloseIdleConnections()
+	}
+
+	if got := conns.Load(); got != 2 {
+		t.Errorf("connections = %d, want 2", got)
+	}
+}
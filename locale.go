@@ -0,0 +1,189 @@
+package mimic
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Locale is a region's language preferences, as a browser installed there is
+// set up by default.
+type Locale struct {
+	// Languages are the preferred languages as BCP 47 tags, most preferred
+	// first. Chromium and Safari add each region's base language after it,
+	// so "de-DE" is sent as "de-DE,de".
+	Languages []string
+
+	// FirefoxLanguages are the languages Firefox's localized build sends,
+	// which its language pack fixes, such as "de, en-US, en" for German.
+	// Languages are used when it is empty.
+	FirefoxLanguages []string
+
+	// TimeZone is the region's IANA time zone. HTTP carries no time zone, so
+	// it is for callers that report one elsewhere, such as in the values a
+	// script would read, to keep them agreeing with the headers.
+	TimeZone string
+}
+
+var (
+	LocaleUS = Locale{
+		Languages:        []string{"en-US"},
+		FirefoxLanguages: []string{"en-US", "en"},
+		TimeZone:         "America/New_York",
+	}
+	LocaleUK = Locale{
+		Languages:        []string{"en-GB", "en-US"},
+		FirefoxLanguages: []string{"en-GB", "en"},
+		TimeZone:         "Europe/London",
+	}
+	LocaleGermany = Locale{
+		Languages:        []string{"de-DE", "en-US"},
+		FirefoxLanguages: []string{"de", "en-US", "en"},
+		TimeZone:         "Europe/Berlin",
+	}
+	LocaleFrance = Locale{
+		Languages:        []string{"fr-FR", "en-US"},
+		FirefoxLanguages: []string{"fr", "fr-FR", "en-US", "en"},
+		TimeZone:         "Europe/Paris",
+	}
+	LocaleSpain = Locale{
+		Languages:        []string{"es-ES", "en-US"},
+		FirefoxLanguages: []string{"es-ES", "es", "en-US", "en"},
+		TimeZone:         "Europe/Madrid",
+	}
+	LocaleItaly = Locale{
+		Languages:        []string{"it-IT", "en-US"},
+		FirefoxLanguages: []string{"it-IT", "it", "en-US", "en"},
+		TimeZone:         "Europe/Rome",
+	}
+	LocaleJapan = Locale{
+		Languages:        []string{"ja", "en-US"},
+		FirefoxLanguages: []string{"ja", "en-US", "en"},
+		TimeZone:         "Asia/Tokyo",
+	}
+	LocaleBrazil = Locale{
+		Languages:        []string{"pt-BR", "en-US"},
+		FirefoxLanguages: []string{"pt-BR", "pt", "en-US", "en"},
+		TimeZone:         "America/Sao_Paulo",
+	}
+)
+
+// WithLocale sets the accept-language header the browser sends by default,
+// formatted from locale the way the browser formats its language settings.
+// Only applies to Chromium, Firefox, and Safari specs.
+func WithLocale(locale Locale) SpecOption {
+	return func(c *specConfig) {
+		c.locale = &locale
+	}
+}
+
+// buildHeaders returns buildHeaders with the accept-language set by WithLocale,
+// formatted by acceptLanguage.
+func (c *specConfig) buildHeaders(buildHeaders func(Platform) (http.Header, error), acceptLanguage func(Locale) string) func(Platform) (http.Header, error) {
+	if c.locale == nil {
+		return buildHeaders
+	}
+
+	value := acceptLanguage(*c.locale)
+	return func(p Platform) (http.Header, error) {
+		h, err := buildHeaders(p)
+		if err != nil {
+			return nil, err
+		}
+		if value != "" {
+			h.Set("accept-language", value)
+		}
+		return h, nil
+	}
+}
+
+// expandedAcceptLanguage formats the locale's languages as Chromium and Safari
+// do: each group of regional tags is followed by its base language unless the
+// list names it, and q-values fall by 0.1 per entry down to 0.1.
+func expandedAcceptLanguage(l Locale) string {
+	var expanded []string
+	for i, tag := range l.Languages {
+		expanded = append(expanded, tag)
+
+		base, _, regional := strings.Cut(tag, "-")
+		if !regional || slices.Contains(l.Languages, base) {
+			continue
+		}
+		if i+1 < len(l.Languages) && strings.HasPrefix(l.Languages[i+1], base+"-") {
+			continue
+		}
+		expanded = append(expanded, base)
+	}
+
+	var b strings.Builder
+	q := 10
+	for i, tag := range expanded {
+		if i > 0 {
+			fmt.Fprintf(&b, ",%s;q=0.%d", tag, q)
+		} else {
+			b.WriteString(tag)
+		}
+		q = max(q-1, 1)
+	}
+	return b.String()
+}
+
+// firefoxAcceptLanguage formats the locale's Firefox languages as Firefox
+// does, spreading q-values evenly from 1 and rounding them to one decimal.
+func firefoxAcceptLanguage(l Locale) string {
+	languages := l.FirefoxLanguages
+	if len(languages) == 0 {
+		languages = l.Languages
+	}
+
+	var b strings.Builder
+	for i, tag := range languages {
+		if i == 0 {
+			b.WriteString(tag)
+			continue
+		}
+		q := math.Round(10 * (1 - float64(i)/float64(len(languages))))
+		fmt.Fprintf(&b, ",%s;q=0.%d", tag, max(int(q), 1))
+	}
+	return b.String()
+}
+
+// Preset is a browser on a platform in a region, so that every header it sends
+// agrees on where the user is.
+type Preset struct {
+	Platform Platform
+	Locale   Locale
+
+	newSpec func(version string, opts ...SpecOption) (*ClientSpec, error)
+}
+
+// Spec creates the preset's ClientSpec for version, with opts applied after the
+// preset's own.
+func (p Preset) Spec(version string, opts ...SpecOption) (*ClientSpec, error) {
+	return p.newSpec(version, slices.Concat([]SpecOption{WithLocale(p.Locale)}, opts)...)
+}
+
+func desktopChrome(locale Locale) Preset {
+	return Preset{
+		Platform: PlatformWindows,
+		Locale:   locale,
+		newSpec: func(version string, opts ...SpecOption) (*ClientSpec, error) {
+			return Chromium(BrandChrome, version, opts...)
+		},
+	}
+}
+
+// Presets for Chrome on Windows in each region.
+var (
+	PresetUSDesktopChrome      = desktopChrome(LocaleUS)
+	PresetUKDesktopChrome      = desktopChrome(LocaleUK)
+	PresetGermanyDesktopChrome = desktopChrome(LocaleGermany)
+	PresetFranceDesktopChrome  = desktopChrome(LocaleFrance)
+	PresetSpainDesktopChrome   = desktopChrome(LocaleSpain)
+	PresetItalyDesktopChrome   = desktopChrome(LocaleItaly)
+	PresetJapanDesktopChrome   = desktopChrome(LocaleJapan)
+	PresetBrazilDesktopChrome  = desktopChrome(LocaleBrazil)
+)
@@ -0,0 +1,79 @@
+package mimic
+
+import "testing"
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		locale   Locale
+		chromium string
+		firefox  string
+	}{
+		{LocaleUS, "en-US,en;q=0.9", "en-US,en;q=0.5"},
+		{LocaleUK, "en-GB,en-US;q=0.9,en;q=0.8", "en-GB,en;q=0.5"},
+		{LocaleGermany, "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7", "de,en-US;q=0.7,en;q=0.3"},
+		{LocaleFrance, "fr-FR,fr;q=0.9,en-US;q=0.8,en;q=0.7", "fr,fr-FR;q=0.8,en-US;q=0.5,en;q=0.3"},
+		{LocaleJapan, "ja,en-US;q=0.9,en;q=0.8", "ja,en-US;q=0.7,en;q=0.3"},
+		{Locale{Languages: []string{"de-CH", "de-DE", "fr", "it", "en", "es", "pt", "nl", "pl", "sv", "da", "fi"}},
+			"de-CH,de-DE;q=0.9,de;q=0.8,fr;q=0.7,it;q=0.6,en;q=0.5,es;q=0.4,pt;q=0.3,nl;q=0.2,pl;q=0.1,sv;q=0.1,da;q=0.1,fi;q=0.1", ""},
+	}
+
+	for _, test := range tests {
+		if got := expandedAcceptLanguage(test.locale); got != test.chromium {
+			t.Errorf("%v: want %s; got %s", test.locale.Languages, test.chromium, got)
+		}
+		if test.firefox == "" {
+			continue
+		}
+		if got := firefoxAcceptLanguage(test.locale); got != test.firefox {
+			t.Errorf("%v: want firefox %s; got %s", test.locale.FirefoxLanguages, test.firefox, got)
+		}
+	}
+}
+
+func TestPreset(t *testing.T) {
+	spec, err := PresetGermanyDesktopChrome.Spec("137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := spec.buildHeaders(PresetGermanyDesktopChrome.Platform)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := headers.Get("accept-language"), "de-DE,de;q=0.9,en-US;q=0.8,en;q=0.7"; got != want {
+		t.Errorf("want %s; got %s", want, got)
+	}
+	if got, want := headers.Get("sec-ch-ua-platform"), `"Windows"`; got != want {
+		t.Errorf("want %s; got %s", want, got)
+	}
+
+	// later options win
+	spec, err = PresetGermanyDesktopChrome.Spec("137.0.0.0", WithLocale(LocaleFrance))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err = spec.buildHeaders(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := headers.Get("accept-language"), "fr-FR,fr;q=0.9,en-US;q=0.8,en;q=0.7"; got != want {
+		t.Errorf("want %s; got %s", want, got)
+	}
+
+	for name, newSpec := range map[string]func() (*ClientSpec, error){
+		"firefox": func() (*ClientSpec, error) { return Firefox("134.0", WithLocale(LocaleGermany)) },
+		"safari":  func() (*ClientSpec, error) { return Safari("18.3", WithLocale(LocaleGermany)) },
+	} {
+		spec, err := newSpec()
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers, err := spec.buildHeaders(PlatformMac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if headers.Get("accept-language") == "" {
+			t.Errorf("%s: want accept-language", name)
+		}
+	}
+}
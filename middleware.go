@@ -0,0 +1,67 @@
+package mimic
+
+import (
+	http "github.com/saucesteals/fhttp"
+)
+
+// Middleware wraps a round tripper with behavior of its own, such as logging,
+// retries, or metrics. It is called once per wrapped round tripper, so state
+// it sets up outside the returned round tripper is shared by its requests.
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts a function to an http.RoundTripper, for writing
+// Middleware.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+// RoundTrip calls f.
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithMiddleware wraps the connection the Transport sends requests on, so
+// middleware sees each request as it goes on the wire, with the browser's
+// headers and header order, and each response before the Transport reads
+// it. Retries the Transport makes, such as for Critical-CH or WithFallback,
+// pass through it again. The first middleware is outermost.
+//
+// Transports made by Clone, and platform variants that get their own pool,
+// call the middleware again for their own connections.
+func WithMiddleware(mw ...Middleware) TransportOption {
+	return func(c *transportConfig) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// WithClientMiddleware wraps the client's round tripper, outside rate
+// limiting, pacing, decompression, and meta refresh, so middleware sees each
+// request as the client sends it, once per redirect, and each response with
+// its body decoded. The first middleware is outermost. To see requests as
+// they are sent on the wire instead, use WithMiddleware.
+//
+// The client's CloseIdleConnections and CancelRequest go past the middleware
+// to the round tripper it wraps, so middleware made with RoundTripperFunc
+// does not hide them.
+func WithClientMiddleware(mw ...Middleware) ClientOption {
+	return func(c *clientConfig) {
+		c.middleware = append(c.middleware, mw...)
+	}
+}
+
+// clientMiddleware sends requests through the client's middleware, and
+// lifecycle calls to the round tripper the middleware wraps.
+type clientMiddleware struct {
+	wrapped
+	chain http.RoundTripper
+}
+
+func (t *clientMiddleware) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.chain.RoundTrip(req)
+}
+
+// chain wraps rt in mw, the first outermost.
+func chain(rt http.RoundTripper, mw []Middleware) http.RoundTripper {
+	for i := len(mw) - 1; i >= 0; i-- {
+		rt = mw[i](rt)
+	}
+	return rt
+}
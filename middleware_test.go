@@ -0,0 +1,102 @@
+package mimic
+
+import (
+	"slices"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// stubEngine answers every request without touching the network.
+type stubEngine struct {
+	stubRoundTripper
+}
+
+func (stubEngine) CloseIdleConnections() {}
+
+// recordMiddleware returns middleware that appends name to calls on each
+// request, and to inits each time it wraps a round tripper.
+func recordMiddleware(name string, calls, inits *[]string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		*inits = append(*inits, name)
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			*calls = append(*calls, name+":"+req.Header.Get("User-Agent")[:7])
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls, inits []string
+	tr, err := NewTransport(spec, PlatformWindows,
+		WithEngine(stubEngine{}),
+		WithMiddleware(recordMiddleware("outer", &calls, &inits), recordMiddleware("inner", &calls, &inits)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"outer:Mozilla", "inner:Mozilla"}; !slices.Equal(calls, want) {
+		t.Errorf("want middleware to see the browser's headers, first outermost %q; got %q", want, calls)
+	}
+	if want := []string{"inner", "outer"}; !slices.Equal(inits, want) {
+		t.Errorf("want each middleware called once %q; got %q", want, inits)
+	}
+
+	if _, err := tr.Clone(); err != nil {
+		t.Fatal(err)
+	}
+	if len(inits) != 4 {
+		t.Errorf("want the clone to call the middleware again; got %q", inits)
+	}
+}
+
+func TestClientMiddleware(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var order []string
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithEngine(stubEngine{})),
+		WithClientMiddleware(func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, "client:"+req.Header.Get("User-Agent"))
+				return next.RoundTrip(req)
+			})
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get("https://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if want := []string{"client:"}; !slices.Equal(order, want) {
+		t.Errorf("want client middleware to see the request before the browser's headers %q; got %q", want, order)
+	}
+}
+
+func TestClientMiddlewareCloseIdleConnections(t *testing.T) {
+	testClientClosesIdle(t, WithClientMiddleware(func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(next.RoundTrip)
+	}))
+}
@@ -1,8 +1,9 @@
 package mimic
 
 import (
-	"errors"
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -20,6 +21,7 @@ const (
 	PlatformLinux   Platform = "linux"
 	PlatformIOS     Platform = "ios"
 	PlatformIPadOS  Platform = "ipados"
+	PlatformAndroid Platform = "android"
 )
 
 // Brand represents the browser brand for Chromium-based browsers.
@@ -31,11 +33,23 @@ const (
 	BrandEdge   Brand = "Microsoft Edge"
 )
 
-var (
-	ErrUnsupportedVersion  = errors.New("unsupported version")
-	ErrUnsupportedPlatform = errors.New("unsupported platform")
+// Arch is the CPU architecture of a Chromium build.
+type Arch string
+
+const (
+	ArchX64   Arch = "x64"
+	ArchARM64 Arch = "arm64"
+	// ArchX86 is a 32-bit build. Chromium dropped 32-bit builds outside
+	// Windows before 83, so it only applies to PlatformWindows, where it runs
+	// under WOW64.
+	ArchX86 Arch = "x86"
 )
 
+// ConnectionFlowDefault has fhttp send its default connection WINDOW_UPDATE,
+// 15663105, after the SETTINGS. fhttp always sends one, so a client that sends
+// none cannot be mimicked.
+const ConnectionFlowDefault uint32 = 0
+
 // HTTP2Options holds HTTP/2 configuration for a browser fingerprint.
 type HTTP2Options struct {
 	// Settings are the HTTP/2 SETTINGS frame entries sent at connection start.
@@ -55,21 +69,45 @@ type HTTP2Options struct {
 	HeaderTableSize uint32
 
 	// ConnectionFlow is the WINDOW_UPDATE value sent on stream 0 at connection start.
-	// A value of 0 uses fhttp's default (15663105).
+	// ConnectionFlowDefault uses fhttp's default (15663105).
 	ConnectionFlow uint32
 
 	// HeaderPriority controls the priority parameters sent in HEADERS frames.
 	// A nil value uses fhttp's default (Exclusive=true, Weight=255).
 	HeaderPriority *http2.PriorityParam
+
+	// UploadChunkSize caps the payload of each request body DATA frame.
+	// Browsers read uploads through a fixed-size buffer, so their DATA frames stay
+	// small even when the server advertises a larger SETTINGS_MAX_FRAME_SIZE.
+	// A value of 0 lets frames grow to the server's limit.
+	UploadChunkSize uint32
+
+	// MaxConcurrentStreams caps the requests in flight on one connection,
+	// whatever SETTINGS_MAX_CONCURRENT_STREAMS the server advertises; further
+	// requests wait for a stream to finish. A value of 0 leaves only the
+	// server's limit.
+	MaxConcurrentStreams uint32
 }
 
 // ClientSpec holds all browser-specific configuration needed to mimic a browser's
 // TLS, HTTP/2, and header fingerprints.
 type ClientSpec struct {
 	version      string
+	fullVersion  string
 	http2Options *HTTP2Options
-	tlsSpecFn    func(platform Platform) (func() *utls.ClientHelloSpec, error)
+	timeouts     Timeouts
+	brands       []BrandVersion
+	windows      WindowsGeneration
+	requireSCTs  bool
+	saveData     bool // the client sends Save-Data when asked to
+	tlsSpecFor   func(platform Platform) (*tlsSpec, error)
 	buildHeaders func(platform Platform) (http.Header, error)
+	clientHints  func(platform Platform) (http.Header, error)
+	fetch        *fetchHeaders
+
+	// quicParameters builds the QUIC transport parameters; nil when the
+	// version does not use HTTP/3 by default
+	quicParameters func() utls.TransportParameters
 }
 
 // Version returns the version string for the mimicked client.
@@ -77,29 +115,78 @@ func (c *ClientSpec) Version() string {
 	return c.version
 }
 
+// FullVersion returns the full version the mimicked client reports where it
+// reveals one, such as a Chromium build's sec-ch-ua-full-version hint, or
+// Version for clients that report no more than their user agent.
+func (c *ClientSpec) FullVersion() string {
+	if c.fullVersion == "" {
+		return c.version
+	}
+	return c.fullVersion
+}
+
 // HTTP2Opts returns the HTTP/2 configuration for the mimicked client.
 func (c *ClientSpec) HTTP2Opts() *HTTP2Options {
 	return c.http2Options
 }
 
+// Timeouts returns the default network timeouts for the mimicked client.
+func (c *ClientSpec) Timeouts() Timeouts {
+	return c.timeouts
+}
+
+// Brands returns the sec-ch-ua brand list the mimicked client sends, in order.
+// It is nil for browsers that do not send client hints.
+func (c *ClientSpec) Brands() []BrandVersion {
+	return slices.Clone(c.brands)
+}
+
+// ClientHints returns the high-entropy client hints the mimicked client sends
+// on the given platform to origins that ask for them with Accept-CH. It is
+// empty for browsers that do not send client hints.
+func (c *ClientSpec) ClientHints(platform Platform) (http.Header, error) {
+	if c.clientHints == nil {
+		return http.Header{}, nil
+	}
+	return c.clientHints(platform)
+}
+
 // PseudoHeaderOrder returns the HTTP/2 pseudo header order for the mimicked client.
 func (c *ClientSpec) PseudoHeaderOrder() []string {
 	return c.http2Options.PseudoHeaderOrder
 }
 
+// ClientHelloSpec returns a fresh copy of the TLS ClientHello spec the mimicked
+// client sends on the given platform.
+func (c *ClientSpec) ClientHelloSpec(platform Platform) (*utls.ClientHelloSpec, error) {
+	ts, err := c.tlsSpecFor(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.New(), nil
+}
+
 // ConfigureTransport configures an http.Transport with the client's TLS and HTTP/2
 // settings for the given platform. The transport is modified in-place.
 func (c *ClientSpec) ConfigureTransport(t *http.Transport, platform Platform) error {
-	specFn, err := c.tlsSpecFn(platform)
+	_, err := c.configureTransport(t, platform)
+	return err
+}
+
+// configureTransport is ConfigureTransport, returning the HTTP/2 transport it
+// configured.
+func (c *ClientSpec) configureTransport(t *http.Transport, platform Platform) (*http2.Transport, error) {
+	ts, err := c.tlsSpecFor(platform)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	t.GetTlsClientHelloSpec = specFn
+	t.GetTlsClientHelloSpec = ts.New
 
 	t2, err := http2.ConfigureTransports(t)
 	if err != nil {
-		return fmt.Errorf("enabling http2 support: %w", err)
+		return nil, fmt.Errorf("enabling http2 support: %w", err)
 	}
 
 	t2.Settings = c.http2Options.Settings
@@ -107,15 +194,65 @@ func (c *ClientSpec) ConfigureTransport(t *http.Transport, platform Platform) er
 	t2.InitialWindowSize = c.http2Options.InitialWindowSize
 	t2.HeaderTableSize = c.http2Options.HeaderTableSize
 
-	if c.http2Options.ConnectionFlow > 0 {
-		t2.TransportConnFlow = c.http2Options.ConnectionFlow
+	// browsers queue requests past the server's stream limit on the connection
+	// they have rather than opening another
+	t2.StrictMaxConcurrentStreams = true
+
+	if flow := c.http2Options.ConnectionFlow; flow != ConnectionFlowDefault {
+		t2.TransportConnFlow = flow
 	}
 
 	if c.http2Options.HeaderPriority != nil {
 		t2.HeaderPriority = c.http2Options.HeaderPriority
 	}
 
-	return nil
+	return t2, nil
+}
+
+// The oldest major versions the spec constructors accept, and the newest
+// Firefox the Firefox tables have been checked against.
+const (
+	chromiumMinMajor = 83
+	firefoxMinMajor  = 55
+	firefoxMaxMajor  = 134
+	safariMinMajor   = 14
+	crawlerMinMajor  = 100
+)
+
+// ChromiumMajors returns the Chromium major versions mimic supports, from the
+// oldest Chromium accepts to the newest it has release data for.
+func ChromiumMajors() []int {
+	return majorRange(chromiumMinMajor, chromiumMaxMajor())
+}
+
+// CrawlerMajors returns the Chrome major versions Googlebot and Bingbot
+// support, from the oldest they accept to the newest Chromium release.
+func CrawlerMajors() []int {
+	return majorRange(crawlerMinMajor, chromiumMaxMajor())
+}
+
+func chromiumMaxMajor() int {
+	return slices.Max(slices.Collect(maps.Keys(chromiumBuilds)))
+}
+
+// FirefoxMajors returns the Firefox major versions mimic supports, from the
+// oldest Firefox accepts to the newest it has been checked against.
+func FirefoxMajors() []int {
+	return majorRange(firefoxMinMajor, firefoxMaxMajor)
+}
+
+// SafariMajors returns the Safari major versions mimic supports, from the
+// oldest Safari accepts to 26, which followed 18.
+func SafariMajors() []int {
+	return append(majorRange(safariMinMajor, 18), 26)
+}
+
+func majorRange(from, to int) []int {
+	majors := make([]int, 0, to-from+1)
+	for major := from; major <= to; major++ {
+		majors = append(majors, major)
+	}
+	return majors
 }
 
 // parseMajorVersion extracts the major version string and number from a version string
@@ -128,21 +265,3 @@ func parseMajorVersion(version string) (string, int, error) {
 	}
 	return majorStr, majorNum, nil
 }
-
-// validateTLSHelloID checks that a utls ClientHelloID can be resolved to a spec.
-func validateTLSHelloID(id utls.ClientHelloID) error {
-	if _, err := utls.UTLSIdToSpec(id); err != nil {
-		return fmt.Errorf("resolving tls spec for %s %s: %w", id.Client, id.Version, err)
-	}
-	return nil
-}
-
-// newTLSSpecFunc returns a function that creates a fresh TLS ClientHelloSpec
-// from the given hello ID on each call. A fresh copy is needed because the spec
-// may be mutated during the TLS handshake.
-func newTLSSpecFunc(id utls.ClientHelloID) func() *utls.ClientHelloSpec {
-	return func() *utls.ClientHelloSpec {
-		spec, _ := utls.UTLSIdToSpec(id)
-		return &spec
-	}
-}
@@ -0,0 +1,77 @@
+package mimic
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// baseRTT is the round trip time of the path under a shaped link, which
+// Shaping.Latency adds to.
+const baseRTT = 50 * time.Millisecond
+
+// saveDataOn is the Save-Data header value, shared by every request.
+var saveDataOn = []string{"on"}
+
+// WithSaveData sends "save-data: on" with every request, as Chromium does when
+// the user asks it to reduce data usage. Users turn it on for slow or metered
+// links, so pair it with WithShaping: the rtt, downlink, and ect hints Chromium
+// specs answer with describe the shaped link, and a fast link with Save-Data
+// stands out, as can a slow mobile one without it. Only applies to specs made by
+// Chromium.
+func WithSaveData() TransportOption {
+	return func(c *transportConfig) {
+		c.saveData = true
+	}
+}
+
+// clientHintsFor returns the hints t answers with when mimicking spec on
+// platform p: the spec's, and for specs that send client hints, the network
+// hints for t's link.
+func (t *Transport) clientHintsFor(spec *ClientSpec, p Platform) (http.Header, error) {
+	hints, err := spec.ClientHints(p)
+	if err != nil || len(hints) == 0 {
+		return hints, err
+	}
+
+	var link Shaping
+	if t.shaper != nil {
+		link = t.shaper.Shaping
+	}
+	networkHints(hints, link)
+
+	return hints, nil
+}
+
+// networkHints adds the rtt, downlink, and ect hints Chromium answers with on
+// link to h. Chromium reports its estimates coarsely, the round trip time to
+// 50ms up to 3s and the downlink to 50kbps up to 10Mbps; an unshaped link
+// reports the maximum downlink.
+func networkHints(h http.Header, link Shaping) {
+	rtt := min((baseRTT + link.Latency).Round(50*time.Millisecond), 3*time.Second)
+
+	kbps := 10000.0
+	if link.Downlink > 0 {
+		kbps = min(math.Round(float64(link.Downlink)*8/1000/50)*50, kbps)
+	}
+
+	h.Set("rtt", strconv.FormatInt(rtt.Milliseconds(), 10))
+	h.Set("downlink", strconv.FormatFloat(kbps/1000, 'f', -1, 64))
+	h.Set("ect", effectiveConnectionType(rtt, kbps))
+}
+
+// effectiveConnectionType returns the connection type whose thresholds rtt
+// and a downlink of kbps fall within, from the Network Information API.
+func effectiveConnectionType(rtt time.Duration, kbps float64) string {
+	switch {
+	case rtt >= 2000*time.Millisecond || kbps <= 50:
+		return "slow-2g"
+	case rtt >= 1400*time.Millisecond || kbps <= 70:
+		return "2g"
+	case rtt >= 270*time.Millisecond || kbps <= 700:
+		return "3g"
+	}
+	return "4g"
+}
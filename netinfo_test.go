@@ -0,0 +1,112 @@
+package mimic
+
+import (
+	"testing"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestNetworkHints(t *testing.T) {
+	tests := []struct {
+		link               Shaping
+		rtt, downlink, ect string
+	}{
+		{Shaping{}, "50", "10", "4g"},
+		{Shaping{Downlink: 1_250_000, Latency: 40 * time.Millisecond}, "100", "10", "4g"},
+		{Shaping{Downlink: 200_000, Latency: 100 * time.Millisecond}, "150", "1.6", "4g"},
+		{Shaping{Downlink: 50_000, Latency: 400 * time.Millisecond}, "450", "0.4", "3g"},
+		{Shaping{Downlink: 6_000, Latency: 5 * time.Second}, "3000", "0.05", "slow-2g"},
+	}
+
+	for _, test := range tests {
+		h := http.Header{}
+		networkHints(h, test.link)
+
+		if h.Get("rtt") != test.rtt || h.Get("downlink") != test.downlink || h.Get("ect") != test.ect {
+			t.Errorf("%+v: want %s, %s, %s; got %s, %s, %s", test.link, test.rtt, test.downlink, test.ect, h.Get("rtt"), h.Get("downlink"), h.Get("ect"))
+		}
+	}
+}
+
+func TestRoundTripSaveData(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows,
+		WithSaveData(),
+		WithShaping(Shaping{Downlink: 50_000, Latency: 400 * time.Millisecond}),
+		WithClientHintStore(&ClientHintCache{}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.transport = acceptCHRoundTripper{acceptCH: "ECT, Downlink"}
+	tr.shaper.Latency = 0
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range 2 {
+		res, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := res.Request.Header.Get("Save-Data"); got != "on" {
+			t.Errorf("want save-data on; got %q", got)
+		}
+		if req.Header.Get("Save-Data") != "" {
+			t.Error("want the caller's request unchanged")
+		}
+	}
+
+	if res, err := tr.RoundTrip(req); err != nil {
+		t.Fatal(err)
+	} else if res.Request.Header.Get("ECT") != "3g" || res.Request.Header.Get("Downlink") != "0.4" {
+		t.Errorf("want the shaped link's network hints; got ect %q, downlink %q", res.Request.Header.Get("ECT"), res.Request.Header.Get("Downlink"))
+	}
+
+	// Chromium sent Save-Data before it sent client hints
+	old, err := Chromium(BrandChrome, "85.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	firefox, err := Firefox("139.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		spec *ClientSpec
+		opts []TransportOption
+		want string
+	}{
+		{"chromium without client hints", old, []TransportOption{WithSaveData()}, "on"},
+		{"chromium without the option", spec, nil, ""},
+		{"firefox", firefox, []TransportOption{WithSaveData()}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewTransport(tt.spec, PlatformWindows, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.transport = stubRoundTripper{}
+
+			res, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Request.Header.Get("Save-Data"); got != tt.want {
+				t.Errorf("want save-data %q; got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,233 @@
+package mimic
+
+import (
+	"fmt"
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+// SpecOption configures a ClientSpec created by Chromium, Safari, or Firefox.
+// Options that do not apply to a browser are ignored.
+type SpecOption func(*specConfig)
+
+type specConfig struct {
+	brands            []BrandVersion
+	greaseStrategy    GreaseStrategy
+	greaseSeed        *int
+	customHello       *CustomHello
+	tlsOverrides      []tlsOverride
+	platformTLS       map[Platform][]tlsOverride
+	windowsVersion    string
+	windowsGeneration WindowsGeneration
+	arch              Arch
+	locale            *Locale
+	preferences       Preferences
+	device            *Device
+	ipadMobile        bool
+	safariBuild       *SafariBuild
+	http2Settings     []http2.Setting
+	connectionFlow    *uint32
+}
+
+// WithBrandList replaces the computed sec-ch-ua brand list, in order. Use it to
+// reproduce a list captured from a real browser build that deviates from the
+// Chromium algorithm. Only applies to Chromium specs.
+func WithBrandList(brands ...BrandVersion) SpecOption {
+	return func(c *specConfig) {
+		c.brands = brands
+	}
+}
+
+// WithWindowsVersion sets the Windows NT version in the user agent on
+// PlatformWindows, such as "6.1" for Windows 7 or "6.3" for Windows 8.1. The
+// default is "10.0", which Windows 11 also reports. Chromium froze the user
+// agent at "10.0" in 110, so other versions require an earlier one. To
+// present a release coherently across the user agent and hints, use
+// WithWindowsGeneration. Only applies to Chromium specs.
+func WithWindowsVersion(nt string) SpecOption {
+	return func(c *specConfig) {
+		c.windowsVersion = nt
+	}
+}
+
+// WithArch sets the CPU architecture of the Chromium build, ArchX64 by
+// default. It sets the sec-ch-ua-arch, sec-ch-ua-bitness, and sec-ch-ua-wow64
+// hints and, before the user agent was frozen in 110, the architecture token
+// of the user agent: "WOW64" for ArchX86 on Windows, none for ArchARM64 on
+// Windows, and "aarch64" on Linux. Chromium on Apple Silicon always claims an
+// Intel Mac in the user agent, so only the hints differ there. Only applies
+// to Chromium specs.
+func WithArch(arch Arch) SpecOption {
+	return func(c *specConfig) {
+		c.arch = arch
+	}
+}
+
+// WithIPadDesktopMode sets whether Safari on PlatformIPadOS requests desktop
+// websites, as iPadOS does by default. In desktop mode the user agent is macOS
+// Safari's; with enabled false it is the iPad's own, as when "Request Desktop
+// Website" is turned off. Either way the iPad sends the iOS TLS fingerprint.
+// Only applies to Safari specs.
+func WithIPadDesktopMode(enabled bool) SpecOption {
+	return func(c *specConfig) {
+		c.ipadMobile = !enabled
+	}
+}
+
+// WithSafariBuild sets the tokens Safari's user agent identifies the release
+// with, for a build the table mimic keeps does not describe, such as a
+// security release that ships in an iOS release of another number. Empty
+// fields keep the table's. Only applies to Safari specs.
+func WithSafariBuild(build SafariBuild) SpecOption {
+	return func(c *specConfig) {
+		c.safariBuild = &build
+	}
+}
+
+// WithGreaseStrategy selects the algorithm used for the GREASE brand in sec-ch-ua.
+// The default, GreaseAuto, matches what the claimed version ships with.
+// Only applies to Chromium specs.
+func WithGreaseStrategy(strategy GreaseStrategy) SpecOption {
+	return func(c *specConfig) {
+		c.greaseStrategy = strategy
+	}
+}
+
+// WithGreaseSeed fixes the seed that drives the sec-ch-ua brand order and GREASE
+// characters. Real Chromium seeds with its major version (or 0 before 103), which
+// is the default; a fixed seed keeps the list stable across a session that spans
+// several claimed versions. Negative seeds are treated as 0. Only applies to
+// Chromium specs.
+func WithGreaseSeed(seed int) SpecOption {
+	return func(c *specConfig) {
+		seed = max(seed, 0)
+		c.greaseSeed = &seed
+	}
+}
+
+// WithHTTP2Settings replaces the SETTINGS the spec sends at connection start
+// with settings, in order, for matching a client whose Akamai fingerprint the
+// browsers' do not. Entries may be left out, repeated, or use IDs HTTP/2 does
+// not define, such as the reserved GREASE IDs of the form 0x?a?a, which
+// servers ignore. The connection's stream window and HPACK table follow the
+// INITIAL_WINDOW_SIZE and HEADER_TABLE_SIZE sent last, or the protocol's
+// defaults when none is sent, so the server is not told one size while
+// another is used.
+func WithHTTP2Settings(settings ...http2.Setting) SpecOption {
+	return func(c *specConfig) {
+		c.http2Settings = slices.Clone(settings)
+		if c.http2Settings == nil {
+			c.http2Settings = []http2.Setting{}
+		}
+	}
+}
+
+// WithConnectionFlow replaces the increment of the connection WINDOW_UPDATE
+// the spec sends after its SETTINGS with flow, for matching a client that
+// sends another. ConnectionFlowDefault sends fhttp's. Leaving the frame out is
+// not supported, as fhttp always writes it.
+func WithConnectionFlow(flow uint32) SpecOption {
+	return func(c *specConfig) {
+		c.connectionFlow = &flow
+	}
+}
+
+// http2Options applies the options that change opts, a browser's HTTP/2
+// options, to it.
+func (c *specConfig) http2Options(opts *HTTP2Options) *HTTP2Options {
+	if c.connectionFlow != nil {
+		opts.ConnectionFlow = *c.connectionFlow
+	}
+	if c.http2Settings == nil {
+		return opts
+	}
+
+	opts.Settings = c.http2Settings
+	opts.InitialWindowSize = 65535
+	opts.HeaderTableSize = 4096
+	for _, s := range c.http2Settings {
+		switch s.ID {
+		case http2.SettingInitialWindowSize:
+			opts.InitialWindowSize = s.Val
+		case http2.SettingHeaderTableSize:
+			opts.HeaderTableSize = s.Val
+		case http2.SettingMaxHeaderListSize:
+			opts.MaxHeaderListSize = s.Val
+		}
+	}
+	return opts
+}
+
+// newTLSSpec resolves id and applies edits to it, followed by the overrides.
+// Browsers use edits for versions utls has no hello ID of their own for.
+func (c *specConfig) newTLSSpec(id utls.ClientHelloID, edits ...tlsOverride) (*tlsSpec, error) {
+	ts, err := newTLSSpec(id)
+	if err != nil {
+		return nil, err
+	}
+	return c.editTLSSpec(id, ts, edits...)
+}
+
+// editTLSSpec applies edits and the overrides to ts, which id names in errors.
+// Browsers whose hello utls has no ID for build ts themselves.
+func (c *specConfig) editTLSSpec(id utls.ClientHelloID, ts *tlsSpec, edits ...tlsOverride) (*tlsSpec, error) {
+	if len(edits)+len(c.tlsOverrides) == 0 {
+		return ts, nil
+	}
+
+	spec := cloneClientHelloSpec(&ts.template)
+	for _, override := range slices.Concat(edits, c.tlsOverrides) {
+		if err := override(spec); err != nil {
+			return nil, &TLSSpecError{HelloID: id, Err: err}
+		}
+	}
+
+	if err := validateHello(cloneClientHelloSpec(spec)); err != nil {
+		return nil, &TLSSpecError{HelloID: id, Err: err}
+	}
+
+	return &tlsSpec{template: *spec, shuffle: ts.shuffle}, nil
+}
+
+// platformTLSSpecs resolves id for each platform that has overrides of its
+// own, set with WithPlatformTLS, which follow the others. The edits apply on
+// every platform. Platforms missing from the result use the hello newTLSSpec
+// returns.
+func (c *specConfig) platformTLSSpecs(id utls.ClientHelloID, edits ...tlsOverride) (map[Platform]*tlsSpec, error) {
+	specs := make(map[Platform]*tlsSpec, len(c.platformTLS))
+	for p, overrides := range c.platformTLS {
+		pc := *c
+		pc.tlsOverrides = slices.Concat(c.tlsOverrides, overrides)
+		ts, err := pc.newTLSSpec(id, edits...)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+		specs[p] = ts
+	}
+	return specs, nil
+}
+
+// tlsSpecFor returns tlsSpecFor with the hello set by WithCustomHello in place
+// of the browser's, keeping the browser's platform checks.
+func (c *specConfig) tlsSpecFor(tlsSpecFor func(Platform) (*tlsSpec, error)) func(Platform) (*tlsSpec, error) {
+	if c.customHello == nil {
+		return tlsSpecFor
+	}
+
+	return func(p Platform) (*tlsSpec, error) {
+		if _, err := tlsSpecFor(p); err != nil {
+			return nil, err
+		}
+		return c.customHello.spec, nil
+	}
+}
+
+func newSpecConfig(opts []SpecOption) *specConfig {
+	cfg := &specConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
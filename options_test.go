@@ -0,0 +1,83 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+func TestWithHTTP2Settings(t *testing.T) {
+	body := strings.Repeat("x", 1<<20)
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		io.WriteString(w, body)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	// no INITIAL_WINDOW_SIZE, so the server sends at most 65535 bytes per
+	// stream before the client updates the window
+	settings := []http2.Setting{
+		{ID: http2.SettingMaxConcurrentStreams, Val: 1000},
+		{ID: 0x0a0a, Val: 0},
+		{ID: http2.SettingHeaderTableSize, Val: 65536},
+	}
+	spec, err := Chromium(BrandChrome, "137.0.0.0", WithHTTP2Settings(settings...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := spec.HTTP2Opts()
+	if opts.InitialWindowSize != 65535 || opts.HeaderTableSize != 65536 {
+		t.Errorf("window = %d and table = %d, want the ones announced", opts.InitialWindowSize, opts.HeaderTableSize)
+	}
+	if drift := checkHTTP2Preface(spec, PlatformWindows); len(drift) > 0 {
+		t.Errorf("preface differs: %q", drift)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	got, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(body) {
+		t.Errorf("read %d bytes, want %d", len(got), len(body))
+	}
+}
+
+func TestWithConnectionFlow(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0", WithConnectionFlow(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if drift := checkHTTP2Preface(spec, PlatformWindows); len(drift) > 0 {
+		t.Errorf("preface differs: %q", drift)
+	}
+}
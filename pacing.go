@@ -0,0 +1,163 @@
+package mimic
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// ThinkTime draws how long a person spends on a page before moving on.
+// Implement it to plug in a distribution fitted to real browsing sessions.
+type ThinkTime interface {
+	Next() time.Duration
+}
+
+// ThinkTimeFunc adapts a function to a ThinkTime.
+type ThinkTimeFunc func() time.Duration
+
+// Next calls f.
+func (f ThinkTimeFunc) Next() time.Duration {
+	return f()
+}
+
+// UniformThinkTime draws think times evenly between lo and hi.
+func UniformThinkTime(lo, hi time.Duration) ThinkTime {
+	return ThinkTimeFunc(func() time.Duration {
+		if hi <= lo {
+			return lo
+		}
+		return lo + rand.N(hi-lo+1)
+	})
+}
+
+// LogNormalThinkTime draws think times from a log-normal distribution with
+// the given median, the shape page dwell times follow: most visits are
+// short, with a long tail of pages that are read. Sigma sets the spread;
+// around 1 is typical of measured sessions.
+func LogNormalThinkTime(median time.Duration, sigma float64) ThinkTime {
+	return ThinkTimeFunc(func() time.Duration {
+		return time.Duration(float64(median) * math.Exp(sigma*rand.NormFloat64()))
+	})
+}
+
+// ExponentialThinkTime draws think times from an exponential distribution
+// with the given mean, so navigations arrive as a Poisson process.
+func ExponentialThinkTime(mean time.Duration) ThinkTime {
+	return ThinkTimeFunc(func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	})
+}
+
+// Pacer spaces navigations the way a person browses. After each navigation
+// it draws how long the visitor stays on the page, and holds the next
+// navigation until that time has passed. Subresources and redirects are not
+// paced, since a browser requests them without waiting on the user.
+//
+// Navigations share one pace across hosts, as one person makes them. The
+// think time drawn for a page comes from the dwell of the first pattern
+// matching its host, or the default think time. The zero value never waits.
+type Pacer struct {
+	mu    sync.Mutex
+	think ThinkTime
+	rules []pacerRule
+	ready time.Time
+}
+
+type pacerRule struct {
+	pattern string
+	dwell   ThinkTime
+}
+
+// NewPacer creates a Pacer that draws the time spent on pages not matched
+// by a more specific pattern from think.
+func NewPacer(think ThinkTime) *Pacer {
+	return &Pacer{think: think}
+}
+
+// SetDwell draws the time spent on pages of hosts matching pattern from
+// dwell. Patterns match as in RateLimiter.SetLimit and are checked in the
+// order they were added.
+func (p *Pacer) SetDwell(pattern string, dwell ThinkTime) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rules = append(p.rules, pacerRule{pattern: strings.ToLower(pattern), dwell: dwell})
+}
+
+// Wait blocks until the visitor would navigate to host or ctx is done. A
+// navigation whose wait is cancelled still counts as made.
+func (p *Pacer) Wait(ctx context.Context, host string) error {
+	delay := p.reserve(host, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, delay)
+}
+
+// reserve schedules a navigation to host and returns how long the caller
+// must wait for it.
+func (p *Pacer) reserve(host string, now time.Time) time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	at := now
+	if p.ready.After(now) {
+		at = p.ready
+	}
+
+	if think := p.thinkFor(strings.ToLower(host)); think != nil {
+		p.ready = at.Add(max(think.Next(), 0))
+	}
+
+	return at.Sub(now)
+}
+
+// thinkFor returns the ThinkTime for pages of host. p.mu must be held.
+func (p *Pacer) thinkFor(host string) ThinkTime {
+	for _, rule := range p.rules {
+		if matchHost(rule.pattern, host) {
+			return rule.dwell
+		}
+	}
+	return p.think
+}
+
+// WithPacer paces the navigations the client sends through pacer. Requests
+// made with Fetch for a subresource, and the redirects of any request, go
+// out without waiting.
+func WithPacer(pacer *Pacer) ClientOption {
+	return func(c *clientConfig) {
+		c.pacer = pacer
+	}
+}
+
+// pacingTransport waits for the pacer before each navigation.
+type pacingTransport struct {
+	wrapped
+	pacer *Pacer
+}
+
+func (t *pacingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if isNavigation(req) && req.Response == nil {
+		if err := t.pacer.Wait(req.Context(), req.URL.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+	return t.transport.RoundTrip(req)
+}
+
+// isNavigation reports whether req loads a document rather than a
+// subresource. Requests without a fetch intent or Sec-Fetch-Mode are sent
+// with navigation headers, so they count as navigations.
+func isNavigation(req *http.Request) bool {
+	if intent := fetchIntentFrom(req.Context()); intent != nil {
+		return intent.mode == ModeNavigate
+	}
+	mode := req.Header.Get("Sec-Fetch-Mode")
+	return mode == "" || mode == string(ModeNavigate)
+}
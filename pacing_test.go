@@ -0,0 +1,130 @@
+package mimic
+
+import (
+	"context"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func constantThinkTime(d time.Duration) ThinkTime {
+	return ThinkTimeFunc(func() time.Duration { return d })
+}
+
+func TestPacerReserve(t *testing.T) {
+	pacer := NewPacer(constantThinkTime(2 * time.Second))
+	pacer.SetDwell("*.example.com", constantThinkTime(10*time.Second))
+
+	now := time.Now()
+
+	tests := []struct {
+		host  string
+		delay time.Duration
+	}{
+		// the first navigation goes out at once
+		{"other.com", 0},
+		// the visitor stays on other.com for the default think time
+		{"EXAMPLE.com", 2 * time.Second},
+		// and on example.com for its dwell, which covers subdomains
+		{"www.example.com", 12 * time.Second},
+		{"other.com", 22 * time.Second},
+	}
+
+	for _, test := range tests {
+		if delay := pacer.reserve(test.host, now); delay != test.delay {
+			t.Errorf("host %s: want %s; got %s", test.host, test.delay, delay)
+		}
+	}
+
+	// time spent on the page counts toward its think time
+	if delay := pacer.reserve("other.com", now.Add(30*time.Second)); delay != 0 {
+		t.Errorf("after dwell: want 0s; got %s", delay)
+	}
+}
+
+func TestPacerZeroValue(t *testing.T) {
+	var pacer Pacer
+	for range 10 {
+		if delay := pacer.reserve("example.com", time.Now()); delay != 0 {
+			t.Fatalf("want no pacing; got delay %s", delay)
+		}
+	}
+}
+
+func TestThinkTimeDistributions(t *testing.T) {
+	uniform := UniformThinkTime(time.Second, 3*time.Second)
+	for range 100 {
+		if d := uniform.Next(); d < time.Second || d > 3*time.Second {
+			t.Fatalf("uniform: %s outside [1s, 3s]", d)
+		}
+	}
+
+	for name, think := range map[string]ThinkTime{
+		"lognormal":   LogNormalThinkTime(5*time.Second, 1),
+		"exponential": ExponentialThinkTime(5 * time.Second),
+	} {
+		for range 100 {
+			if d := think.Next(); d < 0 {
+				t.Fatalf("%s: negative think time %s", name, d)
+			}
+		}
+	}
+}
+
+func TestWithPacer(t *testing.T) {
+	server := stdhttptest.NewServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/redirect" {
+			stdhttp.Redirect(w, r, "/", stdhttp.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var draws int
+	pacer := NewPacer(ThinkTimeFunc(func() time.Duration {
+		draws++
+		return time.Hour
+	}))
+
+	client, err := NewClient(spec, PlatformWindows, WithPacer(pacer))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the first navigation and its redirect are not held
+	res, err := client.Get(server.URL + "/redirect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// nor are subresources the page loads
+	res, err = Fetch(context.Background(), client, server.URL+"/app.js", FetchOptions{Profile: ProfileScript, Referrer: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if draws != 1 {
+		t.Errorf("want 1 think time drawn; got %d", draws)
+	}
+
+	// the next navigation waits for the visitor to finish reading
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Do(req); err == nil {
+		t.Fatal("want navigation held by pacer")
+	}
+}
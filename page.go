@@ -0,0 +1,151 @@
+package mimic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Page is a document loaded the way a browser that runs no scripts loads it,
+// and the context of the requests made from it. Links followed and resources
+// fetched from a page carry its URL as their referrer and share its client's
+// cookies, as they would in a browser tab.
+type Page struct {
+	// URL is the document's URL after redirects, and after refreshes when the
+	// client follows them with WithMetaRefresh. Relative URLs in the page
+	// resolve against it.
+	URL *url.URL
+
+	// Referrer is the URL of the page the navigation started from, or empty
+	// for one started from the address bar.
+	Referrer string
+
+	// Response is the document's response. Its body has been read into Body
+	// and closed.
+	Response *http.Response
+	Body     []byte
+
+	client *http.Client
+}
+
+// Navigate loads the page at rawURL with client as a navigation typed into
+// the address bar, following redirects, and reads the whole document. A
+// response with an error status is still a page, as a browser shows it.
+func Navigate(ctx context.Context, client *http.Client, rawURL string) (*Page, error) {
+	return navigate(ctx, client, rawURL, FetchOptions{Profile: ProfileNavigation})
+}
+
+// Navigate follows a link on p to rawURL, which may be relative, and loads
+// the page it leads to with p as the referrer.
+func (p *Page) Navigate(ctx context.Context, rawURL string) (*Page, error) {
+	u, err := p.Resolve(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return navigate(ctx, p.client, u.String(), FetchOptions{
+		Profile:  ProfileNavigation,
+		Referrer: p.URL.String(),
+	})
+}
+
+// Reload loads p again the way reload does, with its original referrer.
+func (p *Page) Reload(ctx context.Context, reload Reload) (*Page, error) {
+	return navigate(ctx, p.client, p.URL.String(), FetchOptions{
+		Profile:  ProfileNavigation,
+		Referrer: p.Referrer,
+		Reload:   reload,
+	})
+}
+
+// Fetch sends a request for rawURL, which may be relative, the way p would,
+// as Fetch does with p's URL as opts.Referrer. Use it for the page's
+// subresources and for the fetch() and XMLHttpRequest calls its scripts
+// would make. The caller must close the response body.
+func (p *Page) Fetch(ctx context.Context, rawURL string, opts FetchOptions) (*http.Response, error) {
+	u, err := p.Resolve(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Referrer = p.URL.String()
+	return Fetch(ctx, p.client, u.String(), opts)
+}
+
+// Resolve returns ref resolved against p's URL, as the page resolves the
+// URLs in its links and tags.
+func (p *Page) Resolve(ref string) (*url.URL, error) {
+	u, err := p.URL.Parse(ref)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %q: %w", ref, err)
+	}
+	return u, nil
+}
+
+// Submit submits form from p to action, which may be relative, and loads the
+// page it leads to, as a browser does for a <form> with those method and
+// enctype attributes. A GET replaces the query of action with the fields, URL
+// encoded. A POST sends them encoded as Form.Encode does, with the Origin,
+// Referer, and Content-Type of a form submission. An empty method is GET and
+// an empty enctype is FormURLEncoded, as in HTML.
+func (p *Page) Submit(ctx context.Context, action, method string, enctype FormEncoding, form *Form) (*Page, error) {
+	u, err := p.Resolve(action)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(method, http.MethodPost) {
+		u.RawQuery = form.urlEncoded()
+		return navigate(ctx, p.client, u.String(), FetchOptions{
+			Profile:  ProfileNavigation,
+			Referrer: p.URL.String(),
+		})
+	}
+
+	req, intent, err := newFormRequest(ctx, u.String(), p.URL.String(), enctype, form, webkitBoundary)
+	if err != nil {
+		return nil, err
+	}
+	return openPage(p.client, req, intent)
+}
+
+// navigate sends a navigation for rawURL and reads the document it ends at.
+func navigate(ctx context.Context, client *http.Client, rawURL string, opts FetchOptions) (*Page, error) {
+	req, intent, err := newFetchRequest(ctx, rawURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return openPage(client, req, intent)
+}
+
+// openPage sends req, a navigation carrying intent, and reads the document it
+// ends at.
+func openPage(client *http.Client, req *http.Request, intent *fetchIntent) (*Page, error) {
+	res, err := sendFetch(client, req, intent)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading document: %w", err)
+	}
+
+	var referrer string
+	if intent.initiator != nil {
+		referrer = intent.initiator.String()
+	}
+
+	return &Page{
+		URL:      res.Request.URL,
+		Referrer: referrer,
+		Response: res,
+		Body:     body,
+		client:   client,
+	}, nil
+}
@@ -0,0 +1,101 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestPage(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]stdhttp.Header)
+
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		mu.Lock()
+		seen[r.URL.Path] = r.Header.Clone()
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/":
+			stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "sid", Value: "1"})
+			stdhttp.Redirect(w, r, "/wait", stdhttp.StatusFound)
+		case "/wait":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<meta http-equiv="refresh" content="0;url=/home">`)
+		case "/home":
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, `<a href="next">next</a>`)
+		default:
+			io.WriteString(w, "ok")
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	session, err := NewSession(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithMetaRefresh(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Close()
+
+	ctx := context.Background()
+	page, err := session.Navigate(ctx, server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := page.URL.String(), server.URL+"/home"; got != want {
+		t.Errorf("URL = %s, want %s", got, want)
+	}
+	if string(page.Body) != `<a href="next">next</a>` {
+		t.Errorf("Body = %q", page.Body)
+	}
+
+	res, err := page.Fetch(ctx, "api", FetchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	next, err := page.Navigate(ctx, "next")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Referrer != page.URL.String() {
+		t.Errorf("Referrer = %q, want %q", next.Referrer, page.URL)
+	}
+
+	if _, err := next.Reload(ctx, LoadReload); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	api := seen["/api"]
+	if api.Get("Referer") != page.URL.String() || api.Get("Sec-Fetch-Mode") != "cors" || api.Get("Cookie") != "sid=1" {
+		t.Errorf("want the fetch sent from the page with its cookies; got %v", api)
+	}
+
+	// the reload is the last request for /next, and keeps the link's referrer
+	reload := seen["/next"]
+	if reload.Get("Referer") != page.URL.String() || reload.Get("Cache-Control") != "max-age=0" || reload.Get("Sec-Fetch-Site") != "same-origin" {
+		t.Errorf("want the reload sent with the page's referrer; got %v", reload)
+	}
+}
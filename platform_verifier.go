@@ -0,0 +1,96 @@
+package mimic
+
+import (
+	"crypto/x509"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// WithPlatformVerifier verifies server certificates against the operating
+// system's trust store, as browsers do, through CryptoAPI on Windows,
+// Security.framework on macOS, and the system roots elsewhere. Roots installed
+// in the system by the user or by enterprise policy are trusted, and roots set
+// in TLSClientConfig.RootCAs are trusted in addition to them rather than in
+// place of them.
+//
+// Verification is skipped when InsecureSkipVerify is set.
+func WithPlatformVerifier() TransportOption {
+	return func(c *transportConfig) {
+		c.platformVerifier = true
+	}
+}
+
+// platformVerifier verifies certificates in place of the TLS stack, whose
+// verifier trusts only RootCAs once they are set.
+type platformVerifier struct {
+	// extraRoots are trusted alongside the platform's roots
+	extraRoots *x509.CertPool
+	// serverName overrides the name verified, as InsecureServerNameToVerify
+	// does, with "*" verifying no name
+	serverName     string
+	skipTimeVerify bool
+}
+
+// install takes over verification for cfg. The verified chains are passed to
+// any VerifyConnection already set.
+func (v platformVerifier) install(cfg *utls.Config) {
+	if cfg.InsecureSkipVerify {
+		return
+	}
+
+	v.extraRoots = cfg.RootCAs
+	v.serverName = cfg.InsecureServerNameToVerify
+	v.skipTimeVerify = cfg.InsecureSkipTimeVerify
+
+	next := cfg.VerifyConnection
+	cfg.InsecureSkipVerify = true
+	cfg.VerifyConnection = func(cs utls.ConnectionState) error {
+		chains, err := v.verify(cs)
+		if err != nil {
+			return &utls.CertificateVerificationError{UnverifiedCertificates: cs.PeerCertificates, Err: err}
+		}
+
+		cs.VerifiedChains = chains
+		if next != nil {
+			return next(cs)
+		}
+		return nil
+	}
+}
+
+func (v platformVerifier) verify(cs utls.ConnectionState) ([][]*x509.Certificate, error) {
+	if len(cs.PeerCertificates) == 0 {
+		return nil, x509.UnknownAuthorityError{}
+	}
+	leaf := cs.PeerCertificates[0]
+
+	opts := x509.VerifyOptions{
+		DNSName:       cs.ServerName,
+		Intermediates: x509.NewCertPool(),
+	}
+	switch v.serverName {
+	case "":
+	case "*":
+		opts.DNSName = ""
+	default:
+		opts.DNSName = v.serverName
+	}
+	if v.skipTimeVerify {
+		opts.CurrentTime = leaf.NotAfter
+	}
+	for _, cert := range cs.PeerCertificates[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+
+	// nil roots select the platform verifier
+	chains, err := leaf.Verify(opts)
+	if err == nil || v.extraRoots == nil {
+		return chains, err
+	}
+
+	opts.Roots = v.extraRoots
+	if extraChains, extraErr := leaf.Verify(opts); extraErr == nil {
+		return extraChains, nil
+	}
+	return nil, err
+}
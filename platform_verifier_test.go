@@ -0,0 +1,53 @@
+package mimic
+
+import (
+	"crypto/x509"
+	"errors"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestPlatformVerifier(t *testing.T) {
+	server := stdhttptest.NewTLSServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(rootCAs *x509.CertPool) error {
+		t.Helper()
+
+		base := &http.Transport{TLSClientConfig: &utls.Config{RootCAs: rootCAs}}
+		// the test certificate outlives the policy's limit, so it passes only
+		// while chains to RootCAs are exempt
+		tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base), WithPlatformVerifier(), WithBrowserCertificatePolicy())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := (&http.Client{Transport: tr}).Get(server.URL)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	}
+
+	// RootCAs are trusted alongside the platform's roots
+	if err := get(roots); err != nil {
+		t.Fatal(err)
+	}
+
+	var unknownCAErr x509.UnknownAuthorityError
+	if err := get(nil); !errors.As(err, &unknownCAErr) {
+		t.Errorf("want x509.UnknownAuthorityError; got %v", err)
+	}
+}
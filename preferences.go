@@ -0,0 +1,58 @@
+package mimic
+
+import (
+	"fmt"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// ColorScheme is the color scheme the user's system prefers.
+type ColorScheme string
+
+const (
+	ColorSchemeLight ColorScheme = "light"
+	ColorSchemeDark  ColorScheme = "dark"
+)
+
+// Preferences are the user's appearance and accessibility settings. Chromium
+// reports them with the user preference client hints, which it sends only to
+// origins that ask for them with Accept-CH, see WithClientHintStore.
+type Preferences struct {
+	// ColorScheme is sent as sec-ch-prefers-color-scheme, ColorSchemeLight by
+	// default.
+	ColorScheme ColorScheme
+
+	// ReducedMotion is sent as sec-ch-prefers-reduced-motion.
+	ReducedMotion bool
+}
+
+// WithPreferences sets the user's appearance and accessibility settings. Sites
+// that ask for the preference hints notice clients that never answer, so
+// Chromium specs answer with a light color scheme and no motion preference
+// unless set. Chromium sent sec-ch-prefers-color-scheme from 93 and
+// sec-ch-prefers-reduced-motion from 108. Only applies to Chromium specs.
+func WithPreferences(p Preferences) SpecOption {
+	return func(c *specConfig) {
+		c.preferences = p
+	}
+}
+
+// preferenceHints adds the preference hints Chromium at majorNum answers
+// with to h.
+func preferenceHints(h http.Header, majorNum int, p Preferences) {
+	scheme := p.ColorScheme
+	if scheme == "" {
+		scheme = ColorSchemeLight
+	}
+	if majorNum >= 93 {
+		h.Set("sec-ch-prefers-color-scheme", fmt.Sprintf(`"%s"`, scheme))
+	}
+
+	if majorNum >= 108 {
+		motion := "no-preference"
+		if p.ReducedMotion {
+			motion = "reduce"
+		}
+		h.Set("sec-ch-prefers-reduced-motion", fmt.Sprintf(`"%s"`, motion))
+	}
+}
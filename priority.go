@@ -0,0 +1,117 @@
+package mimic
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"sync"
+	"weak"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/saucesteals/fhttp/http2"
+	"github.com/saucesteals/fhttp/httptrace"
+)
+
+// streamPriority sends each request's HEADERS frame with the priority the
+// browser gives its destination. fhttp only reads one HeaderPriority from the
+// HTTP/2 transport, so it is swapped in for the moment a request writes its
+// headers, one request at a time across the transport's connections.
+type streamPriority struct {
+	t2         *http2.Transport
+	byDest     map[FetchDestination]http2.PriorityParam
+	defaultPri *http2.PriorityParam
+
+	mu sync.Mutex
+	// next is the ID of the next stream each connection opens. Browsers
+	// that prioritize by destination depend on idle streams they open first,
+	// which fhttp does not, so a request whose own stream ID is the one it
+	// would depend on goes under the root instead.
+	next map[weak.Pointer[utls.UConn]]uint32
+}
+
+// newStreamPriority returns the streamPriority of t2 for the browser's
+// priorities, or nil if the browser gives every request the same one.
+func newStreamPriority(t2 *http2.Transport, byDest map[FetchDestination]http2.PriorityParam) *streamPriority {
+	if t2 == nil || len(byDest) == 0 {
+		return nil
+	}
+	return &streamPriority{
+		t2:         t2,
+		byDest:     byDest,
+		defaultPri: t2.HeaderPriority,
+		next:       make(map[weak.Pointer[utls.UConn]]uint32),
+	}
+}
+
+// context attaches the trace that gives a request to dest its priority.
+// Requests without a destination, or with one the browser has no priority
+// for, keep the transport's HeaderPriority. Every request must carry the
+// trace, since the swap is only safe while no request writes its headers
+// outside of it.
+func (p *streamPriority) context(ctx context.Context, dest FetchDestination) context.Context {
+	var pri http2.PriorityParam
+	if dp, ok := p.byDest[dest]; ok {
+		pri = dp
+	} else if p.defaultPri != nil {
+		pri = *p.defaultPri
+	}
+
+	var conn weak.Pointer[utls.UConn]
+	var locked bool
+	var id uint32
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if uc, ok := info.Conn.(*utls.UConn); ok {
+				conn = weak.Make(uc)
+			}
+		},
+		// fhttp encodes an HTTP/2 request's headers, starting with the
+		// pseudo-headers, just before writing them
+		WroteHeaderField: func(key string, _ []string) {
+			if locked || !strings.HasPrefix(key, ":") {
+				return
+			}
+			p.mu.Lock()
+			locked = true
+
+			id = p.streamID(conn)
+			sent := pri
+			if sent.StreamDep == id {
+				sent.StreamDep = 0
+			}
+			p.t2.HeaderPriority = &sent
+		},
+		WroteHeaders: func() {
+			if !locked {
+				return
+			}
+			if id != 0 {
+				p.next[conn] = id + 2
+			}
+			p.t2.HeaderPriority = p.defaultPri
+			locked = false
+			p.mu.Unlock()
+		},
+	})
+}
+
+// streamID returns the ID of the next stream conn opens, or 0 if conn is
+// unknown. It must be called with p.mu held.
+func (p *streamPriority) streamID(conn weak.Pointer[utls.UConn]) uint32 {
+	uc := conn.Value()
+	if uc == nil {
+		return 0
+	}
+
+	id, ok := p.next[conn]
+	if !ok {
+		id = 1
+		p.next[conn] = id
+		runtime.AddCleanup(uc, func(conn weak.Pointer[utls.UConn]) {
+			p.mu.Lock()
+			delete(p.next, conn)
+			p.mu.Unlock()
+		}, conn)
+	}
+	return id
+}
@@ -0,0 +1,98 @@
+package mimic
+
+import (
+	"context"
+	"crypto/tls"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"sync"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+	xhttp2 "golang.org/x/net/http2"
+)
+
+// priorityRecorder records the priority of every stream a server opens.
+type priorityRecorder struct {
+	xhttp2.WriteScheduler
+
+	mu   *sync.Mutex
+	seen *[]xhttp2.PriorityParam
+}
+
+func (r priorityRecorder) AdjustStream(streamID uint32, priority xhttp2.PriorityParam) {
+	r.mu.Lock()
+	*r.seen = append(*r.seen, priority)
+	r.mu.Unlock()
+	r.WriteScheduler.AdjustStream(streamID, priority)
+}
+
+func TestFirefoxStreamPriority(t *testing.T) {
+	var mu sync.Mutex
+	var seen []xhttp2.PriorityParam
+
+	srv := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {}))
+	err := xhttp2.ConfigureServer(srv.Config, &xhttp2.Server{
+		NewWriteScheduler: func() xhttp2.WriteScheduler {
+			return priorityRecorder{WriteScheduler: xhttp2.NewRandomWriteScheduler(), mu: &mu, seen: &seen}
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: tr}
+
+	profiles := []FetchProfile{ProfileNavigation, ProfileStyle, ProfileScript, ProfileImage, ProfileFetch}
+	for _, profile := range profiles {
+		res, err := Fetch(context.Background(), client, srv.URL, FetchOptions{Profile: profile, Referrer: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+	}
+
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// the stylesheet is stream 3, which it would depend on, so it goes under
+	// the root; fhttp never opens the idle streams Firefox depends on
+	want := []http2.PriorityParam{
+		{StreamDep: 13, Weight: 41},
+		{StreamDep: 0, Weight: 41},
+		{StreamDep: 3, Weight: 31},
+		{StreamDep: 5, Weight: 21},
+		{StreamDep: 7, Weight: 21},
+		*spec.http2Options.HeaderPriority,
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(seen) != len(want) {
+		t.Fatalf("want %d prioritized streams; got %d", len(want), len(seen))
+	}
+	for i, w := range want {
+		got := seen[i]
+		if got.StreamDep != w.StreamDep || got.Weight != w.Weight || got.Exclusive != w.Exclusive {
+			t.Errorf("stream %d: want %+v; got %+v", i, w, got)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package mimic
+
+import (
+	"math/rand/v2"
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// QUICSpec is the fingerprint a browser presents when it connects over QUIC:
+// the ClientHello in its Initial packets and the transport parameters inside
+// it, which JA4-QUIC style fingerprints are computed from.
+//
+// mimic does not speak HTTP/3. A QUICSpec is meant for a QUIC implementation
+// that performs its handshake with utls's UQUICConn.
+type QUICSpec struct {
+	// ClientHello is the TLS ClientHello spec, ending with a
+	// quic_transport_parameters extension that carries TransportParameters.
+	ClientHello *utls.ClientHelloSpec
+
+	// TransportParameters are the QUIC transport parameters in the order the
+	// browser sends them.
+	TransportParameters utls.TransportParameters
+}
+
+// QUICSpec returns a fresh copy of the QUIC fingerprint the mimicked client
+// presents on the given platform. Browsers that randomize their transport
+// parameter order are reshuffled on each call. It returns ErrQUICNotSupported
+// for browser versions that do not use HTTP/3 by default.
+func (c *ClientSpec) QUICSpec(platform Platform) (*QUICSpec, error) {
+	if c.quicParameters == nil {
+		return nil, ErrQUICNotSupported
+	}
+
+	ts, err := c.tlsSpecFor(platform)
+	if err != nil {
+		return nil, err
+	}
+
+	params := c.quicParameters()
+	return &QUICSpec{
+		ClientHello:         quicClientHello(ts, params),
+		TransportParameters: params,
+	}, nil
+}
+
+// quicClientHello adapts the browser's TLS ClientHello to QUIC, which requires
+// TLS 1.3 and has no use for the extensions that only negotiate TLS 1.2
+// features or pad the record. ALPN and ALPS offer h3 alone.
+func quicClientHello(ts *tlsSpec, params utls.TransportParameters) *utls.ClientHelloSpec {
+	spec := cloneClientHelloSpec(&ts.template)
+	spec.TLSVersMin = utls.VersionTLS13
+	spec.TLSVersMax = utls.VersionTLS13
+	spec.CipherSuites = slices.DeleteFunc(spec.CipherSuites, func(id uint16) bool {
+		return id != utls.GREASE_PLACEHOLDER && (id < utls.TLS_AES_128_GCM_SHA256 || id > utls.TLS_CHACHA20_POLY1305_SHA256)
+	})
+
+	spec.Extensions = slices.DeleteFunc(spec.Extensions, func(ext utls.TLSExtension) bool {
+		switch ext := ext.(type) {
+		case *utls.SupportedPointsExtension, *utls.SessionTicketExtension, *utls.RenegotiationInfoExtension,
+			*utls.ExtendedMasterSecretExtension, *utls.UtlsPaddingExtension, *utls.FakeRecordSizeLimitExtension:
+			return true
+		case *utls.ALPNExtension:
+			ext.AlpnProtocols = []string{"h3"}
+		case *utls.ApplicationSettingsExtension:
+			ext.SupportedProtocols = []string{"h3"}
+		case *utls.ApplicationSettingsExtensionNew:
+			ext.SupportedProtocols = []string{"h3"}
+		case *utls.SupportedVersionsExtension:
+			ext.Versions = slices.DeleteFunc(ext.Versions, func(v uint16) bool {
+				return v != utls.GREASE_PLACEHOLDER && v != utls.VersionTLS13
+			})
+		}
+		return false
+	})
+
+	tp := &utls.QUICTransportParametersExtension{TransportParameters: params}
+	spec.Extensions = append(spec.Extensions, tp)
+
+	// pre_shared_key must stay last
+	if i := slices.IndexFunc(spec.Extensions, func(ext utls.TLSExtension) bool {
+		_, ok := ext.(utls.PreSharedKeyExtension)
+		return ok
+	}); i >= 0 {
+		psk := spec.Extensions[i]
+		spec.Extensions = append(slices.Delete(spec.Extensions, i, i+1), psk)
+	}
+
+	if ts.shuffle {
+		spec.Extensions = utls.ShuffleChromeTLSExtensions(spec.Extensions)
+	}
+
+	return spec
+}
+
+// shuffleTransportParameters randomizes the order of params in place, as
+// Chromium does for every connection.
+func shuffleTransportParameters(params utls.TransportParameters) utls.TransportParameters {
+	rand.Shuffle(len(params), func(i, j int) {
+		params[i], params[j] = params[j], params[i]
+	})
+	return params
+}
@@ -0,0 +1,82 @@
+package mimic
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+func TestQUICSpec(t *testing.T) {
+	chrome, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := chrome.QUICSpec(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range spec.ClientHello.CipherSuites {
+		if id != utls.GREASE_PLACEHOLDER && id>>8 != 0x13 {
+			t.Errorf("want only TLS 1.3 cipher suites; got %#04x", id)
+		}
+	}
+
+	var params *utls.QUICTransportParametersExtension
+	for _, ext := range spec.ClientHello.Extensions {
+		switch ext := ext.(type) {
+		case *utls.QUICTransportParametersExtension:
+			params = ext
+		case *utls.ALPNExtension:
+			if !slices.Equal(ext.AlpnProtocols, []string{"h3"}) {
+				t.Errorf("want ALPN h3; got %v", ext.AlpnProtocols)
+			}
+		case *utls.SessionTicketExtension, *utls.SupportedPointsExtension, *utls.UtlsPaddingExtension:
+			t.Errorf("want %T removed", ext)
+		}
+	}
+	if params == nil || len(params.TransportParameters) != len(spec.TransportParameters) {
+		t.Fatal("want the transport parameters carried in the ClientHello")
+	}
+	if !slices.Contains(spec.TransportParameters, utls.TransportParameter(utls.InitialMaxData(15728640))) {
+		t.Error("want Chromium's initial_max_data")
+	}
+
+	if err := validateHello(spec.ClientHello); err != nil {
+		t.Errorf("want a buildable ClientHello; got %v", err)
+	}
+}
+
+func TestFirefoxQUICSpec(t *testing.T) {
+	old, err := Firefox("80.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.QUICSpec(PlatformWindows); !errors.Is(err, ErrQUICNotSupported) {
+		t.Errorf("want ErrQUICNotSupported before 88; got %v", err)
+	}
+
+	firefox, err := Firefox("135.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := func() string {
+		spec, err := firefox.QUICSpec(PlatformLinux)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var types string
+		for _, p := range spec.TransportParameters {
+			types += fmt.Sprintf("%T,", p)
+		}
+		return types
+	}
+	if a, b := order(), order(); a != b {
+		t.Errorf("want a fixed transport parameter order; got %s and %s", a, b)
+	}
+}
@@ -0,0 +1,297 @@
+package mimic
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// RateLimit describes a token bucket that refills at Rate requests per second
+// and holds up to Burst requests. A zero Rate means unlimited.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimiter paces requests per host. Each host gets its own token bucket,
+// using the limit of the first matching pattern or the default limit.
+// The zero value is an unlimited limiter.
+type RateLimiter struct {
+	mu         sync.Mutex
+	limit      RateLimit
+	rules      []rateLimitRule
+	buckets    map[string]*bucket
+	sweepAt    int
+	onThrottle func(Throttle)
+}
+
+// minBucketSweep is how many buckets a RateLimiter holds before it first drops
+// idle ones.
+const minBucketSweep = 64
+
+// Throttle describes a pause applied to a host after the server asked the client
+// to back off with a 429 or 503 response carrying Retry-After. RequestID and
+// SessionID come from the throttled request's context.
+type Throttle struct {
+	Host       string
+	StatusCode int
+	RetryAfter time.Duration
+	Until      time.Time
+	RequestID  string
+	SessionID  string
+}
+
+type rateLimitRule struct {
+	pattern string
+	limit   RateLimit
+}
+
+// NewRateLimiter creates a RateLimiter that applies limit to every host not
+// matched by a more specific pattern.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	return &RateLimiter{limit: limit}
+}
+
+// SetLimit applies limit to hosts matching pattern. A pattern is either an exact
+// hostname ("api.example.com") or a wildcard covering a domain and all of its
+// subdomains ("*.example.com"). Patterns are checked in the order they were added.
+func (l *RateLimiter) SetLimit(pattern string, limit RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rules = append(l.rules, rateLimitRule{pattern: strings.ToLower(pattern), limit: limit})
+
+	// existing buckets may now resolve to a different rule; they keep their
+	// pauses and the requests they have already let through
+	for host, b := range l.buckets {
+		b.limit = l.limitFor(host)
+	}
+}
+
+// OnThrottle sets a callback invoked whenever a Retry-After response pauses a host.
+func (l *RateLimiter) OnThrottle(fn func(Throttle)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.onThrottle = fn
+}
+
+// Pause holds back requests to host until the given time. Requests already
+// waiting are not affected; later requests wait for the pause and are then paced
+// by the host's normal limit.
+func (l *RateLimiter) Pause(host string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(strings.ToLower(host), time.Now())
+	if until.After(b.pausedUntil) {
+		b.pausedUntil = until
+	}
+}
+
+// Wait blocks until a request to host is allowed or ctx is done. A request
+// whose wait is cancelled still consumes its slot.
+func (l *RateLimiter) Wait(ctx context.Context, host string) error {
+	delay := l.reserve(host, time.Now())
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve takes a slot for host and returns how long the caller must wait for it.
+func (l *RateLimiter) reserve(host string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.bucket(strings.ToLower(host), now).reserve(now)
+}
+
+// bucket returns the bucket for host, creating it if needed. Creating one
+// drops the buckets idle at now once there are enough of them, so a client
+// that visits many hosts does not keep a bucket for each. l.mu must be held.
+func (l *RateLimiter) bucket(host string, now time.Time) *bucket {
+	b, ok := l.buckets[host]
+	if ok {
+		return b
+	}
+
+	if l.buckets == nil {
+		l.buckets = make(map[string]*bucket)
+	}
+	if len(l.buckets) >= max(l.sweepAt, minBucketSweep) {
+		for h, b := range l.buckets {
+			if b.idle(now) {
+				delete(l.buckets, h)
+			}
+		}
+		l.sweepAt = 2 * len(l.buckets)
+	}
+
+	b = &bucket{limit: l.limitFor(host)}
+	l.buckets[host] = b
+	return b
+}
+
+// throttle pauses host if res asks the client to back off.
+func (l *RateLimiter) throttle(host string, res *http.Response, now time.Time) {
+	if res.StatusCode != http.StatusTooManyRequests && res.StatusCode != http.StatusServiceUnavailable {
+		return
+	}
+
+	retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), now)
+	if !ok {
+		return
+	}
+
+	until := now.Add(retryAfter)
+	l.Pause(host, until)
+
+	l.mu.Lock()
+	onThrottle := l.onThrottle
+	l.mu.Unlock()
+
+	if onThrottle != nil {
+		throttle := Throttle{
+			Host:       host,
+			StatusCode: res.StatusCode,
+			RetryAfter: retryAfter,
+			Until:      until,
+		}
+		if res.Request != nil {
+			ctx := res.Request.Context()
+			throttle.RequestID, _ = RequestID(ctx)
+			throttle.SessionID, _ = SessionID(ctx)
+		}
+		onThrottle(throttle)
+	}
+}
+
+// parseRetryAfter parses a Retry-After value in either delay-seconds or
+// HTTP-date form, returning the delay relative to now.
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
+}
+
+func (l *RateLimiter) limitFor(host string) RateLimit {
+	for _, rule := range l.rules {
+		if matchHost(rule.pattern, host) {
+			return rule.limit
+		}
+	}
+	return l.limit
+}
+
+// bucket is a token bucket tracked as the theoretical arrival time of the next
+// request (GCRA), which avoids a background refill goroutine.
+type bucket struct {
+	limit       RateLimit
+	tat         time.Time
+	pausedUntil time.Time
+}
+
+// idle reports whether b is in the state of a new bucket at now: paused no
+// longer and with its whole burst available.
+func (b *bucket) idle(now time.Time) bool {
+	return !b.tat.After(now) && !b.pausedUntil.After(now)
+}
+
+func (b *bucket) reserve(now time.Time) time.Duration {
+	if now.Before(b.pausedUntil) {
+		// resume pacing from the end of the pause
+		if b.tat.Before(b.pausedUntil) {
+			b.tat = b.pausedUntil
+		}
+	}
+
+	if b.limit.Rate <= 0 {
+		return max(b.pausedUntil.Sub(now), 0)
+	}
+
+	interval := time.Duration(float64(time.Second) / b.limit.Rate)
+	burst := max(b.limit.Burst, 1)
+
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	allowAt := tat.Add(-time.Duration(burst-1) * interval)
+	if allowAt.Before(b.pausedUntil) {
+		allowAt = b.pausedUntil
+	}
+	b.tat = tat.Add(interval)
+
+	return max(allowAt.Sub(now), 0)
+}
+
+// matchHost reports whether host matches pattern, which is either an exact
+// hostname or "*." followed by a domain that matches itself and all subdomains.
+func matchHost(pattern, host string) bool {
+	if domain, ok := strings.CutPrefix(pattern, "*."); ok {
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	}
+	return host == pattern
+}
+
+// WithRateLimiter paces every request the client sends, including redirects,
+// through limiter. Responses with status 429 or 503 and a Retry-After header pause
+// further requests to that host for the requested duration.
+func WithRateLimiter(limiter *RateLimiter) ClientOption {
+	return func(c *clientConfig) {
+		c.rateLimiter = limiter
+	}
+}
+
+// rateLimitTransport waits for the limiter before each request.
+type rateLimitTransport struct {
+	wrapped
+	limiter *RateLimiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Hostname()
+	if err := t.limiter.Wait(req.Context(), host); err != nil {
+		closeRequestBody(req)
+		return nil, err
+	}
+
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.limiter.throttle(host, res, time.Now())
+
+	return res, nil
+}
@@ -0,0 +1,165 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestRateLimiterReserve(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 2, Burst: 2})
+	limiter.SetLimit("*.example.com", RateLimit{Rate: 1, Burst: 1})
+
+	now := time.Now()
+
+	tests := []struct {
+		host  string
+		delay time.Duration
+	}{
+		// default limit: two burst slots, then one every 500ms
+		{"other.com", 0},
+		{"other.com", 0},
+		{"other.com", 500 * time.Millisecond},
+		{"other.com", time.Second},
+		// wildcard matches the apex and subdomains, each with its own bucket
+		{"example.com", 0},
+		{"example.com", time.Second},
+		{"API.example.com", 0},
+		{"api.example.com", time.Second},
+	}
+
+	for _, test := range tests {
+		if delay := limiter.reserve(test.host, now); delay != test.delay {
+			t.Errorf("host %s: want %s; got %s", test.host, test.delay, delay)
+		}
+	}
+}
+
+func TestRateLimiterZeroValue(t *testing.T) {
+	var limiter RateLimiter
+	for range 10 {
+		if delay := limiter.reserve("example.com", time.Now()); delay != 0 {
+			t.Fatalf("want unlimited; got delay %s", delay)
+		}
+	}
+}
+
+func TestRateLimiterPause(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1, Burst: 5})
+
+	now := time.Now()
+	limiter.Pause("example.com", now.Add(10*time.Second))
+
+	if delay := limiter.reserve("example.com", now); delay != 10*time.Second {
+		t.Errorf("paused host: want 10s; got %s", delay)
+	}
+
+	if delay := limiter.reserve("other.com", now); delay != 0 {
+		t.Errorf("other host: want 0s; got %s", delay)
+	}
+}
+
+func TestRateLimiterSetLimitKeepsPause(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1, Burst: 5})
+
+	now := time.Now()
+	limiter.Pause("example.com", now.Add(10*time.Second))
+	limiter.SetLimit("*.example.com", RateLimit{Rate: 10, Burst: 1})
+
+	if delay := limiter.reserve("example.com", now); delay != 10*time.Second {
+		t.Errorf("paused host: want 10s; got %s", delay)
+	}
+	if delay := limiter.reserve("example.com", now); delay != 10*time.Second+100*time.Millisecond {
+		t.Errorf("paused host at the new limit: want 10.1s; got %s", delay)
+	}
+}
+
+func TestRateLimiterEvictsIdleBuckets(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1, Burst: 1})
+
+	now := time.Now()
+	limiter.Pause("paused.com", now.Add(time.Hour))
+	for i := range 1000 {
+		limiter.reserve(fmt.Sprintf("host%d.com", i), now.Add(time.Duration(i)*time.Second))
+	}
+
+	if n := len(limiter.buckets); n > 2*minBucketSweep {
+		t.Errorf("want idle buckets dropped; holding %d", n)
+	}
+	if delay := limiter.reserve("paused.com", now); delay != time.Hour {
+		t.Errorf("paused host: want 1h; got %s", delay)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Sun, 01 Jun 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 01 Jun 2025 11:00:00 GMT", 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		delay, ok := parseRetryAfter(test.value, now)
+		if delay != test.delay || ok != test.ok {
+			t.Errorf("value %q: want (%s, %t); got (%s, %t)", test.value, test.delay, test.ok, delay, ok)
+		}
+	}
+}
+
+func TestRateLimiterThrottleContext(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{})
+
+	var got Throttle
+	limiter.OnThrottle(func(th Throttle) { got = th })
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithSessionID(WithRequestID(req.Context(), "req-1"), "profile-a")
+
+	res := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": {"5"}},
+		Request:    req.WithContext(ctx),
+	}
+	limiter.throttle("example.com", res, time.Now())
+
+	if got.RequestID != "req-1" || got.SessionID != "profile-a" {
+		t.Errorf("want request and session IDs from the context; got %q and %q", got.RequestID, got.SessionID)
+	}
+}
+
+func TestRateLimitTransportClosesBodyOnCancel(t *testing.T) {
+	limiter := NewRateLimiter(RateLimit{Rate: 1, Burst: 1})
+	limiter.Pause("example.com", time.Now().Add(time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	body := &closeRecorder{Reader: strings.NewReader("a=1")}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport := &rateLimitTransport{wrapped: wrapped{http.DefaultTransport}, limiter: limiter}
+	if _, err := transport.RoundTrip(req); !errors.Is(err, context.Canceled) || !body.closed {
+		t.Errorf("want the body of a canceled request closed; got %v, closed %t", err, body.closed)
+	}
+}
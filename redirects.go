@@ -0,0 +1,65 @@
+package mimic
+
+import (
+	"net/url"
+	"slices"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Redirect is one hop of a redirect chain: a request and the redirect that
+// answered it.
+type Redirect struct {
+	// Method and URL are the request's.
+	Method string
+	URL    *url.URL
+
+	// Header holds the request headers as the Transport sent them, including
+	// the browser's defaults and the cookies the client added, without
+	// fhttp's header order keys.
+	Header http.Header
+
+	// StatusCode and Location are the redirect's.
+	StatusCode int
+	Location   string
+
+	// Cookies are the cookies the redirect set.
+	Cookies []*http.Cookie
+}
+
+// RedirectsFromResponse returns the redirects an http.Client followed to get
+// res, in the order they happened. It is empty when the first request was
+// answered without a redirect. The request that got res, with the headers it
+// was sent with, is res.Request.
+//
+// Hops are found through each request's Response field, which the client
+// sets to the redirect that caused it, so responses from clients other than
+// NewClient's report their headers as the caller set them, and responses
+// served from a Cache report the headers of the request the cache answered.
+func RedirectsFromResponse(res *http.Response) []Redirect {
+	var chain []Redirect
+	for req := res.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		prev := req.Response
+		hop := Redirect{
+			StatusCode: prev.StatusCode,
+			Location:   prev.Header.Get("Location"),
+			Cookies:    prev.Cookies(),
+		}
+		if sent := prev.Request; sent != nil {
+			hop.Method = sent.Method
+			hop.URL = sent.URL
+			hop.Header = sentHeader(sent.Header)
+		}
+		chain = append(chain, hop)
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// sentHeader returns a copy of h without fhttp's header order keys.
+func sentHeader(h http.Header) http.Header {
+	h = h.Clone()
+	delete(h, http.HeaderOrderKey)
+	delete(h, http.PHeaderOrderKey)
+	return h
+}
@@ -0,0 +1,68 @@
+package mimic
+
+import (
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestRedirectsFromResponse(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		switch r.URL.Path {
+		case "/":
+			stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "step", Value: "1"})
+			stdhttp.Redirect(w, r, "/a", stdhttp.StatusFound)
+		case "/a":
+			stdhttp.Redirect(w, r, "/b", stdhttp.StatusMovedPermanently)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := client.Get(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	chain := RedirectsFromResponse(res)
+	if len(chain) != 2 {
+		t.Fatalf("got %d redirects, want 2", len(chain))
+	}
+
+	first, second := chain[0], chain[1]
+	if first.URL.Path != "/" || first.StatusCode != http.StatusFound || first.Location != "/a" {
+		t.Errorf("first hop = %s %d to %s, want / 302 to /a", first.URL.Path, first.StatusCode, first.Location)
+	}
+	if len(first.Cookies) != 1 || first.Cookies[0].Name != "step" {
+		t.Errorf("first hop cookies = %v, want step", first.Cookies)
+	}
+	if first.Header.Get("User-Agent") == "" || first.Header[http.HeaderOrderKey] != nil {
+		t.Errorf("want the first hop's sent headers without order keys; got %v", first.Header)
+	}
+	if second.URL.Path != "/a" || second.StatusCode != http.StatusMovedPermanently || second.Method != http.MethodGet {
+		t.Errorf("second hop = %s %s %d, want GET /a 301", second.Method, second.URL.Path, second.StatusCode)
+	}
+	if second.Header.Get("Cookie") != "step=1" {
+		t.Errorf("second hop cookie = %q, want step=1", second.Header.Get("Cookie"))
+	}
+
+	if got := RedirectsFromResponse(&http.Response{Request: &http.Request{URL: first.URL}}); len(got) != 0 {
+		t.Errorf("want no redirects for a direct response; got %d", len(got))
+	}
+}
@@ -0,0 +1,189 @@
+package mimic
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+	"golang.org/x/net/html"
+)
+
+// maxRefreshScan bounds how much of an HTML document is searched for a
+// <meta http-equiv="refresh"> tag.
+const maxRefreshScan = 1 << 20
+
+// WithMetaRefresh makes the client follow Refresh headers and
+// <meta http-equiv="refresh"> tags on GET responses, as browsers do without
+// running scripts. Like a browser, the client reads the whole document, then
+// waits out the refresh delay before navigating. Refreshes with a delay longer
+// than maxDelay are left to the caller.
+//
+// A followed refresh is handed to the client as a 303 redirect to the refresh
+// URL, so it shares the redirect limit, CheckRedirect, cookies, and referer
+// handling of ordinary redirects.
+func WithMetaRefresh(maxDelay time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.refreshMaxDelay = &maxDelay
+	}
+}
+
+// refreshTransport turns refreshes into redirects.
+type refreshTransport struct {
+	wrapped
+	maxDelay time.Duration
+}
+
+func (t *refreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method != http.MethodGet || (res.StatusCode >= 300 && res.StatusCode < 400) {
+		return res, nil
+	}
+
+	value := res.Header.Get("Refresh")
+	if value == "" && isHTML(res.Header) {
+		scanned, err := io.ReadAll(io.LimitReader(res.Body, maxRefreshScan))
+		if err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+		res.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(scanned), res.Body), body: res.Body}
+		value = metaRefresh(scanned)
+	}
+	if value == "" {
+		return res, nil
+	}
+
+	delay, target, ok := parseRefresh(value)
+	if !ok || delay > t.maxDelay {
+		return res, nil
+	}
+
+	location, err := req.URL.Parse(target)
+	if err != nil || (location.Scheme != "http" && location.Scheme != "https") {
+		return res, nil
+	}
+
+	// the refresh timer starts once the document has loaded
+	_, err = io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+
+	res.Status = "303 See Other"
+	res.StatusCode = http.StatusSeeOther
+	res.Header.Set("Location", location.String())
+	res.Header.Del("Content-Length")
+	res.ContentLength = 0
+	res.Body = http.NoBody
+
+	return res, nil
+}
+
+// prefixedBody reads the part of a body already consumed, then the rest.
+type prefixedBody struct {
+	io.Reader
+	body io.Closer
+}
+
+func (b *prefixedBody) Close() error {
+	return b.body.Close()
+}
+
+func isHTML(h http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// metaRefresh returns the content of the first <meta http-equiv="refresh">
+// tag in doc. Tags inside <noscript> are skipped, as a browser running scripts
+// skips them.
+func metaRefresh(doc []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(doc))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			if string(name) != "meta" || !hasAttr {
+				continue
+			}
+
+			var refresh bool
+			var content string
+			var hasContent bool
+			for hasAttr {
+				var key, val []byte
+				key, val, hasAttr = z.TagAttr()
+				switch string(key) {
+				case "http-equiv":
+					refresh = strings.EqualFold(string(val), "refresh")
+				case "content":
+					content, hasContent = string(val), true
+				}
+			}
+			if refresh && hasContent {
+				return content
+			}
+		}
+	}
+}
+
+// parseRefresh parses a Refresh header or meta refresh content following the
+// HTML standard's shared declarative refresh steps. An empty target means the
+// document itself.
+func parseRefresh(value string) (delay time.Duration, target string, ok bool) {
+	s := strings.TrimLeft(value, " \t\n\f\r")
+
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	if digits == 0 && !strings.HasPrefix(s, ".") {
+		return 0, "", false
+	}
+	seconds, err := strconv.Atoi(s[:digits])
+	if digits > 0 && err != nil {
+		return 0, "", false
+	}
+	s = strings.TrimLeft(s[digits:], "0123456789.")
+
+	if s != "" && !strings.ContainsRune(";, \t\n\f\r", rune(s[0])) {
+		return 0, "", false
+	}
+	s = strings.TrimLeft(s, " \t\n\f\r")
+	if s != "" && (s[0] == ';' || s[0] == ',') {
+		s = strings.TrimLeft(s[1:], " \t\n\f\r")
+	}
+
+	if len(s) >= 3 && strings.EqualFold(s[:3], "url") {
+		rest := strings.TrimLeft(s[3:], " \t\n\f\r")
+		if strings.HasPrefix(rest, "=") {
+			s = strings.TrimLeft(rest[1:], " \t\n\f\r")
+		}
+	}
+
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		quote := s[0]
+		s = s[1:]
+		if i := strings.IndexByte(s, quote); i >= 0 {
+			s = s[:i]
+		}
+	}
+
+	return time.Duration(seconds) * time.Second, strings.TrimRight(s, " \t\n\f\r"), true
+}
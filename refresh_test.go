@@ -0,0 +1,151 @@
+package mimic
+
+import (
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestParseRefresh(t *testing.T) {
+	tests := []struct {
+		value  string
+		delay  time.Duration
+		target string
+		ok     bool
+	}{
+		{"0; url=/next", 0, "/next", true},
+		{"5;URL='/next?a=1'", 5 * time.Second, "/next?a=1", true},
+		{" 3 , url = \"https://example.com/\" ", 3 * time.Second, "https://example.com/", true},
+		{"1; /next", time.Second, "/next", true},
+		{"2.5;url=/next", 2 * time.Second, "/next", true},
+		{".5", 0, "", true},
+		{"10", 10 * time.Second, "", true},
+		{"url=/next", 0, "", false},
+		{"5x;url=/next", 0, "", false},
+		{"", 0, "", false},
+	}
+
+	for _, tt := range tests {
+		delay, target, ok := parseRefresh(tt.value)
+		if delay != tt.delay || target != tt.target || ok != tt.ok {
+			t.Errorf("%q: want %s %q %v; got %s %q %v", tt.value, tt.delay, tt.target, tt.ok, delay, target, ok)
+		}
+	}
+}
+
+func TestMetaRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"meta", `<html><head><noscript><meta http-equiv="refresh" content="0;url=/wrong"></noscript>` +
+			`<meta http-equiv="Refresh" content="0; url=/next"></head></html>`},
+		{"header", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				switch r.URL.Path {
+				case "/":
+					stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "clearance", Value: "1"})
+					if tt.doc == "" {
+						w.Header().Set("Refresh", "0;url=/next")
+					}
+					w.Header().Set("Content-Type", "text/html; charset=utf-8")
+					w.WriteHeader(stdhttp.StatusServiceUnavailable)
+					io.WriteString(w, tt.doc)
+				case "/next":
+					if _, err := r.Cookie("clearance"); err != nil {
+						w.WriteHeader(stdhttp.StatusForbidden)
+						return
+					}
+					io.WriteString(w, "welcome")
+				default:
+					w.WriteHeader(stdhttp.StatusNotFound)
+				}
+			}))
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+
+			spec, err := Chromium(BrandChrome, "137.0.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+			client, err := NewClient(spec, PlatformWindows,
+				WithTransportOptions(WithBaseTransport(base)),
+				WithMetaRefresh(time.Second),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.CloseIdleConnections()
+
+			res, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.StatusCode != http.StatusOK || string(body) != "welcome" {
+				t.Errorf("want the refresh followed with its cookie; got %d %q", res.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestMetaRefreshMaxDelay(t *testing.T) {
+	doc := `<meta http-equiv="refresh" content="30;url=/next"><p>wait</p>`
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, doc)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformWindows,
+		WithTransportOptions(WithBaseTransport(base)),
+		WithMetaRefresh(time.Second),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	res, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(body) != doc {
+		t.Errorf("want the document returned whole when the delay is too long; got %q", body)
+	}
+}
+
+func TestMetaRefreshCloseIdleConnections(t *testing.T) {
+	testClientClosesIdle(t, WithMetaRefresh(time.Second))
+}
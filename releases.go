@@ -0,0 +1,237 @@
+package mimic
+
+import (
+	"fmt"
+	"time"
+)
+
+// Browser names a browser RecommendedVersions advises on.
+type Browser string
+
+const (
+	BrowserChrome  Browser = "chrome"
+	BrowserFirefox Browser = "firefox"
+	BrowserSafari  Browser = "safari"
+)
+
+// Channel is the release channel a recommended version comes from.
+type Channel string
+
+const (
+	// ChannelStable is the newest stable release.
+	ChannelStable Channel = "stable"
+	// ChannelPrevious is the stable release before it, still run by users
+	// the update has not reached.
+	ChannelPrevious Channel = "previous"
+	// ChannelBeta is the next release, in beta.
+	ChannelBeta Channel = "beta"
+)
+
+// VersionChoice is a browser version plausibly in use at a date.
+type VersionChoice struct {
+	Browser Browser
+	Version string
+	Channel Channel
+	// Weight is the share of the browser's users on Version. The weights of
+	// a browser's choices sum to 1.
+	Weight float64
+}
+
+// Spec returns a ClientSpec for the choice, with Chrome as the Chromium brand.
+func (v VersionChoice) Spec(opts ...SpecOption) (*ClientSpec, error) {
+	switch v.Browser {
+	case BrowserChrome:
+		return Chromium(BrandChrome, v.Version, opts...)
+	case BrowserFirefox:
+		return Firefox(v.Version, opts...)
+	case BrowserSafari:
+		return Safari(v.Version, opts...)
+	default:
+		return nil, fmt.Errorf("browser %q: %w", v.Browser, ErrUnsupportedVersion)
+	}
+}
+
+const (
+	// fourWeeks is the release cadence of Chrome and Firefox, which the
+	// releases after the tables are assumed to keep.
+	fourWeeks = 28 * 24 * time.Hour
+
+	// rolloutPeriod is how long a stable release takes to reach nearly all
+	// of a browser's users, between staged rollout and users restarting.
+	rolloutPeriod = 21 * 24 * time.Hour
+
+	// betaWeight is the share of users on a beta channel.
+	betaWeight = 0.02
+)
+
+// release is the date a browser version reached the stable channel.
+type release struct {
+	major, minor int
+	date         time.Time
+}
+
+// day returns midnight UTC on the given date.
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+// chromeReleases are Chrome's stable releases from the oldest mimic supports.
+var chromeReleases = majorReleases(83,
+	day(2020, 5, 19), day(2020, 7, 14), day(2020, 8, 25), day(2020, 10, 6), day(2020, 11, 17), day(2021, 1, 19),
+	day(2021, 3, 2), day(2021, 4, 13), day(2021, 5, 25), day(2021, 7, 20), day(2021, 8, 31), day(2021, 9, 21),
+	day(2021, 10, 19), day(2021, 11, 15), day(2022, 1, 4), day(2022, 2, 1), day(2022, 3, 1), day(2022, 3, 29),
+	day(2022, 4, 26), day(2022, 5, 24), day(2022, 6, 21), day(2022, 8, 2), day(2022, 8, 30), day(2022, 9, 27),
+	day(2022, 10, 25), day(2022, 11, 29), day(2023, 1, 10), day(2023, 2, 7), day(2023, 3, 7), day(2023, 4, 4),
+	day(2023, 5, 2), day(2023, 5, 30), day(2023, 7, 18), day(2023, 8, 15), day(2023, 9, 12), day(2023, 10, 10),
+	day(2023, 10, 31), day(2023, 12, 6), day(2024, 1, 23), day(2024, 2, 20), day(2024, 3, 19), day(2024, 4, 16),
+	day(2024, 5, 14), day(2024, 6, 11), day(2024, 7, 23), day(2024, 8, 20), day(2024, 9, 17), day(2024, 10, 15),
+	day(2024, 11, 12), day(2025, 1, 14), day(2025, 2, 4), day(2025, 3, 4), day(2025, 4, 1), day(2025, 4, 29),
+	day(2025, 5, 27), day(2025, 6, 24), day(2025, 8, 5), day(2025, 9, 2), day(2025, 9, 30), day(2025, 10, 28),
+)
+
+// firefoxReleases are Firefox's stable releases from the oldest mimic
+// supports.
+var firefoxReleases = majorReleases(55,
+	day(2017, 8, 8), day(2017, 9, 28), day(2017, 11, 14), day(2018, 1, 23), day(2018, 3, 13), day(2018, 5, 9),
+	day(2018, 6, 26), day(2018, 9, 5), day(2018, 10, 23), day(2018, 12, 11), day(2019, 1, 29), day(2019, 3, 19),
+	day(2019, 5, 21), day(2019, 7, 9), day(2019, 9, 3), day(2019, 10, 22), day(2019, 12, 3), day(2020, 1, 7),
+	day(2020, 2, 11), day(2020, 3, 10), day(2020, 4, 7), day(2020, 5, 5), day(2020, 6, 2), day(2020, 6, 30),
+	day(2020, 7, 28), day(2020, 8, 25), day(2020, 9, 22), day(2020, 10, 20), day(2020, 11, 17), day(2020, 12, 15),
+	day(2021, 1, 26), day(2021, 2, 23), day(2021, 3, 23), day(2021, 4, 19), day(2021, 6, 1), day(2021, 7, 13),
+	day(2021, 8, 10), day(2021, 9, 7), day(2021, 10, 5), day(2021, 11, 2), day(2021, 12, 7), day(2022, 1, 11),
+	day(2022, 2, 8), day(2022, 3, 8), day(2022, 4, 5), day(2022, 5, 3), day(2022, 5, 31), day(2022, 6, 28),
+	day(2022, 7, 26), day(2022, 8, 23), day(2022, 9, 20), day(2022, 10, 18), day(2022, 11, 15), day(2022, 12, 13),
+	day(2023, 1, 17), day(2023, 2, 14), day(2023, 3, 14), day(2023, 4, 11), day(2023, 5, 9), day(2023, 6, 6),
+	day(2023, 7, 4), day(2023, 8, 1), day(2023, 8, 29), day(2023, 9, 26), day(2023, 10, 24), day(2023, 11, 21),
+	day(2023, 12, 19), day(2024, 1, 23), day(2024, 2, 20), day(2024, 3, 19), day(2024, 4, 16), day(2024, 5, 14),
+	day(2024, 6, 11), day(2024, 7, 9), day(2024, 8, 6), day(2024, 9, 3), day(2024, 10, 1), day(2024, 10, 29),
+	day(2024, 11, 26), day(2025, 1, 7), day(2025, 2, 4), day(2025, 3, 4), day(2025, 4, 1), day(2025, 4, 29),
+	day(2025, 5, 27), day(2025, 6, 24), day(2025, 7, 22), day(2025, 8, 19), day(2025, 9, 16), day(2025, 10, 14),
+)
+
+// safariReleases are Safari's releases from the oldest mimic supports, by
+// minor version. Safari 26 followed 18, when Apple numbered its releases
+// after the year.
+var safariReleases = []release{
+	{14, 0, day(2020, 9, 16)}, {14, 1, day(2021, 4, 26)},
+	{15, 0, day(2021, 9, 20)}, {15, 1, day(2021, 10, 25)}, {15, 2, day(2021, 12, 13)}, {15, 3, day(2022, 1, 26)},
+	{15, 4, day(2022, 3, 14)}, {15, 5, day(2022, 5, 16)}, {15, 6, day(2022, 7, 20)},
+	{16, 0, day(2022, 9, 12)}, {16, 1, day(2022, 10, 24)}, {16, 2, day(2022, 12, 13)}, {16, 3, day(2023, 1, 23)},
+	{16, 4, day(2023, 3, 27)}, {16, 5, day(2023, 5, 18)}, {16, 6, day(2023, 7, 24)},
+	{17, 0, day(2023, 9, 18)}, {17, 1, day(2023, 10, 25)}, {17, 2, day(2023, 12, 11)}, {17, 3, day(2024, 1, 22)},
+	{17, 4, day(2024, 3, 5)}, {17, 5, day(2024, 5, 13)}, {17, 6, day(2024, 7, 29)},
+	{18, 0, day(2024, 9, 16)}, {18, 1, day(2024, 10, 28)}, {18, 2, day(2024, 12, 11)}, {18, 3, day(2025, 1, 27)},
+	{18, 4, day(2025, 3, 31)}, {18, 5, day(2025, 5, 12)}, {18, 6, day(2025, 7, 29)},
+	{26, 0, day(2025, 9, 15)},
+}
+
+// safariMinorOffsets are the days after a Safari major each of its minor
+// releases typically ships, which the releases after the table are assumed
+// to keep.
+var safariMinorOffsets = []int{0, 42, 87, 130, 175, 240, 315}
+
+func majorReleases(first int, dates ...time.Time) []release {
+	releases := make([]release, len(dates))
+	for i, d := range dates {
+		releases[i] = release{major: first + i, date: d}
+	}
+	return releases
+}
+
+// RecommendedVersions returns the Chrome, Firefox, and Safari versions
+// plausibly in use at asOf: for each, the stable release, the release before
+// it, and for Chrome and Firefox the beta, weighted by their share of the
+// browser's users. Shortly after a release most users are still on the
+// previous one; three weeks on, nearly all have updated. Versions come from
+// the browsers' release histories, and past their end from their release
+// cadences, so a fleet whose configs are pinned for weeks can pick versions
+// that still look current on the date they are used. Browsers mimic did not
+// support yet at asOf are left out.
+func RecommendedVersions(asOf time.Time) []VersionChoice {
+	var choices []VersionChoice
+
+	chrome := extendMajors(chromeReleases, asOf)
+	choices = append(choices, recommend(BrowserChrome, chrome, asOf, true, func(r release) string {
+		return fmt.Sprintf("%d.0.0.0", r.major)
+	})...)
+
+	firefox := extendMajors(firefoxReleases, asOf)
+	choices = append(choices, recommend(BrowserFirefox, firefox, asOf, true, func(r release) string {
+		return fmt.Sprintf("%d.0", r.major)
+	})...)
+
+	safari := extendSafari(safariReleases, asOf)
+	choices = append(choices, recommend(BrowserSafari, safari, asOf, false, func(r release) string {
+		return fmt.Sprintf("%d.%d", r.major, r.minor)
+	})...)
+
+	return choices
+}
+
+// extendMajors returns releases with a major every four weeks after the last
+// until one is past asOf.
+func extendMajors(releases []release, asOf time.Time) []release {
+	last := releases[len(releases)-1]
+	for !last.date.After(asOf) {
+		last = release{major: last.major + 1, date: last.date.Add(fourWeeks)}
+		releases = append(releases[:len(releases):len(releases)], last)
+	}
+	return releases
+}
+
+// extendSafari returns releases with a major every September after the last
+// and its minors at safariMinorOffsets, until one is past asOf.
+func extendSafari(releases []release, asOf time.Time) []release {
+	last := releases[len(releases)-1]
+	base := last.date.AddDate(0, 0, -safariMinorOffsets[last.minor])
+	for major, minor := last.major, last.minor+1; !last.date.After(asOf); minor++ {
+		if minor == len(safariMinorOffsets) {
+			major, minor = major+1, 0
+			base = base.AddDate(1, 0, 0)
+		}
+		last = release{major: major, minor: minor, date: base.AddDate(0, 0, safariMinorOffsets[minor])}
+		releases = append(releases[:len(releases):len(releases)], last)
+	}
+	return releases
+}
+
+// recommend returns the choices for a browser's releases at asOf.
+func recommend(browser Browser, releases []release, asOf time.Time, beta bool, version func(release) string) []VersionChoice {
+	stable := -1
+	for i, r := range releases {
+		if !r.date.After(asOf) {
+			stable = i
+		}
+	}
+	if stable < 0 {
+		return nil
+	}
+
+	rollout := min(float64(asOf.Sub(releases[stable].date))/float64(rolloutPeriod), 1)
+	share := 0.15 + 0.8*rollout
+
+	rest := 1.0
+	if beta {
+		rest -= betaWeight
+	}
+
+	choices := []VersionChoice{{Browser: browser, Version: version(releases[stable]), Channel: ChannelStable, Weight: rest}}
+	if stable > 0 {
+		choices[0].Weight = rest * share
+		choices = append(choices, VersionChoice{
+			Browser: browser,
+			Version: version(releases[stable-1]),
+			Channel: ChannelPrevious,
+			Weight:  rest * (1 - share),
+		})
+	}
+	if beta {
+		choices = append(choices, VersionChoice{
+			Browser: browser,
+			Version: version(releases[stable+1]),
+			Channel: ChannelBeta,
+			Weight:  betaWeight,
+		})
+	}
+	return choices
+}
@@ -0,0 +1,78 @@
+package mimic
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRecommendedVersions(t *testing.T) {
+	tests := []struct {
+		asOf time.Time
+		want map[Browser][]string
+	}{
+		{
+			day(2025, 6, 1),
+			map[Browser][]string{
+				BrowserChrome:  {"137.0.0.0", "136.0.0.0", "138.0.0.0"},
+				BrowserFirefox: {"139.0", "138.0", "140.0"},
+				BrowserSafari:  {"18.5", "18.4"},
+			},
+		},
+		{
+			// past the tables, from the release cadences
+			day(2026, 10, 16),
+			map[Browser][]string{
+				BrowserChrome:  {"154.0.0.0", "153.0.0.0", "155.0.0.0"},
+				BrowserFirefox: {"157.0", "156.0", "158.0"},
+				BrowserSafari:  {"27.0", "26.6"},
+			},
+		},
+		{
+			// before Chrome 83
+			day(2019, 6, 1),
+			map[Browser][]string{
+				BrowserFirefox: {"67.0", "66.0", "68.0"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := make(map[Browser][]string)
+		weights := make(map[Browser]float64)
+		for _, c := range RecommendedVersions(test.asOf) {
+			got[c.Browser] = append(got[c.Browser], c.Version)
+			weights[c.Browser] += c.Weight
+
+			if _, err := c.Spec(); err != nil {
+				t.Errorf("%s: %s %s: %v", test.asOf.Format(time.DateOnly), c.Browser, c.Version, err)
+			}
+		}
+
+		for _, browser := range []Browser{BrowserChrome, BrowserFirefox, BrowserSafari} {
+			if g, w := got[browser], test.want[browser]; !slices.Equal(g, w) {
+				t.Errorf("%s: %s versions = %v, want %v", test.asOf.Format(time.DateOnly), browser, g, w)
+			}
+			if len(got[browser]) > 0 && math.Abs(weights[browser]-1) > 1e-9 {
+				t.Errorf("%s: %s weights sum to %f, want 1", test.asOf.Format(time.DateOnly), browser, weights[browser])
+			}
+		}
+	}
+}
+
+func TestRecommendedVersionsRollout(t *testing.T) {
+	weight := func(asOf time.Time, channel Channel) float64 {
+		for _, c := range RecommendedVersions(asOf) {
+			if c.Browser == BrowserChrome && c.Channel == channel {
+				return c.Weight
+			}
+		}
+		return 0
+	}
+
+	release := day(2025, 5, 27)
+	if early, late := weight(release, ChannelStable), weight(release.AddDate(0, 0, 21), ChannelStable); early >= weight(release, ChannelPrevious) || late <= 0.9 {
+		t.Errorf("stable weight = %f on release and %f three weeks on, want a minority then nearly all", early, late)
+	}
+}
@@ -0,0 +1,113 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"slices"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// NewNavigationRequest returns a GET request for rawURL with the headers spec
+// sends on platform when navigating to a page, in the browser's order.
+// referrer is the page the navigation starts from, or empty for a URL typed
+// into the address bar.
+//
+// The request carries its intent like one sent with Fetch, so the mimic
+// Transport recomputes the headers that depend on the URL, Sec-Fetch-Site,
+// Origin, and Referer, on every redirect.
+func NewNavigationRequest(ctx context.Context, spec *ClientSpec, platform Platform, rawURL, referrer string) (*http.Request, error) {
+	return newProfileRequest(ctx, spec, platform, rawURL, FetchOptions{
+		Profile:  ProfileNavigation,
+		Referrer: referrer,
+	})
+}
+
+// NewReloadRequest returns a GET request that reloads the page at rawURL the
+// way reload does, with the headers spec sends on platform in the browser's
+// order. referrer is the page's original referrer, or empty if it had none.
+func NewReloadRequest(ctx context.Context, spec *ClientSpec, platform Platform, rawURL, referrer string, reload Reload) (*http.Request, error) {
+	return newProfileRequest(ctx, spec, platform, rawURL, FetchOptions{
+		Profile:  ProfileNavigation,
+		Referrer: referrer,
+		Reload:   reload,
+	})
+}
+
+// NewXHRRequest returns a request for rawURL with the headers spec sends on
+// platform for an XMLHttpRequest from the page at referrer, in the browser's
+// order. Browsers do not add X-Requested-With; libraries such as jQuery do.
+func NewXHRRequest(ctx context.Context, spec *ClientSpec, platform Platform, method, rawURL, referrer string, body io.Reader) (*http.Request, error) {
+	return newProfileRequest(ctx, spec, platform, rawURL, FetchOptions{
+		Method:   method,
+		Body:     body,
+		Profile:  ProfileXHR,
+		Referrer: referrer,
+	})
+}
+
+// NewJSONFetchRequest returns a request for rawURL with the headers spec sends
+// on platform for a fetch() call from the page at referrer that exchanges
+// JSON, in the browser's order. It accepts application/json and, when body is
+// not nil, sends it as application/json.
+func NewJSONFetchRequest(ctx context.Context, spec *ClientSpec, platform Platform, method, rawURL, referrer string, body io.Reader) (*http.Request, error) {
+	header := http.Header{"Accept": {"application/json"}}
+	if body != nil {
+		header.Set("Content-Type", "application/json")
+	}
+
+	return newProfileRequest(ctx, spec, platform, rawURL, FetchOptions{
+		Method:   method,
+		Header:   header,
+		Body:     body,
+		Profile:  ProfileFetch,
+		Referrer: referrer,
+	})
+}
+
+// NewFormRequest returns a POST request for rawURL submitting form from the
+// page at referrer, encoded with enctype as Form.Encode does, with the headers
+// spec sends on platform for a form submission, in the browser's order. The
+// multipart boundary is in spec's browser's style. Submit a form with method
+// GET by navigating to its action with the fields as the query.
+func NewFormRequest(ctx context.Context, spec *ClientSpec, platform Platform, rawURL, referrer string, enctype FormEncoding, form *Form) (*http.Request, error) {
+	req, intent, err := newFormRequest(ctx, rawURL, referrer, enctype, form, spec.fetch.boundary)
+	if err != nil {
+		return nil, err
+	}
+	return profileRequest(spec, platform, req, intent)
+}
+
+// newProfileRequest builds the request Fetch would send for opts, with the
+// browser's default and derived headers already set.
+func newProfileRequest(ctx context.Context, spec *ClientSpec, platform Platform, rawURL string, opts FetchOptions) (*http.Request, error) {
+	req, intent, err := newFetchRequest(ctx, rawURL, opts)
+	if err != nil {
+		return nil, err
+	}
+	return profileRequest(spec, platform, req, intent)
+}
+
+// profileRequest sets the browser's default and derived headers on req, a
+// request carrying intent.
+func profileRequest(spec *ClientSpec, platform Platform, req *http.Request, intent *fetchIntent) (*http.Request, error) {
+	defaults, err := spec.buildHeaders(platform)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range defaults {
+		if _, ok := req.Header[key]; !ok {
+			req.Header[key] = values
+		}
+	}
+
+	intent.setHeaders(req.Header, req.Method, req.URL, spec.fetch)
+
+	order := spec.fetch.fetchOrder
+	if intent.mode == ModeNavigate {
+		order = spec.fetch.navigationOrder
+	}
+	req.Header[http.HeaderOrderKey] = slices.Clone(order)
+
+	return req, nil
+}
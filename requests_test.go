@@ -0,0 +1,183 @@
+package mimic
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestNewNavigationRequest(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewNavigationRequest(context.Background(), spec, PlatformWindows, "https://www.example.com/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"sec-ch-ua-platform": `"Windows"`,
+		"user-agent":         "",
+		"accept":             spec.fetch.document,
+		"sec-fetch-site":     "none",
+		"sec-fetch-user":     "?1",
+	} {
+		got := req.Header.Get(key)
+		if got == "" || (want != "" && got != want) {
+			t.Errorf("want %s %q; got %q", key, want, got)
+		}
+	}
+
+	// every header set must be placed by the order, or it sorts last
+	for key := range req.Header {
+		if key == http.HeaderOrderKey {
+			continue
+		}
+		if !slices.Contains(req.Header[http.HeaderOrderKey], strings.ToLower(key)) {
+			t.Errorf("want %s in the header order", key)
+		}
+	}
+	if !slices.Equal(req.Header[http.HeaderOrderKey], chromiumNavigationOrder) {
+		t.Errorf("want chromium's navigation order; got %v", req.Header[http.HeaderOrderKey])
+	}
+
+	// the transport keeps the order rather than shuffling
+	tr := newTestTransport(t)
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Request.Header[http.HeaderOrderKey], chromiumNavigationOrder) {
+		t.Errorf("want the order sent unchanged; got %v", res.Request.Header[http.HeaderOrderKey])
+	}
+}
+
+func TestNewReloadRequest(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		reload       Reload
+		profile      FetchProfile
+		pragma       string
+		cacheControl string
+	}{
+		{LoadNormal, ProfileNavigation, "", ""},
+		{LoadReload, ProfileNavigation, "", "max-age=0"},
+		{LoadHardReload, ProfileNavigation, "no-cache", "no-cache"},
+		{LoadReload, ProfileImage, "", ""},
+		{LoadHardReload, ProfileImage, "no-cache", "no-cache"},
+	}
+
+	for _, test := range tests {
+		req, err := newProfileRequest(context.Background(), spec, PlatformWindows, "https://www.example.com/", FetchOptions{
+			Profile:  test.profile,
+			Referrer: "https://www.example.com/",
+			Reload:   test.reload,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := req.Header.Get("Pragma"); got != test.pragma {
+			t.Errorf("%d, profile %d: want pragma %q; got %q", test.reload, test.profile, test.pragma, got)
+		}
+		if got := req.Header.Get("Cache-Control"); got != test.cacheControl {
+			t.Errorf("%d, profile %d: want cache-control %q; got %q", test.reload, test.profile, test.cacheControl, got)
+		}
+	}
+
+	req, err := NewReloadRequest(context.Background(), spec, PlatformWindows, "https://www.example.com/", "", LoadHardReload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the order also lists the body headers of form POSTs, which come first
+	order := slices.DeleteFunc(slices.Clone(req.Header[http.HeaderOrderKey]), func(key string) bool {
+		return req.Header.Get(key) == ""
+	})
+	if !slices.Equal(order[:3], []string{"pragma", "cache-control", "sec-ch-ua"}) {
+		t.Errorf("want the cache headers first, as chromium sends them; got %v", order)
+	}
+}
+
+func TestNewJSONFetchRequest(t *testing.T) {
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := NewJSONFetchRequest(context.Background(), spec, PlatformLinux,
+		http.MethodPost, "https://api.example.com/cart", "https://www.example.com/", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"accept":         "application/json",
+		"content-type":   "application/json",
+		"origin":         "https://www.example.com",
+		"sec-fetch-mode": "cors",
+		"sec-fetch-site": "same-site",
+		"te":             "trailers",
+	} {
+		if got := req.Header.Get(key); got != want {
+			t.Errorf("want %s %q; got %q", key, want, got)
+		}
+	}
+	if !slices.Equal(req.Header[http.HeaderOrderKey], firefoxFetchOrder) {
+		t.Errorf("want firefox's fetch order; got %v", req.Header[http.HeaderOrderKey])
+	}
+
+	req, err = NewXHRRequest(context.Background(), spec, PlatformLinux,
+		http.MethodGet, "https://www.example.com/poll", "https://www.example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("accept"); got != "*/*" {
+		t.Errorf("want xhr accept */*; got %q", got)
+	}
+	if got := req.Header.Get("origin"); got != "" {
+		t.Errorf("want no origin on a same-origin GET; got %q", got)
+	}
+}
+
+func TestProfileRequestRedirect(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newFetchClient(t, spec)
+
+	const page = "https://www.example.com/"
+	req, err := NewNavigationRequest(context.Background(), spec, PlatformMac,
+		"https://www.example.com/redirect?to=https://tracker.test/final", page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("sec-fetch-site"); got != "same-origin" {
+		t.Fatalf("want same-origin on the first hop; got %q", got)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Request.URL.Host != "tracker.test" {
+		t.Fatalf("want the redirect followed; got %s", res.Request.URL)
+	}
+	for key, want := range map[string]string{
+		"sec-fetch-site": "cross-site",
+		"referer":        "https://www.example.com/",
+	} {
+		if got := res.Request.Header.Get(key); got != want {
+			t.Errorf("second hop: want %s %q; got %q", key, want, got)
+		}
+	}
+}
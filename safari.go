@@ -2,7 +2,9 @@ package mimic
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	utls "github.com/refraction-networking/utls"
 	http "github.com/saucesteals/fhttp"
@@ -15,48 +17,94 @@ import (
 const settingEnableConnectProtocol = http2.SettingID(0x8)
 
 // Safari creates a ClientSpec that mimics Safari's TLS and HTTP/2 fingerprint.
-// Version should be the Safari version (e.g., "18.3", "17.0", "16.0").
-// Minimum supported version is 16.
+// Version should be the Safari version (e.g., "18.3", "17.0", "15.6").
+// Minimum supported version is 14.
 //
-// The TLS fingerprint is platform-dependent: macOS and iPadOS use the Safari
-// desktop fingerprint, while iOS uses the iOS-specific fingerprint.
+// The TLS fingerprint is platform-dependent from 16: macOS uses the Safari
+// desktop fingerprint, while iOS and iPadOS, which share a network stack, use
+// the iOS-specific fingerprint. Safari 14 and 15 send the iOS fingerprint on
+// every platform. On iPadOS the user agent is macOS Safari's, as iPadOS
+// requests desktop websites by default; see WithIPadDesktopMode.
 //
 // Safari does not send sec-ch-ua client hint headers.
-func Safari(version string) (*ClientSpec, error) {
+func Safari(version string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
 	_, majorNum, err := parseMajorVersion(version)
 	if err != nil {
 		return nil, err
 	}
 
+	if majorNum < safariMinMajor {
+		return nil, &VersionTooOldError{Browser: "safari", Min: safariMinMajor, Got: majorNum}
+	}
+
+	// resolve both platform-specific TLS specs at construction time
+	desktopID := utls.HelloSafari_16_0
 	if majorNum < 16 {
-		return nil, fmt.Errorf("safari %s: %w", version, ErrUnsupportedVersion)
+		desktopID = utls.HelloIOS_14
 	}
 
-	// validate both platform-specific TLS specs at construction time
-	for _, id := range []utls.ClientHelloID{utls.HelloSafari_16_0, utls.HelloIOS_14} {
-		if err := validateTLSHelloID(id); err != nil {
-			return nil, fmt.Errorf("safari: %w", err)
-		}
+	desktop, err := cfg.newTLSSpec(desktopID)
+	if err != nil {
+		return nil, fmt.Errorf("safari: %w", err)
 	}
 
-	return &ClientSpec{
+	ios, err := cfg.newTLSSpec(utls.HelloIOS_14)
+	if err != nil {
+		return nil, fmt.Errorf("safari: %w", err)
+	}
+
+	spec := &ClientSpec{
 		version:      version,
-		http2Options: safariHTTP2Options(),
-		tlsSpecFn:    safariTLSSpecFn,
-		buildHeaders: safariBuildHeaders(version),
-	}, nil
+		http2Options: cfg.http2Options(safariHTTP2Options()),
+		timeouts:     safariTimeouts(),
+		requireSCTs:  true,
+		tlsSpecFor:   cfg.tlsSpecFor(safariTLSSpecFor(desktop, ios)),
+		buildHeaders: cfg.buildHeaders(safariBuildHeaders(safariBuildFor(version, majorNum, cfg.safariBuild), cfg.ipadMobile), expandedAcceptLanguage),
+		fetch:        safariFetchHeaders(version, majorNum),
+
+		quicParameters: safariQUICParameters,
+	}
+
+	// HTTP/3 was off by default before 16
+	if majorNum < 16 {
+		spec.http2Options = cfg.http2Options(legacySafariHTTP2Options())
+		spec.quicParameters = nil
+	}
+
+	return spec, nil
 }
 
-// safariTLSSpecFn returns the appropriate TLS spec function based on the platform.
-// iOS uses a different TLS fingerprint than macOS/iPadOS.
-func safariTLSSpecFn(p Platform) (func() *utls.ClientHelloSpec, error) {
-	switch p {
-	case PlatformIOS:
-		return newTLSSpecFunc(utls.HelloIOS_14), nil
-	case PlatformMac, PlatformIPadOS:
-		return newTLSSpecFunc(utls.HelloSafari_16_0), nil
-	default:
-		return nil, fmt.Errorf("safari on %s: %w", p, ErrUnsupportedPlatform)
+// safariTLSSpecFor returns a function that picks the appropriate TLS spec based
+// on the platform. iOS and iPadOS use a different TLS fingerprint than macOS,
+// whichever user agent the iPad sends.
+func safariTLSSpecFor(desktop, ios *tlsSpec) func(Platform) (*tlsSpec, error) {
+	return func(p Platform) (*tlsSpec, error) {
+		switch p {
+		case PlatformIOS, PlatformIPadOS:
+			return ios, nil
+		case PlatformMac:
+			return desktop, nil
+		default:
+			return nil, &PlatformError{Browser: "safari", Platform: p}
+		}
+	}
+}
+
+// legacySafariHTTP2Options are the HTTP/2 settings of Safari 14 and 15, which
+// only announce a 4 MiB stream window and 100 concurrent streams.
+func legacySafariHTTP2Options() *HTTP2Options {
+	return &HTTP2Options{
+		PseudoHeaderOrder: []string{":method", ":scheme", ":path", ":authority"},
+		Settings: []http2.Setting{
+			{ID: http2.SettingInitialWindowSize, Val: 4194304},
+			{ID: http2.SettingMaxConcurrentStreams, Val: 100},
+		},
+		InitialWindowSize: 4194304,
+		HeaderTableSize:   4096,
+		ConnectionFlow:    10485760,
+		UploadChunkSize:   16384,
 	}
 }
 
@@ -75,37 +123,150 @@ func safariHTTP2Options() *HTTP2Options {
 		InitialWindowSize: 2097152,
 		HeaderTableSize:   4096,
 		ConnectionFlow:    10485760,
+		UploadChunkSize:   16384,
 	}
 }
 
+var (
+	safariNavigationOrder = []string{
+		"sec-fetch-dest", "user-agent", "accept", "origin", "sec-fetch-site", "sec-fetch-mode", "content-type",
+		"content-length", "accept-language", "pragma", "cache-control", "referer", "priority", "accept-encoding", "cookie", "if-none-match",
+		"if-modified-since",
+	}
+	safariFetchOrder = []string{
+		"sec-fetch-dest", "user-agent", "accept", "referer", "origin", "sec-fetch-site", "sec-fetch-mode",
+		"content-type", "content-length", "accept-language", "pragma", "cache-control", "priority",
+		"accept-encoding", "cookie", "if-none-match", "if-modified-since",
+	}
+)
+
+// safariFetchHeaders follows Safari adding AVIF to its image accept header in
+// 16, fetch metadata in 16.4, and JPEG XL and HEIC in 17.
+func safariFetchHeaders(version string, majorNum int) *fetchHeaders {
+	fh := &fetchHeaders{
+		document:        "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8",
+		image:           "image/webp,image/avif,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5",
+		acceptEncoding:  "gzip, deflate, br",
+		metadata:        majorNum > 16,
+		navigationOrder: safariNavigationOrder,
+		fetchOrder:      safariFetchOrder,
+	}
+
+	if majorNum == 16 {
+		_, rest, _ := strings.Cut(version, ".")
+		minorStr, _, _ := strings.Cut(rest, ".")
+		minor, _ := strconv.Atoi(minorStr)
+		fh.metadata = minor >= 4
+	}
+	if majorNum < 16 {
+		fh.image = "image/webp,image/png,image/svg+xml,image/*;q=0.8,video/*;q=0.8,*/*;q=0.5"
+	}
+	if majorNum >= 17 {
+		fh.image = "image/webp,image/avif,image/jxl,image/heic,image/heic-sequence,video/*;q=0.8,image/png,image/svg+xml,image/*;q=0.8,*/*;q=0.5"
+	}
+	return fh
+}
+
+// safariTimeouts mirrors NSURLRequest's default 60 second timeout interval, which
+// applies to every phase of the request as the maximum idle time between packets.
+// CFNetwork closes connections left idle for the same interval and does not
+// ping them.
+func safariTimeouts() Timeouts {
+	return Timeouts{
+		Connect:        60 * time.Second,
+		TLSHandshake:   60 * time.Second,
+		ResponseHeader: 60 * time.Second,
+		BodyStall:      60 * time.Second,
+		Idle:           60 * time.Second,
+	}
+}
+
+// SafariBuild holds the tokens a Safari release identifies itself with in
+// its user agent.
+type SafariBuild struct {
+	// Version is the Version token, such as "17.4.1".
+	Version string
+
+	// OSVersion is the iOS or iPadOS version in the mobile user agents, with
+	// underscores, such as "17_4_1".
+	OSVersion string
+
+	// Mobile is the Mobile token of the mobile user agents, such as "15E148".
+	Mobile string
+}
+
+// safariMobileBuild is the Mobile token of every iOS release since 11.3,
+// which froze it.
+const safariMobileBuild = "15E148"
+
+// safariFrozenOSVersion is the iOS version iOS 26 and later report in the
+// user agent, frozen at the last release before them.
+const safariFrozenOSVersion = "18_6"
+
+// safariBuilds are the Safari releases whose tokens do not follow from their
+// version: Safari 14 was updated in later iOS releases than its numbers, so
+// Safari 14.1 shipped in iOS 14.5.
+var safariBuilds = map[string]SafariBuild{
+	"14.0":   {Version: "14.0", OSVersion: "14_0"},
+	"14.0.1": {Version: "14.0.1", OSVersion: "14_4"},
+	"14.1":   {Version: "14.1", OSVersion: "14_5"},
+	"14.1.1": {Version: "14.1.1", OSVersion: "14_6"},
+	"14.1.2": {Version: "14.1.2", OSVersion: "14_8"},
+}
+
+// safariBuildFor returns the tokens of Safari at version: those of
+// safariBuilds, or else the version itself, which iOS also carries until it
+// was frozen in 26. The fields override sets replace them.
+func safariBuildFor(version string, majorNum int, override *SafariBuild) SafariBuild {
+	b, ok := safariBuilds[version]
+	if !ok {
+		b = SafariBuild{Version: version, OSVersion: strings.ReplaceAll(version, ".", "_")}
+		if majorNum >= 26 {
+			b.OSVersion = safariFrozenOSVersion
+		}
+	}
+	b.Mobile = safariMobileBuild
+
+	if override != nil {
+		if override.Version != "" {
+			b.Version = override.Version
+		}
+		if override.OSVersion != "" {
+			b.OSVersion = override.OSVersion
+		}
+		if override.Mobile != "" {
+			b.Mobile = override.Mobile
+		}
+	}
+	return b
+}
+
 // safariBuildHeaders returns a function that generates Safari-appropriate default headers
 // for a given platform. Safari does not send sec-ch-ua client hint headers.
-func safariBuildHeaders(version string) func(Platform) (http.Header, error) {
+// iPadOS sends the macOS user agent unless ipadMobile.
+func safariBuildHeaders(build SafariBuild, ipadMobile bool) func(Platform) (http.Header, error) {
 	return func(p Platform) (http.Header, error) {
 		var ua string
 
-		switch p {
-		case PlatformMac:
+		switch {
+		case p == PlatformMac, p == PlatformIPadOS && !ipadMobile:
 			// macOS Safari freezes the OS version at 10_15_7 for privacy
 			ua = fmt.Sprintf(
 				"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Safari/605.1.15",
-				version,
+				build.Version,
 			)
-		case PlatformIOS:
-			// iOS Safari version generally matches the iOS version
-			iosVer := strings.ReplaceAll(version, ".", "_")
+		case p == PlatformIOS:
 			ua = fmt.Sprintf(
-				"Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/15E148 Safari/604.1",
-				iosVer, version,
+				"Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/%s Safari/604.1",
+				build.OSVersion, build.Version, build.Mobile,
 			)
-		case PlatformIPadOS:
-			iosVer := strings.ReplaceAll(version, ".", "_")
+		case p == PlatformIPadOS:
 			ua = fmt.Sprintf(
-				"Mozilla/5.0 (iPad; CPU OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/15E148 Safari/604.1",
-				iosVer, version,
+				"Mozilla/5.0 (iPad; CPU OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/%s Mobile/%s Safari/604.1",
+				build.OSVersion, build.Version, build.Mobile,
 			)
 		default:
-			return nil, fmt.Errorf("safari on %s: %w", p, ErrUnsupportedPlatform)
+			return nil, &PlatformError{Browser: "safari", Platform: p}
 		}
 
 		h := http.Header{}
@@ -113,3 +274,22 @@ func safariBuildHeaders(version string) func(Platform) (http.Header, error) {
 		return h, nil
 	}
 }
+
+// safariQUICParameters returns the transport parameters Network.framework
+// sends, in its fixed order.
+func safariQUICParameters() utls.TransportParameters {
+	return utls.TransportParameters{
+		utls.InitialMaxStreamDataBidiLocal(2097152),
+		utls.InitialMaxStreamDataBidiRemote(2097152),
+		utls.InitialMaxStreamDataUni(2097152),
+		utls.InitialMaxData(4194304),
+		utls.InitialMaxStreamsBidi(100),
+		utls.InitialMaxStreamsUni(100),
+		utls.MaxIdleTimeout(30000),
+		utls.MaxUDPPayloadSize(1472),
+		utls.ActiveConnectionIDLimit(8),
+		utls.InitialSourceConnectionID{},
+		utls.MaxDatagramFrameSize(65535),
+		&utls.GREASEQUICBit{},
+	}
+}
@@ -0,0 +1,188 @@
+package mimic
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Resource is a subresource of a page, tagged with the kind of request the
+// page makes for it.
+type Resource struct {
+	URL     string
+	Profile FetchProfile
+}
+
+// LoadResult is the outcome of one request made by LoadPage. The response
+// body has already been handled and closed.
+type LoadResult struct {
+	Resource Resource
+	Response *http.Response
+	Err      error
+	Start    time.Time
+	End      time.Time
+}
+
+// LoadOption configures LoadPage.
+type LoadOption func(*loadConfig)
+
+type loadConfig struct {
+	maxConcurrent int
+	handle        func(Resource, *http.Response) error
+}
+
+// WithMaxConcurrent sets how many requests LoadPage keeps in flight. The
+// default is 6, the connections per host browsers open over HTTP/1.1.
+func WithMaxConcurrent(n int) LoadOption {
+	return func(c *loadConfig) {
+		c.maxConcurrent = n
+	}
+}
+
+// WithResponseHandler sets a function that reads each response body before
+// LoadPage closes it. An error it returns becomes the resource's LoadResult.Err.
+// By default bodies are read and discarded, as a browser would download them.
+func WithResponseHandler(fn func(Resource, *http.Response) error) LoadOption {
+	return func(c *loadConfig) {
+		c.handle = fn
+	}
+}
+
+// maxDelayableWhileBlocking is how many images Chromium's resource scheduler
+// lets load while stylesheets or scripts that block layout are in flight.
+const maxDelayableWhileBlocking = 1
+
+// LoadPage loads page and then its resources with client in the order and at
+// the concurrency a browser would: the document first, then stylesheets and
+// scripts, with images held to one at a time until those finish, and fetch()
+// and XMLHttpRequest calls only once the scripts that make them have run.
+// Subresources are sent with Fetch, with the final document URL as their
+// referrer.
+//
+// It returns the document's result followed by one per resource, in the
+// order given. If the document fails, its error is returned and no resources
+// are requested.
+func LoadPage(ctx context.Context, client *http.Client, page string, resources []Resource, opts ...LoadOption) ([]LoadResult, error) {
+	cfg := &loadConfig{maxConcurrent: 6}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	cfg.maxConcurrent = max(cfg.maxConcurrent, 1)
+
+	document := cfg.load(ctx, client, Resource{URL: page, Profile: ProfileNavigation}, "")
+	results := []LoadResult{document}
+	if document.Err != nil {
+		return results, fmt.Errorf("loading page: %w", document.Err)
+	}
+
+	referrer := page
+	if document.Response.Request != nil {
+		referrer = document.Response.Request.URL.String()
+	}
+
+	results = append(results, make([]LoadResult, len(resources))...)
+
+	// browsers discover resources in document order, but issue the ones that
+	// block rendering ahead of the rest
+	order := make([]int, len(resources))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		return cmp.Compare(loadTier(resources[a].Profile), loadTier(resources[b].Profile))
+	})
+
+	var blocking, scripts int
+	for _, r := range resources {
+		if loadTier(r.Profile) == 0 {
+			blocking++
+		}
+		if r.Profile == ProfileScript {
+			scripts++
+		}
+	}
+
+	done := make(chan int)
+	var inFlight, delayable int
+	for remaining := len(resources); remaining > 0; remaining-- {
+		order = slices.DeleteFunc(order, func(i int) bool {
+			if inFlight >= cfg.maxConcurrent {
+				return false
+			}
+			switch loadTier(resources[i].Profile) {
+			case 1:
+				if blocking > 0 && delayable >= maxDelayableWhileBlocking {
+					return false
+				}
+				delayable++
+			case 2:
+				if scripts > 0 {
+					return false
+				}
+			}
+
+			inFlight++
+			go func() {
+				results[i+1] = cfg.load(ctx, client, resources[i], referrer)
+				done <- i
+			}()
+			return true
+		})
+
+		i := <-done
+		inFlight--
+		switch loadTier(resources[i].Profile) {
+		case 0:
+			blocking--
+		case 1:
+			delayable--
+		}
+		if resources[i].Profile == ProfileScript {
+			scripts--
+		}
+	}
+
+	return results, nil
+}
+
+// loadTier orders resources the way browsers schedule them: 0 for
+// stylesheets and scripts, which block rendering, 1 for images, which the
+// scheduler delays, and 2 for requests made by scripts.
+func loadTier(p FetchProfile) int {
+	switch p {
+	case ProfileStyle, ProfileScript:
+		return 0
+	case ProfileImage:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// load fetches r and handles its body.
+func (c *loadConfig) load(ctx context.Context, client *http.Client, r Resource, referrer string) LoadResult {
+	result := LoadResult{Resource: r, Start: time.Now()}
+
+	res, err := Fetch(ctx, client, r.URL, FetchOptions{Profile: r.Profile, Referrer: referrer})
+	if err != nil {
+		result.Err = err
+		result.End = time.Now()
+		return result
+	}
+
+	result.Response = res
+	if c.handle != nil {
+		result.Err = c.handle(r, res)
+	} else {
+		_, result.Err = io.Copy(io.Discard, res.Body)
+	}
+	res.Body.Close()
+	result.End = time.Now()
+
+	return result
+}
@@ -0,0 +1,89 @@
+package mimic
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// loadRoundTripper records the order requests start in and how many images are
+// in flight alongside stylesheets and scripts.
+type loadRoundTripper struct {
+	mu       sync.Mutex
+	started  []string
+	inFlight map[string]int
+	overlap  bool
+}
+
+func (rt *loadRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	dest := req.Header.Get("Sec-Fetch-Dest")
+
+	rt.mu.Lock()
+	rt.started = append(rt.started, req.URL.Path)
+	rt.inFlight[dest]++
+	if rt.inFlight["image"] > 1 && rt.inFlight["script"]+rt.inFlight["style"] > 0 {
+		rt.overlap = true
+	}
+	rt.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	rt.mu.Lock()
+	rt.inFlight[dest]--
+	rt.mu.Unlock()
+
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestLoadPage(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := &loadRoundTripper{inFlight: make(map[string]int)}
+	tr.transport = rt
+
+	resources := []Resource{
+		{URL: "https://example.com/a.png", Profile: ProfileImage},
+		{URL: "https://example.com/app.js", Profile: ProfileScript},
+		{URL: "https://example.com/b.png", Profile: ProfileImage},
+		{URL: "https://example.com/c.png", Profile: ProfileImage},
+		{URL: "https://example.com/api", Profile: ProfileFetch},
+		{URL: "https://example.com/site.css", Profile: ProfileStyle},
+	}
+
+	results, err := LoadPage(context.Background(), &http.Client{Transport: tr}, "https://example.com/", resources)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(resources)+1 {
+		t.Fatalf("want %d results; got %d", len(resources)+1, len(results))
+	}
+	for i, r := range results[1:] {
+		if r.Resource != resources[i] || r.Err != nil || r.End.Before(r.Start) {
+			t.Errorf("result %d: want %s in order without error; got %+v", i, resources[i].URL, r)
+		}
+	}
+
+	if rt.started[0] != "/" {
+		t.Errorf("want the document first; got %v", rt.started)
+	}
+	if rt.overlap {
+		t.Error("want at most one image in flight while stylesheets and scripts load")
+	}
+
+	if results[5].Start.Before(results[2].End) {
+		t.Error("want fetch() to start after the script finished")
+	}
+	if referer := results[1].Response.Request.Header.Get("Referer"); referer != "https://example.com/" {
+		t.Errorf("want the page as referrer; got %q", referer)
+	}
+}
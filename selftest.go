@@ -0,0 +1,299 @@
+package mimic
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+	"github.com/saucesteals/fhttp/http2/hpack"
+)
+
+// fhttpDefaultConnFlow is the connection WINDOW_UPDATE fhttp sends when
+// HTTP2Options.ConnectionFlow is ConnectionFlowDefault.
+const fhttpDefaultConnFlow = 15663105
+
+// selfTestTimeout bounds each HTTP/2 preface SelfTest renders.
+const selfTestTimeout = 5 * time.Second
+
+// selfTestHello is a ClientHello SelfTest checks: the hello spec sends on
+// platform, and the digest of it when mimic was last updated.
+type selfTestHello struct {
+	name     string
+	spec     func() (*ClientSpec, error)
+	platform Platform
+	digest   string
+}
+
+func chromeHello(version, digest string) selfTestHello {
+	return selfTestHello{
+		name:     "chrome " + version,
+		spec:     func() (*ClientSpec, error) { return Chromium(BrandChrome, version) },
+		platform: PlatformWindows,
+		digest:   digest,
+	}
+}
+
+func firefoxHello(version, digest string) selfTestHello {
+	return selfTestHello{
+		name:     "firefox " + version,
+		spec:     func() (*ClientSpec, error) { return Firefox(version) },
+		platform: PlatformWindows,
+		digest:   digest,
+	}
+}
+
+func safariHello(version string, platform Platform, digest string) selfTestHello {
+	return selfTestHello{
+		name:     fmt.Sprintf("safari %s on %s", version, platform),
+		spec:     func() (*ClientSpec, error) { return Safari(version) },
+		platform: platform,
+		digest:   digest,
+	}
+}
+
+// selfTestHellos has one entry for every hello ID the constructors resolve
+// and every edit they make to one, at the first version that sends it.
+var selfTestHellos = []selfTestHello{
+	chromeHello("83.0.0.0", "28bfd551dacc594e"),
+	chromeHello("87.0.0.0", "28bfd551dacc594e"),
+	chromeHello("96.0.0.0", "80c98aac5d29dec4"),
+	chromeHello("100.0.0.0", "ffec7733dc79054f"),
+	chromeHello("102.0.0.0", "ffec7733dc79054f"),
+	chromeHello("106.0.0.0", "3a1856b5802beaf9"),
+	chromeHello("117.0.0.0", "1a5a304ec0a355ec"),
+	chromeHello("124.0.0.0", "28feb1d8d8fd3cfa"),
+	chromeHello("131.0.0.0", "6414bb31322fd87b"),
+	chromeHello("133.0.0.0", "c076b2d5891267fa"),
+	firefoxHello("55.0", "e8210fd145289d9f"),
+	firefoxHello("56.0", "e8210fd145289d9f"),
+	firefoxHello("63.0", "3599489e87510fbf"),
+	firefoxHello("65.0", "3599489e87510fbf"),
+	firefoxHello("77.0", "05684b39b009343d"),
+	firefoxHello("78.0", "eb44f42054d4575b"),
+	firefoxHello("102.0", "092a8b03e2c812be"),
+	firefoxHello("105.0", "e5eb193842fbfa6b"),
+	firefoxHello("120.0", "b1398f2c5ae0dce9"),
+	firefoxHello("132.0", "9338bee02d8e67ee"),
+	safariHello("15.6", PlatformMac, "d9cd73e03eaad677"),
+	safariHello("18.3", PlatformMac, "d37101f6dc1bec4c"),
+	{
+		name:     "playstation 5",
+		spec:     func() (*ClientSpec, error) { return PlayStation5("24.06") },
+		platform: PlatformPlayStation,
+		digest:   "116d3f75921ab812",
+	},
+}
+
+// SelfTest checks that the linked utls and fhttp still send the fingerprints
+// mimic was built against. It resolves every hello ID the constructors use,
+// renders each ClientHello and compares it with the one recorded for that
+// version, and has fhttp write the HTTP/2 preface and a request's HEADERS for
+// each spec to check the SETTINGS, WINDOW_UPDATE, priority, and pseudo header
+// order, including the fhttp defaults specs rely on. Per-connection values,
+// such as GREASE, key shares, and the shuffled extension order, are left out
+// of the comparison.
+//
+// A dependency upgrade can change what is sent without breaking the build, so
+// run SelfTest at startup, or in a test, after upgrading. It returns a
+// *SelfTestError listing every difference.
+func SelfTest() error {
+	var drift []string
+	for _, h := range selfTestHellos {
+		spec, err := h.spec()
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("%s: %v", h.name, err))
+			continue
+		}
+
+		ts, err := spec.tlsSpecFor(h.platform)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("%s: %v", h.name, err))
+			continue
+		}
+		digest, err := helloDigest(ts)
+		if err != nil {
+			drift = append(drift, fmt.Sprintf("%s: rendering client hello: %v", h.name, err))
+		} else if digest != h.digest {
+			drift = append(drift, fmt.Sprintf("%s: client hello digest is %s, want %s", h.name, digest, h.digest))
+		}
+
+		for _, d := range checkHTTP2Preface(spec, h.platform) {
+			drift = append(drift, h.name+": "+d)
+		}
+	}
+
+	if len(drift) > 0 {
+		return &SelfTestError{Drift: drift}
+	}
+	return nil
+}
+
+// helloDigest returns a digest of the ClientHello ts sends, without the
+// values that change per connection.
+func helloDigest(ts *tlsSpec) (string, error) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// there is no session to resume, so the PSK extension of the Chrome
+	// hellos that send one is left empty
+	conn := utls.UClient(client, &utls.Config{ServerName: "example.com", OmitEmptyPsk: true}, utls.HelloCustom)
+	if err := conn.ApplyPreset(ts.New()); err != nil {
+		return "", err
+	}
+	if err := conn.BuildHandshakeState(); err != nil {
+		return "", err
+	}
+
+	// parsing the hello back drops GREASE values, key shares, the ECH
+	// payload, and the padding length
+	fingerprinter := utls.Fingerprinter{AllowBluntMimicry: true}
+	hello, err := fingerprinter.RawClientHello(helloRecord(utls.VersionTLS10, conn.HandshakeState.Hello.Raw))
+	if err != nil {
+		return "", err
+	}
+	if ts.shuffle {
+		slices.SortStableFunc(hello.Extensions, func(a, b utls.TLSExtension) int {
+			return cmp.Compare(extensionID(a), extensionID(b))
+		})
+	}
+
+	doc := struct {
+		CipherSuites       []uint16 `json:"cipher_suites"`
+		CompressionMethods []uint8  `json:"compression_methods"`
+		Extensions         []any    `json:"extensions"`
+	}{
+		CipherSuites:       hello.CipherSuites,
+		CompressionMethods: hello.CompressionMethods,
+	}
+	for _, ext := range hello.Extensions {
+		if e, ok := ext.(*utls.GenericExtension); ok {
+			doc.Extensions = append(doc.Extensions, map[string]any{"id": e.Id, "data": e.Data})
+			continue
+		}
+		m, err := marshalHelloExtension(ext)
+		if err != nil {
+			return "", err
+		}
+		doc.Extensions = append(doc.Extensions, m)
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// checkHTTP2Preface has fhttp open a connection and send a request as spec on
+// platform, and returns how what it wrote differs from spec's HTTP2Options.
+func checkHTTP2Preface(spec *ClientSpec, platform Platform) []string {
+	t2, err := spec.configureTransport(&http.Transport{}, platform)
+	if err != nil {
+		return []string{fmt.Sprintf("configuring transport: %v", err)}
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	server.SetDeadline(time.Now().Add(selfTestTimeout))
+
+	go func() {
+		cc, err := t2.NewClientConn(client)
+		if err != nil {
+			return
+		}
+		req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		if err != nil {
+			return
+		}
+		req.Header = http.Header{http.PHeaderOrderKey: spec.http2Options.PseudoHeaderOrder}
+		if res, err := cc.RoundTrip(req); err == nil {
+			res.Body.Close()
+		}
+	}()
+
+	preface := make([]byte, len(http2.ClientPreface))
+	if _, err := io.ReadFull(server, preface); err != nil {
+		return []string{fmt.Sprintf("reading preface: %v", err)}
+	}
+
+	fr := http2.NewFramer(io.Discard, server)
+	fr.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	// the framer reuses frames, so each is read before the next
+	var (
+		drift    []string
+		settings []http2.Setting
+		window   *uint32
+		headers  *http2.MetaHeadersFrame
+	)
+	for headers == nil {
+		f, err := fr.ReadFrame()
+		if err != nil {
+			return append(drift, fmt.Sprintf("reading frames: %v", err))
+		}
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			f.ForeachSetting(func(s http2.Setting) error {
+				settings = append(settings, s)
+				return nil
+			})
+		case *http2.WindowUpdateFrame:
+			if f.StreamID == 0 && window == nil {
+				increment := f.Increment
+				window = &increment
+			}
+		case *http2.MetaHeadersFrame:
+			headers = f
+		}
+	}
+
+	opts := spec.http2Options
+
+	if !slices.Equal(settings, opts.Settings) {
+		drift = append(drift, fmt.Sprintf("SETTINGS are %v, want %v", settings, opts.Settings))
+	}
+
+	wantFlow := opts.ConnectionFlow
+	if wantFlow == ConnectionFlowDefault {
+		wantFlow = fhttpDefaultConnFlow
+	}
+	switch {
+	case window == nil:
+		drift = append(drift, "no connection WINDOW_UPDATE before HEADERS")
+	case *window != wantFlow:
+		drift = append(drift, fmt.Sprintf("connection WINDOW_UPDATE is %d, want %d", *window, wantFlow))
+	}
+
+	wantPriority := http2.PriorityParam{Exclusive: true, Weight: 255}
+	if opts.HeaderPriority != nil {
+		wantPriority = *opts.HeaderPriority
+	}
+	if got := headers.Priority; !headers.HasPriority() || got != wantPriority {
+		drift = append(drift, fmt.Sprintf("HEADERS priority is %+v, want %+v", got, wantPriority))
+	}
+
+	var pseudo []string
+	for _, f := range headers.Fields {
+		if strings.HasPrefix(f.Name, ":") {
+			pseudo = append(pseudo, f.Name)
+		}
+	}
+	if !slices.Equal(pseudo, opts.PseudoHeaderOrder) {
+		drift = append(drift, fmt.Sprintf("pseudo header order is %v, want %v", pseudo, opts.PseudoHeaderOrder))
+	}
+
+	return drift
+}
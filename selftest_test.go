@@ -0,0 +1,80 @@
+package mimic
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSelfTestReportsDrift(t *testing.T) {
+	saved := selfTestHellos
+	t.Cleanup(func() { selfTestHellos = saved })
+
+	selfTestHellos = []selfTestHello{chromeHello("133.0.0.0", "0000000000000000")}
+
+	err := SelfTest()
+	var selfTestErr *SelfTestError
+	if !errors.As(err, &selfTestErr) {
+		t.Fatalf("SelfTest() = %v, want a *SelfTestError", err)
+	}
+	if len(selfTestErr.Drift) != 1 || !strings.Contains(selfTestErr.Drift[0], "chrome 133.0.0.0: client hello digest") {
+		t.Errorf("drift = %q, want the chrome 133 hello digest", selfTestErr.Drift)
+	}
+}
+
+// TestSelfTestCoversHellos checks that selfTestHellos has a Chromium and a
+// Firefox version for every hello ID, with its edits, that their version
+// tables resolve.
+func TestSelfTestCoversHellos(t *testing.T) {
+	chromeHelloKey := func(major int) string {
+		id := chromiumTLSHelloID(major)
+		return id.Str()
+	}
+	firefoxHelloKey := func(major int) string {
+		id := firefoxTLSHelloID(major)
+		key := id.Str()
+		for _, edit := range firefoxTLSEdits(major) {
+			key += fmt.Sprintf(" %x", reflect.ValueOf(edit).Pointer())
+		}
+		return key
+	}
+
+	covered := make(map[string]bool)
+	for _, h := range selfTestHellos {
+		if version, ok := strings.CutPrefix(h.name, "chrome "); ok {
+			_, major, err := parseMajorVersion(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			covered["chrome "+chromeHelloKey(major)] = true
+		}
+		if version, ok := strings.CutPrefix(h.name, "firefox "); ok {
+			_, major, err := parseMajorVersion(version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			covered["firefox "+firefoxHelloKey(major)] = true
+		}
+	}
+
+	// well past the newest releases, where the tables' last cases apply
+	const maxMajor = 200
+	for major := chromiumMinMajor; major <= maxMajor; major++ {
+		if key := chromeHelloKey(major); !covered["chrome "+key] {
+			t.Errorf("chrome %d sends %s, which selfTestHellos does not check", major, key)
+		}
+	}
+	for major := firefoxMinMajor; major <= maxMajor; major++ {
+		if key := firefoxHelloKey(major); !covered["firefox "+key] {
+			t.Errorf("firefox %d sends %s, which selfTestHellos does not check", major, key)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package mimic
+
+import (
+	"context"
+	"sync/atomic"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// Session is one browser profile: a ClientSpec on a platform with its own
+// cookie jar, proxy, and pool of warm connections. It is safe for concurrent
+// use.
+type Session struct {
+	client    *http.Client
+	transport *Transport
+	cfg       *clientConfig
+	closed    atomic.Bool
+}
+
+// NewSession creates a Session that mimics spec on platform. The options are
+// those of NewClient; use WithTransportOptions(WithProxy(...)) to give the
+// session a proxy.
+func NewSession(spec *ClientSpec, platform Platform, opts ...ClientOption) (*Session, error) {
+	cfg := &clientConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	transport, err := NewTransport(spec, platform, cfg.transportOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSession(transport, cfg)
+}
+
+func newSession(transport *Transport, cfg *clientConfig) (*Session, error) {
+	client, err := newClient(transport, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Session{client: client, transport: transport, cfg: cfg}
+	client.Transport = &sessionTransport{wrapped: wrapped{client.Transport}, session: s}
+	return s, nil
+}
+
+// Do sends req with the session's client, following redirects and storing
+// cookies.
+func (s *Session) Do(req *http.Request) (*http.Response, error) {
+	return s.client.Do(req)
+}
+
+// Navigate loads the page at rawURL in the session, as Navigate does with
+// the session's client.
+func (s *Session) Navigate(ctx context.Context, rawURL string) (*Page, error) {
+	return Navigate(ctx, s.client, rawURL)
+}
+
+// Client returns the session's http.Client.
+func (s *Session) Client() *http.Client {
+	return s.client
+}
+
+// Transport returns the session's Transport.
+func (s *Session) Transport() *Transport {
+	return s.transport
+}
+
+// Jar returns the session's cookie jar.
+func (s *Session) Jar() http.CookieJar {
+	return s.cfg.jar
+}
+
+// Fork returns a Session with the same fingerprint, proxy, options, and cookie
+// jar, and its own connection pool, like a second window of the same browser
+// profile. Cookies set in either session are seen by both.
+func (s *Session) Fork() (*Session, error) {
+	if s.closed.Load() {
+		return nil, ErrSessionClosed
+	}
+
+	transport, err := s.transport.Clone()
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := *s.cfg
+	return newSession(transport, &cfg)
+}
+
+// Close closes the session's idle connections. Requests sent after Close fail
+// with ErrSessionClosed; requests already in flight finish normally. Closing
+// one session does not affect sessions forked from it.
+func (s *Session) Close() error {
+	s.closed.Store(true)
+	s.transport.CloseIdleConnections()
+	return nil
+}
+
+// sessionTransport rejects requests once its session is closed.
+type sessionTransport struct {
+	wrapped
+	session *Session
+}
+
+func (t *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.session.closed.Load() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrSessionClosed
+	}
+	return t.transport.RoundTrip(req)
+}
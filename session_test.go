@@ -0,0 +1,115 @@
+package mimic
+
+import (
+	"errors"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestSession(t *testing.T) {
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if r.URL.Path == "/login" {
+			stdhttp.SetCookie(w, &stdhttp.Cookie{Name: "sid", Value: "1"})
+			return
+		}
+		if _, err := r.Cookie("sid"); err != nil {
+			w.WriteHeader(stdhttp.StatusUnauthorized)
+		}
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	session, err := NewSession(spec, PlatformWindows, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := session.Client().Transport.(closeIdler); !ok {
+		t.Error("want the session's client transport to pass CloseIdleConnections through")
+	}
+
+	get := func(s *Session, path string) (*http.Response, error) {
+		req, err := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := s.Do(req)
+		if err == nil {
+			res.Body.Close()
+		}
+		return res, err
+	}
+
+	if _, err := get(session, "/login"); err != nil {
+		t.Fatal(err)
+	}
+
+	fork, err := session.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fork.Close()
+
+	if fork.Transport().base == session.Transport().base {
+		t.Error("want the fork to have its own connection pool")
+	}
+	if fork.Jar() != session.Jar() {
+		t.Error("want the fork to share the cookie jar")
+	}
+
+	res, err := get(fork, "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("want the fork to send the session's cookie; got %d", res.StatusCode)
+	}
+
+	if err := session.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := get(session, "/"); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("want ErrSessionClosed after Close; got %v", err)
+	}
+	if _, err := session.Fork(); !errors.Is(err, ErrSessionClosed) {
+		t.Errorf("want ErrSessionClosed forking a closed session; got %v", err)
+	}
+	if _, err := get(fork, "/"); err != nil {
+		t.Errorf("want the fork usable after its parent closes; got %v", err)
+	}
+}
+
+func TestWithProxy(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxyURL := &url.URL{Scheme: "http", Host: "127.0.0.1:8080"}
+	tr, err := NewTransport(spec, PlatformWindows, WithProxy(proxyURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := tr.BaseTransport().Proxy(req)
+	if err != nil || got.String() != proxyURL.String() {
+		t.Errorf("want proxy %s; got %v, %v", proxyURL, got, err)
+	}
+}
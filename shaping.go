@@ -0,0 +1,169 @@
+package mimic
+
+import (
+	"context"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// Shaping emulates the link a browser sits behind. Datacenter clients connect
+// in a fraction of a millisecond and download at line rate, which is easy to
+// tell apart from a home or mobile connection; shaping slows the transport to
+// the link's speed and delay. Zero fields leave that dimension unshaped.
+type Shaping struct {
+	// Downlink and Uplink cap the bytes per second read from and written to
+	// the network, shared by all of the transport's connections as they
+	// share one link.
+	Downlink int64
+	Uplink   int64
+
+	// Latency is added once when a connection is dialed and once before
+	// each request, the round trips the link costs on top of the real path.
+	// Each delay varies uniformly by up to Jitter in either direction.
+	Latency time.Duration
+	Jitter  time.Duration
+}
+
+// WithShaping throttles the transport's traffic and delays its connections
+// and requests as s describes. The throughput caps wrap the base transport's
+// DialContext, so they have no effect on a base transport that sets
+// DialTLSContext.
+func WithShaping(s Shaping) TransportOption {
+	return func(c *transportConfig) {
+		c.shaping = &s
+	}
+}
+
+// shaper applies a Shaping to one transport.
+type shaper struct {
+	Shaping
+	down *byteRate
+	up   *byteRate
+}
+
+func newShaper(s Shaping) *shaper {
+	return &shaper{Shaping: s, down: newByteRate(s.Downlink), up: newByteRate(s.Uplink)}
+}
+
+// delay waits out one round trip of added latency, or until ctx is done.
+func (s *shaper) delay(ctx context.Context) error {
+	d := s.Latency
+	if s.Jitter > 0 {
+		d += rand.N(2*s.Jitter+1) - s.Jitter
+	}
+	if d <= 0 {
+		return nil
+	}
+	return sleepContext(ctx, d)
+}
+
+// dialContext wraps dial so connections take a round trip longer to open and
+// are throttled to the link's throughput.
+func (s *shaper) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if err := s.delay(ctx); err != nil {
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if s.down == nil && s.up == nil {
+			return conn, nil
+		}
+		return &shapedConn{Conn: conn, shaper: s}, nil
+	}
+}
+
+// shapedConn paces its reads and writes to the shaper's throughput.
+type shapedConn struct {
+	net.Conn
+	shaper *shaper
+}
+
+func (c *shapedConn) Read(p []byte) (int, error) {
+	if c.shaper.down == nil {
+		return c.Conn.Read(p)
+	}
+
+	n, err := c.Conn.Read(p[:min(len(p), c.shaper.down.chunk)])
+	time.Sleep(c.shaper.down.reserve(n, time.Now()))
+	return n, err
+}
+
+func (c *shapedConn) Write(p []byte) (int, error) {
+	if c.shaper.up == nil {
+		return c.Conn.Write(p)
+	}
+
+	var written int
+	for len(p) > 0 {
+		chunk := p[:min(len(p), c.shaper.up.chunk)]
+		time.Sleep(c.shaper.up.reserve(len(chunk), time.Now()))
+
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// byteRate paces bytes to a fixed rate, tracking when the link is next free
+// the way bucket tracks requests.
+type byteRate struct {
+	rate float64
+	// chunk is the most bytes passed at once, a tenth of a second's worth,
+	// so pacing stays smooth instead of stalling after large buffers
+	chunk int
+
+	mu   sync.Mutex
+	free time.Time
+}
+
+// newByteRate returns a byteRate of bytesPerSecond, or nil if it is unlimited.
+func newByteRate(bytesPerSecond int64) *byteRate {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &byteRate{
+		rate:  float64(bytesPerSecond),
+		chunk: int(max(bytesPerSecond/10, 1024)),
+	}
+}
+
+// reserve takes n bytes of the link's capacity and returns how long the
+// caller must wait until they have been sent.
+func (r *byteRate) reserve(n int, now time.Time) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.free.Before(now) {
+		r.free = now
+	}
+	r.free = r.free.Add(time.Duration(float64(n) / r.rate * float64(time.Second)))
+
+	return r.free.Sub(now)
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,90 @@
+package mimic
+
+import (
+	"bytes"
+	"context"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestShaping(t *testing.T) {
+	body := bytes.Repeat([]byte("a"), 64<<10)
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		w.Write(body)
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shaping := Shaping{Downlink: 256 << 10, Latency: 50 * time.Millisecond}
+	tr, err := NewTransport(spec, PlatformWindows,
+		WithBaseTransport(&http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}),
+		WithShaping(shaping),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+
+	if !bytes.Equal(got, body) {
+		t.Fatalf("want %d byte body; got %d bytes", len(body), len(got))
+	}
+
+	// one round trip to dial, one for the request, and a quarter second to
+	// download 64KB at 256KB/s
+	if want := 2*shaping.Latency + 250*time.Millisecond; elapsed < want {
+		t.Errorf("want at least %v; got %v", want, elapsed)
+	}
+}
+
+func TestShapingCanceled(t *testing.T) {
+	s := newShaper(Shaping{Latency: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.delay(ctx); err != context.Canceled {
+		t.Errorf("want %v; got %v", context.Canceled, err)
+	}
+
+	tr := newTestTransport(t)
+	tr.shaper = s
+
+	body := &closeRecorder{Reader: strings.NewReader("a=1")}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); err != context.Canceled || !body.closed {
+		t.Errorf("want the body of a canceled request closed; got %v, closed %t", err, body.closed)
+	}
+}
@@ -0,0 +1,159 @@
+package mimic
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"slices"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// sniffLen is how much of a body is sniffed, the length net/http's
+// DetectContentType considers.
+const sniffLen = 512
+
+// apacheBugTypes are the Content-Type values Apache historically sent for
+// every file it had no type for, which browsers check for binary content
+// before trusting.
+var apacheBugTypes = []string{
+	"text/plain",
+	"text/plain; charset=ISO-8859-1",
+	"text/plain; charset=iso-8859-1",
+	"text/plain; charset=UTF-8",
+}
+
+// SniffContentType returns the MIME type a browser would treat a response
+// with header and a body starting with body as, following the MIME Sniffing
+// standard. A declared type is trusted, except that a missing or unknown
+// type is sniffed from the body, and text/plain as Apache sends it by default
+// is checked for binary content. With "X-Content-Type-Options: nosniff", a
+// declared type is always trusted, and an undeclared one is never sniffed as
+// HTML, XML, or PDF, which could run script.
+//
+// body should hold the first 512 bytes of the body, or all of it if shorter.
+func SniffContentType(header http.Header, body []byte) string {
+	declared := header.Get("Content-Type")
+
+	switch {
+	case !isDeclared(declared):
+		return sniffUnknown(body, !isNosniff(header))
+	case isNosniff(header):
+		return declared
+	case isApacheBugType(header):
+		if isBinary(body) {
+			return "application/octet-stream"
+		}
+	}
+	return declared
+}
+
+// sniffsBody reports whether SniffContentType reads the body of a response
+// with header, which it does only when the declared type is missing or
+// unknown, or is one Apache sends by default and nosniff is not set.
+func sniffsBody(header http.Header) bool {
+	if !isDeclared(header.Get("Content-Type")) {
+		return true
+	}
+	return !isNosniff(header) && isApacheBugType(header)
+}
+
+// isDeclared reports whether contentType declares a known type.
+func isDeclared(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	switch {
+	case err != nil, mediaType == "unknown/unknown", mediaType == "application/unknown", mediaType == "*/*":
+		return false
+	}
+	return true
+}
+
+// isNosniff reports whether header has "X-Content-Type-Options: nosniff".
+func isNosniff(header http.Header) bool {
+	return strings.EqualFold(strings.TrimSpace(header.Get("X-Content-Type-Options")), "nosniff")
+}
+
+// isApacheBugType reports whether header's only Content-Type is one Apache
+// sends by default.
+func isApacheBugType(header http.Header) bool {
+	values := header.Values("Content-Type")
+	return len(values) == 1 && slices.Contains(apacheBugTypes, values[0])
+}
+
+// sniffUnknown returns the type of body, leaving out the types that can run
+// script unless scriptable is set.
+func sniffUnknown(body []byte, scriptable bool) string {
+	detected := http.DetectContentType(body)
+	if scriptable {
+		return detected
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(detected)
+	switch mediaType {
+	case "text/html", "text/xml", "application/pdf":
+		if isBinary(body) {
+			return "application/octet-stream"
+		}
+		return "text/plain; charset=utf-8"
+	}
+	return detected
+}
+
+// isBinary reports whether body holds a byte no text contains, as the MIME
+// Sniffing standard defines binary data bytes.
+func isBinary(body []byte) bool {
+	for _, b := range body {
+		if b <= 0x08 || b == 0x0b || 0x0e <= b && b <= 0x1a || 0x1c <= b && b <= 0x1f {
+			return true
+		}
+	}
+	return false
+}
+
+// WithContentSniffing sets the Content-Type of each response to the type
+// SniffContentType finds, as a browser would treat it. The body is returned
+// whole. Layers that act on HTML, such as WithMetaRefresh and
+// WithCookieGates, then see the sniffed type.
+//
+// Only responses whose type is missing, unknown, or one Apache sends by
+// default have the first 512 bytes of their body read before they are
+// returned. A response that declares any other type is trusted without
+// reading it, so streamed responses such as text/event-stream are not held
+// up.
+func WithContentSniffing() ClientOption {
+	return func(c *clientConfig) {
+		c.sniff = true
+	}
+}
+
+// sniffTransport sets the Content-Type of responses to their sniffed type.
+type sniffTransport struct {
+	wrapped
+}
+
+func (t *sniffTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Method == http.MethodHead || res.Body == nil || res.Body == http.NoBody || !sniffsBody(res.Header) {
+		return res, nil
+	}
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(res.Body, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		res.Body.Close()
+		return nil, err
+	}
+	head = head[:n]
+	res.Body = &prefixedBody{Reader: io.MultiReader(bytes.NewReader(head), res.Body), body: res.Body}
+
+	if sniffed := SniffContentType(res.Header, head); sniffed != res.Header.Get("Content-Type") {
+		res.Header.Set("Content-Type", sniffed)
+	}
+
+	return res, nil
+}
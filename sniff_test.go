@@ -0,0 +1,62 @@
+package mimic
+
+import (
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestSniffContentType(t *testing.T) {
+	html := []byte("<!DOCTYPE html><html><body>hi</body></html>")
+	binary := []byte{0x00, 0x01, 0x02, 'a', 'b'}
+
+	tests := []struct {
+		name   string
+		header http.Header
+		body   []byte
+		want   string
+	}{
+		{"declared", http.Header{"Content-Type": {"application/json"}}, html, "application/json"},
+		{"missing", http.Header{}, html, "text/html; charset=utf-8"},
+		{"unknown", http.Header{"Content-Type": {"unknown/unknown"}}, html, "text/html; charset=utf-8"},
+		{"missing nosniff", http.Header{"X-Content-Type-Options": {"nosniff"}}, html, "text/plain; charset=utf-8"},
+		{"apache binary", http.Header{"Content-Type": {"text/plain; charset=UTF-8"}}, binary, "application/octet-stream"},
+		{"apache text", http.Header{"Content-Type": {"text/plain"}}, html, "text/plain"},
+		{"apache binary nosniff", http.Header{"Content-Type": {"text/plain"}, "X-Content-Type-Options": {"nosniff"}}, binary, "text/plain"},
+		{"plain utf-16", http.Header{"Content-Type": {"text/plain; charset=utf-16"}}, binary, "text/plain; charset=utf-16"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SniffContentType(tt.header, tt.body); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffsBody(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   bool
+	}{
+		{"declared", http.Header{"Content-Type": {"text/event-stream"}}, false},
+		{"missing", http.Header{}, true},
+		{"unknown", http.Header{"Content-Type": {"application/unknown"}}, true},
+		{"apache", http.Header{"Content-Type": {"text/plain"}}, true},
+		{"apache nosniff", http.Header{"Content-Type": {"text/plain"}, "X-Content-Type-Options": {"nosniff"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffsBody(tt.header); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentSniffingCloseIdleConnections(t *testing.T) {
+	testClientClosesIdle(t, WithContentSniffing())
+}
@@ -0,0 +1,129 @@
+package mimic
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+)
+
+// SocketOptions sets TCP socket options on the connections a transport dials.
+// The zero value leaves the operating system's defaults and Go's, which enables
+// TCP_NODELAY as browsers do.
+type SocketOptions struct {
+	// DisableNoDelay turns TCP_NODELAY off, letting the kernel coalesce small
+	// writes (Nagle's algorithm).
+	DisableNoDelay bool
+
+	// KeepAliveIdle, KeepAliveInterval, and KeepAliveCount configure TCP
+	// keep-alive probes. Setting any of them enables keep-alive; zero fields
+	// keep their system defaults.
+	KeepAliveIdle     time.Duration
+	KeepAliveInterval time.Duration
+	KeepAliveCount    int
+
+	// ReadBuffer and WriteBuffer set SO_RCVBUF and SO_SNDBUF in bytes. A value
+	// of 0 keeps the kernel's auto-tuned size.
+	ReadBuffer  int
+	WriteBuffer int
+
+	// TTL sets the IP time to live, or the hop limit for IPv6. Its initial
+	// value is one of the clearest operating system giveaways: Windows uses
+	// 128, while Linux, macOS, and iOS use 64. A value of 0 keeps the system
+	// default.
+	TTL int
+}
+
+// WithSocketOptions applies opts to every TCP connection the transport dials.
+// It wraps the base transport's DialContext and skips connections that are not
+// TCP, such as those returned by a custom tunneling dialer.
+func WithSocketOptions(opts SocketOptions) TransportOption {
+	return func(c *transportConfig) {
+		c.socketOptions = &opts
+	}
+}
+
+// dialContext wraps dial so the TCP connections it returns have opts applied.
+func (o SocketOptions) dialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if tcp, ok := conn.(*net.TCPConn); ok {
+			if err := o.apply(tcp); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+
+		return conn, nil
+	}
+}
+
+func (o SocketOptions) apply(conn *net.TCPConn) error {
+	if o.DisableNoDelay {
+		if err := conn.SetNoDelay(false); err != nil {
+			return fmt.Errorf("setting TCP_NODELAY: %w", err)
+		}
+	}
+
+	if o.KeepAliveIdle > 0 || o.KeepAliveInterval > 0 || o.KeepAliveCount > 0 {
+		err := conn.SetKeepAliveConfig(net.KeepAliveConfig{
+			Enable:   true,
+			Idle:     o.KeepAliveIdle,
+			Interval: o.KeepAliveInterval,
+			Count:    o.KeepAliveCount,
+		})
+		if err != nil {
+			return fmt.Errorf("setting keep-alive: %w", err)
+		}
+	}
+
+	if o.ReadBuffer > 0 {
+		if err := conn.SetReadBuffer(o.ReadBuffer); err != nil {
+			return fmt.Errorf("setting SO_RCVBUF: %w", err)
+		}
+	}
+
+	if o.WriteBuffer > 0 {
+		if err := conn.SetWriteBuffer(o.WriteBuffer); err != nil {
+			return fmt.Errorf("setting SO_SNDBUF: %w", err)
+		}
+	}
+
+	if o.TTL > 0 {
+		if err := setTTL(conn, o.TTL); err != nil {
+			return fmt.Errorf("setting ttl: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// setTTL sets the IPv4 TTL or IPv6 hop limit of conn, depending on its address
+// family.
+func setTTL(conn *net.TCPConn, ttl int) error {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	ipv6 := false
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		ipv6 = addr.IP.To4() == nil
+	}
+
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		sockErr = setsockoptTTL(fd, ipv6, ttl)
+	}); err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package mimic
+
+import "errors"
+
+func setsockoptTTL(fd uintptr, ipv6 bool, ttl int) error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,50 @@
+//go:build unix
+
+package mimic
+
+import (
+	"context"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSocketOptions(t *testing.T) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	opts := SocketOptions{
+		KeepAliveIdle:     45 * time.Second,
+		KeepAliveInterval: 45 * time.Second,
+		ReadBuffer:        64 << 10,
+		WriteBuffer:       64 << 10,
+		TTL:               128,
+	}
+
+	conn, err := opts.dialContext(nil)(context.Background(), "tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ttl int
+	var sockErr error
+	raw.Control(func(fd uintptr) {
+		ttl, sockErr = syscall.GetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TTL)
+	})
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	if ttl != opts.TTL {
+		t.Errorf("want ttl %d; got %d", opts.TTL, ttl)
+	}
+}
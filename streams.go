@@ -0,0 +1,151 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// maxHTTP1Origins bounds how many origins the limiter remembers answering
+// over HTTP/1.1.
+const maxHTTP1Origins = 1024
+
+// streamLimiter holds requests to an origin back once as many are in flight
+// as the browser opens streams on one HTTP/2 connection, so they wait for a
+// stream to finish the way the browser's requests would rather than being
+// sent all at once. The server's own SETTINGS_MAX_CONCURRENT_STREAMS is
+// enforced by the HTTP/2 transport, which queues requests on the connection
+// instead of dialing another.
+//
+// The limit is kept per origin, not per connection: the connection a request
+// goes out on is only picked once it is sent. Origins that HTTP/2 coalesces
+// onto one connection are each held to the limit, so together they can run
+// more streams on it than the browser would.
+type streamLimiter struct {
+	max int
+
+	mu      sync.Mutex
+	origins map[string]*originStreams
+	http1   map[string]struct{} // origins that last answered over HTTP/1.1
+}
+
+// originStreams are the streams in flight to one origin. The entry is
+// dropped once no request holds or waits for one of its streams, so a client
+// that visits many origins does not keep one for each.
+type originStreams struct {
+	slots chan struct{}
+	refs  int // requests holding or waiting for a stream; l.mu guards it
+}
+
+func newStreamLimiter(max int) *streamLimiter {
+	return &streamLimiter{
+		max:     max,
+		origins: make(map[string]*originStreams),
+		http1:   make(map[string]struct{}),
+	}
+}
+
+// origin returns the streams to the origin key, creating them if needed, and
+// holds them until done is called.
+func (l *streamLimiter) origin(key string) *originStreams {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	o, ok := l.origins[key]
+	if !ok {
+		o = &originStreams{slots: make(chan struct{}, l.max)}
+		l.origins[key] = o
+	}
+	o.refs++
+	return o
+}
+
+// done lets go of the streams to the origin key, noting which protocol the
+// origin answered res with, if any.
+func (l *streamLimiter) done(key string, o *originStreams, res *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if res != nil {
+		l.noteProtocol(key, res.ProtoMajor == 1)
+	}
+	o.refs--
+	if o.refs == 0 {
+		delete(l.origins, key)
+	}
+}
+
+// noteProtocol records whether the origin key answered over HTTP/1.1. Once
+// maxHTTP1Origins are known, one is forgotten to make room; its next request
+// is then limited until it answers again. l.mu must be held.
+func (l *streamLimiter) noteProtocol(key string, http1 bool) {
+	if !http1 {
+		delete(l.http1, key)
+		return
+	}
+	if _, ok := l.http1[key]; ok {
+		return
+	}
+	if len(l.http1) >= maxHTTP1Origins {
+		for k := range l.http1 {
+			delete(l.http1, k)
+			break
+		}
+	}
+	l.http1[key] = struct{}{}
+}
+
+// acquire waits for a stream to the origin key, returning a func that frees
+// it. Origins that answered over HTTP/1.1 are not limited, since each of
+// their requests has a connection of its own.
+func (l *streamLimiter) acquire(ctx context.Context, key string) (func(res *http.Response), error) {
+	o := l.origin(key)
+
+	l.mu.Lock()
+	_, http1 := l.http1[key]
+	l.mu.Unlock()
+
+	var once sync.Once
+	if http1 {
+		return func(res *http.Response) {
+			once.Do(func() { l.done(key, o, res) })
+		}, nil
+	}
+
+	select {
+	case o.slots <- struct{}{}:
+	case <-ctx.Done():
+		l.done(key, o, nil)
+		return nil, ctx.Err()
+	}
+
+	return func(res *http.Response) {
+		once.Do(func() {
+			<-o.slots
+			l.done(key, o, res)
+		})
+	}, nil
+}
+
+// streamBody frees its stream once the body is read to the end or closed.
+type streamBody struct {
+	io.ReadCloser
+	res     *http.Response
+	release func(res *http.Response)
+}
+
+func (b *streamBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil {
+		b.release(b.res)
+	}
+	return n, err
+}
+
+func (b *streamBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release(b.res)
+	return err
+}
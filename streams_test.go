@@ -0,0 +1,177 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestStreamConcurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverStreams int
+		clientStreams uint32
+		want          int32
+	}{
+		{"server limit", 2, 100, 2},
+		{"browser limit", 250, 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var inFlight, peak, conns atomic.Int32
+			// each request holds its stream until the test releases it
+			started := make(chan struct{}, 13)
+			release := make(chan struct{})
+			server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for p := peak.Load(); n > p && !peak.CompareAndSwap(p, n); p = peak.Load() {
+				}
+				started <- struct{}{}
+				<-release
+			}))
+			server.EnableHTTP2 = true
+			server.Config.HTTP2 = &stdhttp.HTTP2Config{MaxConcurrentStreams: tt.serverStreams}
+			server.Config.ConnState = func(_ net.Conn, state stdhttp.ConnState) {
+				if state == stdhttp.StateNew {
+					conns.Add(1)
+				}
+			}
+			server.StartTLS()
+			defer server.Close()
+
+			spec, err := Firefox("139.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.http2Options.MaxConcurrentStreams = tt.clientStreams
+
+			tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+				TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			// one request first, so the server's SETTINGS are known
+			get := func() error {
+				req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+				if err != nil {
+					return err
+				}
+				res, err := tr.RoundTrip(req)
+				if err != nil {
+					return err
+				}
+				_, err = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				return err
+			}
+			first := make(chan error, 1)
+			go func() { first <- get() }()
+			<-started
+			release <- struct{}{}
+			if err := <-first; err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			errs := make(chan error, 12)
+			for range 12 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- get()
+				}()
+			}
+			// once the limit is reached, each stream that finishes lets
+			// exactly one waiting request start
+			for range tt.want {
+				<-started
+			}
+			for range 12 - int(tt.want) {
+				release <- struct{}{}
+				<-started
+			}
+			for range tt.want {
+				release <- struct{}{}
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if got := peak.Load(); got != tt.want {
+				t.Errorf("peak concurrent streams = %d, want %d", got, tt.want)
+			}
+			if got := conns.Load(); got != 1 {
+				t.Errorf("connections = %d, want 1", got)
+			}
+		})
+	}
+}
+
+func TestStreamCanceled(t *testing.T) {
+	tr := newTestTransport(t)
+	tr.streams = newStreamLimiter(1)
+
+	// the one stream to the origin stays in flight
+	if _, err := tr.streams.acquire(context.Background(), "https://example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	body := &closeRecorder{Reader: strings.NewReader("a=1")}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://example.com/", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tr.RoundTrip(req); !errors.Is(err, context.DeadlineExceeded) || !body.closed {
+		t.Errorf("want the body of a request canceled waiting for a stream closed; got %v, closed %t", err, body.closed)
+	}
+}
+
+func TestStreamLimiterDropsIdleOrigins(t *testing.T) {
+	l := newStreamLimiter(1)
+
+	release, err := l.acquire(context.Background(), "https://a.example")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.acquire(ctx, "https://a.example"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("want a canceled wait; got %v", err)
+	}
+	if _, ok := l.origins["https://a.example"]; !ok {
+		t.Fatal("want the origin kept while a stream is in flight")
+	}
+
+	release(&http.Response{ProtoMajor: 1})
+	release(nil)
+	if len(l.origins) != 0 {
+		t.Errorf("want no origins once every stream is freed; got %d", len(l.origins))
+	}
+	if _, ok := l.http1["https://a.example"]; !ok {
+		t.Error("want the origin's protocol remembered after its streams are dropped")
+	}
+}
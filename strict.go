@@ -0,0 +1,65 @@
+package mimic
+
+import (
+	"net/url"
+	"strings"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// WithStrictMode makes RoundTrip fail with a *StrictModeError, before anything
+// is sent, when a request would give away that it did not come from the
+// mimicked browser:
+//   - URLs that cannot negotiate HTTP/2, which every spec claims: any scheme
+//     other than https, after the HSTS upgrade
+//   - headers browsers never send over HTTP/2: Connection, Keep-Alive,
+//     Proxy-Connection, Transfer-Encoding, Upgrade, and TE other than trailers
+//   - Go's default User-Agent
+//   - Content-Type values in Go's "; charset=utf-8" spelling, which browsers
+//     write as ";charset=UTF-8"
+func WithStrictMode() TransportOption {
+	return func(c *transportConfig) {
+		c.strict = true
+	}
+}
+
+// connectionHeaders are the connection-specific headers HTTP/2 forbids.
+var connectionHeaders = []string{"Connection", "Keep-Alive", "Proxy-Connection", "Transfer-Encoding", "Upgrade"}
+
+// checkStrict reports the first way header and target break the browser's
+// identity. Header keys are compared case-insensitively, since callers may set
+// them without canonicalizing.
+func checkStrict(header http.Header, target *url.URL) error {
+	if target.Scheme != "https" {
+		return &StrictModeError{Reason: "scheme " + target.Scheme + " cannot negotiate http2"}
+	}
+
+	for key, values := range header {
+		if len(values) == 0 || key == http.HeaderOrderKey || key == http.PHeaderOrderKey {
+			continue
+		}
+
+		for _, name := range connectionHeaders {
+			if strings.EqualFold(key, name) {
+				return &StrictModeError{Header: name, Reason: "connection-specific header sent over http2"}
+			}
+		}
+
+		switch {
+		case strings.EqualFold(key, "TE"):
+			if !strings.EqualFold(strings.TrimSpace(values[0]), "trailers") {
+				return &StrictModeError{Header: "TE", Reason: "only trailers is allowed over http2"}
+			}
+		case strings.EqualFold(key, "User-Agent"):
+			if strings.HasPrefix(values[0], "Go-http-client/") {
+				return &StrictModeError{Header: "User-Agent", Reason: "go default value"}
+			}
+		case strings.EqualFold(key, "Content-Type"):
+			if strings.Contains(values[0], "; charset=utf-8") {
+				return &StrictModeError{Header: "Content-Type", Reason: "go spelling of the charset parameter"}
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package mimic
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestStrictMode(t *testing.T) {
+	tr := newTestTransport(t)
+	tr.strict = true
+
+	tests := []struct {
+		name   string
+		url    string
+		header http.Header
+		ok     bool
+	}{
+		{name: "browser request", url: "https://example.com/", ok: true},
+		{name: "trailers", url: "https://example.com/", header: http.Header{"Te": {"trailers"}}, ok: true},
+		{name: "browser content type", url: "https://example.com/", header: http.Header{"Content-Type": {"text/plain;charset=UTF-8"}}, ok: true},
+		{name: "cleartext", url: "http://example.com/"},
+		{name: "keep-alive", url: "https://example.com/", header: http.Header{"connection": {"keep-alive"}}},
+		{name: "te", url: "https://example.com/", header: http.Header{"Te": {"gzip"}}},
+		{name: "go user agent", url: "https://example.com/", header: http.Header{"User-Agent": {"Go-http-client/2.0"}}},
+		{name: "go content type", url: "https://example.com/", header: http.Header{"Content-Type": {"text/plain; charset=utf-8"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &closeRecorder{Reader: strings.NewReader("a=1")}
+			req, err := http.NewRequest(http.MethodPost, tt.url, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header = tt.header
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+
+			_, err = tr.RoundTrip(req)
+			var strictErr *StrictModeError
+			if tt.ok && err != nil {
+				t.Errorf("want success; got %v", err)
+			}
+			if !tt.ok && !errors.As(err, &strictErr) {
+				t.Errorf("want *StrictModeError; got %v", err)
+			}
+			if !tt.ok && !body.closed {
+				t.Error("want the request body closed")
+			}
+		})
+	}
+}
+
+// closeRecorder is a request body that records whether it was closed.
+type closeRecorder struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
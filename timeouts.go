@@ -0,0 +1,107 @@
+package mimic
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Timeouts holds the network timeouts a browser applies to its connections and requests.
+// A zero value for any field disables that timeout.
+type Timeouts struct {
+	// Connect limits how long establishing the TCP connection may take.
+	Connect time.Duration
+
+	// TLSHandshake limits how long the TLS handshake may take.
+	TLSHandshake time.Duration
+
+	// ResponseHeader limits how long to wait for response headers after the
+	// request has been written.
+	ResponseHeader time.Duration
+
+	// BodyStall limits how long a single read of the response body may block
+	// without receiving any bytes. Slow but progressing bodies are not affected.
+	BodyStall time.Duration
+
+	// Idle limits how long a connection may sit unused in the pool before it
+	// is closed.
+	Idle time.Duration
+
+	// Ping is how long an HTTP/2 connection may go without receiving a frame
+	// before a PING is sent to check that it is still alive.
+	Ping time.Duration
+
+	// PingTimeout limits how long to wait for a PING to be answered before
+	// the connection is closed. Zero waits 15 seconds; it only applies when
+	// Ping is set.
+	PingTimeout time.Duration
+}
+
+// WithTimeouts overrides the spec's default timeouts.
+// Connect, TLSHandshake, ResponseHeader, and Idle only apply to the default
+// transport; a transport set with WithBaseTransport keeps its own values.
+func WithTimeouts(timeouts Timeouts) TransportOption {
+	return func(c *transportConfig) {
+		c.timeouts = &timeouts
+	}
+}
+
+// WithBodyStallTimeout returns a copy of ctx that gives the request sent with
+// it a body stall timeout of d in place of the Transport's, for downloads
+// expected to pause longer or requests that should fail faster. Zero turns
+// stall detection off for the request. Bound the whole request, however fast
+// its body arrives, with a context deadline instead.
+func WithBodyStallTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, bodyStallTimeoutKey, d)
+}
+
+// bodyStallTimeout returns the body stall timeout for a request with ctx,
+// where t is the Transport's.
+func bodyStallTimeout(ctx context.Context, t time.Duration) time.Duration {
+	if d, ok := ctx.Value(bodyStallTimeoutKey).(time.Duration); ok {
+		return d
+	}
+	return t
+}
+
+// stallReader fails a body read that blocks for longer than timeout. The timer
+// only runs while a Read is in progress, so time the caller spends between reads
+// does not count as a stall.
+type stallReader struct {
+	body     io.ReadCloser
+	timeout  time.Duration
+	timer    *time.Timer
+	stalled  atomic.Bool
+	received int64
+}
+
+func newStallReader(body io.ReadCloser, timeout time.Duration) *stallReader {
+	r := &stallReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.stalled.Store(true)
+		body.Close()
+	})
+	r.timer.Stop()
+	return r
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	if r.stalled.Load() {
+		return 0, &BodyStallError{Timeout: r.timeout, Received: r.received}
+	}
+
+	r.timer.Reset(r.timeout)
+	n, err := r.body.Read(p)
+	r.received += int64(n)
+	if !r.timer.Stop() && r.stalled.Load() {
+		return n, &BodyStallError{Timeout: r.timeout, Received: r.received}
+	}
+
+	return n, err
+}
+
+func (r *stallReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
@@ -0,0 +1,145 @@
+package mimic
+
+import (
+	"context"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// stallingRoundTripper answers with a body that sends prefix and then hangs
+// until it is closed.
+type stallingRoundTripper struct {
+	prefix string
+}
+
+func (s stallingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	pr, pw := io.Pipe()
+	go pw.Write([]byte(s.prefix))
+	return &http.Response{StatusCode: http.StatusOK, Body: pr, Request: req}, nil
+}
+
+func TestRoundTripBodyStall(t *testing.T) {
+	tr := newTestTransport(t)
+	tr.transport = stallingRoundTripper{prefix: "hello"}
+	tr.bodyStallTimeout = 20 * time.Millisecond
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	_, err = io.ReadAll(res.Body)
+	var stall *BodyStallError
+	if !errors.As(err, &stall) || !errors.Is(err, ErrBodyStalled) {
+		t.Fatalf("want a BodyStallError; got %v", err)
+	}
+	if stall.Timeout != 20*time.Millisecond || stall.Received != 5 {
+		t.Errorf("stall = %+v, want 20ms after 5 bytes", stall)
+	}
+
+	ctx, cancel := context.WithCancel(WithBodyStallTimeout(context.Background(), 0))
+	res, err = tr.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(50*time.Millisecond, func() {
+		cancel()
+		res.Body.Close()
+	})
+
+	_, err = io.ReadAll(res.Body)
+	if errors.Is(err, ErrBodyStalled) {
+		t.Error("want WithBodyStallTimeout(0) to turn stall detection off")
+	}
+}
+
+func TestTimeoutsBodyStall(t *testing.T) {
+	tests := []struct {
+		name    string
+		chunks  int           // chunks of one byte the server sends
+		gap     time.Duration // between chunks
+		stalls  bool          // the server stops after the first chunk
+		want    string
+		wantErr bool
+	}{
+		{"stalled mid-body", 1, 0, true, "x", true},
+		{"slow but progressing", 6, 40 * time.Millisecond, false, "xxxxxx", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release := make(chan struct{})
+			server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+				for i := range tt.chunks {
+					if i > 0 {
+						time.Sleep(tt.gap)
+					}
+					w.Write([]byte("x"))
+					w.(stdhttp.Flusher).Flush()
+				}
+				if tt.stalls {
+					select {
+					case <-r.Context().Done():
+					case <-release:
+					}
+				}
+			}))
+			server.EnableHTTP2 = true
+			server.StartTLS()
+			defer server.Close()
+			defer close(release)
+
+			spec, err := Chromium(BrandChrome, "137.0.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr, err := NewTransport(spec, PlatformWindows,
+				WithBaseTransport(&http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}),
+				WithTimeouts(Timeouts{BodyStall: 100 * time.Millisecond}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if string(body) != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+
+			var stall *BodyStallError
+			if got := errors.As(err, &stall); got != tt.wantErr {
+				t.Fatalf("want a BodyStallError %t; got %v", tt.wantErr, err)
+			}
+			if tt.wantErr && stall.Received != int64(len(tt.want)) {
+				t.Errorf("stall received %d bytes, want %d", stall.Received, len(tt.want))
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("want the whole body; got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package mimic
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// greaseExtensionID stands for any GREASE extension in the override options.
+const greaseExtensionID = utls.GREASE_PLACEHOLDER
+
+// tlsOverride edits a ClientHello spec resolved from a built-in hello ID.
+type tlsOverride func(spec *utls.ClientHelloSpec) error
+
+// WithoutTLSExtension removes the extensions with the given IDs from the
+// browser's ClientHello. Use utls.GREASE_PLACEHOLDER to remove GREASE
+// extensions. IDs are listed in utls's dicttls package, such as
+// dicttls.ExtType_application_settings.
+//
+// Overrides apply to the browser's built-in hello, not to one set with
+// WithCustomHello.
+func WithoutTLSExtension(ids ...uint16) SpecOption {
+	return func(c *specConfig) {
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			spec.Extensions = slices.DeleteFunc(spec.Extensions, func(ext utls.TLSExtension) bool {
+				return slices.Contains(ids, extensionID(ext))
+			})
+			return nil
+		})
+	}
+}
+
+// WithPlatformTLS applies the TLS overrides in opts, such as
+// WithoutTLSExtension, only to the ClientHello sent on platform, for builds
+// whose hello differs on one operating system, as during a staged rollout.
+// They follow the overrides that apply on every platform. Options in opts
+// that are not TLS overrides are ignored. Only applies to Chromium specs.
+func WithPlatformTLS(platform Platform, opts ...SpecOption) SpecOption {
+	return func(c *specConfig) {
+		var sub specConfig
+		for _, opt := range opts {
+			opt(&sub)
+		}
+		if c.platformTLS == nil {
+			c.platformTLS = make(map[Platform][]tlsOverride)
+		}
+		c.platformTLS[platform] = append(c.platformTLS[platform], sub.tlsOverrides...)
+	}
+}
+
+// WithTLSExtensionAfter inserts ext right after the extension with ID after.
+// If the hello already has an extension with ext's ID, it is removed first, so the
+// option also moves and replaces extensions. The spec constructor returns an
+// error if no extension has ID after.
+//
+// Specs that shuffle their extensions on each connection, as Chrome 106+ does,
+// keep the extension but not its position.
+func WithTLSExtensionAfter(after uint16, ext utls.TLSExtension) SpecOption {
+	return func(c *specConfig) {
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			id := extensionID(ext)
+			if id != greaseExtensionID {
+				spec.Extensions = slices.DeleteFunc(spec.Extensions, func(e utls.TLSExtension) bool {
+					return extensionID(e) == id
+				})
+			}
+
+			i := slices.IndexFunc(spec.Extensions, func(e utls.TLSExtension) bool {
+				return extensionID(e) == after
+			})
+			if i < 0 {
+				return fmt.Errorf("inserting extension %d: no extension %d", id, after)
+			}
+
+			spec.Extensions = slices.Insert(spec.Extensions, i+1, ext)
+			return nil
+		})
+	}
+}
+
+// WithCipherSuites replaces the cipher suites of the browser's ClientHello
+// with ids, in order. Include utls.GREASE_PLACEHOLDER where the browser sends
+// a GREASE value.
+func WithCipherSuites(ids ...uint16) SpecOption {
+	return func(c *specConfig) {
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			spec.CipherSuites = slices.Clone(ids)
+			return nil
+		})
+	}
+}
+
+// WithoutCipherSuites removes the given cipher suites from the browser's
+// ClientHello.
+func WithoutCipherSuites(ids ...uint16) SpecOption {
+	return func(c *specConfig) {
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			spec.CipherSuites = slices.DeleteFunc(spec.CipherSuites, func(id uint16) bool {
+				return slices.Contains(ids, id)
+			})
+			return nil
+		})
+	}
+}
+
+// WithRecordSizeLimit sets the value of the record_size_limit extension, which
+// Firefox sends as 16385. It is ignored for browsers whose hello has no
+// record_size_limit extension.
+func WithRecordSizeLimit(limit uint16) SpecOption {
+	return func(c *specConfig) {
+		c.tlsOverrides = append(c.tlsOverrides, func(spec *utls.ClientHelloSpec) error {
+			for i, ext := range spec.Extensions {
+				if _, ok := ext.(*utls.FakeRecordSizeLimitExtension); ok {
+					spec.Extensions[i] = &utls.FakeRecordSizeLimitExtension{Limit: limit}
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// extensionID returns the extension type ext writes, or utls.GREASE_PLACEHOLDER
+// for GREASE extensions, whose type is only chosen during the handshake.
+func extensionID(ext utls.TLSExtension) uint16 {
+	switch ext := ext.(type) {
+	case *utls.UtlsGREASEExtension:
+		return greaseExtensionID
+	case *utls.UtlsPaddingExtension:
+		// the padding length, and so the encoding, is only known once the
+		// rest of the hello is
+		return 21
+	case *utls.GenericExtension:
+		return ext.Id
+	}
+
+	buf := make([]byte, ext.Len())
+	if n, _ := ext.Read(buf); n < 2 {
+		return 0
+	}
+	return binary.BigEndian.Uint16(buf)
+}
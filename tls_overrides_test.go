@@ -0,0 +1,127 @@
+package mimic
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+func TestTLSOverrides(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "120.0.0.0",
+		WithoutTLSExtension(dicttls.ExtType_application_settings),
+		WithTLSExtensionAfter(dicttls.ExtType_server_name, &utls.StatusRequestExtension{}),
+		WithoutCipherSuites(utls.TLS_RSA_WITH_AES_128_CBC_SHA, utls.TLS_RSA_WITH_AES_256_CBC_SHA),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helloSpec, err := spec.ClientHelloSpec(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []uint16
+	for _, ext := range helloSpec.Extensions {
+		ids = append(ids, extensionID(ext))
+	}
+	if slices.Contains(ids, dicttls.ExtType_application_settings) {
+		t.Error("want ALPS removed")
+	}
+	if n := len(slices.DeleteFunc(slices.Clone(ids), func(id uint16) bool { return id != dicttls.ExtType_status_request })); n != 1 {
+		t.Errorf("want status_request moved, not duplicated; got %d", n)
+	}
+	if slices.Contains(helloSpec.CipherSuites, utls.TLS_RSA_WITH_AES_128_CBC_SHA) {
+		t.Error("want cipher suite removed")
+	}
+
+	base, err := Chromium(BrandChrome, "120.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseSpec, err := base.ClientHelloSpec(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(baseSpec.Extensions) != len(helloSpec.Extensions)+1 {
+		t.Errorf("want one extension fewer than the built-in hello; got %d and %d", len(helloSpec.Extensions), len(baseSpec.Extensions))
+	}
+}
+
+func TestTLSExtensionAfterOrder(t *testing.T) {
+	spec, err := Firefox("133.0", WithTLSExtensionAfter(dicttls.ExtType_server_name, &utls.GenericExtension{Id: 0xfe02}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helloSpec, err := spec.ClientHelloSpec(PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(helloSpec.Extensions, func(ext utls.TLSExtension) bool {
+		return extensionID(ext) == dicttls.ExtType_server_name
+	})
+	if i < 0 || extensionID(helloSpec.Extensions[i+1]) != 0xfe02 {
+		t.Error("want extension inserted after server_name")
+	}
+
+	_, err = Firefox("133.0", WithTLSExtensionAfter(0xfe03, &utls.GenericExtension{Id: 0xfe02}))
+	var specErr *TLSSpecError
+	if !errors.As(err, &specErr) {
+		t.Errorf("want TLSSpecError for a missing anchor; got %v", err)
+	}
+}
+
+func TestWithCipherSuites(t *testing.T) {
+	order := []uint16{utls.GREASE_PLACEHOLDER, utls.TLS_AES_256_GCM_SHA384, utls.TLS_AES_128_GCM_SHA256, utls.TLS_CHACHA20_POLY1305_SHA256}
+
+	spec, err := Safari("18.0", WithCipherSuites(order...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []Platform{PlatformMac, PlatformIOS} {
+		helloSpec, err := spec.ClientHelloSpec(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(helloSpec.CipherSuites, order) {
+			t.Errorf("%s: want cipher suites %v; got %v", p, order, helloSpec.CipherSuites)
+		}
+	}
+}
+
+func TestWithPlatformTLS(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0",
+		WithoutCipherSuites(utls.TLS_RSA_WITH_AES_128_CBC_SHA),
+		WithPlatformTLS(PlatformAndroid, WithoutTLSExtension(dicttls.ExtType_compress_certificate)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hasCompression := func(p Platform) bool {
+		t.Helper()
+		helloSpec, err := spec.ClientHelloSpec(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(helloSpec.CipherSuites, utls.TLS_RSA_WITH_AES_128_CBC_SHA) {
+			t.Errorf("%s: want the override for every platform applied", p)
+		}
+		return slices.ContainsFunc(helloSpec.Extensions, func(ext utls.TLSExtension) bool {
+			return extensionID(ext) == dicttls.ExtType_compress_certificate
+		})
+	}
+
+	if hasCompression(PlatformAndroid) {
+		t.Error("want certificate compression removed on android")
+	}
+	if !hasCompression(PlatformWindows) {
+		t.Error("want certificate compression kept on windows")
+	}
+}
@@ -0,0 +1,100 @@
+package mimic
+
+import (
+	"reflect"
+	"slices"
+
+	utls "github.com/refraction-networking/utls"
+)
+
+// shuffledHelloIDs are the hello IDs whose utls spec shuffles its extensions
+// with ShuffleChromeTLSExtensions, as Chrome 106+ does on every connection.
+var shuffledHelloIDs = map[utls.ClientHelloID]bool{
+	utls.HelloChrome_106_Shuffle:          true,
+	utls.HelloChrome_112_PSK_Shuf:         true,
+	utls.HelloChrome_114_Padding_PSK_Shuf: true,
+	utls.HelloChrome_115_PQ:               true,
+	utls.HelloChrome_120:                  true,
+	utls.HelloChrome_120_PQ:               true,
+	utls.HelloChrome_131:                  true,
+	utls.HelloChrome_133:                  true,
+}
+
+// tlsSpec hands out copies of a ClientHello spec that was resolved once.
+type tlsSpec struct {
+	template utls.ClientHelloSpec
+	shuffle  bool
+}
+
+// newTLSSpec resolves the given hello ID. A fresh copy of the spec is needed
+// for every connection because the spec may be mutated during the TLS handshake.
+func newTLSSpec(id utls.ClientHelloID) (*tlsSpec, error) {
+	template, err := utls.UTLSIdToSpec(id)
+	if err != nil {
+		return nil, &TLSSpecError{HelloID: id, Err: err}
+	}
+
+	return &tlsSpec{template: template, shuffle: shuffledHelloIDs[id]}, nil
+}
+
+// New returns a copy of the spec. Specs that shuffle their extensions are
+// reshuffled on each call, as the browser does per connection.
+func (s *tlsSpec) New() *utls.ClientHelloSpec {
+	spec := cloneClientHelloSpec(&s.template)
+	if s.shuffle {
+		spec.Extensions = utls.ShuffleChromeTLSExtensions(spec.Extensions)
+	}
+	return spec
+}
+
+// cloneClientHelloSpec returns a copy of spec that shares no mutable state with
+// it, so the copy can be applied to a connection while spec stays pristine.
+func cloneClientHelloSpec(spec *utls.ClientHelloSpec) *utls.ClientHelloSpec {
+	clone := *spec
+	clone.CipherSuites = slices.Clone(spec.CipherSuites)
+	clone.CompressionMethods = slices.Clone(spec.CompressionMethods)
+	clone.Extensions = make([]utls.TLSExtension, len(spec.Extensions))
+	for i, ext := range spec.Extensions {
+		clone.Extensions[i] = cloneExtension(ext)
+	}
+	return &clone
+}
+
+// cloneExtension copies the struct ext points to along with its exported slices,
+// which utls fills in place during the handshake (SNI, GREASE values, key shares).
+// Unexported handshake state is copied from a spec that has never been applied,
+// so it is still zero.
+func cloneExtension(ext utls.TLSExtension) utls.TLSExtension {
+	v := reflect.ValueOf(ext)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return ext
+	}
+
+	clone := reflect.New(v.Elem().Type())
+	clone.Elem().Set(v.Elem())
+	cloneSlices(clone.Elem())
+
+	return clone.Interface().(utls.TLSExtension)
+}
+
+// cloneSlices replaces every settable slice in the struct v with a copy,
+// recursing into slices of structs.
+func cloneSlices(v reflect.Value) {
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	for i := range v.NumField() {
+		field := v.Field(i)
+		if !field.CanSet() || field.Kind() != reflect.Slice || field.IsNil() {
+			continue
+		}
+
+		clone := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+		reflect.Copy(clone, field)
+		for j := range clone.Len() {
+			cloneSlices(clone.Index(j))
+		}
+		field.Set(clone)
+	}
+}
@@ -0,0 +1,410 @@
+package mimic
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	"github.com/refraction-networking/utls/dicttls"
+)
+
+func TestTLSSpecCopies(t *testing.T) {
+	ts, err := newTLSSpec(utls.HelloChrome_133)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied := ts.New()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn := utls.UClient(client, &utls.Config{ServerName: "example.com"}, utls.HelloCustom)
+	if err := conn.ApplyPreset(applied); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.BuildHandshakeState(); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := ts.New()
+	if len(fresh.Extensions) != len(applied.Extensions) {
+		t.Fatalf("want %d extensions; got %d", len(applied.Extensions), len(fresh.Extensions))
+	}
+
+	for _, ext := range fresh.Extensions {
+		switch ext := ext.(type) {
+		case *utls.SNIExtension:
+			if ext.ServerName != "" {
+				t.Errorf("sni leaked from applied spec: %q", ext.ServerName)
+			}
+		case *utls.KeyShareExtension:
+			for _, share := range ext.KeyShares {
+				if len(share.Data) > 1 {
+					t.Errorf("key share for %v leaked from applied spec", share.Group)
+				}
+			}
+		case *utls.UtlsGREASEExtension:
+			if ext.Value != 0 {
+				t.Errorf("grease value leaked from applied spec: %#x", ext.Value)
+			}
+		}
+	}
+}
+
+func TestTLSSpecShuffles(t *testing.T) {
+	ts, err := newTLSSpec(utls.HelloChrome_133)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	order := func(spec *utls.ClientHelloSpec) string {
+		var s string
+		for _, ext := range spec.Extensions {
+			s += fmt.Sprintf("%T,", ext)
+		}
+		return s
+	}
+
+	first := order(ts.New())
+	for range 20 {
+		if order(ts.New()) != first {
+			return
+		}
+	}
+
+	t.Error("extension order did not change across 20 specs")
+}
+
+func TestFirefoxTLSVersions(t *testing.T) {
+	tests := []struct {
+		version   string
+		dc        bool
+		dheSuites bool
+	}{
+		{"70.0", false, true},
+		{"77.0", true, true},
+		{"78.0", true, false},
+		{"98.0", true, false},
+	}
+
+	for _, tt := range tests {
+		spec, err := Firefox(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		helloSpec, err := spec.ClientHelloSpec(PlatformLinux)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		dc := slices.ContainsFunc(helloSpec.Extensions, func(ext utls.TLSExtension) bool {
+			return extensionID(ext) == dicttls.ExtType_delegated_credentials
+		})
+		if dc != tt.dc {
+			t.Errorf("firefox %s: want delegated_credentials %v; got %v", tt.version, tt.dc, dc)
+		}
+		if dhe := slices.Contains(helloSpec.CipherSuites, utls.FAKE_TLS_DHE_RSA_WITH_AES_128_CBC_SHA); dhe != tt.dheSuites {
+			t.Errorf("firefox %s: want DHE suites %v; got %v", tt.version, tt.dheSuites, dhe)
+		}
+	}
+}
+
+func TestFirefoxKeyShares(t *testing.T) {
+	tests := []struct {
+		version string
+		want    []utls.CurveID
+	}{
+		{"120.0", []utls.CurveID{utls.X25519, utls.CurveP256}},
+		{"128.0", []utls.CurveID{utls.X25519, utls.CurveP256}},
+		{"132.0", []utls.CurveID{utls.X25519MLKEM768, utls.X25519, utls.CurveP256}},
+		{"140.0", []utls.CurveID{utls.X25519MLKEM768, utls.X25519, utls.CurveP256}},
+	}
+
+	for _, tt := range tests {
+		spec, err := Firefox(tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		helloSpec, err := spec.ClientHelloSpec(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []utls.CurveID
+		for _, ext := range helloSpec.Extensions {
+			if ks, ok := ext.(*utls.KeyShareExtension); ok {
+				for _, share := range ks.KeyShares {
+					got = append(got, share.Group)
+				}
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("firefox %s: want key shares %v; got %v", tt.version, tt.want, got)
+		}
+	}
+}
+
+func TestWithRecordSizeLimit(t *testing.T) {
+	spec, err := Firefox("133.0", WithRecordSizeLimit(4096))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	helloSpec, err := spec.ClientHelloSpec(PlatformLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	i := slices.IndexFunc(helloSpec.Extensions, func(ext utls.TLSExtension) bool {
+		return extensionID(ext) == dicttls.ExtType_record_size_limit
+	})
+	if i < 0 {
+		t.Fatal("want record_size_limit kept")
+	}
+	if got := helloSpec.Extensions[i].(*utls.FakeRecordSizeLimitExtension).Limit; got != 4096 {
+		t.Errorf("want limit 4096; got %d", got)
+	}
+}
+
+func TestChromiumCurrentHello(t *testing.T) {
+	want := []uint16{
+		dicttls.ExtType_server_name,
+		dicttls.ExtType_extended_master_secret,
+		dicttls.ExtType_renegotiation_info,
+		dicttls.ExtType_supported_groups,
+		dicttls.ExtType_ec_point_formats,
+		dicttls.ExtType_session_ticket,
+		dicttls.ExtType_application_layer_protocol_negotiation,
+		dicttls.ExtType_status_request,
+		dicttls.ExtType_signature_algorithms,
+		dicttls.ExtType_signed_certificate_timestamp,
+		dicttls.ExtType_key_share,
+		dicttls.ExtType_psk_key_exchange_modes,
+		dicttls.ExtType_supported_versions,
+		dicttls.ExtType_compress_certificate,
+		dicttls.ExtType_application_settings_new,
+		0xfe0d, // encrypted_client_hello, GREASEd
+	}
+	slices.Sort(want)
+
+	for _, version := range []string{"133.0.0.0", "134.0.0.0", "137.0.0.0", "140.0.0.0"} {
+		spec, err := Chromium(BrandChrome, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		helloSpec, err := spec.ClientHelloSpec(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var got []uint16
+		var shares []utls.CurveID
+		for _, ext := range helloSpec.Extensions {
+			if id := extensionID(ext); id != utls.GREASE_PLACEHOLDER {
+				got = append(got, id)
+			}
+			if ks, ok := ext.(*utls.KeyShareExtension); ok {
+				for _, share := range ks.KeyShares {
+					shares = append(shares, share.Group)
+				}
+			}
+		}
+		slices.Sort(got)
+
+		if !slices.Equal(got, want) {
+			t.Errorf("chrome %s: want extensions %v; got %v", version, want, got)
+		}
+		if !slices.Contains(shares, utls.X25519MLKEM768) {
+			t.Errorf("chrome %s: want an X25519MLKEM768 key share; got %v", version, shares)
+		}
+	}
+}
+
+func TestChromiumHelloBuckets(t *testing.T) {
+	tests := []struct {
+		from, to int
+		shuffle  bool
+		ech      bool
+		pq       utls.CurveID
+		alps     uint16
+	}{
+		{83, 86, false, false, 0, 0},
+		{87, 95, false, false, 0, 0},
+		{96, 99, false, false, 0, dicttls.ExtType_application_settings},
+		{100, 101, false, false, 0, dicttls.ExtType_application_settings},
+		{102, 105, false, false, 0, dicttls.ExtType_application_settings},
+		{106, 116, true, false, 0, dicttls.ExtType_application_settings},
+		{117, 123, true, true, 0, dicttls.ExtType_application_settings},
+		{124, 130, true, true, utls.X25519Kyber768Draft00, dicttls.ExtType_application_settings},
+		{131, 132, true, true, utls.X25519MLKEM768, dicttls.ExtType_application_settings},
+		{133, chromiumMaxMajor(), true, true, utls.X25519MLKEM768, dicttls.ExtType_application_settings_new},
+	}
+
+	for _, test := range tests {
+		for major := test.from; major <= test.to; major++ {
+			version := fmt.Sprintf("%d.0.0.0", major)
+			spec, err := Chromium(BrandChrome, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ts, err := spec.tlsSpecFor(PlatformWindows)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if ts.shuffle != test.shuffle {
+				t.Errorf("chrome %s: want shuffle %t", version, test.shuffle)
+			}
+
+			helloSpec := ts.New()
+			var ech, psk bool
+			var pq utls.CurveID
+			var alps uint16
+			for _, ext := range helloSpec.Extensions {
+				switch id := extensionID(ext); id {
+				case 0xfe0d:
+					ech = true
+				case dicttls.ExtType_pre_shared_key:
+					psk = true
+				case dicttls.ExtType_application_settings, dicttls.ExtType_application_settings_new:
+					alps = id
+				}
+				if ks, ok := ext.(*utls.KeyShareExtension); ok {
+					for _, share := range ks.KeyShares {
+						if share.Group == utls.X25519Kyber768Draft00 || share.Group == utls.X25519MLKEM768 {
+							pq = share.Group
+						}
+					}
+				}
+			}
+
+			if ech != test.ech || pq != test.pq || alps != test.alps {
+				t.Errorf("chrome %s: want ech %t, pq %v, alps %d; got %t, %v, %d", version, test.ech, test.pq, test.alps, ech, pq, alps)
+			}
+
+			// a new connection has no session to resume
+			if psk {
+				t.Errorf("chrome %s: want no pre_shared_key extension", version)
+			}
+
+			client, server := net.Pipe()
+			conn := utls.UClient(client, &utls.Config{ServerName: "example.com"}, utls.HelloCustom)
+			if err := conn.ApplyPreset(helloSpec); err != nil {
+				t.Errorf("chrome %s: %v", version, err)
+			} else if err := conn.BuildHandshakeState(); err != nil {
+				t.Errorf("chrome %s: %v", version, err)
+			}
+			client.Close()
+			server.Close()
+		}
+	}
+}
+
+func TestLegacySafari(t *testing.T) {
+	spec, err := Safari("15.6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac, err := spec.ClientHelloSpec(PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ios, err := spec.ClientHelloSpec(PlatformIOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(mac.CipherSuites, ios.CipherSuites) || len(mac.Extensions) != len(ios.Extensions) {
+		t.Error("want the iOS hello on macOS before Safari 16")
+	}
+
+	settings := spec.HTTP2Opts().Settings
+	if len(settings) != 2 || settings[0].Val != 4194304 || settings[1].Val != 100 {
+		t.Errorf("want a 4 MiB window and 100 streams; got %v", settings)
+	}
+	if _, err := spec.QUICSpec(PlatformMac); !errors.Is(err, ErrQUICNotSupported) {
+		t.Errorf("want ErrQUICNotSupported before Safari 16; got %v", err)
+	}
+
+	if _, err := Safari("13.1"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion before Safari 14; got %v", err)
+	}
+}
+
+func TestSafariIPad(t *testing.T) {
+	tests := []struct {
+		opts    []SpecOption
+		uaToken string
+	}{
+		{nil, "(Macintosh; Intel Mac OS X 10_15_7)"},
+		{[]SpecOption{WithIPadDesktopMode(false)}, "(iPad; CPU OS 18_3 like Mac OS X)"},
+	}
+
+	for _, test := range tests {
+		spec, err := Safari("18.3", test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers, err := spec.buildHeaders(PlatformIPadOS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ua := headers.Get("user-agent"); !strings.Contains(ua, test.uaToken) {
+			t.Errorf("want %s in the user agent; got %s", test.uaToken, ua)
+		}
+
+		ipad, err := spec.tlsSpecFor(PlatformIPadOS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ios, err := spec.tlsSpecFor(PlatformIOS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mac, err := spec.tlsSpecFor(PlatformMac)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ipad != ios || ipad == mac {
+			t.Errorf("%s: want the iOS hello on iPadOS", test.uaToken)
+		}
+	}
+}
+
+func TestSafariBuild(t *testing.T) {
+	tests := []struct {
+		version string
+		opts    []SpecOption
+		ios     string
+	}{
+		{"18.3", nil, "iPhone OS 18_3 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/18.3 Mobile/15E148"},
+		{"14.1", nil, "iPhone OS 14_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1 Mobile/15E148"},
+		{"26.0", nil, "iPhone OS 18_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/26.0 Mobile/15E148"},
+		{"17.4", []SpecOption{WithSafariBuild(SafariBuild{Version: "17.4.1", OSVersion: "17_4_1"})}, "iPhone OS 17_4_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4.1 Mobile/15E148"},
+	}
+
+	for _, test := range tests {
+		spec, err := Safari(test.version, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		headers, err := spec.buildHeaders(PlatformIOS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ua := headers.Get("user-agent"); !strings.Contains(ua, test.ios) {
+			t.Errorf("%s: want %s in the user agent; got %s", test.version, test.ios, ua)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package mimic
+
+import (
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestTrailers(t *testing.T) {
+	// fhttp's own test server does not decode mimic's HTTP/2 requests, so the
+	// standard library's server stands in for a real one.
+	server := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		if got := r.Header.Get("Te"); got != "trailers" {
+			t.Errorf("want te: trailers; got %q", got)
+		}
+
+		io.Copy(io.Discard, r.Body)
+		if got := r.Trailer.Get("X-Checksum"); got != "abc" {
+			t.Errorf("want request trailer X-Checksum abc; got %q", got)
+		}
+
+		w.Header().Set("Trailer", "Grpc-Status")
+		w.Write([]byte("ok"))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+	client, err := NewClient(spec, PlatformLinux, WithTransportOptions(WithBaseTransport(base)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, io.NopCloser(strings.NewReader("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Trailer = http.Header{"X-Checksum": {"abc"}}
+
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.ProtoMajor != 2 {
+		t.Fatalf("want http/2; got %s", res.Proto)
+	}
+
+	if _, err := io.ReadAll(res.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := res.Trailer.Get("Grpc-Status"); got != "0" {
+		t.Errorf("want response trailer Grpc-Status 0; got %q", got)
+	}
+}
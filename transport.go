@@ -1,19 +1,50 @@
 package mimic
 
 import (
+	"bytes"
+	"context"
 	"fmt"
-	"math/rand/v2"
+	"io"
 	"net"
+	"net/url"
 	"time"
 
 	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
 )
 
 // TransportOption configures a Transport.
 type TransportOption func(*transportConfig)
 
 type transportConfig struct {
-	baseTransport *http.Transport
+	baseTransport         *http.Transport
+	proxy                 *url.URL
+	timeouts              *Timeouts
+	expectContinueTimeout time.Duration
+	altSvc                *AltSvcCache
+	hsts                  *HSTSStore
+	clientHints           ClientHintStore
+	coalesce              *bool
+	certPolicy            bool
+	platformVerifier      bool
+	helloFragmentation    *HelloFragmentation
+	socketOptions         *SocketOptions
+	fallbackSpec          *ClientSpec
+	onFallback            func(req *http.Request, err error)
+	engine                Engine
+	strict                bool
+	connHooks             *ConnHooks
+	connectTo             connectTo
+	shaping               *Shaping
+	echConfigList         []byte
+	onECHReject           func(ECHRejection)
+	saveData              bool
+	headerRules           []HeaderRule
+	middleware            []Middleware
+	bans                  *BanList
+	hostPolicy            HostPolicy
+	rewriteAddr           AddressRewriter
+	connSelector          ConnSelector
 }
 
 // WithBaseTransport sets the underlying HTTP transport.
@@ -24,6 +55,25 @@ func WithBaseTransport(t *http.Transport) TransportOption {
 	}
 }
 
+// WithProxy sends requests through the proxy at proxyURL instead of the one
+// named by the environment. The http, https, and socks5 schemes are supported.
+func WithProxy(proxyURL *url.URL) TransportOption {
+	return func(c *transportConfig) {
+		c.proxy = proxyURL
+	}
+}
+
+// WithExpectContinue allows requests to carry an "Expect: 100-continue" header,
+// waiting up to timeout for the server's interim response before sending the body.
+//
+// Browsers never send Expect, so by default the header is removed from every
+// request and bodies are sent immediately.
+func WithExpectContinue(timeout time.Duration) TransportOption {
+	return func(c *transportConfig) {
+		c.expectContinueTimeout = timeout
+	}
+}
+
 // NewTransport creates a new Transport that mimics the given browser spec on the given platform.
 // It configures TLS fingerprinting, HTTP/2 settings, and default headers to match
 // the specified browser's real-world behavior.
@@ -35,77 +85,619 @@ func NewTransport(spec *ClientSpec, platform Platform, opts ...TransportOption)
 		opt(cfg)
 	}
 
+	timeouts := spec.timeouts
+	if cfg.timeouts != nil {
+		timeouts = *cfg.timeouts
+	}
+
+	coalesce := cfg.baseTransport == nil && cfg.connectTo == nil && cfg.rewriteAddr == nil
+	if cfg.coalesce != nil {
+		coalesce = *cfg.coalesce
+	}
+
 	if cfg.baseTransport == nil {
-		cfg.baseTransport = defaultTransport()
+		cfg.baseTransport = defaultTransport(timeouts)
+		cfg.baseTransport.ExpectContinueTimeout = cfg.expectContinueTimeout
 	}
 
-	if err := spec.ConfigureTransport(cfg.baseTransport, platform); err != nil {
+	if cfg.proxy != nil {
+		cfg.baseTransport.Proxy = http.ProxyURL(cfg.proxy)
+	}
+
+	t2, err := spec.configureTransport(cfg.baseTransport, platform)
+	if err != nil {
 		return nil, fmt.Errorf("configuring transport: %w", err)
 	}
+	t2.ReadIdleTimeout, t2.PingTimeout = timeouts.Ping, timeouts.PingTimeout
+
+	if cfg.echConfigList != nil {
+		cfg.baseTransport.TLSClientConfig.EncryptedClientHelloConfigList = cfg.echConfigList
+	}
+
+	// the policy goes first so it sees the address actually dialed
+	if cfg.hostPolicy != nil {
+		cfg.baseTransport.DialContext = cfg.hostPolicy.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	if cfg.rewriteAddr != nil {
+		cfg.baseTransport.DialContext = cfg.rewriteAddr.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	if cfg.connectTo != nil {
+		cfg.baseTransport.DialContext = cfg.connectTo.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	// socket options go first so they see the dialer's *net.TCPConn
+	if cfg.socketOptions != nil {
+		cfg.baseTransport.DialContext = cfg.socketOptions.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	var shaper *shaper
+	if cfg.shaping != nil {
+		shaper = newShaper(*cfg.shaping)
+		cfg.baseTransport.DialContext = shaper.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	if cfg.helloFragmentation != nil {
+		cfg.baseTransport.DialContext = cfg.helloFragmentation.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	if cfg.bans != nil {
+		cfg.baseTransport.DialContext = cfg.bans.dialContext(cfg.baseTransport.DialContext)
+	}
+
+	// hooks go last so they count the bytes on the wire
+	events := &eventBus{}
+	hooks := events.hooks(cfg.connHooks)
+	cfg.baseTransport.DialContext = hooks.dialContext(cfg.baseTransport.DialContext)
+
+	if cfg.certPolicy {
+		certificatePolicy{requireSCTs: spec.requireSCTs}.install(cfg.baseTransport.TLSClientConfig)
+	}
+
+	// the verifier goes last so the policy sees the chains it verified
+	if cfg.platformVerifier {
+		platformVerifier{}.install(cfg.baseTransport.TLSClientConfig)
+	}
+
+	var pool *coalescingPool
+	if coalesce {
+		pool = enableCoalescing(cfg.baseTransport, t2)
+	}
+
+	hooks.install(cfg.baseTransport, spec.http2Options)
 
 	headers, err := spec.buildHeaders(platform)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Transport{
-		transport:         cfg.baseTransport,
+	var transport http.RoundTripper = cfg.baseTransport
+	if cfg.engine != nil {
+		transport = cfg.engine
+	}
+	transport = chain(transport, cfg.middleware)
+
+	t := &Transport{
+		transport:         transport,
+		base:              cfg.baseTransport,
+		h2:                t2,
+		engine:            cfg.engine,
+		spec:              spec,
+		platform:          platform,
+		requests:          newRequestTracker(),
+		pool:              pool,
+		altSvc:            cfg.altSvc,
+		hsts:              cfg.hsts,
+		clientHints:       cfg.clientHints,
 		pseudoHeaderOrder: spec.http2Options.PseudoHeaderOrder,
-		defaultHeaders:    headers,
-	}, nil
+		defaultHeaders:    newDefaultHeaders(headers),
+		bodyStallTimeout:  timeouts.BodyStall,
+		ping:              timeouts.Ping,
+		pingTimeout:       timeouts.PingTimeout,
+		uploadChunkSize:   int(spec.http2Options.UploadChunkSize),
+		allowExpect:       cfg.expectContinueTimeout > 0,
+		strict:            cfg.strict,
+		connHooks:         hooks,
+		events:            events,
+		shaper:            shaper,
+		saveData:          cfg.saveData,
+		headerRules:       cfg.headerRules,
+		bans:              cfg.bans,
+		middleware:        cfg.middleware,
+	}
+
+	t.highEntropyHints, err = t.clientHintsFor(spec, platform)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.engine == nil {
+		t.priority = newStreamPriority(t2, spec.fetch.priority)
+		if n := spec.http2Options.MaxConcurrentStreams; n > 0 {
+			t.streams = newStreamLimiter(int(n))
+		}
+		t.connSelector = cfg.connSelector
+		t.partitions = newConnPartitions()
+	}
+
+	if cfg.echConfigList != nil {
+		t.ech = newECHRecovery(t, cfg.onECHReject)
+	}
+
+	if cfg.fallbackSpec != nil {
+		fb, err := t.rebuild(cfg.fallbackSpec, platform)
+		if err != nil {
+			return nil, fmt.Errorf("configuring fallback: %w", err)
+		}
+		t.fallback = newFallback(fb, cfg.onFallback)
+	}
+
+	return t, nil
 }
 
-func defaultTransport() *http.Transport {
+// defaultHeader is a header the Transport adds to requests that do not set it.
+type defaultHeader struct {
+	key    string
+	values []string
+}
+
+// newDefaultHeaders flattens h into a slice so RoundTrip can add each header
+// without canonicalizing its key or allocating its value. The value slices are
+// shared by every request; their capacity equals their length, so appending to
+// one copies it rather than changing the default.
+func newDefaultHeaders(h http.Header) []defaultHeader {
+	headers := make([]defaultHeader, 0, len(h))
+	for key, values := range h {
+		if len(values) == 0 {
+			continue
+		}
+		headers = append(headers, defaultHeader{
+			key:    http.CanonicalHeaderKey(key),
+			values: values[:1:1],
+		})
+	}
+	return headers
+}
+
+func defaultTransport(timeouts Timeouts) *http.Transport {
 	return &http.Transport{
 		Proxy: http.ProxyFromEnvironment,
 		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
+			Timeout:   timeouts.Connect,
 			KeepAlive: 30 * time.Second,
 		}).DialContext,
 		ForceAttemptHTTP2:     true,
 		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
+		IdleConnTimeout:       timeouts.Idle,
+		TLSHandshakeTimeout:   timeouts.TLSHandshake,
+		ResponseHeaderTimeout: timeouts.ResponseHeader,
 	}
 }
 
 // Transport implements http.RoundTripper and handles:
 //   - Setting default headers for the mimicked browser
 //   - Setting the HTTP/2 pseudo-header order
-//   - Randomizing header order to match real browser behavior
+//   - Serializing the host in :authority and Host the way browsers do
+//   - Sending headers in the browser's order unless WithHeaderOrder sets one
+//   - Failing response bodies that stall longer than the browser would wait
+//   - Sizing request body DATA frames like the browser's upload buffer
+//   - Giving HTTP/2 requests from Fetch the priority the browser gives their destination
+//   - Removing the Expect header, which browsers never send
+//   - Recording advertised alternative services when WithAltSvcCache is set
+//   - Upgrading http:// requests to HTTPS-only hosts when WithHSTS is set
+//   - Sending the client hints origins asked for when WithClientHintStore is set
+//   - Sharing HTTP/2 connections across hostnames, see WithCoalescing
+//   - Retrying failed handshakes with a fallback spec when WithFallback is set
+//   - Retrying requests whose server rejects ECH, see WithECH
+//   - Deriving fetch metadata, referrer, and credentials for requests from Fetch
+//   - Rejecting requests that would break the browser's identity, see WithStrictMode
+//   - Delaying requests by the emulated link's latency, see WithShaping
+//   - Asking servers to reduce data usage when WithSaveData is set
+//   - Changing the headers of requests to some hosts, see WithHeaderRules
+//   - Keeping requests away from hosts that banned the client, see WithBanList
+//   - Reporting connections, requests, and challenges, see Transport.Events
 type Transport struct {
 	transport         http.RoundTripper
+	base              *http.Transport
+	h2                *http2.Transport
+	engine            Engine
+	spec              *ClientSpec
+	platform          Platform
+	requests          *requestTracker
+	pool              *coalescingPool
+	fallback          *fallback
+	ech               *echRecovery
+	altSvc            *AltSvcCache
+	hsts              *HSTSStore
+	clientHints       ClientHintStore
+	highEntropyHints  http.Header
 	pseudoHeaderOrder []string
-	defaultHeaders    http.Header
+	defaultHeaders    []defaultHeader
+	bodyStallTimeout  time.Duration
+	ping              time.Duration
+	pingTimeout       time.Duration
+	uploadChunkSize   int
+	allowExpect       bool
+	strict            bool
+	connHooks         *ConnHooks
+	priority          *streamPriority
+	streams           *streamLimiter
+	connSelector      ConnSelector
+	partitions        *connPartitions
+	shaper            *shaper
+	saveData          bool
+	headerRules       []HeaderRule
+	middleware        []Middleware
+	bans              *BanList
+	events            *eventBus
 }
 
 // RoundTrip executes a single HTTP transaction, injecting browser-appropriate
 // headers and pseudo-header ordering.
+//
+// As the http.RoundTripper contract requires, req is not modified: headers are
+// added to a copy, so requests and header maps can be retried, reused, and
+// shared across goroutines. The response's Request is that copy, with the
+// headers as sent, and is the caller's to change.
 func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	header := req.Header
+	if t.ech != nil {
+		if switched := t.ech.switched(req); switched != nil {
+			return switched.RoundTrip(req)
+		}
+	}
+
+	if t.fallback != nil && t.fallback.downgraded(req) {
+		return t.fallback.transport.RoundTrip(req)
+	}
+
+	// the values are shared with req.Header, which is safe because they are only
+	// ever replaced, never written in place
+	header := make(http.Header, len(req.Header)+len(t.defaultHeaders)+2)
+	for key, values := range req.Header {
+		header[key] = values
+	}
 
 	header[http.PHeaderOrderKey] = t.pseudoHeaderOrder
 
-	for key, values := range t.defaultHeaders {
-		if existing := header.Get(key); existing != "" {
+	if !t.allowExpect {
+		header.Del("Expect")
+	}
+
+	target := browserURL(req.URL)
+	if t.hsts != nil && target.Scheme == "http" && t.hsts.Match(target.Hostname()) {
+		target = hstsUpgrade(target)
+	}
+
+	intent := fetchIntentFrom(req.Context())
+	if intent != nil {
+		intent.setHeaders(header, req.Method, target, t.spec.fetch)
+		if !intent.sendsCredentials(target) {
+			header.Del("Cookie")
+		}
+	}
+
+	for _, h := range t.defaultHeaders {
+		if existing := header[h.key]; len(existing) > 0 && existing[0] != "" {
 			continue
 		}
-		if len(values) > 0 {
-			header.Set(key, values[0])
+		header[h.key] = h.values
+	}
+
+	if t.saveData && t.spec.saveData && header["Save-Data"] == nil {
+		header["Save-Data"] = saveDataOn
+	}
+
+	if t.clientHints != nil {
+		t.addClientHints(header, target, intent)
+	}
+
+	if t.headerRules != nil {
+		applyHeaderRules(t.headerRules, header, target.Hostname())
+	}
+
+	if t.strict {
+		if err := checkStrict(header, target); err != nil {
+			closeRequestBody(req)
+			return nil, err
 		}
 	}
 
-	if header[http.HeaderOrderKey] == nil {
-		keys := make([]string, 0, len(header))
-		for key := range header {
-			keys = append(keys, key)
+	// requests sent by intent take the order the browser uses for it
+	fetch := t.spec.fetch
+	browserOrder := fetch.navigationOrder
+	if intent != nil && intent.mode != ModeNavigate {
+		browserOrder = fetch.fetchOrder
+	}
+	if order, ok := headerOrderFrom(req.Context()); ok {
+		merged, err := mergeHeaderOrder(order, header, browserOrder, fetch.navigationOrder, fetch.fetchOrder)
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
 		}
-		rand.Shuffle(len(keys), func(i, j int) {
-			keys[i], keys[j] = keys[j], keys[i]
-		})
-		header[http.HeaderOrderKey] = keys
+		header[http.HeaderOrderKey] = merged
+	} else if header[http.HeaderOrderKey] == nil {
+		header[http.HeaderOrderKey] = browserHeaderOrder(header, browserOrder)
+	}
+
+	out := *req
+	out.Header = header
+	out.URL = target
+	if req.Host != "" {
+		out.Host = browserHost(req.Host, target.Scheme)
+	}
+
+	if intent != nil && intent.form != nil {
+		if body, contentType, ok := intent.form.rebound(req, header, t.spec.fetch); ok {
+			req.Body.Close()
+			header.Set("Content-Type", contentType)
+			out.Body = io.NopCloser(bytes.NewReader(body))
+			out.ContentLength = int64(len(body))
+		}
+	}
+
+	if t.uploadChunkSize > 0 && out.Body != nil && out.Body != http.NoBody {
+		out.Body = &chunkReader{body: out.Body, size: t.uploadChunkSize}
+	}
+
+	sent := &out
+	var handshake *handshakeWatch
+	if t.fallback != nil {
+		handshake = &handshakeWatch{}
+		sent = sent.WithContext(handshake.context(req.Context()))
+	}
+
+	if ctx := t.connHooks.context(sent.Context()); ctx != sent.Context() {
+		sent = sent.WithContext(ctx)
+	}
+
+	transport, priority, streams := t.transport, t.priority, t.streams
+	if t.partitions != nil {
+		part, err := t.partition(req, sent)
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+		if part != nil {
+			transport, priority, streams = part.transport, part.priority, part.streams
+		}
+	}
+
+	if priority != nil {
+		var dest FetchDestination
+		if intent != nil {
+			dest = intent.dest
+		}
+		sent = sent.WithContext(priority.context(sent.Context(), dest))
+	}
+
+	var ban banTarget
+	if t.bans != nil {
+		ban = t.banTargetFor(sent, target.Hostname())
+		if err := t.bans.admit(req.Context(), ban); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+		sent = sent.WithContext(context.WithValue(sent.Context(), banTargetKey, ban))
+	}
+
+	if t.shaper != nil {
+		if err := t.shaper.delay(req.Context()); err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	}
+
+	if t.events.active() {
+		sent = sent.WithContext(t.events.context(sent.Context(), sent, header))
+	}
+
+	release := func(*http.Response) {}
+	if streams != nil {
+		var err error
+		release, err = streams.acquire(req.Context(), target.Scheme+"://"+target.Host)
+		if err != nil {
+			closeRequestBody(req)
+			return nil, err
+		}
+	}
+
+	sent = t.requests.add(req, sent)
+
+	res, err := transport.RoundTrip(sent)
+	if err != nil {
+		release(nil)
+		t.requests.remove(req, sent)
+		err = classifyError(err)
+		if t.ech != nil && isECHRejection(err) {
+			// a rejection is answered by the server's retry configs, not by
+			// falling back to another ClientHello
+			return t.ech.retry(req, err)
+		}
+		if t.fallback != nil {
+			return t.fallback.retry(req, handshake, err)
+		}
+		return nil, err
+	}
+
+	// the sent header shares its values with t's defaults and req.Header, so
+	// the caller is handed a copy it may change in place
+	if res.Request == sent {
+		exposed := *sent
+		exposed.Header = sent.Header.Clone()
+		res.Request = &exposed
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		release(res)
+	} else {
+		res.Body = &streamBody{ReadCloser: res.Body, res: res, release: release}
+	}
+
+	if t.events.active() {
+		t.events.responseReceived(sent, res)
+	}
+
+	if t.altSvc != nil {
+		t.altSvc.record(res, time.Now())
+	}
+
+	if t.hsts != nil {
+		t.hsts.record(res, time.Now())
+	}
+
+	var banUntil time.Time
+	if t.bans != nil {
+		if d, ok := t.bans.cooldown(res, time.Now()); ok {
+			banUntil = time.Now().Add(d)
+		}
+	}
+
+	if t.clientHints != nil {
+		recordAcceptCH(t.clientHints, req, res)
+		if t.missedCriticalHint(req, header, res) {
+			if retry, ok := replayable(req); ok {
+				t.requests.remove(req, sent)
+				return t.retryCriticalCH(retry, res)
+			}
+		}
+	}
+
+	if intent != nil && !intent.sendsCredentials(target) {
+		res.Header.Del("Set-Cookie")
+	}
+
+	if res.Body == nil || res.Body == http.NoBody {
+		t.requests.remove(req, sent)
+	} else {
+		res.Body = &trackedBody{ReadCloser: res.Body, tracker: t.requests, req: req, sent: sent}
+	}
+
+	if stall := bodyStallTimeout(req.Context(), t.bodyStallTimeout); stall > 0 && res.Body != nil && res.Body != http.NoBody {
+		res.Body = newStallReader(res.Body, stall)
+	}
+
+	if !banUntil.IsZero() {
+		if res.Body == nil || res.Body == http.NoBody {
+			t.bans.ban(ban, banUntil)
+		} else {
+			res.Body = &banOnClose{ReadCloser: res.Body, list: t.bans, target: ban, until: banUntil}
+		}
+	}
+
+	return res, nil
+}
+
+// closeRequestBody closes the body of req, which a RoundTripper must do even
+// when it fails before sending the request.
+func closeRequestBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// Clone returns a copy of t with its own connection pool. The copy mimics the
+// same browser on the same platform and keeps t's options, including a base
+// transport set with WithBaseTransport.
+func (t *Transport) Clone() (*Transport, error) {
+	return t.rebuild(t.spec, t.platform)
+}
+
+// WithPlatform returns a Transport that mimics the same browser on platform p.
+//
+// When p uses the same TLS fingerprint as t's platform, the returned Transport
+// shares t's connection pool and differs only in its default headers, so serving
+// many identities does not cost a transport and pool each. Otherwise, as for
+// Safari on iOS and macOS, it gets its own pool like Clone.
+func (t *Transport) WithPlatform(p Platform) (*Transport, error) {
+	current, err := t.spec.tlsSpecFor(t.platform)
+	if err != nil {
+		return nil, err
+	}
+
+	next, err := t.spec.tlsSpecFor(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if next != current {
+		return t.rebuild(t.spec, p)
+	}
+
+	headers, err := t.spec.buildHeaders(p)
+	if err != nil {
+		return nil, err
+	}
+
+	hints, err := t.clientHintsFor(t.spec, p)
+	if err != nil {
+		return nil, err
+	}
+
+	variant := *t
+	variant.platform = p
+	variant.defaultHeaders = newDefaultHeaders(headers)
+	variant.highEntropyHints = hints
+
+	if t.fallback != nil {
+		fb, err := t.fallback.transport.WithPlatform(p)
+		if err != nil {
+			return nil, fmt.Errorf("configuring fallback: %w", err)
+		}
+		variant.fallback = newFallback(fb, t.fallback.onFallback)
+	}
+	if t.ech != nil {
+		variant.ech = newECHRecovery(&variant, t.ech.onReject)
+	}
+
+	return &variant, nil
+}
+
+// rebuild returns a Transport mimicking spec on platform p, with t's options
+// and a new base transport cloned from t's.
+func (t *Transport) rebuild(spec *ClientSpec, p Platform) (*Transport, error) {
+	headers, err := spec.buildHeaders(p)
+	if err != nil {
+		return nil, err
+	}
+
+	hints, err := t.clientHintsFor(spec, p)
+	if err != nil {
+		return nil, err
+	}
+
+	conns, err := t.newConnPartition(spec, p)
+	if err != nil {
+		return nil, err
+	}
+
+	clone := *t
+	if t.fallback != nil {
+		fb, err := t.fallback.transport.rebuild(t.fallback.transport.spec, p)
+		if err != nil {
+			return nil, fmt.Errorf("configuring fallback: %w", err)
+		}
+		clone.fallback = newFallback(fb, t.fallback.onFallback)
+	}
+	if t.ech != nil {
+		clone.ech = newECHRecovery(&clone, t.ech.onReject)
+	}
+	clone.transport = conns.transport
+	clone.pool = conns.pool
+	clone.priority = conns.priority
+	clone.streams = conns.streams
+	if t.partitions != nil {
+		clone.partitions = newConnPartitions()
 	}
+	clone.base = conns.base
+	clone.h2 = conns.h2
+	clone.spec = spec
+	clone.platform = p
+	clone.requests = newRequestTracker()
+	clone.pseudoHeaderOrder = spec.http2Options.PseudoHeaderOrder
+	clone.defaultHeaders = newDefaultHeaders(headers)
+	clone.highEntropyHints = hints
+	clone.uploadChunkSize = int(spec.http2Options.UploadChunkSize)
 
-	return t.transport.RoundTrip(req)
+	return &clone, nil
 }
@@ -0,0 +1,398 @@
+package mimic
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+)
+
+// stubRoundTripper returns an empty response without touching the network.
+type stubRoundTripper struct{}
+
+func (stubRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func newBenchTransport(b *testing.B) *Transport {
+	b.Helper()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		b.Fatal(err)
+	}
+	tr.transport = stubRoundTripper{}
+
+	return tr
+}
+
+func BenchmarkRoundTrip(b *testing.B) {
+	tr := newBenchTransport(b)
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		req.Header = http.Header{
+			"Accept":          {"text/html"},
+			"Accept-Language": {"en-US,en;q=0.9"},
+		}
+		if _, err := tr.RoundTrip(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRoundTripParallel(b *testing.B) {
+	tr := newBenchTransport(b)
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err := tr.RoundTrip(req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRoundTripBody(b *testing.B) {
+	tr := newBenchTransport(b)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		req, err := http.NewRequest(http.MethodPost, "https://example.com/", strings.NewReader("a=1"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := tr.RoundTrip(req); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func newTestTransport(t *testing.T) *Transport {
+	t.Helper()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr.transport = stubRoundTripper{}
+
+	return tr
+}
+
+func TestRoundTripDefaultHeaders(t *testing.T) {
+	tr := newTestTransport(t)
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("user-agent", "custom")
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sent := res.Request.Header
+	if got := sent.Get("user-agent"); got != "custom" {
+		t.Errorf("want caller's user-agent kept; got %q", got)
+	}
+	if got := sent.Get("sec-ch-ua-platform"); got != `"Windows"` {
+		t.Errorf("want default sec-ch-ua-platform; got %q", got)
+	}
+
+	// appending to an injected default must not change it for later requests
+	sent["Sec-Ch-Ua-Mobile"] = append(sent["Sec-Ch-Ua-Mobile"], "?1")
+
+	res, err = tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := res.Request.Header.Values("sec-ch-ua-mobile"); len(got) != 1 || got[0] != "?0" {
+		t.Errorf("want default sec-ch-ua-mobile [?0]; got %q", got)
+	}
+
+	// nor may writing over one in place
+	res.Request.Header["Sec-Ch-Ua-Mobile"][0] = "?1"
+	res.Request.Header["User-Agent"][0] = "changed"
+
+	res, err = tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := res.Request.Header.Get("sec-ch-ua-mobile"); got != "?0" {
+		t.Errorf("want default sec-ch-ua-mobile ?0; got %q", got)
+	}
+	if got := req.Header.Get("user-agent"); got != "custom" {
+		t.Errorf("want caller's user-agent unchanged; got %q", got)
+	}
+}
+
+func TestRoundTripDoesNotModifyRequest(t *testing.T) {
+	tr := newTestTransport(t)
+
+	req, err := http.NewRequest(http.MethodPost, "https://example.com/", strings.NewReader("a=1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("accept", "*/*")
+	req.Header.Set("expect", "100-continue")
+	body := req.Body
+
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(req.Header) != 2 || req.Header.Get("expect") == "" {
+		t.Errorf("want request header unchanged; got %v", req.Header)
+	}
+	if req.Body != body {
+		t.Error("want request body unchanged")
+	}
+	if res.Request == req {
+		t.Error("want the base transport to receive a copy of the request")
+	}
+	if res.Request.Header.Get("expect") != "" {
+		t.Error("want expect removed from the sent request")
+	}
+}
+
+func TestRoundTripSharedHeader(t *testing.T) {
+	tr := newTestTransport(t)
+
+	shared := http.Header{"Accept": {"*/*"}}
+
+	var wg sync.WaitGroup
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				req.Header = shared
+
+				res, err := tr.RoundTrip(req)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if res.Request.Header.Get("user-agent") == "" {
+					t.Error("want default user-agent on sent request")
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(shared) != 1 {
+		t.Errorf("want shared header unchanged; got %v", shared)
+	}
+}
+
+func TestTransportWithPlatform(t *testing.T) {
+	tr := newTestTransport(t)
+
+	mac, err := tr.WithPlatform(PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac.base != tr.base {
+		t.Error("want chromium platforms to share a base transport")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := mac.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Request.Header.Get("sec-ch-ua-platform"); got != `"macOS"` {
+		t.Errorf("want macOS sec-ch-ua-platform; got %q", got)
+	}
+
+	res, err = tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := res.Request.Header.Get("sec-ch-ua-platform"); got != `"Windows"` {
+		t.Errorf("want original transport unchanged; got %q", got)
+	}
+
+	if _, err := tr.WithPlatform(PlatformIOS); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("want ErrUnsupportedPlatform; got %v", err)
+	}
+}
+
+func TestTransportWithPlatformTLS(t *testing.T) {
+	spec, err := Safari("18.3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mac, err := tr.WithPlatform(PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mac.base != tr.base {
+		t.Error("want the same platform to share a base transport")
+	}
+
+	ios, err := tr.WithPlatform(PlatformIOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ios.base == tr.base {
+		t.Error("want iOS to get its own base transport")
+	}
+
+	ipad, err := tr.WithPlatform(PlatformIPadOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ipad.base == tr.base {
+		t.Error("want iPadOS, with the iOS hello, to get its own base transport")
+	}
+	if ios.base.GetTlsClientHelloSpec == nil || ios.base.TLSNextProto["h2"] == nil {
+		t.Error("want iOS base transport configured for tls and http2")
+	}
+}
+
+func TestTransportClone(t *testing.T) {
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformLinux, WithExpectContinue(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := tr.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if clone.base == tr.base {
+		t.Error("want clone to have its own base transport")
+	}
+	if clone.base.ExpectContinueTimeout != time.Second || !clone.allowExpect {
+		t.Error("want clone to keep transport options")
+	}
+}
+
+func TestTransportIdleTimeouts(t *testing.T) {
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tr, err := NewTransport(spec, PlatformLinux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clone, err := tr.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tr := range map[string]*Transport{"transport": tr, "clone": clone} {
+		if got := tr.base.IdleConnTimeout; got != 170*time.Second {
+			t.Errorf("%s: idle timeout = %s, want 2m50s", name, got)
+		}
+		if t2 := tr.priority.t2; t2.ReadIdleTimeout != 58*time.Second || t2.PingTimeout != 8*time.Second {
+			t.Errorf("%s: ping = %s, %s, want 58s, 8s", name, t2.ReadIdleTimeout, t2.PingTimeout)
+		}
+	}
+
+	tr, err = NewTransport(spec, PlatformLinux, WithTimeouts(Timeouts{Idle: time.Minute}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.base.IdleConnTimeout != time.Minute || tr.priority.t2.ReadIdleTimeout != 0 {
+		t.Error("want WithTimeouts to replace the spec's idle policy")
+	}
+}
+
+func TestRoundTripExpect(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts []TransportOption
+		want string
+	}{
+		{"default", nil, ""},
+		{"expect continue", []TransportOption{WithExpectContinue(time.Second)}, "100-continue"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := NewTransport(spec, PlatformWindows, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.transport = stubRoundTripper{}
+
+			req, err := http.NewRequest(http.MethodPost, "https://example.com/", strings.NewReader("body"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("Expect", "100-continue")
+
+			res, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := res.Request.Header.Get("Expect"); got != tt.want {
+				t.Errorf("Expect = %q, want %q", got, tt.want)
+			}
+			if req.Header.Get("Expect") != "100-continue" {
+				t.Error("want the caller's request left as it was")
+			}
+		})
+	}
+}
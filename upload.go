@@ -0,0 +1,62 @@
+package mimic
+
+import (
+	"io"
+)
+
+// NewUploadBody wraps r for use as a streaming request body, calling progress
+// after every read with the number of bytes sent so far and total. Pass -1 for
+// total when the size is unknown; the request is then streamed without a
+// Content-Length, as browsers do for ReadableStream uploads.
+//
+// The returned body is read by the transport as the upload is written to the
+// connection, so progress reflects bytes handed to the HTTP/2 flow controller
+// rather than bytes acknowledged by the server.
+func NewUploadBody(r io.Reader, total int64, progress func(sent, total int64)) io.ReadCloser {
+	return &progressReader{r: r, total: total, progress: progress}
+}
+
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	if n > 0 {
+		p.sent += int64(n)
+		if p.progress != nil {
+			p.progress(p.sent, p.total)
+		}
+	}
+	return n, err
+}
+
+func (p *progressReader) Close() error {
+	if c, ok := p.r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// chunkReader limits every Read of body to size bytes. fhttp writes each read as
+// (at most) one DATA frame, so this bounds request DATA frames the way a browser's
+// fixed-size upload buffer does, even when the server advertises a larger
+// SETTINGS_MAX_FRAME_SIZE.
+type chunkReader struct {
+	body io.ReadCloser
+	size int
+}
+
+func (c *chunkReader) Read(p []byte) (int, error) {
+	if len(p) > c.size {
+		p = p[:c.size]
+	}
+	return c.body.Read(p)
+}
+
+func (c *chunkReader) Close() error {
+	return c.body.Close()
+}
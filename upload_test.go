@@ -0,0 +1,246 @@
+package mimic
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"io"
+	stdhttp "net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+	xhttp2 "golang.org/x/net/http2"
+)
+
+const frameHeaderLen = 9
+
+// frameScanner follows the frame boundaries of an HTTP/2 byte stream.
+type frameScanner struct {
+	header  [frameHeaderLen]byte
+	n       int // bytes of the frame header seen
+	payload int // bytes of the frame payload left
+}
+
+func frameLength(h [frameHeaderLen]byte) int {
+	return int(h[0])<<16 | int(h[1])<<8 | int(h[2])
+}
+
+// dataFrameRecorder records the payload length of every DATA frame a server
+// reads from its connection.
+type dataFrameRecorder struct {
+	*tls.Conn
+
+	mu      *sync.Mutex
+	lengths *[]int
+
+	preface int
+	scan    frameScanner
+}
+
+func (r *dataFrameRecorder) Read(p []byte) (int, error) {
+	n, err := r.Conn.Read(p)
+	b := p[:n]
+
+	skip := min(r.preface, len(b))
+	r.preface -= skip
+	b = b[skip:]
+
+	for len(b) > 0 {
+		if r.scan.payload > 0 {
+			m := min(r.scan.payload, len(b))
+			r.scan.payload -= m
+			b = b[m:]
+			continue
+		}
+		m := copy(r.scan.header[r.scan.n:], b)
+		r.scan.n += m
+		b = b[m:]
+		if r.scan.n < frameHeaderLen {
+			continue
+		}
+		r.scan.n = 0
+		r.scan.payload = frameLength(r.scan.header)
+		if http2.FrameType(r.scan.header[3]) == http2.FrameData && r.scan.payload > 0 {
+			r.mu.Lock()
+			*r.lengths = append(*r.lengths, r.scan.payload)
+			r.mu.Unlock()
+		}
+	}
+	return n, err
+}
+
+// newUploadServer starts an HTTP/2 server that reads request bodies whole and
+// accepts DATA frames far larger than browsers send, recording their lengths.
+func newUploadServer(t *testing.T) (*stdhttptest.Server, func() []int) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var lengths []int
+
+	srv := stdhttptest.NewUnstartedServer(stdhttp.HandlerFunc(func(w stdhttp.ResponseWriter, r *stdhttp.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		if err != nil {
+			w.WriteHeader(stdhttp.StatusBadRequest)
+			return
+		}
+		io.WriteString(w, strings.Repeat("x", int(n%10)))
+	}))
+	h2 := &xhttp2.Server{MaxReadFrameSize: 1 << 20}
+	if err := xhttp2.ConfigureServer(srv.Config, h2); err != nil {
+		t.Fatal(err)
+	}
+	srv.Config.TLSNextProto["h2"] = func(s *stdhttp.Server, c *tls.Conn, h stdhttp.Handler) {
+		rec := &dataFrameRecorder{Conn: c, mu: &mu, lengths: &lengths, preface: len(http2.ClientPreface)}
+		h2.ServeConn(rec, &xhttp2.ServeConnOpts{Handler: h, BaseConfig: s})
+	}
+	srv.TLS = &tls.Config{NextProtos: []string{"h2"}}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	return srv, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int(nil), lengths...)
+	}
+}
+
+func newUploadClient(t *testing.T) *http.Client {
+	t.Helper()
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(tr.CloseIdleConnections)
+	return &http.Client{Transport: tr}
+}
+
+func TestUploadChunkSize(t *testing.T) {
+	srv, lengths := newUploadServer(t)
+	client := newUploadClient(t)
+
+	// the server's SETTINGS arrive with the first response, and only then
+	// does fhttp know it may send larger frames
+	res, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	const size = 200_000
+	var reports []int64
+	body := NewUploadBody(bytes.NewReader(bytes.Repeat([]byte("a"), size)), size, func(sent, total int64) {
+		if total != size {
+			t.Errorf("total = %d, want %d", total, size)
+		}
+		reports = append(reports, sent)
+	})
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.ContentLength = size
+
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.ProtoMajor != 2 {
+		t.Fatalf("want an HTTP/2 200; got %s %d", res.Proto, res.StatusCode)
+	}
+
+	var total int
+	frames := lengths()
+	for _, n := range frames {
+		if n > 16384 {
+			t.Errorf("DATA frame of %d bytes, want at most the 16384 byte upload chunk", n)
+		}
+		total += n
+	}
+	if total != size || len(frames) < size/16384 {
+		t.Errorf("want the body in %d byte frames; got %d bytes in %v", 16384, total, frames)
+	}
+
+	if len(reports) == 0 || reports[len(reports)-1] != size {
+		t.Fatalf("want progress to end at %d; got %v", size, reports)
+	}
+	for i := 1; i < len(reports); i++ {
+		if reports[i] <= reports[i-1] {
+			t.Fatalf("want increasing progress; got %v", reports)
+		}
+	}
+}
+
+// failingReader returns n bytes and then err.
+type failingReader struct {
+	n   int
+	err error
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, r.err
+	}
+	m := min(r.n, len(p))
+	r.n -= m
+	return m, nil
+}
+
+func TestUploadBodyErrors(t *testing.T) {
+	srv, _ := newUploadServer(t)
+	client := newUploadClient(t)
+
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{"error", &failingReader{n: 50_000, err: errors.New("boom")}},
+		{"short", &failingReader{n: 50_000, err: io.EOF}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var last int64
+			body := NewUploadBody(tt.body, 100_000, func(sent, _ int64) { last = sent })
+
+			req, err := http.NewRequest(http.MethodPost, srv.URL, body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.ContentLength = 100_000
+
+			// a failed read resets the stream; a short body ends it early,
+			// which the server refuses against the declared length
+			res, err := client.Do(req)
+			switch {
+			case tt.name == "error":
+				if err == nil || !strings.Contains(err.Error(), "boom") {
+					t.Errorf("err = %v, want the body's error", err)
+				}
+			case err != nil:
+				t.Errorf("err = %v, want the server's answer", err)
+			default:
+				res.Body.Close()
+				if res.StatusCode != http.StatusBadRequest {
+					t.Errorf("status = %d, want the truncated body refused", res.StatusCode)
+				}
+			}
+			if last != 50_000 {
+				t.Errorf("progress ended at %d, want the 50000 bytes read", last)
+			}
+		})
+	}
+}
@@ -0,0 +1,376 @@
+package verify
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+
+	"github.com/aarock1234/mimic"
+)
+
+// defaultConnectionFlow is the WINDOW_UPDATE fhttp sends for mimic.ConnectionFlowDefault.
+const defaultConnectionFlow = 15663105
+
+// TLS extension IDs the fingerprints read.
+const (
+	extServerName          = 0x0000
+	extSupportedGroups     = 0x000a
+	extECPointFormats      = 0x000b
+	extSignatureAlgorithms = 0x000d
+	extALPN                = 0x0010
+	extSupportedVersions   = 0x002b
+)
+
+var errShortHello = errors.New("truncated client hello")
+
+// Fingerprint holds the JA3, JA4, and Akamai fingerprints of a client, using the
+// same formats as the tls.peet.ws echo endpoint.
+type Fingerprint struct {
+	JA3        string `json:"ja3"`
+	JA3Hash    string `json:"ja3_hash"`
+	JA4        string `json:"ja4"`
+	Akamai     string `json:"akamai"`
+	AkamaiHash string `json:"akamai_hash"`
+}
+
+// Local computes the fingerprint spec produces on platform without opening a
+// connection. The ClientHello is built for serverName, which affects the SNI
+// extension and, for specs that pad their hello, its length.
+//
+// Specs that shuffle their TLS extensions produce a different JA3 on every
+// call. JA4 sorts extensions, so it is stable.
+func Local(spec *mimic.ClientSpec, platform mimic.Platform, serverName string) (Fingerprint, error) {
+	helloSpec, err := spec.ClientHelloSpec(platform)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	raw, err := marshalHello(helloSpec, serverName)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	hello, err := parseHello(raw)
+	if err != nil {
+		return Fingerprint{}, err
+	}
+
+	ja3 := hello.ja3()
+	akamai := akamaiFingerprint(spec.HTTP2Opts())
+
+	return Fingerprint{
+		JA3:        ja3,
+		JA3Hash:    md5Hex(ja3),
+		JA4:        hello.ja4(),
+		Akamai:     akamai,
+		AkamaiHash: md5Hex(akamai),
+	}, nil
+}
+
+// marshalHello builds the ClientHello the way fhttp does when dialing, without
+// sending it anywhere.
+func marshalHello(spec *utls.ClientHelloSpec, serverName string) ([]byte, error) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	conn := utls.UClient(client, &utls.Config{ServerName: serverName}, utls.HelloCustom)
+	if err := conn.ApplyPreset(spec); err != nil {
+		return nil, fmt.Errorf("applying tls spec: %w", err)
+	}
+
+	if err := conn.BuildHandshakeState(); err != nil {
+		return nil, fmt.Errorf("building client hello: %w", err)
+	}
+
+	return conn.HandshakeState.Hello.Raw, nil
+}
+
+// clientHello holds the ClientHello fields the fingerprints are built from, in
+// wire order with GREASE values removed.
+type clientHello struct {
+	version             uint16
+	supportedVersions   []uint16
+	cipherSuites        []uint16
+	extensions          []uint16
+	supportedGroups     []uint16
+	pointFormats        []uint8
+	signatureAlgorithms []uint16
+	alpn                []string
+	hasServerName       bool
+}
+
+// parseHello parses a ClientHello handshake message, including its 4 byte header.
+func parseHello(raw []byte) (*clientHello, error) {
+	r := reader(raw)
+	var hello clientHello
+
+	// handshake type, length, legacy version, random, session ID, compression methods
+	if !r.skip(4) || !r.uint16(&hello.version) || !r.skip(32) || !r.skipVector8() {
+		return nil, errShortHello
+	}
+
+	var suites reader
+	if !r.vector16(&suites) {
+		return nil, errShortHello
+	}
+	for !suites.empty() {
+		var suite uint16
+		if !suites.uint16(&suite) {
+			return nil, errShortHello
+		}
+		if !isGREASE(suite) {
+			hello.cipherSuites = append(hello.cipherSuites, suite)
+		}
+	}
+
+	var exts reader
+	if !r.skipVector8() || !r.vector16(&exts) {
+		return nil, errShortHello
+	}
+
+	for !exts.empty() {
+		var (
+			id   uint16
+			data reader
+		)
+		if !exts.uint16(&id) || !exts.vector16(&data) {
+			return nil, errShortHello
+		}
+		if isGREASE(id) {
+			continue
+		}
+		hello.extensions = append(hello.extensions, id)
+
+		var (
+			list reader
+			ok   bool
+		)
+		switch id {
+		case extServerName:
+			hello.hasServerName = true
+			ok = true
+		case extSupportedGroups:
+			ok = data.vector16(&list) && list.uint16List(&hello.supportedGroups)
+		case extECPointFormats:
+			ok = data.vector8(&list)
+			hello.pointFormats = list
+		case extSignatureAlgorithms:
+			ok = data.vector16(&list) && list.uint16List(&hello.signatureAlgorithms)
+		case extSupportedVersions:
+			ok = data.vector8(&list) && list.uint16List(&hello.supportedVersions)
+		case extALPN:
+			ok = data.vector16(&list)
+			for ok && !list.empty() {
+				var proto reader
+				ok = list.vector8(&proto)
+				hello.alpn = append(hello.alpn, string(proto))
+			}
+		default:
+			ok = true
+		}
+		if !ok {
+			return nil, fmt.Errorf("parsing extension %d: %w", id, errShortHello)
+		}
+	}
+
+	return &hello, nil
+}
+
+// ja3 returns the JA3 string: version, ciphers, extensions, groups, and point formats.
+func (h *clientHello) ja3() string {
+	return strings.Join([]string{
+		strconv.Itoa(int(h.version)),
+		joinUint16(h.cipherSuites, "-", "%d"),
+		joinUint16(h.extensions, "-", "%d"),
+		joinUint16(h.supportedGroups, "-", "%d"),
+		joinUint8(h.pointFormats, "-"),
+	}, ",")
+}
+
+// ja4 returns the JA4 fingerprint for a TCP ClientHello.
+func (h *clientHello) ja4() string {
+	version := h.version
+	for _, v := range h.supportedVersions {
+		version = max(version, v)
+	}
+
+	sni := "i"
+	if h.hasServerName {
+		sni = "d"
+	}
+
+	alpn := "00"
+	if len(h.alpn) > 0 && h.alpn[0] != "" {
+		first := h.alpn[0]
+		alpn = first[:1] + first[len(first)-1:]
+	}
+
+	a := fmt.Sprintf("t%s%s%02d%02d%s", ja4Version(version), sni,
+		min(len(h.cipherSuites), 99), min(len(h.extensions), 99), alpn)
+
+	ciphers := slices.Sorted(slices.Values(h.cipherSuites))
+
+	var exts []uint16
+	for _, id := range h.extensions {
+		if id != extServerName && id != extALPN {
+			exts = append(exts, id)
+		}
+	}
+	slices.Sort(exts)
+
+	c := joinUint16(exts, ",", "%04x")
+	if len(h.signatureAlgorithms) > 0 {
+		c += "_" + joinUint16(h.signatureAlgorithms, ",", "%04x")
+	}
+
+	return a + "_" + ja4Hash(joinUint16(ciphers, ",", "%04x")) + "_" + ja4Hash(c)
+}
+
+func ja4Version(v uint16) string {
+	switch v {
+	case utls.VersionTLS13:
+		return "13"
+	case utls.VersionTLS12:
+		return "12"
+	case utls.VersionTLS11:
+		return "11"
+	case utls.VersionTLS10:
+		return "10"
+	default:
+		return "00"
+	}
+}
+
+// ja4Hash returns the first 12 hex characters of the SHA-256 of s, or zeros if s is empty.
+func ja4Hash(s string) string {
+	if s == "" {
+		return "000000000000"
+	}
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// akamaiFingerprint returns the Akamai HTTP/2 fingerprint: SETTINGS, the
+// connection WINDOW_UPDATE, PRIORITY frames, and the pseudo header order.
+func akamaiFingerprint(opts *mimic.HTTP2Options) string {
+	settings := make([]string, len(opts.Settings))
+	for i, s := range opts.Settings {
+		settings[i] = fmt.Sprintf("%d:%d", uint16(s.ID), s.Val)
+	}
+
+	flow := opts.ConnectionFlow
+	if flow == mimic.ConnectionFlowDefault {
+		flow = defaultConnectionFlow
+	}
+
+	pseudo := make([]string, len(opts.PseudoHeaderOrder))
+	for i, h := range opts.PseudoHeaderOrder {
+		pseudo[i] = h[1:2]
+	}
+
+	// the underlying transport never sends standalone PRIORITY frames
+	return fmt.Sprintf("%s|%d|0|%s", strings.Join(settings, ";"), flow, strings.Join(pseudo, ","))
+}
+
+// isGREASE reports whether v is a GREASE value reserved by RFC 8701.
+func isGREASE(v uint16) bool {
+	return v&0x0f0f == 0x0a0a && v>>8 == v&0xff
+}
+
+func joinUint16(values []uint16, sep, format string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf(format, v)
+	}
+	return strings.Join(parts, sep)
+}
+
+func joinUint8(values []uint8, sep string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(int(v))
+	}
+	return strings.Join(parts, sep)
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// reader is a minimal TLS vector parser. Each method reports false if the input
+// is too short.
+type reader []byte
+
+func (r *reader) empty() bool {
+	return len(*r) == 0
+}
+
+func (r *reader) skip(n int) bool {
+	if len(*r) < n {
+		return false
+	}
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *reader) uint16(v *uint16) bool {
+	if len(*r) < 2 {
+		return false
+	}
+	*v = binary.BigEndian.Uint16(*r)
+	*r = (*r)[2:]
+	return true
+}
+
+func (r *reader) vector8(out *reader) bool {
+	if len(*r) < 1 {
+		return false
+	}
+	n := int((*r)[0])
+	if len(*r) < 1+n {
+		return false
+	}
+	*out = (*r)[1 : 1+n]
+	*r = (*r)[1+n:]
+	return true
+}
+
+func (r *reader) vector16(out *reader) bool {
+	var n uint16
+	if !r.uint16(&n) || len(*r) < int(n) {
+		return false
+	}
+	*out = (*r)[:n]
+	*r = (*r)[n:]
+	return true
+}
+
+func (r *reader) skipVector8() bool {
+	var discard reader
+	return r.vector8(&discard)
+}
+
+// uint16List reads the rest of r as uint16 values, skipping GREASE.
+func (r *reader) uint16List(out *[]uint16) bool {
+	for !r.empty() {
+		var v uint16
+		if !r.uint16(&v) {
+			return false
+		}
+		if !isGREASE(v) {
+			*out = append(*out, v)
+		}
+	}
+	return true
+}
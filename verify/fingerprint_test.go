@@ -0,0 +1,105 @@
+package verify
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/aarock1234/mimic"
+)
+
+func TestLocal(t *testing.T) {
+	tests := []struct {
+		target Target
+		ja4    string
+		akamai string
+	}{
+		{
+			Target{BrowserChromium, mimic.BrandChrome, "133.0.0.0", mimic.PlatformWindows},
+			"t13d1516h2_8daaf6152771_d8a2da3f94cd",
+			"1:65536;2:0;4:6291456;6:262144|15663105|0|m,a,s,p",
+		},
+		{
+			Target{BrowserFirefox, "", "120.0", mimic.PlatformLinux},
+			"t13d1715h2_5b57614c22b0_5c2c66f702b0",
+			"1:65536;4:131072;5:16384|12517377|0|m,p,a,s",
+		},
+		{
+			Target{BrowserSafari, "", "18.0", mimic.PlatformMac},
+			"t13d2014h2_a09f3c656075_14788d8d241b",
+			"1:4096;2:0;3:100;4:2097152;5:16384;8:1|10485760|0|m,s,p,a",
+		},
+	}
+
+	for _, test := range tests {
+		spec, err := test.target.Spec()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fp, err := Local(spec, test.target.Platform, "tls.peet.ws")
+		if err != nil {
+			t.Fatalf("%s: %v", test.target, err)
+		}
+
+		if fp.JA4 != test.ja4 {
+			t.Errorf("%s: want ja4 %s; got %s", test.target, test.ja4, fp.JA4)
+		}
+		if fp.Akamai != test.akamai {
+			t.Errorf("%s: want akamai %s; got %s", test.target, test.akamai, fp.Akamai)
+		}
+		if fp.JA3Hash != md5Hex(fp.JA3) {
+			t.Errorf("%s: ja3 hash %s does not match ja3 %s", test.target, fp.JA3Hash, fp.JA3)
+		}
+	}
+}
+
+func TestAllWithTargets(t *testing.T) {
+	targets := []Target{
+		{BrowserChromium, mimic.BrandEdge, "137.0.0.0", mimic.PlatformMac},
+		{BrowserSafari, "", "17.0", mimic.PlatformIOS},
+		{BrowserChromium, mimic.BrandChrome, "80.0.0.0", mimic.PlatformWindows},
+	}
+
+	report, err := All(context.Background(), WithTargets(targets...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Results) != len(targets) {
+		t.Fatalf("want %d results; got %d", len(targets), len(report.Results))
+	}
+
+	failed := report.Failed()
+	if len(failed) != 1 || failed[0].Target != targets[2] {
+		t.Fatalf("want only %s to fail; got %v", targets[2], failed)
+	}
+}
+
+func TestTargets(t *testing.T) {
+	newest := map[string]string{
+		BrowserChromium:  fmt.Sprintf("%d.0.0.0", slices.Max(mimic.ChromiumMajors())),
+		BrowserGooglebot: fmt.Sprintf("%d.0.0.0", slices.Max(mimic.CrawlerMajors())),
+		BrowserFirefox:   fmt.Sprintf("%d.0", slices.Max(mimic.FirefoxMajors())),
+		BrowserSafari:    fmt.Sprintf("%d.0", slices.Max(mimic.SafariMajors())),
+	}
+
+	var whale bool
+	for _, target := range Targets() {
+		if _, err := target.Spec(); err != nil {
+			t.Errorf("%s: %v", target, err)
+		}
+		if newest[target.Browser] == target.Version {
+			delete(newest, target.Browser)
+		}
+		whale = whale || target.Brand == mimic.BrandWhale(whaleVersion)
+	}
+
+	if len(newest) > 0 {
+		t.Errorf("want the newest supported versions covered; missing %v", newest)
+	}
+	if !whale {
+		t.Error("want Naver Whale covered")
+	}
+}
@@ -0,0 +1,313 @@
+// Package verify checks every browser, version, and platform combination mimic
+// supports, so upgrading mimic or utls can be confirmed not to regress any
+// fingerprint in one call.
+//
+//	report, err := verify.All(ctx)
+//	if err != nil {
+//	    return err
+//	}
+//	for _, r := range report.Failed() {
+//	    fmt.Println(r.Target, r.Err, r.Mismatches)
+//	}
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	http "github.com/saucesteals/fhttp"
+
+	"github.com/aarock1234/mimic"
+)
+
+// Endpoint is the echo service live checks request. It responds with the JA3,
+// JA4, and Akamai fingerprints of the calling client.
+const Endpoint = "https://tls.peet.ws/api/clean"
+
+// Browser names used in Target.
+const (
+	BrowserChromium  = "chromium"
+	BrowserSafari    = "safari"
+	BrowserFirefox   = "firefox"
+	BrowserGooglebot = "googlebot"
+	BrowserBingbot   = "bingbot"
+)
+
+// whaleVersion is the Whale version Targets checks Naver Whale at, on every
+// Chromium version.
+const whaleVersion = "4.29.282.14"
+
+// Target is one browser, version, and platform combination.
+type Target struct {
+	Browser  string         `json:"browser"`
+	Brand    mimic.Brand    `json:"brand,omitempty"`
+	Version  string         `json:"version"`
+	Platform mimic.Platform `json:"platform"`
+}
+
+func (t Target) String() string {
+	name := t.Browser
+	if t.Brand != "" {
+		name = string(t.Brand)
+	}
+	return fmt.Sprintf("%s %s on %s", name, t.Version, t.Platform)
+}
+
+// Spec builds the ClientSpec for t.
+func (t Target) Spec() (*mimic.ClientSpec, error) {
+	switch t.Browser {
+	case BrowserChromium:
+		return mimic.Chromium(t.Brand, t.Version)
+	case BrowserSafari:
+		return mimic.Safari(t.Version)
+	case BrowserFirefox:
+		return mimic.Firefox(t.Version)
+	case BrowserGooglebot:
+		return mimic.Googlebot(t.Version)
+	case BrowserBingbot:
+		return mimic.Bingbot(t.Version)
+	default:
+		return nil, fmt.Errorf("unknown browser %q", t.Browser)
+	}
+}
+
+// Targets returns every supported combination of browser brand, major version,
+// and platform, over the versions mimic.ChromiumMajors, mimic.CrawlerMajors,
+// mimic.FirefoxMajors, and mimic.SafariMajors report.
+func Targets() []Target {
+	var targets []Target
+
+	desktop := []mimic.Platform{mimic.PlatformWindows, mimic.PlatformMac, mimic.PlatformLinux}
+	brands := []mimic.Brand{mimic.BrandChrome, mimic.BrandEdge, mimic.BrandBrave, mimic.BrandWhale(whaleVersion)}
+	for _, brand := range brands {
+		for _, major := range mimic.ChromiumMajors() {
+			for _, p := range desktop {
+				targets = append(targets, Target{BrowserChromium, brand, fmt.Sprintf("%d.0.0.0", major), p})
+			}
+		}
+	}
+
+	for _, bot := range []string{BrowserGooglebot, BrowserBingbot} {
+		for _, major := range mimic.CrawlerMajors() {
+			for _, p := range []mimic.Platform{mimic.PlatformLinux, mimic.PlatformAndroid} {
+				targets = append(targets, Target{bot, "", fmt.Sprintf("%d.0.0.0", major), p})
+			}
+		}
+	}
+
+	for _, major := range mimic.FirefoxMajors() {
+		for _, p := range desktop {
+			targets = append(targets, Target{BrowserFirefox, "", fmt.Sprintf("%d.0", major), p})
+		}
+	}
+
+	for _, major := range mimic.SafariMajors() {
+		for _, p := range []mimic.Platform{mimic.PlatformMac, mimic.PlatformIOS, mimic.PlatformIPadOS} {
+			targets = append(targets, Target{BrowserSafari, "", fmt.Sprintf("%d.0", major), p})
+		}
+	}
+
+	return targets
+}
+
+// Result is the outcome of verifying one Target.
+type Result struct {
+	Target Target `json:"target"`
+
+	// Local is the fingerprint computed without a connection.
+	Local Fingerprint `json:"local"`
+
+	// Live is the fingerprint Endpoint reported, or nil if live checks are off.
+	Live *Fingerprint `json:"live,omitempty"`
+
+	// Mismatches names each fingerprint that differs between Local and Live.
+	// JA3 is not compared because specs that shuffle extensions change it on
+	// every connection.
+	Mismatches []string `json:"mismatches,omitempty"`
+
+	// Err is set if the spec, transport, or live request failed.
+	Err error `json:"-"`
+}
+
+// OK reports whether the target built, and matched Endpoint if checked live.
+func (r Result) OK() bool {
+	return r.Err == nil && len(r.Mismatches) == 0
+}
+
+// MarshalJSON includes Err as a string.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	var errStr string
+	if r.Err != nil {
+		errStr = r.Err.Error()
+	}
+	return json.Marshal(struct {
+		result
+		Error string `json:"error,omitempty"`
+	}{result(r), errStr})
+}
+
+// Report is the result of verifying a set of targets.
+type Report struct {
+	Results  []Result      `json:"results"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Failed returns the results that did not pass.
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, res := range r.Results {
+		if !res.OK() {
+			failed = append(failed, res)
+		}
+	}
+	return failed
+}
+
+// OK reports whether every target passed.
+func (r *Report) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Option configures All.
+type Option func(*config)
+
+type config struct {
+	targets    []Target
+	live       bool
+	clientOpts []mimic.ClientOption
+}
+
+// WithTargets limits verification to targets instead of every supported combination.
+func WithTargets(targets ...Target) Option {
+	return func(c *config) {
+		c.targets = targets
+	}
+}
+
+// WithLive requests Endpoint with each target and compares the fingerprint it
+// reports with the locally computed one.
+func WithLive() Option {
+	return func(c *config) {
+		c.live = true
+	}
+}
+
+// WithClientOptions sets options for the clients used by live checks, such as a proxy.
+func WithClientOptions(opts ...mimic.ClientOption) Option {
+	return func(c *config) {
+		c.clientOpts = opts
+	}
+}
+
+// All verifies every supported target. For each one it builds the spec and
+// transport and computes its fingerprint locally; with WithLive it also checks
+// the fingerprint against Endpoint.
+//
+// Per-target failures are recorded in the report. All returns an error only if
+// ctx is done before every target was checked.
+func All(ctx context.Context, opts ...Option) (*Report, error) {
+	cfg := &config{targets: Targets()}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	endpoint, err := url.Parse(Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("parsing endpoint: %w", err)
+	}
+
+	start := time.Now()
+	report := &Report{Results: make([]Result, 0, len(cfg.targets))}
+
+	for _, target := range cfg.targets {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		report.Results = append(report.Results, check(ctx, cfg, target, endpoint.Hostname()))
+	}
+
+	report.Duration = time.Since(start)
+
+	return report, nil
+}
+
+func check(ctx context.Context, cfg *config, target Target, serverName string) Result {
+	result := Result{Target: target}
+
+	spec, err := target.Spec()
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if _, err := mimic.NewTransport(spec, target.Platform); err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Local, err = Local(spec, target.Platform, serverName)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	if !cfg.live {
+		return result
+	}
+
+	live, err := fetchLive(ctx, spec, target.Platform, cfg.clientOpts)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Live = live
+
+	if live.JA4 != result.Local.JA4 {
+		result.Mismatches = append(result.Mismatches, "ja4")
+	}
+	if live.Akamai != result.Local.Akamai {
+		result.Mismatches = append(result.Mismatches, "akamai")
+	}
+
+	return result
+}
+
+func fetchLive(ctx context.Context, spec *mimic.ClientSpec, platform mimic.Platform, opts []mimic.ClientOption) (*Fingerprint, error) {
+	client, err := mimic.NewClient(spec, platform, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer client.CloseIdleConnections()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, Endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("requesting fingerprint: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("requesting fingerprint: unexpected status: %d", res.StatusCode)
+	}
+
+	var fp Fingerprint
+	if err := json.NewDecoder(res.Body).Decode(&fp); err != nil {
+		return nil, fmt.Errorf("decoding fingerprint: %w", err)
+	}
+
+	if fp.JA4 == "" {
+		return nil, errors.New("decoding fingerprint: missing ja4")
+	}
+
+	return &fp, nil
+}
@@ -0,0 +1,289 @@
+package mimic
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+	"github.com/saucesteals/fhttp/http2"
+)
+
+const (
+	// warmCheckInterval is how often a WarmPool checks that its connections
+	// are still open.
+	warmCheckInterval = time.Second
+
+	// warmRetryDelay is how long a WarmPool waits to dial an origin again
+	// after failing to.
+	warmRetryDelay = 5 * time.Second
+)
+
+// Preconnect opens an HTTP/2 connection to origin, an https URL, and adds it
+// to the Transport's pool, as a browser does for a preconnect link: the
+// connection is dialed, handshaken, and sent its HTTP/2 preface and SETTINGS,
+// so the next request to origin is sent on it without waiting for any of
+// that. Nothing is done if the pool already has a connection for the origin.
+//
+// The connection is made as a request with ctx would make it, so it goes
+// through the request's proxy, which must be an http:// proxy, and into the
+// pool ConnSelector picks. It fails for origins that do not negotiate HTTP/2,
+// and with WithEngine or a base transport that dials TLS itself.
+func (t *Transport) Preconnect(ctx context.Context, origin string) error {
+	req, addr, err := preconnectRequest(ctx, origin)
+	if err != nil {
+		return err
+	}
+
+	base, h2, err := t.poolFor(req)
+	if err != nil {
+		return err
+	}
+	if _, err := h2.ConnPool.GetClientConn(req, addr); err == nil {
+		return nil
+	}
+
+	if err := preconnect(ctx, base, req, addr); err != nil {
+		return fmt.Errorf("preconnect %s: %w", origin, err)
+	}
+	return nil
+}
+
+// preconnectRequest returns the request a preconnect to origin stands in for,
+// and the address it connects to.
+func preconnectRequest(ctx context.Context, origin string) (*http.Request, string, error) {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil, "", err
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, "", fmt.Errorf("preconnect %s: origin must be an https URL", origin)
+	}
+	u = &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/"}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "443")
+	}
+	return req, addr, nil
+}
+
+// poolFor returns the base transport and HTTP/2 transport of the pool req
+// would be sent from.
+func (t *Transport) poolFor(req *http.Request) (*http.Transport, *http2.Transport, error) {
+	if t.engine != nil {
+		return nil, nil, errors.New("preconnect: not supported with an engine")
+	}
+	if t.base.DialTLSContext != nil || t.base.DialTLS != nil {
+		return nil, nil, errors.New("preconnect: not supported with a base transport that dials tls")
+	}
+
+	if t.partitions != nil {
+		part, err := t.partition(req, req)
+		if err != nil {
+			return nil, nil, err
+		}
+		if part != nil {
+			return part.base, part.h2, nil
+		}
+	}
+	return t.base, t.h2, nil
+}
+
+// preconnect dials addr the way base would for req and hands the connection
+// to base's HTTP/2 pool.
+func preconnect(ctx context.Context, base *http.Transport, req *http.Request, addr string) error {
+	var proxy *url.URL
+	if base.Proxy != nil {
+		var err error
+		if proxy, err = base.Proxy(req); err != nil {
+			return err
+		}
+	}
+
+	dial := base.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	dialAddr := addr
+	if proxy != nil {
+		if proxy.Scheme != "http" {
+			return fmt.Errorf("%s proxies are not supported", proxy.Scheme)
+		}
+		dialAddr = proxy.Host
+		if proxy.Port() == "" {
+			dialAddr = net.JoinHostPort(proxy.Hostname(), "80")
+		}
+	}
+
+	conn, err := dial(ctx, "tcp", dialAddr)
+	if err != nil {
+		return err
+	}
+
+	if proxy != nil {
+		if err := connectTunnel(ctx, conn, base, proxy, addr); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+
+	cfg := base.TLSClientConfig.Clone()
+	if cfg.ServerName == "" {
+		cfg.ServerName = req.URL.Hostname()
+	}
+
+	var tlsConn *utls.UConn
+	if base.GetTlsClientHelloSpec != nil {
+		tlsConn = utls.UClient(conn, cfg, utls.HelloCustom)
+		if err := tlsConn.ApplyPreset(base.GetTlsClientHelloSpec()); err != nil {
+			conn.Close()
+			return err
+		}
+	} else {
+		tlsConn = utls.UClient(conn, cfg, utls.HelloGolang)
+	}
+
+	handshakeCtx := ctx
+	if d := base.TLSHandshakeTimeout; d > 0 {
+		var cancel context.CancelFunc
+		handshakeCtx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+	}
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		conn.Close()
+		return err
+	}
+
+	upgrade := base.TLSNextProto["h2"]
+	if tlsConn.ConnectionState().NegotiatedProtocol != "h2" || upgrade == nil {
+		tlsConn.Close()
+		return errors.New("server did not negotiate http2")
+	}
+
+	if e, ok := upgrade(addr, tlsConn).(interface{ RoundTripErr() error }); ok {
+		return e.RoundTripErr()
+	}
+	return nil
+}
+
+// connectTunnel asks the proxy at the other end of conn to tunnel it to addr.
+func connectTunnel(ctx context.Context, conn net.Conn, base *http.Transport, proxy *url.URL, addr string) error {
+	header := base.ProxyConnectHeader
+	if base.GetProxyConnectHeader != nil {
+		var err error
+		if header, err = base.GetProxyConnectHeader(ctx, proxy, addr); err != nil {
+			return err
+		}
+	}
+	header = header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(proxy.User.Username()+":"+password)))
+	}
+
+	connect := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: header,
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+
+	if err := connect.Write(conn); err != nil {
+		return err
+	}
+	// the server does not speak before the ClientHello, so nothing is left
+	// in the buffer
+	res, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused tunnel: %s", res.Status)
+	}
+	return nil
+}
+
+// WarmPool keeps an HTTP/2 connection open to each of a set of origins, so
+// the first request to one after a long wait is sent without a handshake.
+// Browsers multiplex an origin's requests over one connection, and so does
+// the Transport, so one connection per origin is kept.
+type WarmPool struct {
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// KeepWarm preconnects to each of origins and keeps a connection to each open
+// until the WarmPool is closed. A connection that closes, including at the
+// Transport's idle timeout or by the server, is replaced within a second. A
+// failed preconnect is reported to onError, if set, and retried after 5
+// seconds. The connections are made as a request with ctx would make them;
+// see Preconnect.
+func (t *Transport) KeepWarm(ctx context.Context, origins []string, onError func(origin string, err error)) (*WarmPool, error) {
+	for _, origin := range origins {
+		if _, _, err := preconnectRequest(ctx, origin); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	p := &WarmPool{cancel: cancel}
+	for _, origin := range origins {
+		p.done.Add(1)
+		go func() {
+			defer p.done.Done()
+			p.keepWarm(ctx, t, origin, onError)
+		}()
+	}
+	return p, nil
+}
+
+// Close stops keeping connections open. The open ones are left in the pool,
+// to be used or closed like any other.
+func (p *WarmPool) Close() {
+	p.cancel()
+	p.done.Wait()
+}
+
+func (p *WarmPool) keepWarm(ctx context.Context, t *Transport, origin string, onError func(string, error)) {
+	for {
+		wait := warmCheckInterval
+		if err := t.Preconnect(ctx, origin); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if onError != nil {
+				onError(origin, err)
+			}
+			wait = warmRetryDelay
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+	}
+}
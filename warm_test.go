@@ -0,0 +1,145 @@
+package mimic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+func TestPreconnect(t *testing.T) {
+	tests := []struct {
+		name  string
+		proxy bool
+	}{
+		{"direct", false},
+		{"proxy", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, conns := countingServer(t)
+
+			spec, err := Chromium(BrandChrome, "137.0.0.0")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			base := &http.Transport{TLSClientConfig: &utls.Config{InsecureSkipVerify: true}}
+			if tt.proxy {
+				base.Proxy = http.ProxyURL(startConnectProxy(t))
+			}
+			tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(base))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tr.CloseIdleConnections()
+
+			for range 2 {
+				if err := tr.Preconnect(context.Background(), server.URL); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := conns.Load(); got != 1 {
+				t.Fatalf("connections after preconnect = %d, want 1", got)
+			}
+
+			req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := tr.RoundTrip(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+
+			if res.ProtoMajor != 2 {
+				t.Errorf("proto = %s, want HTTP/2", res.Proto)
+			}
+			if got := conns.Load(); got != 1 {
+				t.Errorf("connections after request = %d, want the preconnected one", got)
+			}
+		})
+	}
+}
+
+func TestPreconnectRejectsPlainOrigins(t *testing.T) {
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tr.Preconnect(context.Background(), "http://example.com"); err == nil {
+		t.Error("want an error preconnecting to an http origin")
+	}
+}
+
+func TestKeepWarm(t *testing.T) {
+	server, conns := countingServer(t)
+
+	spec, err := Chromium(BrandChrome, "137.0.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformWindows, WithBaseTransport(&http.Transport{
+		TLSClientConfig: &utls.Config{InsecureSkipVerify: true},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tr.CloseIdleConnections()
+
+	pool, err := tr.KeepWarm(context.Background(), []string{server.URL}, func(origin string, err error) {
+		t.Errorf("keeping %s warm: %v", origin, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	req, addr, err := preconnectRequest(context.Background(), server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// waitFor waits for the pool to hold the want-th connection
+	waitFor := func(want int32) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			if _, err := tr.h2.ConnPool.GetClientConn(req, addr); err == nil && conns.Load() >= want {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("connections = %d, want %d", conns.Load(), want)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	waitFor(1)
+	// as the idle timeout would
+	tr.CloseIdleConnections()
+	waitFor(2)
+
+	req, err = http.NewRequest(http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if got := conns.Load(); got != 2 {
+		t.Errorf("connections = %d, want the request on the replacement", got)
+	}
+}
@@ -0,0 +1,202 @@
+package mimic
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	utls "github.com/refraction-networking/utls"
+	http "github.com/saucesteals/fhttp"
+)
+
+// App is a social app whose in-app browser a webview spec mimics.
+type App string
+
+const (
+	AppFacebook  App = "facebook"
+	AppInstagram App = "instagram"
+	AppTikTok    App = "tiktok"
+)
+
+// brandAndroidWebView is the brand Android WebView sends in sec-ch-ua.
+const brandAndroidWebView Brand = "Android WebView"
+
+// androidPackages are the package names Android WebView sends in
+// X-Requested-With for each app.
+var androidPackages = map[App]string{
+	AppFacebook:  "com.facebook.katana",
+	AppInstagram: "com.instagram.android",
+	AppTikTok:    "com.zhiliaoapp.musically",
+}
+
+// IOSWebView creates a ClientSpec that mimics app's in-app browser on iOS, a
+// WKWebView that sends Safari's TLS and HTTP/2 fingerprint. AppVersion is the
+// app's version (e.g., "470.0.0.46.107") and iosVersion the iOS version (e.g.,
+// "17.5"). Use it with PlatformIOS.
+//
+// WKWebView's user agent carries the app's suffix in place of Safari's
+// "Version/... Safari/604.1" tokens. Minimum supported iOS version is 16.
+func IOSWebView(app App, appVersion, iosVersion string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
+	_, majorNum, err := parseMajorVersion(iosVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if majorNum < 16 {
+		return nil, &VersionTooOldError{Browser: string(app), Min: 16, Got: majorNum}
+	}
+
+	suffix, err := iosAppSuffix(app, appVersion, iosVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := cfg.newTLSSpec(utls.HelloIOS_14)
+	if err != nil {
+		return nil, fmt.Errorf("%s ios %s: %w", app, iosVersion, err)
+	}
+
+	ua := fmt.Sprintf(
+		"Mozilla/5.0 (iPhone; CPU iPhone OS %s like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Mobile/15E148 %s",
+		strings.ReplaceAll(iosVersion, ".", "_"), suffix,
+	)
+
+	return &ClientSpec{
+		version:      appVersion,
+		http2Options: cfg.http2Options(safariHTTP2Options()),
+		timeouts:     safariTimeouts(),
+		requireSCTs:  true,
+		tlsSpecFor: cfg.tlsSpecFor(func(p Platform) (*tlsSpec, error) {
+			if p != PlatformIOS {
+				return nil, &PlatformError{Browser: string(app), Platform: p}
+			}
+			return ts, nil
+		}),
+		buildHeaders: deviceBuildHeaders(string(app), map[Platform]http.Header{
+			PlatformIOS: {"User-Agent": {ua}},
+		}),
+		fetch: safariFetchHeaders(iosVersion, majorNum),
+
+		quicParameters: safariQUICParameters,
+	}, nil
+}
+
+// iosAppSuffix returns the token app appends to WKWebView's user agent.
+func iosAppSuffix(app App, appVersion, iosVersion string) (string, error) {
+	switch app {
+	case AppFacebook:
+		return fmt.Sprintf(
+			"[FBAN/FBIOS;FBAV/%s;FBBV/0;FBDV/iPhone15,2;FBMD/iPhone;FBSN/iOS;FBSV/%s;FBSS/3;FBID/phone;FBLC/en_US;FBOP/5;FBRV/0]",
+			appVersion, iosVersion,
+		), nil
+	case AppInstagram:
+		return fmt.Sprintf(
+			"Instagram %s (iPhone15,2; iOS %s; en_US; en-US; scale=3.00; 1179x2556; 0)",
+			appVersion, strings.ReplaceAll(iosVersion, ".", "_"),
+		), nil
+	case AppTikTok:
+		return fmt.Sprintf(
+			"musical_ly_%s JsSdk/2.0 NetType/WIFI Channel/App Store ByteLocale/en Region/US isDarkMode/0 WKWebView/1",
+			appVersion,
+		), nil
+	default:
+		return "", fmt.Errorf("app %q: %w", app, ErrUnsupportedVersion)
+	}
+}
+
+// AndroidWebView creates a ClientSpec that mimics app's in-app browser on
+// Android, the system WebView, which is Chromium. AppVersion is the app's
+// version and chromiumVersion the WebView's full Chromium version (e.g.,
+// "137.0.7151.89"). Use it with PlatformAndroid.
+//
+// The WebView marks its user agent with "wv" and "Version/4.0", brands
+// sec-ch-ua as "Android WebView", and names the app's package in
+// X-Requested-With. Minimum supported Chromium version is 100.
+func AndroidWebView(app App, appVersion, chromiumVersion string, opts ...SpecOption) (*ClientSpec, error) {
+	cfg := newSpecConfig(opts)
+
+	majorStr, majorNum, err := parseMajorVersion(chromiumVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if majorNum < 100 {
+		return nil, &VersionTooOldError{Browser: string(app), Min: 100, Got: majorNum}
+	}
+
+	pkg, ok := androidPackages[app]
+	if !ok {
+		return nil, fmt.Errorf("app %q: %w", app, ErrUnsupportedVersion)
+	}
+
+	ts, err := cfg.newTLSSpec(chromiumTLSHelloID(majorNum))
+	if err != nil {
+		return nil, fmt.Errorf("%s android %s: %w", app, chromiumVersion, err)
+	}
+
+	brands := cfg.brands
+	if brands == nil {
+		seed := greaseSeed(majorNum)
+		if cfg.greaseSeed != nil {
+			seed = *cfg.greaseSeed
+		}
+		hint := BrandVersion{Brand: string(brandAndroidWebView), Version: majorStr}
+		brands = clientHintBrands(hint, majorStr, majorNum, seed, cfg.greaseStrategy)
+	}
+
+	ua := fmt.Sprintf(
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8 Build/AP2A.240805.005; wv) AppleWebKit/537.36 (KHTML, like Gecko) Version/4.0 Chrome/%s Mobile Safari/537.36 %s",
+		chromiumVersion, androidAppSuffix(app, appVersion),
+	)
+
+	fetch := chromiumFetchHeaders(majorNum)
+	fetch.navigationOrder = withRequestedWith(fetch.navigationOrder)
+	fetch.fetchOrder = withRequestedWith(fetch.fetchOrder)
+
+	return &ClientSpec{
+		version:      appVersion,
+		http2Options: cfg.http2Options(chromiumHTTP2Options(majorNum)),
+		timeouts:     chromiumTimeouts(),
+		brands:       brands,
+		requireSCTs:  true,
+		tlsSpecFor: cfg.tlsSpecFor(func(p Platform) (*tlsSpec, error) {
+			if p != PlatformAndroid {
+				return nil, &PlatformError{Browser: string(app), Platform: p}
+			}
+			return ts, nil
+		}),
+		buildHeaders: deviceBuildHeaders(string(app), map[Platform]http.Header{
+			PlatformAndroid: {
+				"User-Agent":         {ua},
+				"Sec-Ch-Ua":          {formatBrandList(brands)},
+				"Sec-Ch-Ua-Mobile":   {"?1"},
+				"Sec-Ch-Ua-Platform": {`"Android"`},
+				"X-Requested-With":   {pkg},
+			},
+		}),
+		fetch: fetch,
+
+		quicParameters: chromiumQUICParameters,
+	}, nil
+}
+
+// androidAppSuffix returns the token app appends to the WebView's user agent.
+func androidAppSuffix(app App, appVersion string) string {
+	switch app {
+	case AppFacebook:
+		return fmt.Sprintf("[FB_IAB/FB4A;FBAV/%s;]", appVersion)
+	case AppInstagram:
+		return fmt.Sprintf("Instagram %s Android (34/14; 420dpi; 1080x2400; Google/google; Pixel 8; shiba; shiba; en_US; 0)", appVersion)
+	default: // AppTikTok
+		return fmt.Sprintf("trill_%s JsSdk/1.0 NetType/WIFI Channel/googleplay AppName/musical_ly app_version/%s ByteLocale/en Region/US", strings.ReplaceAll(appVersion, ".", ""), appVersion)
+	}
+}
+
+// withRequestedWith returns order with x-requested-with after accept, where
+// the WebView writes it.
+func withRequestedWith(order []string) []string {
+	i := slices.Index(order, "accept")
+	return slices.Insert(slices.Clone(order), i+1, "x-requested-with")
+}
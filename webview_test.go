@@ -0,0 +1,57 @@
+package mimic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWebViewSpecs(t *testing.T) {
+	ios, err := IOSWebView(AppInstagram, "334.0.4.32.98", "17.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, err := ios.buildHeaders(PlatformIOS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ua := headers.Get("User-Agent")
+	if !strings.Contains(ua, "iPhone OS 17_5") || !strings.Contains(ua, "Mobile/15E148 Instagram 334.0.4.32.98 (") {
+		t.Errorf("want a WKWebView user agent with the Instagram suffix; got %q", ua)
+	}
+	if strings.Contains(ua, "Safari/") {
+		t.Errorf("want no Safari token in a WKWebView user agent; got %q", ua)
+	}
+	if _, err := NewTransport(ios, PlatformMac); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("want ErrUnsupportedPlatform on macOS; got %v", err)
+	}
+
+	android, err := AndroidWebView(AppFacebook, "470.0.0.46.107", "137.0.7151.89")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers, err = android.buildHeaders(PlatformAndroid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua := headers.Get("User-Agent"); !strings.Contains(ua, "; wv) ") || !strings.HasSuffix(ua, "[FB_IAB/FB4A;FBAV/470.0.0.46.107;]") {
+		t.Errorf("want a WebView user agent with the Facebook suffix; got %q", ua)
+	}
+	if got := headers.Get("X-Requested-With"); got != "com.facebook.katana" {
+		t.Errorf("want the app package in X-Requested-With; got %q", got)
+	}
+	if hint := headers.Get("Sec-Ch-Ua"); !strings.Contains(hint, `"Android WebView";v="137"`) {
+		t.Errorf("want the Android WebView brand; got %q", hint)
+	}
+	if headers.Get("Sec-Ch-Ua-Mobile") != "?1" {
+		t.Errorf("want a mobile client hint; got %q", headers.Get("Sec-Ch-Ua-Mobile"))
+	}
+
+	if _, err := AndroidWebView(App("snapchat"), "1.0", "137.0.7151.89"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion for an unknown app; got %v", err)
+	}
+	if _, err := IOSWebView(AppTikTok, "34.5.0", "15.7"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("want ErrUnsupportedVersion before iOS 16; got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package mimic
+
+import "fmt"
+
+// WindowsGeneration is the Windows release a Chromium spec presents on
+// PlatformWindows.
+type WindowsGeneration string
+
+const (
+	Windows7  WindowsGeneration = "7"
+	Windows8  WindowsGeneration = "8"
+	Windows81 WindowsGeneration = "8.1"
+	Windows10 WindowsGeneration = "10"
+	Windows11 WindowsGeneration = "11"
+)
+
+// windowsNTVersions maps each generation to the NT version its user agent
+// names. Windows 11 kept Windows 10's.
+var windowsNTVersions = map[WindowsGeneration]string{
+	Windows7:  "6.1",
+	Windows8:  "6.2",
+	Windows81: "6.3",
+	Windows10: "10.0",
+	Windows11: "10.0",
+}
+
+// WithWindowsGeneration sets the Windows release the browser presents on
+// PlatformWindows, Windows10 by default. Every signal follows it: the user
+// agent names its NT version, which is "10.0" on Windows 11 too, and the
+// sec-ch-ua-platform-version hint names its API contract version, 13 or above
+// only on Windows 11.
+//
+// Chromium reported the API contract version from 95, so Windows11 requires
+// 95 or later, and froze the user agent at "10.0" in 110, so releases before
+// Windows10 require an earlier version. Only applies to Chromium specs.
+func WithWindowsGeneration(g WindowsGeneration) SpecOption {
+	return func(c *specConfig) {
+		c.windowsGeneration = g
+	}
+}
+
+// windowsIdentity is how a Chromium spec presents Windows.
+type windowsIdentity struct {
+	generation WindowsGeneration
+	nt         string
+	// platformVersion is the sec-ch-ua-platform-version value
+	platformVersion string
+}
+
+// resolveWindows returns the Windows identity Chromium at majorNum presents
+// with the generation and NT version set by options, checking that they agree
+// with each other and with the version.
+func resolveWindows(g WindowsGeneration, nt string, version string, majorNum int) (windowsIdentity, error) {
+	if g != "" {
+		gnt, ok := windowsNTVersions[g]
+		if !ok {
+			return windowsIdentity{}, fmt.Errorf("windows %s: %w", g, ErrUnsupportedPlatform)
+		}
+		if nt != "" && nt != gnt {
+			return windowsIdentity{}, fmt.Errorf("windows %s on windows nt %s: %w", g, nt, ErrUnsupportedPlatform)
+		}
+		nt = gnt
+	}
+
+	if nt == "" {
+		nt = "10.0"
+	}
+	if g == "" {
+		for gen, gnt := range windowsNTVersions {
+			if gnt == nt && gen != Windows11 {
+				g = gen
+			}
+		}
+	}
+
+	if nt != "10.0" && majorNum >= 110 {
+		return windowsIdentity{}, fmt.Errorf("chromium %s on windows nt %s: %w", version, nt, ErrUnsupportedVersion)
+	}
+	if g == Windows11 && majorNum < 95 {
+		return windowsIdentity{}, fmt.Errorf("chromium %s on windows 11: %w", version, ErrUnsupportedVersion)
+	}
+
+	id := windowsIdentity{generation: g, nt: nt}
+	switch {
+	case majorNum < 95:
+		// earlier versions sent the NT version, the same for 10 and 11
+		id.platformVersion = nt
+	case g == Windows11:
+		// 22H2 and 23H2
+		id.platformVersion = "15.0.0"
+	case g == Windows10:
+		id.platformVersion = "10.0.0"
+	default:
+		id.platformVersion = "0.0.0"
+	}
+	return id, nil
+}
+
+// WindowsGeneration returns the Windows release the spec presents on
+// PlatformWindows. It reports false for specs other than Chromium's, whose
+// user agent cannot tell Windows 10 from 11, and for NT versions no release
+// is known for.
+func (c *ClientSpec) WindowsGeneration() (WindowsGeneration, bool) {
+	return c.windows, c.windows != ""
+}
+
+// WindowsGeneration returns the Windows release t presents. It reports false
+// when t is not on PlatformWindows or its spec does not tell, see
+// ClientSpec.WindowsGeneration.
+func (t *Transport) WindowsGeneration() (WindowsGeneration, bool) {
+	if t.platform != PlatformWindows {
+		return "", false
+	}
+	return t.spec.WindowsGeneration()
+}
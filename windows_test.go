@@ -0,0 +1,95 @@
+package mimic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWindowsGeneration(t *testing.T) {
+	tests := []struct {
+		version         string
+		opts            []SpecOption
+		want            WindowsGeneration
+		nt              string
+		platformVersion string
+	}{
+		{"137.0.0.0", nil, Windows10, "Windows NT 10.0;", `"10.0.0"`},
+		{"137.0.0.0", []SpecOption{WithWindowsGeneration(Windows11)}, Windows11, "Windows NT 10.0;", `"15.0.0"`},
+		{"137.0.0.0", []SpecOption{WithWindowsGeneration(Windows11), WithWindowsVersion("10.0")}, Windows11, "Windows NT 10.0;", `"15.0.0"`},
+		{"99.0.4844.51", []SpecOption{WithWindowsGeneration(Windows7)}, Windows7, "Windows NT 6.1;", `"0.0.0"`},
+		{"99.0.4844.51", []SpecOption{WithWindowsVersion("6.3")}, Windows81, "Windows NT 6.3;", `"0.0.0"`},
+		{"91.0.4472.124", []SpecOption{WithWindowsVersion("6.1")}, Windows7, "Windows NT 6.1;", `"6.1"`},
+	}
+
+	for _, test := range tests {
+		spec, err := Chromium(BrandChrome, test.version, test.opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tr, err := NewTransport(spec, PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := tr.WindowsGeneration(); !ok || got != test.want {
+			t.Errorf("%s: want windows %s; got %s, %t", test.version, test.want, got, ok)
+		}
+
+		headers, err := spec.buildHeaders(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ua := headers.Get("user-agent"); !strings.Contains(ua, test.nt) {
+			t.Errorf("%s: want %s in the user agent; got %s", test.version, test.nt, ua)
+		}
+
+		hints, err := spec.ClientHints(PlatformWindows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hints.Get("sec-ch-ua-platform-version"); got != test.platformVersion {
+			t.Errorf("%s: want platform version %s; got %s", test.version, test.platformVersion, got)
+		}
+	}
+
+	for name, newSpec := range map[string]func() (*ClientSpec, error){
+		"windows 11 before 95": func() (*ClientSpec, error) {
+			return Chromium(BrandChrome, "94.0.4606.81", WithWindowsGeneration(Windows11))
+		},
+		"windows 7 after the freeze": func() (*ClientSpec, error) {
+			return Chromium(BrandChrome, "120.0.0.0", WithWindowsGeneration(Windows7))
+		},
+		"conflicting nt version": func() (*ClientSpec, error) {
+			return Chromium(BrandChrome, "99.0.4844.51", WithWindowsGeneration(Windows11), WithWindowsVersion("6.1"))
+		},
+		"unknown generation": func() (*ClientSpec, error) {
+			return Chromium(BrandChrome, "137.0.0.0", WithWindowsGeneration("12"))
+		},
+	} {
+		if _, err := newSpec(); !errors.Is(err, ErrUnsupportedVersion) && !errors.Is(err, ErrUnsupportedPlatform) {
+			t.Errorf("%s: want an error; got %v", name, err)
+		}
+	}
+
+	// the user agent of other browsers cannot tell 10 from 11
+	spec, err := Firefox("134.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := spec.WindowsGeneration(); ok {
+		t.Errorf("firefox: want no windows generation; got %s", g)
+	}
+
+	spec, err = Chromium(BrandChrome, "137.0.0.0", WithWindowsGeneration(Windows11))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTransport(spec, PlatformMac)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := tr.WindowsGeneration(); ok {
+		t.Errorf("mac: want no windows generation; got %s", g)
+	}
+}